@@ -0,0 +1,135 @@
+package main
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/masoncfrancis/homelogger/server/internal/database"
+	"gorm.io/gorm"
+)
+
+// apiV2Prefix is the route group of the versioned REST API. The original
+// routes under /api remain as v1.
+const apiV2Prefix = "/api/v2"
+
+// Error codes carried in the v2 error envelope. Clients should branch on the
+// code; messages are for people and may change.
+const (
+	codeInvalidRequest   = "invalid_request"
+	codeValidationFailed = "validation_failed"
+	codeUnauthorized     = "unauthorized"
+	codeForbidden        = "forbidden"
+	codeNotFound         = "not_found"
+	codeConflict         = "conflict"
+	codeUnavailable      = "unavailable"
+	codeInternal         = "internal_error"
+)
+
+// fieldError describes a problem with one body field or query parameter.
+type fieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (e *fieldError) Error() string {
+	return e.Message
+}
+
+// apiError is the body of every v2 error response:
+//
+//	{"error": {"code": "validation_failed", "message": "...", "details": [{"field": "label", "message": "..."}]}}
+type apiError struct {
+	Code    string       `json:"code"`
+	Message string       `json:"message"`
+	Details []fieldError `json:"details,omitempty"`
+}
+
+type errorEnvelope struct {
+	Error apiError `json:"error"`
+}
+
+// isAPIv2 reports whether the request was made against the v2 API.
+func isAPIv2(c fiber.Ctx) bool {
+	path := requestPath(c)
+	return path == apiV2Prefix || strings.HasPrefix(path, apiV2Prefix+"/")
+}
+
+// statusCode returns the default error code for an HTTP status.
+func statusCode(status int) string {
+	switch status {
+	case fiber.StatusBadRequest:
+		return codeInvalidRequest
+	case fiber.StatusUnauthorized:
+		return codeUnauthorized
+	case fiber.StatusForbidden:
+		return codeForbidden
+	case fiber.StatusNotFound:
+		return codeNotFound
+	case fiber.StatusConflict:
+		return codeConflict
+	case fiber.StatusServiceUnavailable:
+		return codeUnavailable
+	default:
+		return codeInternal
+	}
+}
+
+// sendError writes an error response. v2 requests get the JSON envelope; v1
+// requests keep the plain-text body they have always had. A bad request that
+// names the offending fields is reported as validation_failed.
+func sendError(c fiber.Ctx, status int, message string, details ...fieldError) error {
+	if !isAPIv2(c) {
+		return c.Status(status).SendString(message)
+	}
+	code := statusCode(status)
+	if status == fiber.StatusBadRequest && len(details) > 0 {
+		code = codeValidationFailed
+	}
+	return c.Status(status).JSON(errorEnvelope{Error: apiError{Code: code, Message: message, Details: details}})
+}
+
+// sendStoreError reports an error returned by the database package, prefixed
+// with message. Missing records are 404s, rejected field values 400s naming
+// the field, and records still in use 409s; anything else gets status.
+func sendStoreError(c fiber.Ctx, status int, message string, err error) error {
+	var invalid *database.ValidationError
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		status = fiber.StatusNotFound
+	case errors.As(err, &invalid):
+		return sendError(c, fiber.StatusBadRequest, message+": "+err.Error(), fieldError{Field: invalid.Field, Message: invalid.Message})
+	case errors.Is(err, database.ErrInvalidListOptions):
+		status = fiber.StatusBadRequest
+	case errors.Is(err, database.ErrSpaceInUse), errors.Is(err, database.ErrDuplicateSpace),
+		errors.Is(err, database.ErrLocationInUse),
+		errors.Is(err, database.ErrPropertyInUse), errors.Is(err, database.ErrLastProperty),
+		errors.Is(err, database.ErrTrashParentDeleted),
+		errors.Is(err, database.ErrTaskNotRecurring), errors.Is(err, database.ErrTaskClosed),
+		errors.Is(err, database.ErrDuplicateTaskPack), errors.Is(err, database.ErrBuiltInTaskPack),
+		errors.Is(err, database.ErrTaskBlocked), errors.Is(err, database.ErrTaskHasOpenSubtasks):
+		return sendError(c, fiber.StatusConflict, err.Error())
+	}
+	return sendError(c, status, message+": "+err.Error())
+}
+
+// sendQueryError reports a malformed query parameter, naming it when known.
+func sendQueryError(c fiber.Ctx, err error) error {
+	var field *fieldError
+	if errors.As(err, &field) {
+		return sendError(c, fiber.StatusBadRequest, field.Message, *field)
+	}
+	return sendError(c, fiber.StatusBadRequest, err.Error())
+}
+
+// rejectRequest answers a request refused by middleware. v2 clients get the
+// error envelope; v1 clients the {status, message} body they always had.
+func rejectRequest(c fiber.Ctx, status int, legacyStatus, message string) error {
+	if isAPIv2(c) {
+		return sendError(c, status, message)
+	}
+	return c.Status(status).JSON(fiber.Map{
+		"status":  legacyStatus,
+		"message": message,
+	})
+}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"context"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/masoncfrancis/homelogger/server/internal/database"
+	"gorm.io/gorm"
+)
+
+// requestDB returns the connection bound to the request's context, so that
+// changes made through it are audited under the request's user.
+func requestDB(c fiber.Ctx, db func() *gorm.DB) *gorm.DB {
+	return db().WithContext(c.Context())
+}
+
+// systemDB returns db with its changes audited as made by the server itself.
+func systemDB(db *gorm.DB) *gorm.DB {
+	return db.WithContext(database.WithActor(context.Background(), database.SystemActor))
+}
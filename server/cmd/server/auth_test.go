@@ -0,0 +1,346 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/masoncfrancis/homelogger/server/internal/database"
+	"github.com/masoncfrancis/homelogger/server/internal/models"
+	"gorm.io/gorm"
+)
+
+func createAuthTestApp(db *gorm.DB, enabled bool) *fiber.App {
+	cfg := authConfig{Enabled: enabled, SessionTTL: time.Hour}
+	dbFn := func() *gorm.DB { return db }
+
+	app := fiber.New()
+	app.Use(AuthMiddleware(dbFn, cfg))
+
+	api := app.Group("/api")
+	api.Get("/health", func(c fiber.Ctx) error { return c.SendString("ok") })
+	api.Post("/auth/setup", AuthSetupHandler(dbFn, cfg))
+	api.Post("/auth/login", AuthLoginHandler(dbFn, cfg))
+	api.Post("/auth/logout", AuthLogoutHandler(dbFn))
+	api.Get("/auth/me", AuthMeHandler())
+	api.Get("/whoami", func(c fiber.Ctx) error { return c.SendString(requestUserID(c, "1")) })
+	app.Get("/", func(c fiber.Ctx) error { return c.SendString("spa") })
+	return app
+}
+
+// doWithToken sends a request with an optional JSON body and bearer token.
+// Hashing passwords is slow under the race detector, so requests get longer
+// than app.Test's default second.
+func doWithToken(t *testing.T, app *fiber.App, method, path string, body interface{}, token string) (*http.Response, []byte) {
+	t.Helper()
+	var reader io.Reader
+	if body != nil {
+		b, _ := json.Marshal(body)
+		reader = bytes.NewReader(b)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := app.Test(req, fiber.TestConfig{Timeout: 30 * time.Second, FailOnTimeout: true})
+	if err != nil {
+		t.Fatalf("request %s %s: %v", method, path, err)
+	}
+	data, _ := io.ReadAll(resp.Body)
+	return resp, data
+}
+
+func postJSON(t *testing.T, app *fiber.App, path string, body interface{}, token string) (*http.Response, []byte) {
+	t.Helper()
+	return doWithToken(t, app, "POST", path, body, token)
+}
+
+func getWithToken(t *testing.T, app *fiber.App, path, token string) (int, string) {
+	t.Helper()
+	resp, data := doWithToken(t, app, "GET", path, nil, token)
+	return resp.StatusCode, string(data)
+}
+
+func TestAuthDisabledKeepsAPIOpen(t *testing.T) {
+	db := openTestDB(t)
+	app := createAuthTestApp(db, false)
+
+	status, body := getWithToken(t, app, "/api/whoami", "")
+	if status != fiber.StatusOK {
+		t.Fatalf("expected 200 with auth disabled, got %d", status)
+	}
+	if body != "1" {
+		t.Fatalf("expected fallback user ID 1, got %q", body)
+	}
+}
+
+func TestAuthSetupLoginLogout(t *testing.T) {
+	db := openTestDB(t)
+	app := createAuthTestApp(db, true)
+
+	// Anonymous API calls are rejected, public paths and the SPA are not
+	if status, _ := getWithToken(t, app, "/api/whoami", ""); status != fiber.StatusUnauthorized {
+		t.Fatalf("expected 401 before login, got %d", status)
+	}
+	if status, _ := getWithToken(t, app, "/api/health", ""); status != fiber.StatusOK {
+		t.Fatalf("expected health to stay public, got %d", status)
+	}
+	if status, _ := getWithToken(t, app, "/", ""); status != fiber.StatusOK {
+		t.Fatalf("expected SPA to stay public, got %d", status)
+	}
+
+	// First account via setup
+	creds := map[string]string{"username": "alice", "password": "correct-horse"}
+	resp, data := postJSON(t, app, "/api/auth/setup", creds, "")
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("expected 200 on setup, got %d: %s", resp.StatusCode, data)
+	}
+	if !strings.Contains(resp.Header.Get("Set-Cookie"), sessionCookieName+"=") {
+		t.Fatalf("expected session cookie, got %q", resp.Header.Get("Set-Cookie"))
+	}
+
+	// Setup is refused once an account exists
+	resp, _ = postJSON(t, app, "/api/auth/setup", map[string]string{"username": "mallory", "password": "correct-horse"}, "")
+	if resp.StatusCode != fiber.StatusConflict {
+		t.Fatalf("expected 409 on second setup, got %d", resp.StatusCode)
+	}
+
+	// Wrong password
+	resp, _ = postJSON(t, app, "/api/auth/login", map[string]string{"username": "alice", "password": "nope-nope"}, "")
+	if resp.StatusCode != fiber.StatusUnauthorized {
+		t.Fatalf("expected 401 on bad login, got %d", resp.StatusCode)
+	}
+
+	// Login and use the bearer token
+	resp, data = postJSON(t, app, "/api/auth/login", creds, "")
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("expected 200 on login, got %d", resp.StatusCode)
+	}
+	var login struct {
+		Token string `json:"token"`
+		User  struct {
+			ID uint `json:"id"`
+		} `json:"user"`
+	}
+	if err := json.Unmarshal(data, &login); err != nil {
+		t.Fatalf("decode login: %v", err)
+	}
+
+	status, body := getWithToken(t, app, "/api/whoami", login.Token)
+	if status != fiber.StatusOK {
+		t.Fatalf("expected 200 with token, got %d", status)
+	}
+	if body != strconv.FormatUint(uint64(login.User.ID), 10) {
+		t.Fatalf("expected authenticated user ID %d, got %q", login.User.ID, body)
+	}
+
+	if status, _ := getWithToken(t, app, "/api/auth/me", login.Token); status != fiber.StatusOK {
+		t.Fatalf("expected 200 on /auth/me, got %d", status)
+	}
+
+	// Logout revokes the token
+	resp, _ = postJSON(t, app, "/api/auth/logout", nil, login.Token)
+	if resp.StatusCode != fiber.StatusNoContent {
+		t.Fatalf("expected 204 on logout, got %d", resp.StatusCode)
+	}
+	if status, _ := getWithToken(t, app, "/api/whoami", login.Token); status != fiber.StatusUnauthorized {
+		t.Fatalf("expected 401 after logout, got %d", status)
+	}
+}
+
+func TestAuthMiddlewareIgnoresPathCase(t *testing.T) {
+	app := newAuthTestApp(t, newDBProvider(openTestDB(t)))
+
+	for _, path := range []string{"/API/appliances", "/Api/backup/download", "/api/APPLIANCES", "/API/V2/tasks"} {
+		if status, _ := getWithToken(t, app, path, ""); status != fiber.StatusUnauthorized {
+			t.Errorf("GET %s without credentials: expected 401, got %d", path, status)
+		}
+	}
+	if status, _ := getWithToken(t, app, "/API/Health", ""); status != fiber.StatusOK {
+		t.Errorf("expected health to stay public in any case, got %d", status)
+	}
+}
+
+func TestRoleMiddlewareEnforcesRoles(t *testing.T) {
+	db := openTestDB(t)
+	cfg := authConfig{Enabled: true, SessionTTL: time.Hour}
+	dbFn := func() *gorm.DB { return db }
+
+	app := fiber.New()
+	app.Use(AuthMiddleware(dbFn, cfg))
+	app.Use(RoleMiddleware())
+	api := app.Group("/api")
+	api.Get("/health", func(c fiber.Ctx) error { return c.SendString("ok") })
+	api.Get("/appliances", func(c fiber.Ctx) error { return c.SendString("list") })
+	api.Post("/appliances/add", func(c fiber.Ctx) error { return c.SendString("added") })
+	api.Get("/backup/download", func(c fiber.Ctx) error { return c.SendString("zip") })
+
+	tokens := map[string]string{}
+	for _, role := range []string{models.RoleOwner, models.RoleMember, models.RoleViewer} {
+		user, err := database.CreateUser(db, role+"-user", "", "correct-horse", role)
+		if err != nil {
+			t.Fatalf("CreateUser %s: %v", role, err)
+		}
+		token, _, err := database.CreateSession(db, user.ID, time.Hour)
+		if err != nil {
+			t.Fatalf("CreateSession %s: %v", role, err)
+		}
+		tokens[role] = token
+	}
+
+	cases := []struct {
+		role, method, path string
+		want               int
+	}{
+		{models.RoleViewer, "GET", "/api/appliances", fiber.StatusOK},
+		{models.RoleViewer, "POST", "/api/appliances/add", fiber.StatusForbidden},
+		{models.RoleViewer, "GET", "/api/backup/download", fiber.StatusForbidden},
+		{models.RoleMember, "POST", "/api/appliances/add", fiber.StatusOK},
+		{models.RoleMember, "GET", "/api/backup/download", fiber.StatusForbidden},
+		{models.RoleOwner, "GET", "/api/backup/download", fiber.StatusOK},
+		{"", "GET", "/api/health", fiber.StatusOK},
+	}
+	for _, tc := range cases {
+		resp, _ := doWithToken(t, app, tc.method, tc.path, nil, tokens[tc.role])
+		if resp.StatusCode != tc.want {
+			t.Errorf("%s %s as %q: expected %d, got %d", tc.method, tc.path, tc.role, tc.want, resp.StatusCode)
+		}
+	}
+}
+
+func TestRoleMiddlewareIgnoresPathCase(t *testing.T) {
+	db := openTestDB(t)
+	app := newAuthTestApp(t, newDBProvider(db))
+
+	tokens := map[string]string{}
+	for _, role := range []string{models.RoleMember, models.RoleViewer} {
+		user, err := database.CreateUser(db, role+"-user", "", "correct-horse", role)
+		if err != nil {
+			t.Fatalf("CreateUser %s: %v", role, err)
+		}
+		token, _, err := database.CreateSession(db, user.ID, time.Hour)
+		if err != nil {
+			t.Fatalf("CreateSession %s: %v", role, err)
+		}
+		tokens[role] = token
+	}
+
+	cases := []struct {
+		role, method, path string
+	}{
+		{models.RoleViewer, "GET", "/api/Backup/download"},
+		{models.RoleViewer, "POST", "/API/appliances/add"},
+		{models.RoleMember, "GET", "/API/BACKUP/download"},
+		{models.RoleMember, "POST", "/api/Backup/import"},
+		{models.RoleMember, "GET", "/api/Auth/users"},
+		{models.RoleMember, "GET", "/api/Audit"},
+		{models.RoleMember, "GET", "/API/v2/Audit"},
+	}
+	for _, tc := range cases {
+		if resp, _ := doWithToken(t, app, tc.method, tc.path, nil, tokens[tc.role]); resp.StatusCode != fiber.StatusForbidden {
+			t.Errorf("%s %s as %s: expected 403, got %d", tc.method, tc.path, tc.role, resp.StatusCode)
+		}
+	}
+}
+
+func TestMetersScopedTokenPostsReadings(t *testing.T) {
+	db := openTestDB(t)
+	app := newAuthTestApp(t, newDBProvider(db))
+
+	user, err := database.CreateUser(db, "automation", "", "correct-horse", models.RoleMember)
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	token, _, err := database.CreateAPIToken(db, user.ID, "energy monitor", []string{"meters"}, nil)
+	if err != nil {
+		t.Fatalf("CreateAPIToken: %v", err)
+	}
+	appliance, err := database.AddAppliance(db, &models.Appliance{ApplianceName: "Generator"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	meter, err := database.AddMeter(db, &models.Meter{ApplianceID: appliance.ID, Name: "Runtime", Unit: "hours"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, body := postJSON(t, app, fmt.Sprintf("/api/v2/meters/%d/readings", meter.ID), map[string]interface{}{"value": 12.5, "readAt": "2026-03-01"}, token)
+	if resp.StatusCode != fiber.StatusCreated {
+		t.Fatalf("expected the meters scope to post a reading, got %d: %s", resp.StatusCode, body)
+	}
+	if status, _ := getWithToken(t, app, "/api/notes", token); status != fiber.StatusForbidden {
+		t.Fatalf("expected the meters scope to block notes, got %d", status)
+	}
+}
+
+func TestAPITokenAuthentication(t *testing.T) {
+	db := openTestDB(t)
+	cfg := authConfig{Enabled: true, SessionTTL: time.Hour}
+	dbFn := func() *gorm.DB { return db }
+
+	app := fiber.New()
+	app.Use(AuthMiddleware(dbFn, cfg))
+	app.Use(RoleMiddleware())
+	api := app.Group("/api")
+	api.Post("/auth/tokens", APITokenCreateHandler(dbFn))
+	api.Delete("/auth/tokens/:id", APITokenRevokeHandler(dbFn))
+	api.Get("/task", func(c fiber.Ctx) error { return c.SendString(requestUserID(c, "")) })
+	api.Post("/task/add", func(c fiber.Ctx) error { return c.SendString("added") })
+	api.Get("/appliances", func(c fiber.Ctx) error { return c.SendString("list") })
+
+	user, err := database.CreateUser(db, "alice", "", "correct-horse", models.RoleMember)
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	session, _, err := database.CreateSession(db, user.ID, time.Hour)
+	if err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+
+	resp, data := postJSON(t, app, "/api/auth/tokens", map[string]interface{}{"name": "cron", "scopes": []string{"tasks"}}, session)
+	if resp.StatusCode != fiber.StatusCreated {
+		t.Fatalf("expected 201 creating token, got %d: %s", resp.StatusCode, data)
+	}
+	var created struct {
+		Token    string `json:"token"`
+		APIToken struct {
+			ID uint `json:"id"`
+		} `json:"apiToken"`
+	}
+	if err := json.Unmarshal(data, &created); err != nil {
+		t.Fatalf("decode token: %v", err)
+	}
+
+	status, body := getWithToken(t, app, "/api/task", created.Token)
+	if status != fiber.StatusOK || body != strconv.FormatUint(uint64(user.ID), 10) {
+		t.Fatalf("expected token to act as user %d, got %d %q", user.ID, status, body)
+	}
+	if resp, _ := postJSON(t, app, "/api/task/add", map[string]string{"label": "x"}, created.Token); resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("expected tasks scope to allow task writes, got %d", resp.StatusCode)
+	}
+	if status, _ := getWithToken(t, app, "/api/appliances", created.Token); status != fiber.StatusForbidden {
+		t.Fatalf("expected tasks scope to block appliances, got %d", status)
+	}
+	if resp, _ := postJSON(t, app, "/api/auth/tokens", map[string]interface{}{"name": "again", "scopes": []string{"*"}}, created.Token); resp.StatusCode != fiber.StatusForbidden {
+		t.Fatalf("expected scoped token to be unable to mint tokens, got %d", resp.StatusCode)
+	}
+
+	resp, _ = doWithToken(t, app, "DELETE", "/api/auth/tokens/"+strconv.FormatUint(uint64(created.APIToken.ID), 10), nil, session)
+	if resp.StatusCode != fiber.StatusNoContent {
+		t.Fatalf("expected 204 revoking token, got %d", resp.StatusCode)
+	}
+	if status, _ := getWithToken(t, app, "/api/task", created.Token); status != fiber.StatusUnauthorized {
+		t.Fatalf("expected 401 for revoked token, got %d", status)
+	}
+}
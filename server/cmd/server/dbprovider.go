@@ -0,0 +1,64 @@
+package main
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/gofiber/fiber/v3"
+	"gorm.io/gorm"
+)
+
+// dbProvider holds the connection every handler uses. The demo reset replaces
+// it at runtime, so handlers are given p.Get rather than a *gorm.DB.
+//
+// Requests pass through Middleware, which holds a read lock for the whole
+// request. Swap takes the write lock: it waits for in-flight requests to
+// finish, and requests arriving meanwhile wait for the swap, so no request
+// sees its connection closed or changed part-way through.
+type dbProvider struct {
+	current atomic.Pointer[gorm.DB]
+	mu      sync.RWMutex
+}
+
+func newDBProvider(db *gorm.DB) *dbProvider {
+	p := &dbProvider{}
+	p.current.Store(db)
+	return p
+}
+
+// Get returns the current connection. It never blocks.
+func (p *dbProvider) Get() *gorm.DB {
+	return p.current.Load()
+}
+
+// Middleware keeps Swap from running while the request is being handled.
+func (p *dbProvider) Middleware() fiber.Handler {
+	return func(c fiber.Ctx) error {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+		return c.Next()
+	}
+}
+
+// Do runs fn on the current connection, keeping Swap from running until it
+// returns. Background workers use it the way requests use Middleware.
+func (p *dbProvider) Do(fn func(db *gorm.DB) error) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return fn(p.current.Load())
+}
+
+// Swap waits for in-flight requests to drain, then calls replace with the
+// current connection and installs the one it returns, even alongside an
+// error (a reconnected but partly seeded database is still the live one).
+// A nil connection keeps the old one. Swap must not be called from inside a
+// request, which would wait on itself.
+func (p *dbProvider) Swap(replace func(old *gorm.DB) (*gorm.DB, error)) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	next, err := replace(p.current.Load())
+	if next != nil {
+		p.current.Store(next)
+	}
+	return err
+}
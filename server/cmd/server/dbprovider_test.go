@@ -0,0 +1,233 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/gofiber/fiber/v3"
+	"github.com/masoncfrancis/homelogger/server/internal/database"
+	"github.com/masoncfrancis/homelogger/server/internal/models"
+	"gorm.io/gorm"
+)
+
+const sampleDataPath = "../../internal/demo/sample_data.json"
+
+// demoConnect opens the SQLite file the demo reset deletes and recreates.
+func demoConnect(path string) func() (*gorm.DB, error) {
+	return func() (*gorm.DB, error) {
+		return gorm.Open(sqlite.Open(path), &gorm.Config{})
+	}
+}
+
+// testLongRequest is app.Test without the one-second limit: requests made
+// during a swap wait for the demo seed to finish.
+func testLongRequest(app *fiber.App, req *http.Request) (*http.Response, error) {
+	return app.Test(req, fiber.TestConfig{Timeout: 30 * time.Second, FailOnTimeout: true})
+}
+
+func TestDBProviderSwapWaitsForInFlightRequests(t *testing.T) {
+	first, second := openTestDB(t), openTestDB(t)
+	dbs := newDBProvider(first)
+
+	started, release := make(chan struct{}), make(chan struct{})
+	app := fiber.New()
+	app.Use(dbs.Middleware())
+	app.Get("/slow", func(c fiber.Ctx) error {
+		close(started)
+		<-release
+		if dbs.Get() != first {
+			return c.SendStatus(fiber.StatusConflict)
+		}
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	status := make(chan int, 1)
+	go func() {
+		resp, err := testLongRequest(app, httptest.NewRequest("GET", "/slow", nil))
+		if err != nil {
+			t.Errorf("request failed: %v", err)
+			status <- 0
+			return
+		}
+		status <- resp.StatusCode
+	}()
+	<-started
+
+	swapped := make(chan struct{})
+	go func() {
+		_ = dbs.Swap(func(*gorm.DB) (*gorm.DB, error) { return second, nil })
+		close(swapped)
+	}()
+
+	select {
+	case <-swapped:
+		t.Fatal("swap finished while a request was still in flight")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	<-swapped
+
+	if got := <-status; got != fiber.StatusOK {
+		t.Fatalf("in-flight request saw the new connection (status %d)", got)
+	}
+	if dbs.Get() != second {
+		t.Fatal("expected the new connection after the swap")
+	}
+}
+
+func TestDBProviderSwapWaitsForBackgroundWork(t *testing.T) {
+	first, second := openTestDB(t), openTestDB(t)
+	dbs := newDBProvider(first)
+
+	started, release := make(chan struct{}), make(chan struct{})
+	sawFirst := make(chan bool, 1)
+	go func() {
+		_ = dbs.Do(func(db *gorm.DB) error {
+			close(started)
+			<-release
+			sawFirst <- db == first && dbs.Get() == first
+			return nil
+		})
+	}()
+	<-started
+
+	swapped := make(chan struct{})
+	go func() {
+		_ = dbs.Swap(func(*gorm.DB) (*gorm.DB, error) { return second, nil })
+		close(swapped)
+	}()
+
+	select {
+	case <-swapped:
+		t.Fatal("swap finished while background work was still running")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	<-swapped
+
+	if !<-sawFirst {
+		t.Fatal("background work saw the new connection")
+	}
+}
+
+func TestDBProviderSwapErrorKeepsOldConnection(t *testing.T) {
+	db := openTestDB(t)
+	dbs := newDBProvider(db)
+	if err := dbs.Swap(func(*gorm.DB) (*gorm.DB, error) { return nil, gorm.ErrInvalidDB }); err == nil {
+		t.Fatal("expected the replace error to be returned")
+	}
+	if dbs.Get() != db {
+		t.Fatal("a failed swap must keep the old connection")
+	}
+}
+
+// Requests keep succeeding while the demo data is reset underneath them. Run
+// with -race to check the handlers' access to the connection.
+func TestDemoResetUnderLoad(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "demo.db")
+	connect := demoConnect(dbPath)
+	db, err := resetDemo(nil, dbPath, filepath.Join(dir, "demo-uploads"), sampleDataPath, connect)
+	if err != nil {
+		t.Fatalf("initial seed: %v", err)
+	}
+	dbs := newDBProvider(db)
+	app := newTestApp(t, dbs)
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for _, path := range []string{"/api/appliances", "/api/v2/tasks", "/api/notes", "/api/health"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				resp, err := testLongRequest(app, httptest.NewRequest("GET", path, nil))
+				if err != nil {
+					t.Errorf("GET %s: %v", path, err)
+					return
+				}
+				if resp.StatusCode != fiber.StatusOK {
+					t.Errorf("GET %s during reset: status %d", path, resp.StatusCode)
+					return
+				}
+			}
+		}()
+	}
+
+	for range 3 {
+		err := dbs.Swap(func(old *gorm.DB) (*gorm.DB, error) {
+			return resetDemo(old, dbPath, filepath.Join(dir, "demo-uploads"), sampleDataPath, connect)
+		})
+		if err != nil {
+			t.Errorf("reset: %v", err)
+		}
+	}
+	close(stop)
+	wg.Wait()
+
+	var count int64
+	dbs.Get().Model(&models.Appliance{}).Count(&count)
+	if count == 0 {
+		t.Fatal("expected the reseeded demo appliances")
+	}
+}
+
+// A demo reset that arrives mid-import waits for the import to finish rather
+// than closing the connection the import is writing to.
+func TestImportAndDemoResetDoNotOverlap(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "demo.db")
+	connect := demoConnect(dbPath)
+	db, err := resetDemo(nil, dbPath, filepath.Join(dir, "demo-uploads"), sampleDataPath, connect)
+	if err != nil {
+		t.Fatalf("initial seed: %v", err)
+	}
+	dbs := newDBProvider(db)
+	app := newTestApp(t, dbs)
+
+	zipData, filename := createTestBackupZIP(t, &models.BackupPayload{
+		Version:      database.BackupVersion,
+		DatabaseType: db.Dialector.Name(),
+		Entities: models.Entities{
+			Appliances: []models.Appliance{{ApplianceName: "Imported Fridge"}},
+		},
+	})
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		resp, err := testLongRequest(app, multipartRequest("/api/backup/import", zipData, filename))
+		if err != nil {
+			t.Errorf("import: %v", err)
+			return
+		}
+		if resp.StatusCode != fiber.StatusOK {
+			t.Errorf("import during reset: status %d: %s", resp.StatusCode, readBody(resp))
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		err := dbs.Swap(func(old *gorm.DB) (*gorm.DB, error) {
+			return resetDemo(old, dbPath, filepath.Join(dir, "demo-uploads"), sampleDataPath, connect)
+		})
+		if err != nil {
+			t.Errorf("reset: %v", err)
+		}
+	}()
+	wg.Wait()
+
+	if err := dbs.Get().Exec("SELECT 1").Error; err != nil {
+		t.Fatalf("connection unusable after import and reset: %v", err)
+	}
+}
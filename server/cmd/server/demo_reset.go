@@ -0,0 +1,64 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/masoncfrancis/homelogger/server/internal/database"
+	"github.com/masoncfrancis/homelogger/server/internal/demo"
+	"gorm.io/gorm"
+)
+
+// resetDemo throws away the demo database and uploads and seeds a fresh
+// database from seedPath. connect opens the new connection (ConnectGorm in
+// the server). Run it through dbProvider.Swap so that no request is using
+// old when it is closed.
+func resetDemo(old *gorm.DB, dbPath, uploadsDir, seedPath string, connect func() (*gorm.DB, error)) (*gorm.DB, error) {
+	var errs []string
+
+	// Close existing DB connection before replacing the file
+	if old != nil {
+		if sqlDB, err := old.DB(); err == nil {
+			if err2 := sqlDB.Close(); err2 != nil {
+				errs = append(errs, fmt.Sprintf("close db: %v", err2))
+			}
+		}
+	}
+
+	// Remove demo DB file
+	if dbPath != "" {
+		if err := os.Remove(dbPath); err != nil && !os.IsNotExist(err) {
+			errs = append(errs, fmt.Sprintf("remove demo db: %v", err))
+		}
+	}
+
+	// Remove demo uploads folder
+	if err := os.RemoveAll(uploadsDir); err != nil {
+		errs = append(errs, fmt.Sprintf("remove demo uploads: %v", err))
+	}
+
+	// Reconnect and re-seed
+	db, err := connect()
+	if err != nil {
+		errs = append(errs, fmt.Sprintf("connect gorm: %v", err))
+		return nil, errors.New(strings.Join(errs, "; "))
+	}
+	if err := database.MigrateGorm(db); err != nil {
+		errs = append(errs, fmt.Sprintf("migrate gorm: %v", err))
+		return db, errors.New(strings.Join(errs, "; "))
+	}
+	if err := demo.Seed(systemDB(db), seedPath); err != nil {
+		errs = append(errs, fmt.Sprintf("seed demo: %v", err))
+		return db, errors.New(strings.Join(errs, "; "))
+	}
+	if err := database.LogAuditEvent(systemDB(db), database.AuditReset, database.AuditDemo, 0, 0, nil); err != nil {
+		errs = append(errs, err.Error())
+	}
+
+	if len(errs) > 0 {
+		return db, errors.New(strings.Join(errs, "; "))
+	}
+	return db, nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/masoncfrancis/homelogger/server/internal/database"
+	"github.com/masoncfrancis/homelogger/server/internal/extract"
+	"github.com/masoncfrancis/homelogger/server/internal/models"
+	"gorm.io/gorm"
+)
+
+// textExtractorInterval is how often the worker looks for pending files it
+// was not told about, such as files restored from a backup.
+const textExtractorInterval = 5 * time.Minute
+
+// textExtractor pulls text out of uploaded files in the background so uploads
+// return immediately. Work is read from the database rather than queued in
+// memory: each pass processes every pending file, so nothing is lost to a
+// restart or an import.
+type textExtractor struct {
+	dbs  *dbProvider
+	wake chan struct{}
+}
+
+// startTextExtractor starts the extraction worker and schedules a pass over
+// any files still pending.
+func startTextExtractor(dbs *dbProvider) *textExtractor {
+	e := &textExtractor{dbs: dbs, wake: make(chan struct{}, 1)}
+	go func() {
+		ticker := time.NewTicker(textExtractorInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-e.wake:
+			case <-ticker.C:
+			}
+			if err := e.dbs.Do(extractPendingText); err != nil {
+				fmt.Printf("Text extraction failed: %v\n", err)
+			}
+		}
+	}()
+	e.notify()
+	return e
+}
+
+// notify asks the worker for another pass. It never blocks; a pass already
+// scheduled covers newly uploaded files too.
+func (e *textExtractor) notify() {
+	select {
+	case e.wake <- struct{}{}:
+	default:
+	}
+}
+
+// extractPendingText extracts and stores the text of every pending file.
+// Unreadable files are marked failed so they are not retried on every pass.
+func extractPendingText(db *gorm.DB) error {
+	files, err := database.GetFilesPendingText(db)
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		text, err := extract.Text(f.Path, f.OriginalName)
+		status := models.TextStatusDone
+		switch {
+		case errors.Is(err, extract.ErrUnsupported):
+			status = models.TextStatusUnsupported
+		case err != nil:
+			fmt.Printf("Could not extract text from file %d (%s): %v\n", f.ID, f.OriginalName, err)
+			status = models.TextStatusFailed
+		}
+		if err := database.SetExtractedText(db, f.ID, status, text); err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/masoncfrancis/homelogger/server/internal/database"
+	"github.com/masoncfrancis/homelogger/server/internal/models"
+)
+
+func TestExtractPendingText(t *testing.T) {
+	db := openTestDB(t)
+	dir := t.TempDir()
+
+	upload := func(name, content string) uint {
+		t.Helper()
+		f, err := database.UploadFile(db, &models.SavedFile{OriginalName: name, UserID: "1"})
+		if err != nil {
+			t.Fatalf("UploadFile error: %v", err)
+		}
+		f.Path = filepath.Join(dir, name)
+		if err := os.WriteFile(f.Path, []byte(content), 0644); err != nil {
+			t.Fatalf("write upload: %v", err)
+		}
+		if _, err := database.UpdateFilePath(db, f); err != nil {
+			t.Fatalf("UpdateFilePath error: %v", err)
+		}
+		return f.ID
+	}
+	receipt := upload("receipt.txt", "AC install. Compressor warranty: 10 years parts and labor.")
+	photo := upload("photo.jpg", "\xff\xd8\xff\xe0\x00\x10JFIF\x00")
+	broken := upload("manual.docx", "not a zip")
+
+	if info, _ := database.GetFileInfo(db, receipt); info.TextStatus != models.TextStatusPending {
+		t.Fatalf("expected a new upload to be pending, got %+v", info)
+	}
+
+	if err := extractPendingText(db); err != nil {
+		t.Fatalf("extractPendingText error: %v", err)
+	}
+
+	info, err := database.GetFileInfo(db, receipt)
+	if err != nil {
+		t.Fatalf("GetFileInfo error: %v", err)
+	}
+	if info.TextStatus != models.TextStatusDone || info.ExtractedText != "AC install. Compressor warranty: 10 years parts and labor." {
+		t.Fatalf("unexpected extraction result: %+v", info)
+	}
+	if info, _ := database.GetFileInfo(db, photo); info.TextStatus != models.TextStatusUnsupported {
+		t.Fatalf("expected the photo to be unsupported, got %+v", info)
+	}
+	if info, _ := database.GetFileInfo(db, broken); info.TextStatus != models.TextStatusFailed {
+		t.Fatalf("expected the broken document to fail, got %+v", info)
+	}
+	if pending, _ := database.GetFilesPendingText(db); len(pending) != 0 {
+		t.Fatalf("expected nothing left pending, got %+v", pending)
+	}
+
+	results, err := database.Search(db, 0, "compressor warranty", 20)
+	if err != nil {
+		t.Fatalf("Search error: %v", err)
+	}
+	if len(results) != 1 || results[0].Type != "file" || results[0].ID != receipt {
+		t.Fatalf("expected the receipt to be found by its contents, got %+v", results)
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/masoncfrancis/homelogger/server/internal/database"
+	"github.com/masoncfrancis/homelogger/server/internal/models"
+	"gorm.io/gorm"
+)
+
+// ApplianceListHandler lists appliances, optionally limited to one property.
+func ApplianceListHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		propertyID, err := queryPropertyID(c)
+		if err != nil {
+			return sendError(c, fiber.StatusBadRequest, "Invalid propertyId format")
+		}
+		opts, err := queryListOptions(c)
+		if err != nil {
+			return sendError(c, fiber.StatusBadRequest, err.Error())
+		}
+		appliances, err := database.ListAppliances(db(), propertyID, opts)
+		if err != nil {
+			return sendListError(c, "appliances", err)
+		}
+		return sendPage(c, appliances)
+	}
+}
+
+// ApplianceAddHandler creates an appliance.
+func ApplianceAddHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		var body applianceInput
+		if err := c.Bind().Body(&body); err != nil {
+			return sendError(c, fiber.StatusBadRequest, "Error parsing body")
+		}
+		appliance := &models.Appliance{PropertyID: body.PropertyID}
+		body.apply(appliance)
+		created, err := database.AddAppliance(requestDB(c, db), appliance)
+		if err != nil {
+			return sendError(c, fiber.StatusInternalServerError, "Error adding appliance:"+err.Error())
+		}
+		return c.JSON(created)
+	}
+}
+
+// ApplianceGetHandler returns a single appliance.
+func ApplianceGetHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		idUint, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return sendError(c, fiber.StatusBadRequest, "Invalid ID format")
+		}
+		appliance, err := database.GetAppliance(db(), uint(idUint))
+		if err != nil {
+			return sendStoreError(c, fiber.StatusInternalServerError, "Error getting appliance", err)
+		}
+		return c.JSON(appliance)
+	}
+}
+
+// ApplianceUpdateHandler replaces an appliance's details. The property is
+// fixed when the appliance is created.
+func ApplianceUpdateHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		idUint, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return sendError(c, fiber.StatusBadRequest, "Invalid ID format")
+		}
+		var body applianceInput
+		if err := c.Bind().Body(&body); err != nil {
+			return sendError(c, fiber.StatusBadRequest, "Error parsing body")
+		}
+		appliance, err := database.GetAppliance(db(), uint(idUint))
+		if err != nil {
+			return sendStoreError(c, fiber.StatusInternalServerError, "Error getting appliance", err)
+		}
+		body.apply(appliance)
+		updated, err := database.UpdateAppliance(requestDB(c, db), appliance)
+		if err != nil {
+			return sendError(c, fiber.StatusInternalServerError, "Error updating appliance:"+err.Error())
+		}
+		return c.JSON(updated)
+	}
+}
+
+// ApplianceDeleteHandler moves an appliance to the trash.
+func ApplianceDeleteHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		idUint, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return sendError(c, fiber.StatusBadRequest, "Invalid ID format")
+		}
+		if err := database.DeleteAppliance(requestDB(c, db), uint(idUint)); err != nil {
+			return sendError(c, fiber.StatusInternalServerError, "Error deleting appliance:"+err.Error())
+		}
+		return c.SendString("Appliance deleted")
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"github.com/gofiber/fiber/v3"
+	"github.com/masoncfrancis/homelogger/server/internal/database"
+	"gorm.io/gorm"
+)
+
+// AuditListHandler lists the audit log, newest first, filtered by propertyId,
+// actor, action, entityType, entityId and the dateFrom/dateTo day range.
+func AuditListHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		filter := database.AuditFilter{
+			Actor:      c.Query("actor"),
+			Action:     c.Query("action"),
+			EntityType: c.Query("entityType"),
+		}
+		var err error
+		if filter.PropertyID, err = queryPropertyID(c); err != nil {
+			return sendQueryError(c, err)
+		}
+		if filter.EntityID, err = queryUint(c, "entityId"); err != nil {
+			return sendQueryError(c, err)
+		}
+		opts, err := queryListOptions(c)
+		if err != nil {
+			return sendQueryError(c, err)
+		}
+		page, err := database.ListAuditEvents(db(), filter, opts)
+		if err != nil {
+			return sendListError(c, "audit events", err)
+		}
+		return sendPage(c, page)
+	}
+}
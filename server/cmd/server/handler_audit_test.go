@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/masoncfrancis/homelogger/server/internal/database"
+	"github.com/masoncfrancis/homelogger/server/internal/models"
+)
+
+func TestAuditRecordsTheRequestUser(t *testing.T) {
+	db := openTestDB(t)
+	app := newTestApp(t, newDBProvider(db))
+
+	sessionFor := func(username, role string) string {
+		user, err := database.CreateUser(db, username, "", "correct-horse", role)
+		if err != nil {
+			t.Fatal(err)
+		}
+		token, _, err := database.CreateSession(db, user.ID, time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	owner, member := sessionFor("alice", models.RoleOwner), sessionFor("bob", models.RoleMember)
+
+	resp, body := postJSON(t, app, "/api/v2/maintenance", map[string]interface{}{
+		"description": "Furnace filter", "date": "2026-01-10", "cost": 80, "spaceType": "HVAC",
+	}, member)
+	if resp.StatusCode != fiber.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", resp.StatusCode, body)
+	}
+	var record models.Maintenance
+	_ = json.Unmarshal(body, &record)
+	path := fmt.Sprintf("/api/v2/maintenance/%d", record.ID)
+	resp, body = doWithToken(t, app, "PUT", path, map[string]interface{}{
+		"description": "Furnace filter", "date": "2026-01-10", "cost": 95.5,
+	}, member)
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", resp.StatusCode, body)
+	}
+
+	if resp, _ := doWithToken(t, app, "GET", "/api/v2/audit", nil, member); resp.StatusCode != fiber.StatusForbidden {
+		t.Fatalf("expected members to get 403, got %d", resp.StatusCode)
+	}
+
+	resp, body = doWithToken(t, app, "GET", fmt.Sprintf("/api/v2/audit?entityType=maintenance&entityId=%d", record.ID), nil, owner)
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", resp.StatusCode, body)
+	}
+	var page database.Page[models.AuditEvent]
+	if err := json.Unmarshal(body, &page); err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 2 {
+		t.Fatalf("expected a create and an update, got %+v", page.Items)
+	}
+	update := page.Items[0]
+	if update.Action != database.AuditUpdate || update.Actor != "bob" {
+		t.Fatalf("expected bob's update first, got %+v", update)
+	}
+	if len(update.Changes) != 1 || update.Changes["cost"].Old != 80.0 || update.Changes["cost"].New != 95.5 {
+		t.Fatalf("expected only the cost to change, got %+v", update.Changes)
+	}
+}
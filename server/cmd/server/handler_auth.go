@@ -0,0 +1,302 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/masoncfrancis/homelogger/server/internal/database"
+	"github.com/masoncfrancis/homelogger/server/internal/models"
+	"gorm.io/gorm"
+)
+
+const defaultSessionTTL = 30 * 24 * time.Hour
+
+// authConfig holds the AUTH_* settings.
+type authConfig struct {
+	Enabled      bool
+	SessionTTL   time.Duration
+	CookieSecure bool
+}
+
+func envBool(name string) bool {
+	v := strings.ToLower(strings.TrimSpace(os.Getenv(name)))
+	return v == "1" || v == "true"
+}
+
+// authConfigFromEnv reads AUTH_ENABLED, AUTH_SESSION_TTL and AUTH_COOKIE_SECURE.
+func authConfigFromEnv() authConfig {
+	cfg := authConfig{
+		Enabled:      envBool("AUTH_ENABLED"),
+		SessionTTL:   defaultSessionTTL,
+		CookieSecure: envBool("AUTH_COOKIE_SECURE"),
+	}
+	if raw := strings.TrimSpace(os.Getenv("AUTH_SESSION_TTL")); raw != "" {
+		if ttl, err := time.ParseDuration(raw); err == nil && ttl > 0 {
+			cfg.SessionTTL = ttl
+		} else {
+			fmt.Printf("Warning: invalid AUTH_SESSION_TTL %q, using %s\n", raw, defaultSessionTTL)
+		}
+	}
+	return cfg
+}
+
+// sessionSweepInterval is how often expired sessions are deleted.
+const sessionSweepInterval = time.Hour
+
+// startSessionSweeper deletes expired sessions, now and then every
+// sessionSweepInterval. Otherwise a session is only removed when its token is
+// used again, and most never are.
+func startSessionSweeper(dbs *dbProvider) {
+	go func() {
+		ticker := time.NewTicker(sessionSweepInterval)
+		defer ticker.Stop()
+		for {
+			if err := dbs.Do(database.DeleteExpiredSessions); err != nil {
+				fmt.Printf("Deleting expired sessions failed: %v\n", err)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+type credentialsBody struct {
+	Username    string `json:"username"`
+	Password    string `json:"password"`
+	DisplayName string `json:"displayName"`
+	Role        string `json:"role"`
+}
+
+// issueSession creates a session for userID and sets the session cookie.
+func issueSession(c fiber.Ctx, db *gorm.DB, cfg authConfig, userID uint) (string, *models.Session, error) {
+	token, session, err := database.CreateSession(db, userID, cfg.SessionTTL)
+	if err != nil {
+		return "", nil, err
+	}
+	c.Cookie(&fiber.Cookie{
+		Name:     sessionCookieName,
+		Value:    token,
+		Path:     "/",
+		Expires:  session.ExpiresAt,
+		HTTPOnly: true,
+		Secure:   cfg.CookieSecure,
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+	return token, session, nil
+}
+
+// startSession creates a session for userID, sets the session cookie and
+// returns the response body shared by login and setup.
+func startSession(c fiber.Ctx, db *gorm.DB, cfg authConfig, userID uint) error {
+	user, err := database.GetUser(db, userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString("Error loading user: " + err.Error())
+	}
+	token, session, err := issueSession(c, db, cfg, user.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString("Error creating session: " + err.Error())
+	}
+	return c.JSON(fiber.Map{
+		"token":     token,
+		"expiresAt": session.ExpiresAt,
+		"user":      user,
+	})
+}
+
+// AuthSetupHandler creates the first account as the household owner.
+// It is refused once any account exists.
+func AuthSetupHandler(db func() *gorm.DB, cfg authConfig) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		var body credentialsBody
+		if err := c.Bind().Body(&body); err != nil {
+			return c.Status(fiber.StatusBadRequest).SendString("Error parsing body: " + err.Error())
+		}
+
+		conn := db()
+		user, err := database.SetupOwner(conn, body.Username, body.DisplayName, body.Password)
+		if errors.Is(err, database.ErrSetupDone) {
+			return c.Status(fiber.StatusConflict).SendString("Setup has already been completed")
+		}
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).SendString("Error creating user: " + err.Error())
+		}
+		return startSession(c, conn, cfg, user.ID)
+	}
+}
+
+// AuthLoginHandler checks a username and password and starts a session.
+func AuthLoginHandler(db func() *gorm.DB, cfg authConfig) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		var body credentialsBody
+		if err := c.Bind().Body(&body); err != nil {
+			return c.Status(fiber.StatusBadRequest).SendString("Error parsing body: " + err.Error())
+		}
+
+		conn := db()
+		user, err := database.AuthenticateUser(conn, body.Username, body.Password)
+		if err != nil {
+			if errors.Is(err, database.ErrInvalidCredentials) {
+				return c.Status(fiber.StatusUnauthorized).SendString(err.Error())
+			}
+			return c.Status(fiber.StatusInternalServerError).SendString("Error logging in: " + err.Error())
+		}
+		return startSession(c, conn, cfg, user.ID)
+	}
+}
+
+// AuthLogoutHandler ends the current session and clears the cookie.
+func AuthLogoutHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		if token := requestToken(c); token != "" {
+			if err := database.DeleteSession(requestDB(c, db), token); err != nil {
+				return c.Status(fiber.StatusInternalServerError).SendString("Error ending session: " + err.Error())
+			}
+		}
+		c.ClearCookie(sessionCookieName)
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+}
+
+// AuthMeHandler returns the authenticated user.
+func AuthMeHandler() fiber.Handler {
+	return func(c fiber.Ctx) error {
+		user := currentUser(c)
+		if user == nil {
+			return c.Status(fiber.StatusUnauthorized).SendString("Not logged in")
+		}
+		return c.JSON(user)
+	}
+}
+
+// AuthCreateUserHandler adds another household account. Role defaults to member.
+// Access is limited to owners by RoleMiddleware.
+func AuthCreateUserHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		if currentUser(c) == nil {
+			return c.Status(fiber.StatusUnauthorized).SendString("Not logged in")
+		}
+		var body credentialsBody
+		if err := c.Bind().Body(&body); err != nil {
+			return c.Status(fiber.StatusBadRequest).SendString("Error parsing body: " + err.Error())
+		}
+		if body.Role == "" {
+			body.Role = models.RoleMember
+		}
+		user, err := database.CreateUser(requestDB(c, db), body.Username, body.DisplayName, body.Password, body.Role)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).SendString("Error creating user: " + err.Error())
+		}
+		return c.Status(fiber.StatusCreated).JSON(user)
+	}
+}
+
+// AuthListUsersHandler lists every household account.
+func AuthListUsersHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		users, err := database.ListUsers(db())
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).SendString("Error listing users: " + err.Error())
+		}
+		return c.JSON(users)
+	}
+}
+
+// AuthUpdateRoleHandler changes a household member's role.
+func AuthUpdateRoleHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		idUint, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).SendString("Invalid ID format")
+		}
+		var body struct {
+			Role string `json:"role"`
+		}
+		if err := c.Bind().Body(&body); err != nil {
+			return c.Status(fiber.StatusBadRequest).SendString("Error parsing body: " + err.Error())
+		}
+		user, err := database.UpdateUserRole(requestDB(c, db), uint(idUint), body.Role)
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return c.Status(fiber.StatusNotFound).SendString("User not found")
+		case errors.Is(err, database.ErrLastOwner):
+			return c.Status(fiber.StatusConflict).SendString(err.Error())
+		case err != nil:
+			return c.Status(fiber.StatusBadRequest).SendString("Error updating role: " + err.Error())
+		}
+		return c.JSON(user)
+	}
+}
+
+// APITokenCreateHandler creates a personal API token for the current user.
+// The raw token is only shown in this response.
+func APITokenCreateHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		user := currentUser(c)
+		if user == nil {
+			return c.Status(fiber.StatusUnauthorized).SendString("Not logged in")
+		}
+		var body struct {
+			Name          string   `json:"name"`
+			Scopes        []string `json:"scopes"`
+			ExpiresInDays int      `json:"expiresInDays"`
+		}
+		if err := c.Bind().Body(&body); err != nil {
+			return c.Status(fiber.StatusBadRequest).SendString("Error parsing body: " + err.Error())
+		}
+
+		var expiresAt *time.Time
+		if body.ExpiresInDays > 0 {
+			t := time.Now().AddDate(0, 0, body.ExpiresInDays)
+			expiresAt = &t
+		}
+
+		token, apiToken, err := database.CreateAPIToken(requestDB(c, db), user.ID, body.Name, body.Scopes, expiresAt)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).SendString("Error creating API token: " + err.Error())
+		}
+		return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+			"token":    token,
+			"apiToken": apiToken,
+		})
+	}
+}
+
+// APITokenListHandler lists the current user's personal API tokens.
+func APITokenListHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		user := currentUser(c)
+		if user == nil {
+			return c.Status(fiber.StatusUnauthorized).SendString("Not logged in")
+		}
+		tokens, err := database.ListAPITokens(db(), user.ID)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).SendString("Error listing API tokens: " + err.Error())
+		}
+		return c.JSON(tokens)
+	}
+}
+
+// APITokenRevokeHandler revokes one of the current user's personal API tokens.
+func APITokenRevokeHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		user := currentUser(c)
+		if user == nil {
+			return c.Status(fiber.StatusUnauthorized).SendString("Not logged in")
+		}
+		idUint, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).SendString("Invalid ID format")
+		}
+		if err := database.RevokeAPIToken(requestDB(c, db), user.ID, uint(idUint)); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return c.Status(fiber.StatusNotFound).SendString("API token not found")
+			}
+			return c.Status(fiber.StatusInternalServerError).SendString("Error revoking API token: " + err.Error())
+		}
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/masoncfrancis/homelogger/server/internal/database"
+	"gorm.io/gorm"
+)
+
+// BackupDownloadHandler streams a ZIP with every record as data.json and the
+// files under uploadsRoot in uploads/. backupMu keeps an import from running
+// while the archive is written.
+func BackupDownloadHandler(db func() *gorm.DB, uploadsRoot string, backupMu *sync.Mutex) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		pr, pw := io.Pipe()
+
+		go func() {
+			zw := zip.NewWriter(pw)
+			defer func() {
+				_ = zw.Close()
+				_ = pw.Close()
+			}()
+
+			backupMu.Lock()
+			defer backupMu.Unlock()
+
+			// note: Universal JSON export — works on any GORM dialect, no raw dump needed.
+			conn := db()
+			payload, err := database.ExportToJSON(conn, conn.Dialector.Name())
+			if err != nil {
+				_ = pw.CloseWithError(fmt.Errorf("export data: %w", err))
+				return
+			}
+
+			jsonData, err := json.Marshal(payload)
+			if err != nil {
+				_ = pw.CloseWithError(fmt.Errorf("marshal payload: %w", err))
+				return
+			}
+
+			w, err := zw.Create("data.json")
+			if err != nil {
+				_ = pw.CloseWithError(fmt.Errorf("zip entry data.json: %w", err))
+				return
+			}
+			if _, err := w.Write(jsonData); err != nil {
+				_ = pw.CloseWithError(fmt.Errorf("write data.json: %w", err))
+				return
+			}
+
+			_ = filepath.Walk(uploadsRoot, func(path string, info os.FileInfo, err error) error {
+				if err != nil {
+					return err
+				}
+				if info.IsDir() {
+					// files being deleted are not part of the backup
+					if info.Name() == database.FileTrashDir {
+						return filepath.SkipDir
+					}
+					return nil
+				}
+				rel, err := filepath.Rel(uploadsRoot, path)
+				if err != nil {
+					return err
+				}
+				f, err := os.Open(path)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				dst, err := zw.Create(filepath.ToSlash(filepath.Join("uploads", rel)))
+				if err != nil {
+					return err
+				}
+				_, err = io.Copy(dst, f)
+				return err
+			})
+		}()
+
+		c.Set("Content-Type", "application/zip")
+		c.Set("Content-Disposition", "attachment; filename=homelogger-backup.zip")
+		return c.SendStream(pr)
+	}
+}
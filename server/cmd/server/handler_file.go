@@ -0,0 +1,275 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/masoncfrancis/homelogger/server/internal/database"
+	"github.com/masoncfrancis/homelogger/server/internal/models"
+	"gorm.io/gorm"
+)
+
+// FileUploadHandler stores a multipart upload (field "file") under uploadsDir,
+// named by its ID, and queues it for text extraction. The optional
+// propertyId, spaceId, spaceType and locationId form values place the file.
+func FileUploadHandler(db func() *gorm.DB, uploadsDir string, extractor *textExtractor) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		form, err := c.MultipartForm()
+		if err != nil {
+			return sendError(c, fiber.StatusBadRequest, "Error parsing multipart form: "+err.Error())
+		}
+
+		// Get the file and userID. The authenticated user wins over the form value.
+		files := form.File["file"]
+		formUserID := ""
+		if vals, ok := form.Value["userID"]; ok && len(vals) > 0 {
+			formUserID = vals[0]
+		}
+		userID := requestUserID(c, formUserID)
+
+		// optional spaceId, or spaceType to find the space by name
+		spaceType := ""
+		if vals, ok := form.Value["spaceType"]; ok && len(vals) > 0 {
+			spaceType = vals[0]
+		}
+		var spaceID *uint
+		if vals, ok := form.Value["spaceId"]; ok && len(vals) > 0 && vals[0] != "" {
+			idUint, err := strconv.ParseUint(vals[0], 10, 32)
+			if err != nil {
+				return sendError(c, fiber.StatusBadRequest, "Invalid spaceId format")
+			}
+			id := uint(idUint)
+			spaceID = &id
+		}
+
+		// optional locationId
+		var locationID *uint
+		if vals, ok := form.Value["locationId"]; ok && len(vals) > 0 && vals[0] != "" {
+			idUint, err := strconv.ParseUint(vals[0], 10, 32)
+			if err != nil {
+				return sendError(c, fiber.StatusBadRequest, "Invalid locationId format")
+			}
+			id := uint(idUint)
+			locationID = &id
+		}
+
+		// optional propertyId; defaults to the first property
+		var propertyID uint
+		if vals, ok := form.Value["propertyId"]; ok && len(vals) > 0 && vals[0] != "" {
+			idUint, err := strconv.ParseUint(vals[0], 10, 32)
+			if err != nil {
+				return sendError(c, fiber.StatusBadRequest, "Invalid propertyId format")
+			}
+			propertyID = uint(idUint)
+		}
+
+		if len(files) == 0 || userID == "" {
+			return sendError(c, fiber.StatusBadRequest, "Missing file or userID")
+		}
+
+		file := files[0]
+		savedFile := &models.SavedFile{
+			PropertyID:   propertyID,
+			OriginalName: file.Filename,
+			UserID:       userID,
+			SpaceID:      spaceID,
+			LocationID:   locationID,
+		}
+		if spaceType != "" {
+			savedFile.SpaceType = &spaceType
+		}
+
+		newFile, err := database.UploadFile(requestDB(c, db), savedFile)
+		if err != nil {
+			return sendError(c, fiber.StatusInternalServerError, "Error saving file information: "+err.Error())
+		}
+
+		// The stored file is named by its ID
+		if err := os.MkdirAll(uploadsDir, 0755); err != nil {
+			return sendError(c, fiber.StatusInternalServerError, "Error creating uploads directory: "+err.Error())
+		}
+		filePath := filepath.Join(uploadsDir, strconv.FormatUint(uint64(newFile.ID), 10))
+		if err := c.SaveFile(file, filePath); err != nil {
+			return sendError(c, fiber.StatusInternalServerError, "Error saving file: "+err.Error())
+		}
+
+		newFile.Path = filePath
+		if _, err := database.UpdateFilePath(requestDB(c, db), newFile); err != nil {
+			return sendError(c, fiber.StatusInternalServerError, "Error updating file path: "+err.Error())
+		}
+		extractor.notify()
+
+		return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+			"id":           newFile.ID,
+			"originalName": newFile.OriginalName,
+			"userID":       newFile.UserID,
+		})
+	}
+}
+
+// FileInfoHandler returns a file's metadata, including any text extracted
+// from it.
+func FileInfoHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		idUint, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return sendError(c, fiber.StatusBadRequest, "Invalid ID format")
+		}
+		fileInfo, err := database.GetFileInfo(db(), uint(idUint))
+		if err != nil {
+			return sendError(c, fiber.StatusNotFound, "File not found: "+err.Error())
+		}
+		return c.JSON(fileInfo)
+	}
+}
+
+// FileDownloadHandler sends a stored file under its original name.
+func FileDownloadHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		idUint, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return sendError(c, fiber.StatusBadRequest, "Invalid ID format")
+		}
+		fileInfo, err := database.GetFileInfo(db(), uint(idUint))
+		if err != nil {
+			return sendError(c, fiber.StatusNotFound, "File not found: "+err.Error())
+		}
+		filePath, err := database.GetFilePath(db(), uint(idUint))
+		if err != nil {
+			return sendError(c, fiber.StatusNotFound, "File path not found: "+err.Error())
+		}
+		c.Set("Content-Disposition", "attachment; filename="+fileInfo.OriginalName)
+		return c.SendFile(filePath)
+	}
+}
+
+// fileListHandler lists the files matching the filter built from the :id
+// route parameter.
+func fileListHandler(db func() *gorm.DB, filter func(id uint) database.FileFilter) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		idUint, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return sendError(c, fiber.StatusBadRequest, "Invalid ID format")
+		}
+		opts, err := queryListOptions(c)
+		if err != nil {
+			return sendError(c, fiber.StatusBadRequest, err.Error())
+		}
+		files, err := database.ListFiles(db(), filter(uint(idUint)), opts)
+		if err != nil {
+			return sendListError(c, "files", err)
+		}
+		return sendPage(c, files)
+	}
+}
+
+// MaintenanceFilesHandler lists the files attached to a maintenance record.
+func MaintenanceFilesHandler(db func() *gorm.DB) fiber.Handler {
+	return fileListHandler(db, func(id uint) database.FileFilter { return database.FileFilter{MaintenanceID: id} })
+}
+
+// RepairFilesHandler lists the files attached to a repair record.
+func RepairFilesHandler(db func() *gorm.DB) fiber.Handler {
+	return fileListHandler(db, func(id uint) database.FileFilter { return database.FileFilter{RepairID: id} })
+}
+
+// ApplianceFilesHandler lists the files attached to an appliance.
+func ApplianceFilesHandler(db func() *gorm.DB) fiber.Handler {
+	return fileListHandler(db, func(id uint) database.FileFilter { return database.FileFilter{ApplianceID: id} })
+}
+
+// SpaceTypeFilesHandler lists the files attached to a space, found by name.
+// See SpaceFilesHandler for lookup by ID.
+func SpaceTypeFilesHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		spaceType := c.Params("spaceType")
+		if spaceType == "" {
+			return sendError(c, fiber.StatusBadRequest, "Missing spaceType")
+		}
+		propertyID, err := queryPropertyID(c)
+		if err != nil {
+			return sendError(c, fiber.StatusBadRequest, "Invalid propertyId format")
+		}
+		opts, err := queryListOptions(c)
+		if err != nil {
+			return sendError(c, fiber.StatusBadRequest, err.Error())
+		}
+
+		space, err := database.GetSpaceByName(db(), propertyID, spaceType)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON([]database.FileInfoResponse{})
+		}
+		if err != nil {
+			return sendError(c, fiber.StatusInternalServerError, "Error getting files: "+err.Error())
+		}
+
+		files, err := database.ListFiles(db(), database.FileFilter{SpaceID: space.ID}, opts)
+		if err != nil {
+			return sendListError(c, "files", err)
+		}
+		return sendPage(c, files)
+	}
+}
+
+// FileAttachHandler links an uploaded file to any of the maintenance record,
+// repair, appliance, space or location named in the body.
+func FileAttachHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		var body struct {
+			FileID uint `json:"fileId"`
+			fileAttachInput
+		}
+		if err := c.Bind().Body(&body); err != nil {
+			return sendError(c, fiber.StatusBadRequest, "Error parsing body: "+err.Error())
+		}
+
+		if body.MaintenanceID != 0 {
+			if err := database.AttachFileToMaintenance(requestDB(c, db), body.FileID, body.MaintenanceID); err != nil {
+				return sendError(c, fiber.StatusInternalServerError, "Error attaching file: "+err.Error())
+			}
+		}
+		if body.RepairID != 0 {
+			if err := database.AttachFileToRepair(requestDB(c, db), body.FileID, body.RepairID); err != nil {
+				return sendError(c, fiber.StatusInternalServerError, "Error attaching file: "+err.Error())
+			}
+		}
+		if body.ApplianceID != 0 {
+			if err := database.AttachFileToAppliance(requestDB(c, db), body.FileID, body.ApplianceID); err != nil {
+				return sendError(c, fiber.StatusInternalServerError, "Error attaching file: "+err.Error())
+			}
+		}
+		if body.SpaceID != 0 || body.SpaceType != "" {
+			if err := database.AttachFileToSpace(requestDB(c, db), body.FileID, body.SpaceID, body.SpaceType); err != nil {
+				return sendError(c, fiber.StatusInternalServerError, "Error attaching file to space: "+err.Error())
+			}
+		}
+		if body.LocationID != 0 {
+			if err := database.AttachFileToLocation(requestDB(c, db), body.FileID, body.LocationID); err != nil {
+				return sendError(c, fiber.StatusInternalServerError, "Error attaching file to location: "+err.Error())
+			}
+		}
+
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+}
+
+// FileDeleteHandler moves a file to the trash. Its stored content is kept
+// until the file is purged.
+func FileDeleteHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		idUint, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return sendError(c, fiber.StatusBadRequest, "Invalid ID format")
+		}
+		if _, err := database.GetFilePath(db(), uint(idUint)); err != nil {
+			return sendError(c, fiber.StatusNotFound, "File path not found: "+err.Error())
+		}
+		if err := database.DeleteFile(requestDB(c, db), uint(idUint)); err != nil {
+			return sendError(c, fiber.StatusInternalServerError, "Error deleting file record: "+err.Error())
+		}
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+}
@@ -18,7 +18,7 @@ import (
 	"gorm.io/gorm"
 )
 
-func ImportBackupHandler(db *gorm.DB, importing *atomic.Bool, backupMu *sync.Mutex) fiber.Handler {
+func ImportBackupHandler(db func() *gorm.DB, importing *atomic.Bool, backupMu *sync.Mutex) fiber.Handler {
 	return func(c fiber.Ctx) error {
 		backupMu.Lock()
 		defer backupMu.Unlock()
@@ -26,7 +26,8 @@ func ImportBackupHandler(db *gorm.DB, importing *atomic.Bool, backupMu *sync.Mut
 		importing.Store(true)
 		defer importing.Store(false)
 
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+		// c.Context() carries the user the import is audited under
+		ctx, cancel := context.WithTimeout(c.Context(), 5*time.Minute)
 		defer cancel()
 
 		file, err := c.FormFile("backup")
@@ -134,7 +135,8 @@ func ImportBackupHandler(db *gorm.DB, importing *atomic.Bool, backupMu *sync.Mut
 			})
 		}
 
-		dbCtx := db.WithContext(ctx)
+		conn := db()
+		dbCtx := conn.WithContext(ctx)
 		var importResult *models.ImportResult
 		switch {
 		case dataJSONPath != "":
@@ -177,7 +179,7 @@ func ImportBackupHandler(db *gorm.DB, importing *atomic.Bool, backupMu *sync.Mut
 		}
 
 		if err := ctx.Err(); err != nil {
-			database.FailImport(db, importResult.ImportID, "import timed out after database import")
+			database.FailImport(conn, importResult.ImportID, "import timed out after database import")
 			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
 				"status":   "failed",
 				"importId": importResult.ImportID,
@@ -186,7 +188,7 @@ func ImportBackupHandler(db *gorm.DB, importing *atomic.Bool, backupMu *sync.Mut
 		}
 
 		if err := database.ImportUploads(uploadsExtractedPath); err != nil {
-			database.FailImport(db, importResult.ImportID, err.Error())
+			database.FailImport(conn, importResult.ImportID, err.Error())
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 				"status":   "failed",
 				"importId": importResult.ImportID,
@@ -195,7 +197,7 @@ func ImportBackupHandler(db *gorm.DB, importing *atomic.Bool, backupMu *sync.Mut
 			})
 		}
 
-		database.CompleteImport(db, importResult.ImportID)
+		database.CompleteImport(conn, importResult.ImportID)
 		return c.JSON(fiber.Map{
 			"status":   "completed",
 			"importId": importResult.ImportID,
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/masoncfrancis/homelogger/server/internal/database"
+)
+
+// List responses stay plain JSON arrays; the paging details travel in headers.
+const (
+	headerTotalCount = "X-Total-Count"
+	headerNextCursor = "X-Next-Cursor"
+)
+
+// queryListOptions reads the paging, sorting and filtering parameters shared
+// by every list endpoint: limit, offset, cursor, sort, order, dateFrom,
+// dateTo, costMin, costMax and assigneeId. Whether a list supports a given
+// sort or filter is checked by the database package.
+func queryListOptions(c fiber.Ctx) (database.ListOptions, error) {
+	opts := database.ListOptions{
+		Cursor:   c.Query("cursor"),
+		Sort:     c.Query("sort"),
+		Order:    c.Query("order"),
+		DateFrom: c.Query("dateFrom"),
+		DateTo:   c.Query("dateTo"),
+	}
+	for name, dst := range map[string]*int{"limit": &opts.Limit, "offset": &opts.Offset} {
+		if raw := c.Query(name); raw != "" {
+			n, err := strconv.Atoi(raw)
+			if err != nil {
+				return opts, &fieldError{Field: name, Message: "invalid " + name + " format"}
+			}
+			*dst = n
+		}
+	}
+	for name, dst := range map[string]**float64{"costMin": &opts.CostMin, "costMax": &opts.CostMax} {
+		if raw := c.Query(name); raw != "" {
+			f, err := strconv.ParseFloat(raw, 64)
+			if err != nil {
+				return opts, &fieldError{Field: name, Message: "invalid " + name + " format"}
+			}
+			*dst = &f
+		}
+	}
+	if raw := c.Query("assigneeId"); raw != "" {
+		id, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil {
+			return opts, &fieldError{Field: "assigneeId", Message: "invalid assigneeId format"}
+		}
+		opts.AssigneeID = uint(id)
+	}
+	return opts, nil
+}
+
+// sendPage writes a page of a list. v1 responses are the bare JSON array of
+// items, with the total count and the cursor for the next page in the
+// X-Total-Count and X-Next-Cursor headers; v2 responses are the page object
+// itself, {items, total, nextCursor}.
+func sendPage[T any](c fiber.Ctx, page *database.Page[T]) error {
+	c.Set(headerTotalCount, strconv.FormatInt(page.Total, 10))
+	if page.NextCursor != "" {
+		c.Set(headerNextCursor, page.NextCursor)
+	}
+	if isAPIv2(c) {
+		return c.JSON(page)
+	}
+	return c.JSON(page.Items)
+}
+
+// sendListError reports a failed list query: 400 for options the list does
+// not support, 500 otherwise.
+func sendListError(c fiber.Ctx, what string, err error) error {
+	if errors.Is(err, database.ErrInvalidListOptions) {
+		return sendError(c, fiber.StatusBadRequest, err.Error())
+	}
+	return sendStoreError(c, fiber.StatusInternalServerError, "Error getting "+what, err)
+}
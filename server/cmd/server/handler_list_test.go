@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/masoncfrancis/homelogger/server/internal/database"
+	"github.com/masoncfrancis/homelogger/server/internal/models"
+	"gorm.io/gorm"
+)
+
+func TestListEndpointPagingHeaders(t *testing.T) {
+	db := openTestDB(t)
+	space, err := database.AddSpace(db, 0, "Garage")
+	if err != nil {
+		t.Fatalf("AddSpace error: %v", err)
+	}
+	for i := range 3 {
+		if _, err := database.UploadFile(db, &models.SavedFile{OriginalName: fmt.Sprintf("photo-%d.jpg", i), UserID: "1", SpaceID: &space.ID}); err != nil {
+			t.Fatalf("UploadFile error: %v", err)
+		}
+	}
+	app := fiber.New()
+	app.Get("/api/spaces/:id/files", SpaceFilesHandler(func() *gorm.DB { return db }))
+	url := fmt.Sprintf("/api/spaces/%d/files", space.ID)
+
+	resp, err := app.Test(httptest.NewRequest("GET", url+"?limit=2&sort=originalName&order=desc", nil))
+	if err != nil || resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("expected 200, got %v %v", resp, err)
+	}
+	var files []database.FileInfoResponse
+	if err := json.NewDecoder(resp.Body).Decode(&files); err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if len(files) != 2 || files[0].OriginalName != "photo-2.jpg" {
+		t.Fatalf("unexpected first page: %+v", files)
+	}
+	if total := resp.Header.Get(headerTotalCount); total != "3" {
+		t.Fatalf("expected %s 3, got %q", headerTotalCount, total)
+	}
+	cursor := resp.Header.Get(headerNextCursor)
+	if cursor == "" {
+		t.Fatalf("expected a next cursor")
+	}
+
+	resp, _ = app.Test(httptest.NewRequest("GET", url+"?limit=2&sort=originalName&order=desc&cursor="+cursor, nil))
+	files = nil
+	if err := json.NewDecoder(resp.Body).Decode(&files); err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if len(files) != 1 || files[0].OriginalName != "photo-0.jpg" || resp.Header.Get(headerNextCursor) != "" {
+		t.Fatalf("unexpected last page: %+v", files)
+	}
+
+	for _, query := range []string{"?limit=many", "?sort=path", "?costMin=5"} {
+		resp, _ = app.Test(httptest.NewRequest("GET", url+query, nil))
+		if resp.StatusCode != fiber.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, resp.StatusCode)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/masoncfrancis/homelogger/server/internal/database"
+	"github.com/masoncfrancis/homelogger/server/internal/models"
+	"gorm.io/gorm"
+)
+
+type locationBody struct {
+	PropertyID uint   `json:"propertyId"`
+	ParentID   *uint  `json:"parentId"`
+	Kind       string `json:"kind"`
+	Name       string `json:"name"`
+}
+
+// LocationListHandler lists locations as a flat list, optionally limited to one property.
+func LocationListHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		propertyID, err := queryPropertyID(c)
+		if err != nil {
+			return sendError(c, fiber.StatusBadRequest, "Invalid propertyId format")
+		}
+		locations, err := database.GetLocations(db(), propertyID)
+		if err != nil {
+			return sendStoreError(c, fiber.StatusInternalServerError, "Error getting locations", err)
+		}
+		return c.JSON(locations)
+	}
+}
+
+// LocationTreeHandler returns the location tree, optionally limited to one property.
+func LocationTreeHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		propertyID, err := queryPropertyID(c)
+		if err != nil {
+			return sendError(c, fiber.StatusBadRequest, "Invalid propertyId format")
+		}
+		tree, err := database.GetLocationTree(db(), propertyID)
+		if err != nil {
+			return sendStoreError(c, fiber.StatusInternalServerError, "Error getting locations", err)
+		}
+		return c.JSON(tree)
+	}
+}
+
+// LocationAddHandler creates a floor, room or zone.
+func LocationAddHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		var body locationBody
+		if err := c.Bind().Body(&body); err != nil {
+			return sendStoreError(c, fiber.StatusBadRequest, "Error parsing body", err)
+		}
+		location, err := database.AddLocation(requestDB(c, db), &models.Location{
+			PropertyID: body.PropertyID,
+			ParentID:   body.ParentID,
+			Kind:       body.Kind,
+			Name:       body.Name,
+		})
+		if err != nil {
+			return sendStoreError(c, fiber.StatusBadRequest, "Error adding location", err)
+		}
+		return c.Status(fiber.StatusCreated).JSON(location)
+	}
+}
+
+// LocationGetHandler returns a single location.
+func LocationGetHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		idUint, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return sendError(c, fiber.StatusBadRequest, "Invalid ID format")
+		}
+		location, err := database.GetLocation(db(), uint(idUint))
+		if err != nil {
+			return sendStoreError(c, fiber.StatusNotFound, "Location not found", err)
+		}
+		return c.JSON(location)
+	}
+}
+
+// LocationUpdateHandler renames or moves a location.
+func LocationUpdateHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		idUint, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return sendError(c, fiber.StatusBadRequest, "Invalid ID format")
+		}
+		var body locationBody
+		if err := c.Bind().Body(&body); err != nil {
+			return sendStoreError(c, fiber.StatusBadRequest, "Error parsing body", err)
+		}
+		location, err := database.UpdateLocation(requestDB(c, db), uint(idUint), body.Name, body.Kind, body.ParentID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return sendError(c, fiber.StatusNotFound, "Location not found")
+			}
+			return sendStoreError(c, fiber.StatusBadRequest, "Error updating location", err)
+		}
+		return c.JSON(location)
+	}
+}
+
+// LocationDeleteHandler deletes a location with nothing in it.
+func LocationDeleteHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		idUint, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return sendError(c, fiber.StatusBadRequest, "Invalid ID format")
+		}
+		err = database.DeleteLocation(requestDB(c, db), uint(idUint))
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return sendError(c, fiber.StatusNotFound, "Location not found")
+		case errors.Is(err, database.ErrLocationInUse):
+			return sendError(c, fiber.StatusConflict, err.Error())
+		case err != nil:
+			return sendStoreError(c, fiber.StatusInternalServerError, "Error deleting location", err)
+		}
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+}
+
+// locationSubtreeHandler wraps a subtree query, mapping an unknown location to 404.
+func locationSubtreeHandler(db func() *gorm.DB, what string, query func(c fiber.Ctx, db *gorm.DB, id uint) (any, error)) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		idUint, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return sendError(c, fiber.StatusBadRequest, "Invalid ID format")
+		}
+		result, err := query(c, db(), uint(idUint))
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return sendError(c, fiber.StatusNotFound, "Location not found")
+			}
+			return sendStoreError(c, fiber.StatusInternalServerError, "Error getting "+what, err)
+		}
+		return c.JSON(result)
+	}
+}
+
+// LocationAppliancesHandler lists the appliances anywhere under a location.
+// With ?history=true each appliance carries its maintenance and repair records.
+func LocationAppliancesHandler(db func() *gorm.DB) fiber.Handler {
+	return locationSubtreeHandler(db, "appliances", func(c fiber.Ctx, db *gorm.DB, id uint) (any, error) {
+		return database.GetAppliancesInLocation(db, id, fiber.Query[bool](c, "history", false))
+	})
+}
+
+// LocationTasksHandler lists the open tasks under a location, including tasks
+// on appliances placed there. ?includeCompleted=true adds completed tasks.
+func LocationTasksHandler(db func() *gorm.DB) fiber.Handler {
+	return locationSubtreeHandler(db, "tasks", func(c fiber.Ctx, db *gorm.DB, id uint) (any, error) {
+		return database.GetTasksInLocation(db, id, fiber.Query[bool](c, "includeCompleted", false))
+	})
+}
+
+// LocationNotesHandler lists the notes under a location.
+func LocationNotesHandler(db func() *gorm.DB) fiber.Handler {
+	return locationSubtreeHandler(db, "notes", func(c fiber.Ctx, db *gorm.DB, id uint) (any, error) {
+		return database.GetNotesInLocation(db, id)
+	})
+}
+
+// LocationFilesHandler lists the files under a location.
+func LocationFilesHandler(db func() *gorm.DB) fiber.Handler {
+	return locationSubtreeHandler(db, "files", func(c fiber.Ctx, db *gorm.DB, id uint) (any, error) {
+		return database.GetFilesInLocation(db, id)
+	})
+}
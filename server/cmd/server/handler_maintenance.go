@@ -0,0 +1,142 @@
+package main
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/masoncfrancis/homelogger/server/internal/database"
+	"github.com/masoncfrancis/homelogger/server/internal/models"
+	"gorm.io/gorm"
+)
+
+// recordUpdateBody is the v1 body for updating a maintenance or repair record.
+type recordUpdateBody struct {
+	Description string  `json:"description"`
+	Date        string  `json:"date"`
+	Cost        float64 `json:"cost"`
+	Notes       string  `json:"notes"`
+}
+
+// MaintenanceListHandler lists the maintenance records of one appliance
+// (referenceType=Appliance&applianceId=) or one space (referenceType=Space
+// with spaceId or spaceType).
+func MaintenanceListHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		propertyID, err := queryPropertyID(c)
+		if err != nil {
+			return sendError(c, fiber.StatusBadRequest, "Invalid propertyId format")
+		}
+		opts, err := queryListOptions(c)
+		if err != nil {
+			return sendError(c, fiber.StatusBadRequest, err.Error())
+		}
+		applianceId := c.Query("applianceId")
+		referenceType := c.Query("referenceType")
+
+		if referenceType == "" {
+			return sendError(c, fiber.StatusBadRequest, "Missing required query parameter: referenceType")
+		}
+
+		if referenceType == "Space" {
+			spaceID, ok, err := querySpaceID(c, db(), propertyID)
+			if err != nil {
+				return sendError(c, fiber.StatusBadRequest, err.Error())
+			}
+			if !ok {
+				return c.JSON([]models.Maintenance{})
+			}
+			if spaceID == 0 {
+				return sendError(c, fiber.StatusBadRequest, "Missing required query parameter: spaceId or spaceType for Space reference")
+			}
+			maintenances, err := database.ListMaintenances(db(), propertyID, 0, referenceType, spaceID, opts)
+			if err != nil {
+				return sendListError(c, "maintenance records", err)
+			}
+			return sendPage(c, maintenances)
+		}
+
+		if applianceId == "" {
+			return sendError(c, fiber.StatusBadRequest, "Missing required query parameter: applianceId for Appliance reference")
+		}
+		applianceIdUint, err := strconv.ParseUint(applianceId, 10, 32)
+		if err != nil {
+			return sendError(c, fiber.StatusBadRequest, "Invalid applianceId format")
+		}
+
+		maintenances, err := database.ListMaintenances(db(), propertyID, uint(applianceIdUint), referenceType, 0, opts)
+		if err != nil {
+			return sendListError(c, "maintenance records", err)
+		}
+		return sendPage(c, maintenances)
+	}
+}
+
+// MaintenanceAddHandler creates a maintenance record and attaches the
+// uploaded files listed in attachmentIds.
+func MaintenanceAddHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		var body struct {
+			models.Maintenance
+			AttachmentIDs []uint `json:"attachmentIds"`
+		}
+		if err := c.Bind().Body(&body); err != nil {
+			return sendError(c, fiber.StatusBadRequest, "Error parsing body: "+err.Error())
+		}
+		newMaintenance, err := database.AddMaintenance(requestDB(c, db), &body.Maintenance)
+		if err != nil {
+			return sendError(c, fiber.StatusInternalServerError, "Error adding maintenance record: "+err.Error())
+		}
+		for _, fid := range body.AttachmentIDs {
+			_ = database.AttachFileToMaintenance(requestDB(c, db), fid, newMaintenance.ID)
+		}
+		return c.Status(fiber.StatusCreated).JSON(newMaintenance)
+	}
+}
+
+// MaintenanceGetHandler returns a single maintenance record.
+func MaintenanceGetHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		idUint, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return sendError(c, fiber.StatusBadRequest, "Invalid ID format")
+		}
+		maintenance, err := database.GetMaintenance(db(), uint(idUint))
+		if err != nil {
+			return sendError(c, fiber.StatusNotFound, "Maintenance record not found: "+err.Error())
+		}
+		return c.JSON(maintenance)
+	}
+}
+
+// MaintenanceUpdateHandler changes a record's description, date, cost and notes.
+func MaintenanceUpdateHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		idUint, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return sendError(c, fiber.StatusBadRequest, "Invalid ID format")
+		}
+		var body recordUpdateBody
+		if err := c.Bind().Body(&body); err != nil {
+			return sendError(c, fiber.StatusBadRequest, "Error parsing body: "+err.Error())
+		}
+		updated, err := database.UpdateMaintenance(requestDB(c, db), uint(idUint), body.Description, body.Date, body.Cost, body.Notes)
+		if err != nil {
+			return sendError(c, fiber.StatusInternalServerError, "Error updating maintenance record: "+err.Error())
+		}
+		return c.JSON(updated)
+	}
+}
+
+// MaintenanceDeleteHandler moves a maintenance record and its files to the trash.
+func MaintenanceDeleteHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		idUint, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return sendError(c, fiber.StatusBadRequest, "Invalid ID format")
+		}
+		if err := database.DeleteMaintenance(requestDB(c, db), uint(idUint)); err != nil {
+			return sendError(c, fiber.StatusInternalServerError, "Error deleting maintenance record: "+err.Error())
+		}
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+}
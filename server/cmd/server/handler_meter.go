@@ -0,0 +1,176 @@
+package main
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/masoncfrancis/homelogger/server/internal/database"
+	"github.com/masoncfrancis/homelogger/server/internal/models"
+	"gorm.io/gorm"
+)
+
+type meterBody struct {
+	ApplianceID uint   `json:"applianceId"`
+	Name        string `json:"name"`
+	Unit        string `json:"unit"`
+}
+
+type meterReadingBody struct {
+	Value  *float64 `json:"value"`
+	ReadAt string   `json:"readAt"`
+	Notes  string   `json:"notes"`
+}
+
+// MeterListHandler lists the meters of a property, or of one appliance with
+// applianceId.
+func MeterListHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		propertyID, err := queryPropertyID(c)
+		if err != nil {
+			return sendQueryError(c, err)
+		}
+		applianceID, err := queryUint(c, "applianceId")
+		if err != nil {
+			return sendQueryError(c, err)
+		}
+		opts, err := queryListOptions(c)
+		if err != nil {
+			return sendQueryError(c, err)
+		}
+		page, err := database.ListMeters(db(), propertyID, applianceID, opts)
+		if err != nil {
+			return sendListError(c, "meters", err)
+		}
+		return sendPage(c, page)
+	}
+}
+
+// MeterAddHandler creates a meter on an appliance.
+func MeterAddHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		var body meterBody
+		if err := c.Bind().Body(&body); err != nil {
+			return sendStoreError(c, fiber.StatusBadRequest, "Error parsing body", err)
+		}
+		meter := &models.Meter{ApplianceID: body.ApplianceID, Name: body.Name, Unit: body.Unit}
+		created, err := database.AddMeter(requestDB(c, db), meter)
+		if err != nil {
+			return sendStoreError(c, fiber.StatusInternalServerError, "Error adding meter", err)
+		}
+		return c.Status(fiber.StatusCreated).JSON(created)
+	}
+}
+
+// MeterGetHandler returns a single meter with its latest reading.
+func MeterGetHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		id, err := paramID(c)
+		if err != nil {
+			return sendQueryError(c, err)
+		}
+		meter, err := database.GetMeter(db(), id)
+		if err != nil {
+			return sendStoreError(c, fiber.StatusNotFound, "Meter not found", err)
+		}
+		return c.JSON(meter)
+	}
+}
+
+// MeterUpdateHandler replaces a meter's name, unit and appliance.
+func MeterUpdateHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		id, err := paramID(c)
+		if err != nil {
+			return sendQueryError(c, err)
+		}
+		var body meterBody
+		if err := c.Bind().Body(&body); err != nil {
+			return sendStoreError(c, fiber.StatusBadRequest, "Error parsing body", err)
+		}
+		meter := &models.Meter{ID: id, ApplianceID: body.ApplianceID, Name: body.Name, Unit: body.Unit}
+		updated, err := database.UpdateMeter(requestDB(c, db), meter)
+		if err != nil {
+			return sendStoreError(c, fiber.StatusInternalServerError, "Error updating meter", err)
+		}
+		return c.JSON(updated)
+	}
+}
+
+// MeterDeleteHandler deletes a meter and its readings.
+func MeterDeleteHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		id, err := paramID(c)
+		if err != nil {
+			return sendQueryError(c, err)
+		}
+		if err := database.DeleteMeter(requestDB(c, db), id); err != nil {
+			return sendStoreError(c, fiber.StatusInternalServerError, "Error deleting meter", err)
+		}
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+}
+
+// MeterReadingListHandler lists a meter's readings, newest first.
+func MeterReadingListHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		id, err := paramID(c)
+		if err != nil {
+			return sendQueryError(c, err)
+		}
+		opts, err := queryListOptions(c)
+		if err != nil {
+			return sendQueryError(c, err)
+		}
+		page, err := database.ListMeterReadings(db(), id, opts)
+		if err != nil {
+			return sendListError(c, "meter readings", err)
+		}
+		return sendPage(c, page)
+	}
+}
+
+// MeterReadingAddHandler records a reading of a meter, dated today unless
+// readAt is given. Tasks whose threshold it reaches come due.
+func MeterReadingAddHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		id, err := paramID(c)
+		if err != nil {
+			return sendQueryError(c, err)
+		}
+		var body meterReadingBody
+		if err := c.Bind().Body(&body); err != nil {
+			return sendStoreError(c, fiber.StatusBadRequest, "Error parsing body", err)
+		}
+		if body.Value == nil {
+			return sendQueryError(c, &fieldError{Field: "value", Message: "value is required"})
+		}
+		if body.ReadAt == "" {
+			body.ReadAt = time.Now().Format("2006-01-02")
+		}
+		reading := &models.MeterReading{Value: *body.Value, ReadAt: body.ReadAt, Notes: body.Notes}
+		created, err := database.AddMeterReading(requestDB(c, db), id, reading)
+		if err != nil {
+			return sendStoreError(c, fiber.StatusInternalServerError, "Error adding meter reading", err)
+		}
+		return c.Status(fiber.StatusCreated).JSON(created)
+	}
+}
+
+// MeterReadingDeleteHandler deletes one reading of a meter.
+func MeterReadingDeleteHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		id, err := paramID(c)
+		if err != nil {
+			return sendQueryError(c, err)
+		}
+		readingID, err := strconv.ParseUint(c.Params("readingId"), 10, 32)
+		if err != nil || readingID == 0 {
+			return sendQueryError(c, &fieldError{Field: "readingId", Message: "invalid readingId format"})
+		}
+		if err := database.DeleteMeterReading(requestDB(c, db), id, uint(readingID)); err != nil {
+			return sendStoreError(c, fiber.StatusInternalServerError, "Error deleting meter reading", err)
+		}
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/masoncfrancis/homelogger/server/internal/database"
+	"github.com/masoncfrancis/homelogger/server/internal/models"
+)
+
+func TestMeterReadingShowsTaskOnDashboard(t *testing.T) {
+	db := openTestDB(t)
+	app := newTestApp(t, newDBProvider(db))
+
+	appliance, err := database.AddAppliance(db, &models.Appliance{ApplianceName: "Furnace"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, body := postJSON(t, app, "/api/v2/meters", map[string]interface{}{"applianceId": appliance.ID, "name": "Runtime", "unit": "hours"}, "")
+	if resp.StatusCode != fiber.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", resp.StatusCode, body)
+	}
+	var meter models.Meter
+	if err := json.Unmarshal(body, &meter); err != nil {
+		t.Fatal(err)
+	}
+	resp, body = postJSON(t, app, "/api/v2/tasks", map[string]interface{}{"label": "Replace filter", "meterId": meter.ID, "meterInterval": 300}, "")
+	if resp.StatusCode != fiber.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", resp.StatusCode, body)
+	}
+
+	readings := fmt.Sprintf("/api/v2/meters/%d/readings", meter.ID)
+	if resp, body := postJSON(t, app, readings, map[string]interface{}{"readAt": "2026-05-02"}, ""); resp.StatusCode != fiber.StatusBadRequest {
+		t.Fatalf("expected 400 without a value, got %d: %s", resp.StatusCode, body)
+	}
+	if resp, body := postJSON(t, app, readings, map[string]interface{}{"value": 320, "readAt": "2026-05-02"}, ""); resp.StatusCode != fiber.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", resp.StatusCode, body)
+	}
+
+	resp, body = doWithToken(t, app, "GET", "/api/task/dashboard?dateTo=2026-05-31", nil, "")
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", resp.StatusCode, body)
+	}
+	var tasks []models.Task
+	if err := json.Unmarshal(body, &tasks); err != nil {
+		t.Fatalf("decode %s: %v", body, err)
+	}
+	if len(tasks) != 1 || tasks[0].DueDate == nil || *tasks[0].DueDate != "2026-05-02" {
+		t.Fatalf("expected the filter task due 2026-05-02 on the dashboard, got %s", body)
+	}
+}
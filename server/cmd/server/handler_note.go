@@ -0,0 +1,131 @@
+package main
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/masoncfrancis/homelogger/server/internal/database"
+	"github.com/masoncfrancis/homelogger/server/internal/models"
+	"gorm.io/gorm"
+)
+
+// NoteListHandler lists notes, optionally limited to a property and to one
+// appliance or space.
+func NoteListHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		propertyID, err := queryPropertyID(c)
+		if err != nil {
+			return sendError(c, fiber.StatusBadRequest, "Invalid propertyId format")
+		}
+		spaceID, ok, err := querySpaceID(c, db(), propertyID)
+		if err != nil {
+			return sendError(c, fiber.StatusBadRequest, err.Error())
+		}
+		if !ok {
+			return c.JSON([]models.Note{})
+		}
+		var applianceId uint = 0
+		if applianceIdStr := c.Query("applianceId"); applianceIdStr != "" {
+			if idUint, err := strconv.ParseUint(applianceIdStr, 10, 32); err == nil {
+				applianceId = uint(idUint)
+			}
+		}
+		opts, err := queryListOptions(c)
+		if err != nil {
+			return sendError(c, fiber.StatusBadRequest, err.Error())
+		}
+
+		notes, err := database.ListNotes(db(), propertyID, applianceId, spaceID, opts)
+		if err != nil {
+			return sendListError(c, "notes", err)
+		}
+		return sendPage(c, notes)
+	}
+}
+
+// NoteAddHandler creates a note.
+func NoteAddHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		var body struct {
+			PropertyID  uint   `json:"propertyId"`
+			Title       string `json:"title"`
+			Body        string `json:"body"`
+			ApplianceID *uint  `json:"applianceId"`
+			SpaceID     *uint  `json:"spaceId"`
+			SpaceType   string `json:"spaceType"`
+			LocationID  *uint  `json:"locationId"`
+		}
+		if err := c.Bind().Body(&body); err != nil {
+			return sendError(c, fiber.StatusBadRequest, "Error parsing body")
+		}
+
+		newNote := &models.Note{
+			PropertyID:  body.PropertyID,
+			Title:       body.Title,
+			Body:        body.Body,
+			ApplianceID: body.ApplianceID,
+			SpaceID:     body.SpaceID,
+			LocationID:  body.LocationID,
+		}
+		if body.SpaceType != "" {
+			newNote.SpaceType = &body.SpaceType
+		}
+
+		note, err := database.AddNote(requestDB(c, db), newNote)
+		if err != nil {
+			return sendError(c, fiber.StatusInternalServerError, "Error adding note:"+err.Error())
+		}
+		return c.JSON(note)
+	}
+}
+
+// NoteGetHandler returns a single note.
+func NoteGetHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		idUint, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return sendError(c, fiber.StatusBadRequest, "Invalid ID format")
+		}
+		note, err := database.GetNote(db(), uint(idUint))
+		if err != nil {
+			return sendError(c, fiber.StatusNotFound, "Note not found: "+err.Error())
+		}
+		return c.JSON(note)
+	}
+}
+
+// NoteUpdateHandler changes a note's title and body.
+func NoteUpdateHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		idUint, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return sendError(c, fiber.StatusBadRequest, "Invalid ID format")
+		}
+		var body struct {
+			Title string `json:"title"`
+			Body  string `json:"body"`
+		}
+		if err := c.Bind().Body(&body); err != nil {
+			return sendError(c, fiber.StatusBadRequest, "Error parsing body")
+		}
+		updated, err := database.UpdateNote(requestDB(c, db), uint(idUint), body.Title, body.Body)
+		if err != nil {
+			return sendError(c, fiber.StatusInternalServerError, "Error updating note:"+err.Error())
+		}
+		return c.JSON(updated)
+	}
+}
+
+// NoteDeleteHandler moves a note to the trash.
+func NoteDeleteHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		idUint, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return sendError(c, fiber.StatusBadRequest, "Invalid ID format")
+		}
+		if err := database.DeleteNote(requestDB(c, db), uint(idUint)); err != nil {
+			return sendError(c, fiber.StatusInternalServerError, "Error deleting note:"+err.Error())
+		}
+		return c.SendString("Note deleted")
+	}
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/masoncfrancis/homelogger/server/internal/database"
+	"github.com/masoncfrancis/homelogger/server/internal/sso"
+	"gorm.io/gorm"
+)
+
+// oidcCookieName holds the state, nonce and PKCE verifier between the login
+// redirect and the callback.
+const oidcCookieName = "homelogger_oidc"
+
+const oidcFlowTTL = 10 * time.Minute
+
+// oidcFlow is the per-login state kept in oidcCookieName.
+type oidcFlow struct {
+	State    string
+	Nonce    string
+	Verifier string
+	Link     bool
+}
+
+func (f oidcFlow) encode() string {
+	mode := "login"
+	if f.Link {
+		mode = "link"
+	}
+	return strings.Join([]string{f.State, f.Nonce, f.Verifier, mode}, ".")
+}
+
+func decodeOIDCFlow(raw string) (oidcFlow, bool) {
+	parts := strings.Split(raw, ".")
+	if len(parts) != 4 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return oidcFlow{}, false
+	}
+	return oidcFlow{State: parts[0], Nonce: parts[1], Verifier: parts[2], Link: parts[3] == "link"}, true
+}
+
+// OIDCConfigHandler tells the client whether single sign-on is available.
+func OIDCConfigHandler(provider *sso.Provider) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		if provider == nil || !provider.Config().Enabled() {
+			return c.JSON(fiber.Map{"enabled": false})
+		}
+		return c.JSON(fiber.Map{
+			"enabled":      true,
+			"providerName": provider.Config().ProviderName,
+		})
+	}
+}
+
+// OIDCLoginHandler redirects the browser to the identity provider.
+// With ?link=true the resulting identity is linked to the logged-in account
+// instead of starting a new session.
+func OIDCLoginHandler(provider *sso.Provider, cfg authConfig) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		if provider == nil || !provider.Config().Enabled() {
+			return c.Status(fiber.StatusNotFound).SendString("Single sign-on is not configured")
+		}
+
+		link := c.Query("link") == "true"
+		if link && currentUser(c) == nil {
+			return c.Status(fiber.StatusUnauthorized).SendString("Log in before linking an account")
+		}
+
+		state, err := sso.RandomString()
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).SendString("Error starting login: " + err.Error())
+		}
+		nonce, err := sso.RandomString()
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).SendString("Error starting login: " + err.Error())
+		}
+		flow := oidcFlow{State: state, Nonce: nonce, Verifier: sso.GenerateVerifier(), Link: link}
+
+		url, err := provider.AuthCodeURL(c.Context(), flow.State, flow.Nonce, flow.Verifier)
+		if err != nil {
+			return c.Status(fiber.StatusBadGateway).SendString("Error contacting identity provider: " + err.Error())
+		}
+
+		c.Cookie(&fiber.Cookie{
+			Name:     oidcCookieName,
+			Value:    flow.encode(),
+			Path:     "/api/auth/oidc",
+			Expires:  time.Now().Add(oidcFlowTTL),
+			HTTPOnly: true,
+			Secure:   cfg.CookieSecure,
+			SameSite: fiber.CookieSameSiteLaxMode,
+		})
+		return c.Redirect().To(url)
+	}
+}
+
+// OIDCCallbackHandler completes the login started by OIDCLoginHandler. The
+// identity is matched to an account by issuer and subject; unknown identities
+// are provisioned only when OIDC_AUTO_PROVISION is enabled.
+func OIDCCallbackHandler(db func() *gorm.DB, provider *sso.Provider, cfg authConfig) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		if provider == nil || !provider.Config().Enabled() {
+			return c.Status(fiber.StatusNotFound).SendString("Single sign-on is not configured")
+		}
+		ssoCfg := provider.Config()
+
+		flow, ok := decodeOIDCFlow(c.Cookies(oidcCookieName))
+		c.ClearCookie(oidcCookieName)
+		if !ok || c.Query("state") != flow.State {
+			return c.Status(fiber.StatusBadRequest).SendString("Invalid or expired login state")
+		}
+		if errParam := c.Query("error"); errParam != "" {
+			return c.Status(fiber.StatusUnauthorized).SendString("Identity provider returned an error: " + errParam)
+		}
+
+		identity, err := provider.Exchange(c.Context(), c.Query("code"), flow.Nonce, flow.Verifier)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).SendString("Error verifying login: " + err.Error())
+		}
+
+		conn := requestDB(c, db)
+
+		if flow.Link {
+			user := currentUser(c)
+			if user == nil {
+				return c.Status(fiber.StatusUnauthorized).SendString("Log in before linking an account")
+			}
+			if existing, err := database.GetUserByOIDCSubject(conn, identity.Issuer, identity.Subject); err == nil && existing.ID != user.ID {
+				return c.Status(fiber.StatusConflict).SendString("This identity is already linked to another account")
+			}
+			if err := database.LinkOIDCSubject(conn, user.ID, identity.Issuer, identity.Subject); err != nil {
+				return c.Status(fiber.StatusInternalServerError).SendString("Error linking account: " + err.Error())
+			}
+			return c.Redirect().To(ssoCfg.PostLoginRedirect)
+		}
+
+		user, err := database.GetUserByOIDCSubject(conn, identity.Issuer, identity.Subject)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			if !ssoCfg.AutoProvision {
+				return c.Status(fiber.StatusForbidden).SendString("No account is linked to this identity")
+			}
+			displayName := identity.DisplayName
+			if displayName == "" {
+				displayName = identity.Username
+			}
+			// Nobody is logged in yet, so the new account is provisioned by
+			// the server rather than by a user.
+			user, err = database.ProvisionOIDCUser(systemDB(conn), identity.Issuer, identity.Subject, identity.Username, displayName, ssoCfg.DefaultRole)
+		}
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).SendString("Error loading user: " + err.Error())
+		}
+
+		if _, _, err := issueSession(c, conn, cfg, user.ID); err != nil {
+			return c.Status(fiber.StatusInternalServerError).SendString("Error creating session: " + err.Error())
+		}
+		return c.Redirect().To(ssoCfg.PostLoginRedirect)
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/masoncfrancis/homelogger/server"
+	"github.com/masoncfrancis/homelogger/server/internal/version"
+	"gopkg.in/yaml.v3"
+)
+
+// openAPIDocument parses the embedded spec and stamps it with the running
+// version, so info.version always matches /api/health.
+func openAPIDocument() (map[string]interface{}, error) {
+	var doc map[string]interface{}
+	if err := yaml.Unmarshal(server.OpenAPISpec, &doc); err != nil {
+		return nil, fmt.Errorf("parsing openapi.yaml: %w", err)
+	}
+	if info, ok := doc["info"].(map[string]interface{}); ok {
+		info["version"] = version.Version
+	}
+	return doc, nil
+}
+
+// OpenAPIHandler serves the API description as JSON. The document is built
+// once, when the route is registered.
+func OpenAPIHandler() fiber.Handler {
+	doc, err := openAPIDocument()
+	var body []byte
+	if err == nil {
+		body, err = json.Marshal(doc)
+	}
+	return func(c fiber.Ctx) error {
+		if err != nil {
+			return sendError(c, fiber.StatusInternalServerError, "Error loading API description: "+err.Error())
+		}
+		c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		return c.Send(body)
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+var routeParam = regexp.MustCompile(`:(\w+)`)
+
+// specKey turns a Fiber method and path into the "METHOD /path" form used to
+// compare routes with the spec, whose paths have no /api prefix.
+func specKey(method, path string) string {
+	path = strings.TrimPrefix(path, "/api")
+	return strings.ToUpper(method) + " " + routeParam.ReplaceAllString(path, "{$1}")
+}
+
+// registeredRoutes builds the full server and lists its API routes.
+func registeredRoutes(t *testing.T) []string {
+	t.Helper()
+	app := newTestApp(t, newDBProvider(nil))
+	var routes []string
+	for _, r := range app.GetRoutes(true) {
+		switch r.Method {
+		case fiber.MethodGet, fiber.MethodPost, fiber.MethodPut, fiber.MethodDelete, fiber.MethodPatch:
+		default:
+			continue
+		}
+		if !strings.HasPrefix(r.Path, "/api/") || strings.HasSuffix(r.Path, "*") {
+			continue // the SPA fallback and the v2 catch-all 404
+		}
+		routes = append(routes, specKey(r.Method, r.Path))
+	}
+	return routes
+}
+
+func specOperations(t *testing.T) map[string]bool {
+	t.Helper()
+	doc, err := openAPIDocument()
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths, _ := doc["paths"].(map[string]interface{})
+	ops := map[string]bool{}
+	for path, item := range paths {
+		methods, _ := item.(map[string]interface{})
+		for method := range methods {
+			if method == "parameters" {
+				continue
+			}
+			ops[strings.ToUpper(method)+" "+path] = true
+		}
+	}
+	return ops
+}
+
+func TestOpenAPISpecCoversRoutes(t *testing.T) {
+	ops := specOperations(t)
+	registered := map[string]bool{}
+	var missing []string
+	for _, route := range registeredRoutes(t) {
+		registered[route] = true
+		if !ops[route] {
+			missing = append(missing, route)
+		}
+	}
+	if len(registered) < 50 {
+		t.Fatalf("found only %d routes; is registerRoutes still registering everything?", len(registered))
+	}
+	sort.Strings(missing)
+	if len(missing) > 0 {
+		t.Errorf("routes missing from openapi.yaml:\n  %s", strings.Join(missing, "\n  "))
+	}
+
+	var stale []string
+	for op := range ops {
+		if !registered[op] {
+			stale = append(stale, op)
+		}
+	}
+	sort.Strings(stale)
+	if len(stale) > 0 {
+		t.Errorf("openapi.yaml documents routes the server does not register:\n  %s", strings.Join(stale, "\n  "))
+	}
+}
+
+func TestOpenAPIRefsResolve(t *testing.T) {
+	doc, err := openAPIDocument()
+	if err != nil {
+		t.Fatal(err)
+	}
+	components, _ := doc["components"].(map[string]interface{})
+	var check func(v interface{})
+	check = func(v interface{}) {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			if ref, ok := v["$ref"].(string); ok {
+				parts := strings.Split(strings.TrimPrefix(ref, "#/components/"), "/")
+				section, _ := components[parts[0]].(map[string]interface{})
+				if len(parts) != 2 || section[parts[1]] == nil {
+					t.Errorf("unresolved $ref %s", ref)
+				}
+			}
+			for _, child := range v {
+				check(child)
+			}
+		case []interface{}:
+			for _, child := range v {
+				check(child)
+			}
+		}
+	}
+	check(doc)
+}
+
+func TestOpenAPIHandlerServesJSON(t *testing.T) {
+	app := fiber.New()
+	app.Get("/api/openapi.json", OpenAPIHandler())
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/api/openapi.json", nil))
+	if err != nil || resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("expected 200, got %v %v", resp, err)
+	}
+	var doc struct {
+		OpenAPI string `json:"openapi"`
+		Info    struct {
+			Version string `json:"version"`
+		} `json:"info"`
+		Paths map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if !strings.HasPrefix(doc.OpenAPI, "3.") || doc.Info.Version == "" || doc.Paths["/v2/tasks"] == nil {
+		t.Fatalf("unexpected document: openapi=%q version=%q paths=%d", doc.OpenAPI, doc.Info.Version, len(doc.Paths))
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/masoncfrancis/homelogger/server/internal/database"
+	"gorm.io/gorm"
+)
+
+// queryPropertyID reads the optional propertyId query parameter used by list
+// endpoints. A missing parameter means every property and yields 0.
+func queryPropertyID(c fiber.Ctx) (uint, error) {
+	raw := c.Query("propertyId")
+	if raw == "" {
+		return 0, nil
+	}
+	id, err := strconv.ParseUint(raw, 10, 32)
+	if err != nil {
+		return 0, &fieldError{Field: "propertyId", Message: "invalid propertyId format"}
+	}
+	return uint(id), nil
+}
+
+type propertyBody struct {
+	Name    string `json:"name"`
+	Address string `json:"address"`
+}
+
+// PropertyListHandler lists every property.
+func PropertyListHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		properties, err := database.GetProperties(db())
+		if err != nil {
+			return sendStoreError(c, fiber.StatusInternalServerError, "Error getting properties", err)
+		}
+		return c.JSON(properties)
+	}
+}
+
+// PropertyAddHandler creates a property.
+func PropertyAddHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		var body propertyBody
+		if err := c.Bind().Body(&body); err != nil {
+			return sendStoreError(c, fiber.StatusBadRequest, "Error parsing body", err)
+		}
+		property, err := database.AddProperty(requestDB(c, db), body.Name, body.Address)
+		if err != nil {
+			return sendStoreError(c, fiber.StatusBadRequest, "Error adding property", err)
+		}
+		return c.Status(fiber.StatusCreated).JSON(property)
+	}
+}
+
+// PropertyGetHandler returns a single property.
+func PropertyGetHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		idUint, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return sendError(c, fiber.StatusBadRequest, "Invalid ID format")
+		}
+		property, err := database.GetProperty(db(), uint(idUint))
+		if err != nil {
+			return sendStoreError(c, fiber.StatusNotFound, "Property not found", err)
+		}
+		return c.JSON(property)
+	}
+}
+
+// PropertyUpdateHandler renames a property or changes its address.
+func PropertyUpdateHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		idUint, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return sendError(c, fiber.StatusBadRequest, "Invalid ID format")
+		}
+		var body propertyBody
+		if err := c.Bind().Body(&body); err != nil {
+			return sendStoreError(c, fiber.StatusBadRequest, "Error parsing body", err)
+		}
+		property, err := database.UpdateProperty(requestDB(c, db), uint(idUint), body.Name, body.Address)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return sendError(c, fiber.StatusNotFound, "Property not found")
+			}
+			return sendStoreError(c, fiber.StatusBadRequest, "Error updating property", err)
+		}
+		return c.JSON(property)
+	}
+}
+
+// PropertyDeleteHandler deletes an empty property.
+func PropertyDeleteHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		idUint, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return sendError(c, fiber.StatusBadRequest, "Invalid ID format")
+		}
+		err = database.DeleteProperty(requestDB(c, db), uint(idUint))
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return sendError(c, fiber.StatusNotFound, "Property not found")
+		case errors.Is(err, database.ErrLastProperty), errors.Is(err, database.ErrPropertyInUse):
+			return sendError(c, fiber.StatusConflict, err.Error())
+		case err != nil:
+			return sendStoreError(c, fiber.StatusInternalServerError, "Error deleting property", err)
+		}
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/masoncfrancis/homelogger/server/internal/database"
+	"github.com/masoncfrancis/homelogger/server/internal/models"
+	"gorm.io/gorm"
+)
+
+// RepairListHandler lists the repair records of one appliance
+// (referenceType=Appliance&applianceId=) or one space (referenceType=Space
+// with spaceId or spaceType).
+func RepairListHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		propertyID, err := queryPropertyID(c)
+		if err != nil {
+			return sendError(c, fiber.StatusBadRequest, "Invalid propertyId format")
+		}
+		opts, err := queryListOptions(c)
+		if err != nil {
+			return sendError(c, fiber.StatusBadRequest, err.Error())
+		}
+		applianceId := c.Query("applianceId")
+		referenceType := c.Query("referenceType")
+
+		if referenceType == "" {
+			return sendError(c, fiber.StatusBadRequest, "Missing required query parameter: referenceType")
+		}
+
+		if referenceType == "Space" {
+			spaceID, ok, err := querySpaceID(c, db(), propertyID)
+			if err != nil {
+				return sendError(c, fiber.StatusBadRequest, err.Error())
+			}
+			if !ok {
+				return c.JSON([]models.Repair{})
+			}
+			if spaceID == 0 {
+				return sendError(c, fiber.StatusBadRequest, "Missing required query parameter: spaceId or spaceType for Space reference")
+			}
+			repairs, err := database.ListRepairs(db(), propertyID, 0, referenceType, spaceID, opts)
+			if err != nil {
+				return sendListError(c, "repair records", err)
+			}
+			return sendPage(c, repairs)
+		}
+
+		if applianceId == "" {
+			return sendError(c, fiber.StatusBadRequest, "Missing required query parameter: applianceId for Appliance reference")
+		}
+		applianceIdUint, err := strconv.ParseUint(applianceId, 10, 32)
+		if err != nil {
+			return sendError(c, fiber.StatusBadRequest, "Invalid applianceId format")
+		}
+
+		repairs, err := database.ListRepairs(db(), propertyID, uint(applianceIdUint), referenceType, 0, opts)
+		if err != nil {
+			return sendListError(c, "repair records", err)
+		}
+		return sendPage(c, repairs)
+	}
+}
+
+// RepairAddHandler creates a repair record and attaches the
+// uploaded files listed in attachmentIds.
+func RepairAddHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		var body struct {
+			models.Repair
+			AttachmentIDs []uint `json:"attachmentIds"`
+		}
+		if err := c.Bind().Body(&body); err != nil {
+			return sendError(c, fiber.StatusBadRequest, "Error parsing body: "+err.Error())
+		}
+		newRepair, err := database.AddRepair(requestDB(c, db), &body.Repair)
+		if err != nil {
+			return sendError(c, fiber.StatusInternalServerError, "Error adding repair record: "+err.Error())
+		}
+		for _, fid := range body.AttachmentIDs {
+			_ = database.AttachFileToRepair(requestDB(c, db), fid, newRepair.ID)
+		}
+		return c.Status(fiber.StatusCreated).JSON(newRepair)
+	}
+}
+
+// RepairGetHandler returns a single repair record.
+func RepairGetHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		idUint, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return sendError(c, fiber.StatusBadRequest, "Invalid ID format")
+		}
+		repair, err := database.GetRepair(db(), uint(idUint))
+		if err != nil {
+			return sendError(c, fiber.StatusNotFound, "Repair record not found: "+err.Error())
+		}
+		return c.JSON(repair)
+	}
+}
+
+// RepairUpdateHandler changes a record's description, date, cost and notes.
+func RepairUpdateHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		idUint, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return sendError(c, fiber.StatusBadRequest, "Invalid ID format")
+		}
+		var body recordUpdateBody
+		if err := c.Bind().Body(&body); err != nil {
+			return sendError(c, fiber.StatusBadRequest, "Error parsing body: "+err.Error())
+		}
+		updated, err := database.UpdateRepair(requestDB(c, db), uint(idUint), body.Description, body.Date, body.Cost, body.Notes)
+		if err != nil {
+			return sendError(c, fiber.StatusInternalServerError, "Error updating repair record: "+err.Error())
+		}
+		return c.JSON(updated)
+	}
+}
+
+// RepairDeleteHandler moves a repair record and its files to the trash.
+func RepairDeleteHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		idUint, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return sendError(c, fiber.StatusBadRequest, "Invalid ID format")
+		}
+		if err := database.DeleteRepair(requestDB(c, db), uint(idUint)); err != nil {
+			return sendError(c, fiber.StatusInternalServerError, "Error deleting repair record: "+err.Error())
+		}
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+}
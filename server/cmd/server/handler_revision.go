@@ -0,0 +1,97 @@
+package main
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/masoncfrancis/homelogger/server/internal/database"
+	"gorm.io/gorm"
+)
+
+// revisionParams reads the :type and :id route parameters of a record that
+// keeps revisions.
+func revisionParams(c fiber.Ctx) (string, uint, error) {
+	kind := c.Params("type")
+	if !slices.Contains(database.RevisionTypes, kind) {
+		return "", 0, &fieldError{Field: "type", Message: "type must be one of " + strings.Join(database.RevisionTypes, ", ")}
+	}
+	id, err := paramID(c)
+	return kind, id, err
+}
+
+// parseVersion reads a revision version number; blank is 0 when optional.
+func parseVersion(raw, name string, optional bool) (int, error) {
+	if raw == "" && optional {
+		return 0, nil
+	}
+	version, err := strconv.ParseUint(raw, 10, 31)
+	if err != nil || version == 0 {
+		return 0, &fieldError{Field: name, Message: name + " must be a revision version"}
+	}
+	return int(version), nil
+}
+
+// RevisionListHandler lists a record's earlier versions, newest first.
+func RevisionListHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		kind, id, err := revisionParams(c)
+		if err != nil {
+			return sendQueryError(c, err)
+		}
+		opts, err := queryListOptions(c)
+		if err != nil {
+			return sendQueryError(c, err)
+		}
+		page, err := database.ListRevisions(db(), kind, id, opts)
+		if err != nil {
+			return sendListError(c, kind+" revisions", err)
+		}
+		return sendPage(c, page)
+	}
+}
+
+// RevisionDiffHandler shows how a record's fields changed between the
+// versions given by ?from= and ?to= (the latest when omitted).
+func RevisionDiffHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		kind, id, err := revisionParams(c)
+		if err != nil {
+			return sendQueryError(c, err)
+		}
+		from, err := parseVersion(c.Query("from"), "from", false)
+		if err != nil {
+			return sendQueryError(c, err)
+		}
+		to, err := parseVersion(c.Query("to"), "to", true)
+		if err != nil {
+			return sendQueryError(c, err)
+		}
+		diff, err := database.DiffRevisions(db(), kind, id, from, to)
+		if err != nil {
+			return sendStoreError(c, fiber.StatusInternalServerError, "Error comparing "+kind+" revisions", err)
+		}
+		return c.JSON(diff)
+	}
+}
+
+// RevisionRevertHandler restores a record's fields to an earlier version and
+// returns the updated record.
+func RevisionRevertHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		kind, id, err := revisionParams(c)
+		if err != nil {
+			return sendQueryError(c, err)
+		}
+		version, err := parseVersion(c.Params("version"), "version", false)
+		if err != nil {
+			return sendQueryError(c, err)
+		}
+		record, err := database.RevertToRevision(requestDB(c, db), kind, id, version)
+		if err != nil {
+			return sendStoreError(c, fiber.StatusInternalServerError, "Error reverting "+kind, err)
+		}
+		return c.JSON(record)
+	}
+}
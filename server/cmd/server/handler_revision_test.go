@@ -0,0 +1,57 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/masoncfrancis/homelogger/server/internal/database"
+	"github.com/masoncfrancis/homelogger/server/internal/models"
+)
+
+func TestRevisionRevert(t *testing.T) {
+	db := openTestDB(t)
+	app := newTestApp(t, newDBProvider(db))
+
+	task, err := database.AddTask(db, &models.Task{Label: "Clean gutters", Notes: "Use the tall ladder"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := fmt.Sprintf("/api/v2/tasks/%d", task.ID)
+	if resp, body := doWithToken(t, app, "PUT", path, map[string]interface{}{"label": "Clean gutters", "notes": ""}, ""); resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", resp.StatusCode, body)
+	}
+
+	resp, body := doWithToken(t, app, "GET", fmt.Sprintf("/api/v2/revisions/task/%d/diff?from=1", task.ID), nil, "")
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", resp.StatusCode, body)
+	}
+	var diff database.RevisionDiff
+	if err := json.Unmarshal(body, &diff); err != nil {
+		t.Fatal(err)
+	}
+	if diff.To != 2 || diff.Changes["notes"].Old != "Use the tall ladder" {
+		t.Fatalf("expected the notes to have been cleared, got %+v", diff)
+	}
+
+	resp, body = postJSON(t, app, fmt.Sprintf("/api/v2/revisions/task/%d/1/revert", task.ID), nil, "")
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", resp.StatusCode, body)
+	}
+	var reverted models.Task
+	if err := json.Unmarshal(body, &reverted); err != nil || reverted.Notes != "Use the tall ladder" {
+		t.Fatalf("expected the notes back, got %s", body)
+	}
+
+	resp, body = doWithToken(t, app, "GET", fmt.Sprintf("/api/revisions/task/%d", task.ID), nil, "")
+	if resp.StatusCode != fiber.StatusOK || resp.Header.Get("X-Total-Count") != "3" {
+		t.Fatalf("expected three versions, got %d: %s", resp.StatusCode, body)
+	}
+	if resp, _ := doWithToken(t, app, "GET", "/api/v2/revisions/appliance/1", nil, ""); resp.StatusCode != fiber.StatusBadRequest {
+		t.Fatalf("expected 400 for a type without revisions, got %d", resp.StatusCode)
+	}
+	if resp, _ := doWithToken(t, app, "GET", fmt.Sprintf("/api/v2/revisions/task/%d/diff", task.ID), nil, ""); resp.StatusCode != fiber.StatusBadRequest {
+		t.Fatalf("expected 400 without from, got %d", resp.StatusCode)
+	}
+}
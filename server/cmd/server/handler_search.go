@@ -0,0 +1,44 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/masoncfrancis/homelogger/server/internal/database"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+// SearchHandler runs a full-text search over appliances, maintenance, repairs,
+// tasks, notes and file names, returning ranked results with snippets.
+func SearchHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		q := strings.TrimSpace(c.Query("q"))
+		if q == "" {
+			return sendError(c, fiber.StatusBadRequest, "q is required")
+		}
+		propertyID, err := queryPropertyID(c)
+		if err != nil {
+			return sendError(c, fiber.StatusBadRequest, "Invalid propertyId format")
+		}
+		limit := defaultSearchLimit
+		if raw := c.Query("limit"); raw != "" {
+			limit, err = strconv.Atoi(raw)
+			if err != nil || limit < 1 {
+				return sendError(c, fiber.StatusBadRequest, "Invalid limit format")
+			}
+			limit = min(limit, maxSearchLimit)
+		}
+
+		results, err := database.Search(db(), propertyID, q, limit)
+		if err != nil {
+			return sendStoreError(c, fiber.StatusInternalServerError, "Error searching", err)
+		}
+		return c.JSON(results)
+	}
+}
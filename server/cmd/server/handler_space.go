@@ -0,0 +1,151 @@
+package main
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/masoncfrancis/homelogger/server/internal/database"
+	"gorm.io/gorm"
+)
+
+// querySpaceID reads the space filter of list endpoints: spaceId, or the older
+// spaceType name looked up within the property. ok is false when spaceType
+// names no space, so nothing can match. Without either parameter it returns 0.
+func querySpaceID(c fiber.Ctx, db *gorm.DB, propertyID uint) (id uint, ok bool, err error) {
+	if raw := c.Query("spaceId"); raw != "" {
+		parsed, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil {
+			return 0, false, &fieldError{Field: "spaceId", Message: "invalid spaceId format"}
+		}
+		return uint(parsed), true, nil
+	}
+	name := c.Query("spaceType")
+	if name == "" {
+		return 0, true, nil
+	}
+	space, err := database.GetSpaceByName(db, propertyID, name)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return space.ID, true, nil
+}
+
+type spaceBody struct {
+	PropertyID uint   `json:"propertyId"`
+	Name       string `json:"name"`
+}
+
+// SpaceListHandler lists spaces, optionally limited to one property.
+func SpaceListHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		propertyID, err := queryPropertyID(c)
+		if err != nil {
+			return sendError(c, fiber.StatusBadRequest, "Invalid propertyId format")
+		}
+		spaces, err := database.GetSpaces(db(), propertyID)
+		if err != nil {
+			return sendStoreError(c, fiber.StatusInternalServerError, "Error getting spaces", err)
+		}
+		return c.JSON(spaces)
+	}
+}
+
+// SpaceAddHandler creates a space. Without a propertyId it goes into the default property.
+func SpaceAddHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		var body spaceBody
+		if err := c.Bind().Body(&body); err != nil {
+			return sendStoreError(c, fiber.StatusBadRequest, "Error parsing body", err)
+		}
+		space, err := database.AddSpace(requestDB(c, db), body.PropertyID, body.Name)
+		if errors.Is(err, database.ErrDuplicateSpace) {
+			return sendError(c, fiber.StatusConflict, err.Error())
+		}
+		if err != nil {
+			return sendStoreError(c, fiber.StatusBadRequest, "Error adding space", err)
+		}
+		return c.Status(fiber.StatusCreated).JSON(space)
+	}
+}
+
+// SpaceGetHandler returns a single space.
+func SpaceGetHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		idUint, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return sendError(c, fiber.StatusBadRequest, "Invalid ID format")
+		}
+		space, err := database.GetSpace(db(), uint(idUint))
+		if err != nil {
+			return sendStoreError(c, fiber.StatusNotFound, "Space not found", err)
+		}
+		return c.JSON(space)
+	}
+}
+
+// SpaceUpdateHandler renames a space. Records stay attached to it.
+func SpaceUpdateHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		idUint, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return sendError(c, fiber.StatusBadRequest, "Invalid ID format")
+		}
+		var body spaceBody
+		if err := c.Bind().Body(&body); err != nil {
+			return sendStoreError(c, fiber.StatusBadRequest, "Error parsing body", err)
+		}
+		space, err := database.RenameSpace(requestDB(c, db), uint(idUint), body.Name)
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return sendError(c, fiber.StatusNotFound, "Space not found")
+		case errors.Is(err, database.ErrDuplicateSpace):
+			return sendError(c, fiber.StatusConflict, err.Error())
+		case err != nil:
+			return sendStoreError(c, fiber.StatusBadRequest, "Error updating space", err)
+		}
+		return c.JSON(space)
+	}
+}
+
+// SpaceDeleteHandler deletes a space that has no records left.
+func SpaceDeleteHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		idUint, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return sendError(c, fiber.StatusBadRequest, "Invalid ID format")
+		}
+		err = database.DeleteSpace(requestDB(c, db), uint(idUint))
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return sendError(c, fiber.StatusNotFound, "Space not found")
+		case errors.Is(err, database.ErrSpaceInUse):
+			return sendError(c, fiber.StatusConflict, err.Error())
+		case err != nil:
+			return sendStoreError(c, fiber.StatusInternalServerError, "Error deleting space", err)
+		}
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+}
+
+// SpaceFilesHandler lists the files attached to a space.
+func SpaceFilesHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		idUint, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return sendError(c, fiber.StatusBadRequest, "Invalid ID format")
+		}
+		opts, err := queryListOptions(c)
+		if err != nil {
+			return sendQueryError(c, err)
+		}
+		files, err := database.ListFiles(db(), database.FileFilter{SpaceID: uint(idUint)}, opts)
+		if err != nil {
+			return sendListError(c, "files", err)
+		}
+		return sendPage(c, files)
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/masoncfrancis/homelogger/server/internal/database"
+	"github.com/masoncfrancis/homelogger/server/internal/models"
+	"gorm.io/gorm"
+)
+
+// TaskListHandler lists the tasks of one appliance or space by due date.
+// Completed tasks are left out unless includeCompleted=true.
+func TaskListHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		propertyID, err := queryPropertyID(c)
+		if err != nil {
+			return sendError(c, fiber.StatusBadRequest, "Invalid propertyId format")
+		}
+		spaceID, ok, err := querySpaceID(c, db(), propertyID)
+		if err != nil {
+			return sendError(c, fiber.StatusBadRequest, err.Error())
+		}
+		if !ok {
+			return c.JSON([]models.Task{})
+		}
+		includeCompleted := c.Query("includeCompleted") == "true"
+
+		var applianceId uint = 0
+		if applianceIdStr := c.Query("applianceId"); applianceIdStr != "" {
+			if idUint, err := strconv.ParseUint(applianceIdStr, 10, 32); err == nil {
+				applianceId = uint(idUint)
+			}
+		}
+		opts, err := queryListOptions(c)
+		if err != nil {
+			return sendQueryError(c, err)
+		}
+
+		tasks, err := database.ListTasks(db(), propertyID, applianceId, spaceID, includeCompleted, opts)
+		if err != nil {
+			return sendListError(c, "tasks", err)
+		}
+		return sendPage(c, tasks)
+	}
+}
+
+// TaskDashboardHandler lists every task of a property by due date, or with
+// assigneeId one household member's tasks. Usage-based tasks are dated the
+// day their meter reached the threshold, and list last until it has.
+func TaskDashboardHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		propertyID, err := queryPropertyID(c)
+		if err != nil {
+			return sendError(c, fiber.StatusBadRequest, "Invalid propertyId format")
+		}
+		includeCompleted := fiber.Query[bool](c, "includeCompleted", false)
+		opts, err := queryListOptions(c)
+		if err != nil {
+			return sendQueryError(c, err)
+		}
+		tasks, err := database.ListAllTasks(db(), propertyID, includeCompleted, opts)
+		if err != nil {
+			return sendListError(c, "tasks", err)
+		}
+		return sendPage(c, tasks)
+	}
+}
+
+// TaskAddHandler creates a task owned by the current user.
+func TaskAddHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		var body taskInput
+		if err := c.Bind().Body(&body); err != nil {
+			return sendError(c, fiber.StatusBadRequest, "Error parsing body: "+err.Error())
+		}
+		if body.Label == "" {
+			return sendError(c, fiber.StatusBadRequest, "label is required")
+		}
+
+		task := &models.Task{PropertyID: body.PropertyID, UserID: requestUserID(c, "1")}
+		body.apply(task)
+		created, err := database.AddTask(requestDB(c, db), task)
+		if err != nil {
+			return sendStoreError(c, fiber.StatusInternalServerError, "Error adding task", err)
+		}
+		return c.Status(fiber.StatusCreated).JSON(created)
+	}
+}
+
+// TaskGetHandler returns a single task.
+func TaskGetHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		idUint, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return sendError(c, fiber.StatusBadRequest, "Invalid ID format")
+		}
+		task, err := database.GetTask(db(), uint(idUint))
+		if err != nil {
+			return sendError(c, fiber.StatusNotFound, "Task not found: "+err.Error())
+		}
+		return c.JSON(task)
+	}
+}
+
+// TaskUpdateHandler replaces the editable fields of a task.
+func TaskUpdateHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		idUint, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return sendError(c, fiber.StatusBadRequest, "Invalid ID format")
+		}
+		existing, err := database.GetTask(db(), uint(idUint))
+		if err != nil {
+			return sendError(c, fiber.StatusNotFound, "Task not found: "+err.Error())
+		}
+		var body taskInput
+		if err := c.Bind().Body(&body); err != nil {
+			return sendError(c, fiber.StatusBadRequest, "Error parsing body: "+err.Error())
+		}
+		body.apply(existing)
+		updated, err := database.UpdateTask(requestDB(c, db), existing)
+		if err != nil {
+			return sendStoreError(c, fiber.StatusInternalServerError, "Error updating task", err)
+		}
+		return c.JSON(updated)
+	}
+}
+
+// TaskCompleteHandler completes a task, advancing recurring tasks to their
+// next due date, and optionally logs a maintenance or repair record.
+func TaskCompleteHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		idUint, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return sendError(c, fiber.StatusBadRequest, "Invalid ID format")
+		}
+		var body taskCompletion
+		if err := c.Bind().Body(&body); err != nil {
+			return sendError(c, fiber.StatusBadRequest, "Error parsing body: "+err.Error())
+		}
+		if body.CompletionDate == "" {
+			return sendError(c, fiber.StatusBadRequest, "completionDate is required")
+		}
+		task, err := completeTask(requestDB(c, db), uint(idUint), body)
+		if err != nil {
+			return sendStoreError(c, fiber.StatusInternalServerError, "Error completing task", err)
+		}
+		return c.JSON(task)
+	}
+}
+
+// TaskUncompleteHandler reopens a completed task, or undoes the last advance
+// of a recurring one.
+func TaskUncompleteHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		idUint, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return sendError(c, fiber.StatusBadRequest, "Invalid ID format")
+		}
+		task, err := database.UncompleteTask(requestDB(c, db), uint(idUint))
+		if err != nil {
+			return sendError(c, fiber.StatusInternalServerError, "Error uncompleting task: "+err.Error())
+		}
+		return c.JSON(task)
+	}
+}
+
+// TaskDeleteHandler moves a task to the trash.
+func TaskDeleteHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		idUint, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return sendError(c, fiber.StatusBadRequest, "Invalid ID format")
+		}
+		if err := database.DeleteTask(requestDB(c, db), uint(idUint)); err != nil {
+			return sendError(c, fiber.StatusInternalServerError, "Error deleting task: "+err.Error())
+		}
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"github.com/gofiber/fiber/v3"
+	"github.com/masoncfrancis/homelogger/server/internal/database"
+	"gorm.io/gorm"
+)
+
+type taskAssignBody struct {
+	AssigneeID *uint `json:"assigneeId"`
+}
+
+// TaskMineHandler lists the tasks assigned to the current user across every
+// appliance and space, by due date, like the dashboard.
+func TaskMineHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		user := currentUser(c)
+		if user == nil {
+			return sendError(c, fiber.StatusUnauthorized, "Not logged in")
+		}
+		propertyID, err := queryPropertyID(c)
+		if err != nil {
+			return sendQueryError(c, err)
+		}
+		opts, err := queryListOptions(c)
+		if err != nil {
+			return sendQueryError(c, err)
+		}
+		opts.AssigneeID = user.ID
+		includeCompleted := fiber.Query[bool](c, "includeCompleted", false)
+		page, err := database.ListAllTasks(db(), propertyID, includeCompleted, opts)
+		if err != nil {
+			return sendListError(c, "tasks", err)
+		}
+		return sendPage(c, page)
+	}
+}
+
+// TaskAssignHandler assigns a task to a household member, or to no one when
+// assigneeId is null or 0, and returns the task.
+func TaskAssignHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		id, err := paramID(c)
+		if err != nil {
+			return sendQueryError(c, err)
+		}
+		var body taskAssignBody
+		if err := c.Bind().Body(&body); err != nil {
+			return sendStoreError(c, fiber.StatusBadRequest, "Error parsing body", err)
+		}
+		if body.AssigneeID != nil && *body.AssigneeID == 0 {
+			body.AssigneeID = nil
+		}
+		task, err := database.AssignTask(requestDB(c, db), id, body.AssigneeID)
+		if err != nil {
+			return sendStoreError(c, fiber.StatusInternalServerError, "Error assigning task", err)
+		}
+		return c.JSON(task)
+	}
+}
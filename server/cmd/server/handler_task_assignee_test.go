@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/masoncfrancis/homelogger/server/internal/database"
+	"github.com/masoncfrancis/homelogger/server/internal/models"
+)
+
+func TestTaskAssigneeRoutes(t *testing.T) {
+	db := openTestDB(t)
+	app := newAuthTestApp(t, newDBProvider(db))
+
+	resp, body := postJSON(t, app, "/api/auth/setup", map[string]string{"username": "alice", "password": "correct-horse"}, "")
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("expected 200 on setup, got %d: %s", resp.StatusCode, body)
+	}
+	var login struct {
+		Token string `json:"token"`
+		User  struct {
+			ID uint `json:"id"`
+		} `json:"user"`
+	}
+	resp, body = postJSON(t, app, "/api/auth/login", map[string]string{"username": "alice", "password": "correct-horse"}, "")
+	if err := json.Unmarshal(body, &login); err != nil {
+		t.Fatalf("decode login %s: %v", body, err)
+	}
+	bob, err := database.CreateUser(db, "bob", "Bob", "battery-staple", models.RoleMember)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mine, err := database.AddTask(db, &models.Task{Label: "Change furnace filter"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := database.AddTask(db, &models.Task{Label: "Clean gutters", AssigneeID: &bob.ID}); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, body = doWithToken(t, app, "PUT", fmt.Sprintf("/api/task/assign/%d", mine.ID), map[string]interface{}{"assigneeId": 999}, login.Token)
+	if resp.StatusCode != fiber.StatusBadRequest {
+		t.Fatalf("expected 400 assigning to an unknown user, got %d: %s", resp.StatusCode, body)
+	}
+	resp, body = doWithToken(t, app, "PUT", fmt.Sprintf("/api/task/assign/%d", mine.ID), map[string]interface{}{"assigneeId": login.User.ID}, login.Token)
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", resp.StatusCode, body)
+	}
+
+	var tasks []models.Task
+	resp, body = doWithToken(t, app, "GET", "/api/task/mine", nil, login.Token)
+	if err := json.Unmarshal(body, &tasks); err != nil {
+		t.Fatalf("decode %s: %v", body, err)
+	}
+	if len(tasks) != 1 || tasks[0].ID != mine.ID {
+		t.Fatalf("expected only alice's task, got %s", body)
+	}
+	resp, body = doWithToken(t, app, "GET", fmt.Sprintf("/api/task/dashboard?assigneeId=%d", bob.ID), nil, login.Token)
+	if err := json.Unmarshal(body, &tasks); err != nil {
+		t.Fatalf("decode %s: %v", body, err)
+	}
+	if len(tasks) != 1 || tasks[0].Label != "Clean gutters" {
+		t.Fatalf("expected only bob's task, got %s", body)
+	}
+	resp, body = doWithToken(t, app, "GET", "/api/task/dashboard?assigneeId=bob", nil, login.Token)
+	if resp.StatusCode != fiber.StatusBadRequest || string(body) != "invalid assigneeId format" {
+		t.Fatalf("expected 400 for a malformed assigneeId, got %d: %s", resp.StatusCode, body)
+	}
+
+	// Alice completes bob's task; it stays bob's but records who did it.
+	resp, body = doWithToken(t, app, "PUT", fmt.Sprintf("/api/task/complete/%d", tasks[0].ID), map[string]interface{}{"completionDate": "2026-03-01"}, login.Token)
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", resp.StatusCode, body)
+	}
+	var done models.Task
+	if err := json.Unmarshal(body, &done); err != nil {
+		t.Fatalf("decode %s: %v", body, err)
+	}
+	if done.LastCompletedBy != "alice" || done.LastCompletedByID == nil || *done.LastCompletedByID != login.User.ID || done.AssigneeID == nil || *done.AssigneeID != bob.ID {
+		t.Fatalf("expected completed by alice and still bob's, got %s", body)
+	}
+
+	resp, body = doWithToken(t, app, "POST", fmt.Sprintf("/api/v2/tasks/%d/assign", mine.ID), map[string]interface{}{"assigneeId": 0}, login.Token)
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", resp.StatusCode, body)
+	}
+	resp, body = doWithToken(t, app, "GET", "/api/v2/tasks/mine", nil, login.Token)
+	var page struct {
+		Total int64 `json:"total"`
+	}
+	if err := json.Unmarshal(body, &page); err != nil || page.Total != 0 {
+		t.Fatalf("expected no tasks left for alice, got %s", body)
+	}
+}
+
+func TestTaskMineNeedsLogin(t *testing.T) {
+	app := newTestApp(t, newDBProvider(openTestDB(t)))
+	if resp, body := doWithToken(t, app, "GET", "/api/task/mine", nil, ""); resp.StatusCode != fiber.StatusUnauthorized {
+		t.Fatalf("expected 401 without a user, got %d: %s", resp.StatusCode, body)
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/masoncfrancis/homelogger/server/internal/database"
+	"gorm.io/gorm"
+)
+
+// TaskCompletionListHandler lists a task's completions, newest first.
+func TaskCompletionListHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		id, err := paramID(c)
+		if err != nil {
+			return sendQueryError(c, err)
+		}
+		opts, err := queryListOptions(c)
+		if err != nil {
+			return sendQueryError(c, err)
+		}
+		page, err := database.ListTaskCompletions(db(), id, opts)
+		if err != nil {
+			return sendListError(c, "task completions", err)
+		}
+		return sendPage(c, page)
+	}
+}
+
+// TaskCompletionStatsHandler summarizes a task's completions: how often it is
+// done, what it has cost and how often it was done on time.
+func TaskCompletionStatsHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		id, err := paramID(c)
+		if err != nil {
+			return sendQueryError(c, err)
+		}
+		stats, err := database.GetTaskCompletionStats(db(), id)
+		if err != nil {
+			return sendStoreError(c, fiber.StatusInternalServerError, "Error getting task completion stats", err)
+		}
+		return c.JSON(stats)
+	}
+}
+
+// TaskCompletionUndoHandler undoes one completion of a task and returns the
+// task.
+func TaskCompletionUndoHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		id, err := paramID(c)
+		if err != nil {
+			return sendQueryError(c, err)
+		}
+		completionID, err := strconv.ParseUint(c.Params("completionId"), 10, 32)
+		if err != nil || completionID == 0 {
+			return sendQueryError(c, &fieldError{Field: "completionId", Message: "invalid completionId format"})
+		}
+		task, err := database.UndoTaskCompletion(requestDB(c, db), id, uint(completionID))
+		if err != nil {
+			return sendStoreError(c, fiber.StatusInternalServerError, "Error undoing task completion", err)
+		}
+		return c.JSON(task)
+	}
+}
+
+// taskOccurrenceBody is the body for skipping or snoozing an occurrence of a
+// task. Date is the day it is done, today when empty; Days is how long a
+// snooze lasts.
+type taskOccurrenceBody struct {
+	Date string `json:"date"`
+	Days int    `json:"days"`
+}
+
+// bindOccurrenceBody reads the optional body of a skip or snooze.
+func bindOccurrenceBody(c fiber.Ctx) (taskOccurrenceBody, error) {
+	var body taskOccurrenceBody
+	if len(c.Body()) > 0 {
+		if err := c.Bind().Body(&body); err != nil {
+			return body, err
+		}
+	}
+	if body.Date == "" {
+		body.Date = time.Now().Format("2006-01-02")
+	}
+	return body, nil
+}
+
+// TaskSkipHandler moves a recurring task on to its next occurrence without
+// completing it, and returns the task.
+func TaskSkipHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		id, err := paramID(c)
+		if err != nil {
+			return sendQueryError(c, err)
+		}
+		body, err := bindOccurrenceBody(c)
+		if err != nil {
+			return sendStoreError(c, fiber.StatusBadRequest, "Error parsing body", err)
+		}
+		task, err := database.SkipTaskOccurrence(requestDB(c, db), id, body.Date)
+		if err != nil {
+			return sendStoreError(c, fiber.StatusInternalServerError, "Error skipping task", err)
+		}
+		return c.JSON(task)
+	}
+}
+
+// TaskSnoozeHandler puts a task's due date off by some days without shifting
+// its series, and returns the task.
+func TaskSnoozeHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		id, err := paramID(c)
+		if err != nil {
+			return sendQueryError(c, err)
+		}
+		body, err := bindOccurrenceBody(c)
+		if err != nil {
+			return sendStoreError(c, fiber.StatusBadRequest, "Error parsing body", err)
+		}
+		task, err := database.SnoozeTask(requestDB(c, db), id, body.Days, body.Date)
+		if err != nil {
+			return sendStoreError(c, fiber.StatusInternalServerError, "Error snoozing task", err)
+		}
+		return c.JSON(task)
+	}
+}
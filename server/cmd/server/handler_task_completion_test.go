@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/masoncfrancis/homelogger/server/internal/database"
+	"github.com/masoncfrancis/homelogger/server/internal/models"
+)
+
+func TestTaskCompletionUndoTrashesRecord(t *testing.T) {
+	db := openTestDB(t)
+	app := newTestApp(t, newDBProvider(db))
+
+	task, err := database.AddTask(db, &models.Task{Label: "Clean dryer vent"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := fmt.Sprintf("/api/v2/tasks/%d", task.ID)
+	resp, body := postJSON(t, app, base+"/complete", map[string]interface{}{
+		"completionDate": "2026-03-01", "createRecord": true, "cost": 40, "notes": "Lint everywhere",
+	}, "")
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", resp.StatusCode, body)
+	}
+
+	resp, body = doWithToken(t, app, "GET", base+"/completions", nil, "")
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", resp.StatusCode, body)
+	}
+	var page database.Page[models.TaskCompletion]
+	if err := json.Unmarshal(body, &page); err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 1 || page.Items[0].MaintenanceID == nil || page.Items[0].Notes != "Lint everywhere" {
+		t.Fatalf("expected one completion linked to its record, got %+v", page.Items)
+	}
+	completion := page.Items[0]
+
+	resp, body = doWithToken(t, app, "DELETE", fmt.Sprintf("%s/completions/%d", base, completion.ID), nil, "")
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", resp.StatusCode, body)
+	}
+	var reopened models.Task
+	if err := json.Unmarshal(body, &reopened); err != nil || reopened.Checked {
+		t.Fatalf("expected the task to be open again, got %s", body)
+	}
+	if resp, _ := doWithToken(t, app, "GET", fmt.Sprintf("/api/v2/maintenance/%d", *completion.MaintenanceID), nil, ""); resp.StatusCode != fiber.StatusNotFound {
+		t.Fatalf("expected the record to be in the trash, got %d", resp.StatusCode)
+	}
+	if resp, _ := doWithToken(t, app, "DELETE", fmt.Sprintf("/api/task/completions/undo/%d/%d", task.ID, completion.ID), nil, ""); resp.StatusCode != fiber.StatusNotFound {
+		t.Fatalf("expected 404 for an undone completion, got %d", resp.StatusCode)
+	}
+}
+
+func TestTaskSkipAndSnoozeErrors(t *testing.T) {
+	db := openTestDB(t)
+	app := newTestApp(t, newDBProvider(db))
+
+	due := "2026-04-01"
+	task, err := database.AddTask(db, &models.Task{Label: "Paint trim", DueDate: &due})
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := fmt.Sprintf("/api/v2/tasks/%d", task.ID)
+
+	resp, body := postJSON(t, app, base+"/snooze", map[string]interface{}{"days": 0}, "")
+	if resp.StatusCode != fiber.StatusBadRequest || !slices.Contains(detailFields(decodeAPIError(t, body)), "days") {
+		t.Fatalf("expected 400 on days, got %d: %s", resp.StatusCode, body)
+	}
+	resp, body = postJSON(t, app, base+"/snooze", map[string]interface{}{"days": 3}, "")
+	var snoozed models.Task
+	if resp.StatusCode != fiber.StatusOK || json.Unmarshal(body, &snoozed) != nil || *snoozed.DueDate != "2026-04-04" {
+		t.Fatalf("expected the task snoozed to 2026-04-04, got %d: %s", resp.StatusCode, body)
+	}
+	if resp, body := doWithToken(t, app, "PUT", fmt.Sprintf("/api/task/skip/%d", task.ID), nil, ""); resp.StatusCode != fiber.StatusConflict {
+		t.Fatalf("expected 409 skipping a one-off task, got %d: %s", resp.StatusCode, body)
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/masoncfrancis/homelogger/server/internal/database"
+	"gorm.io/gorm"
+)
+
+type taskDependencyBody struct {
+	BlockerID uint `json:"blockerId"`
+}
+
+// TaskSubtasksHandler returns a task's subtasks with how many are done and
+// what they cost.
+func TaskSubtasksHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		id, err := paramID(c)
+		if err != nil {
+			return sendQueryError(c, err)
+		}
+		rollup, err := database.GetTaskRollup(db(), id)
+		if err != nil {
+			return sendStoreError(c, fiber.StatusNotFound, "Task not found", err)
+		}
+		return c.JSON(rollup)
+	}
+}
+
+// TaskDependencyListHandler returns the tasks a task waits on and the tasks
+// waiting on it.
+func TaskDependencyListHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		id, err := paramID(c)
+		if err != nil {
+			return sendQueryError(c, err)
+		}
+		deps, err := database.GetTaskDependencies(db(), id)
+		if err != nil {
+			return sendStoreError(c, fiber.StatusNotFound, "Task not found", err)
+		}
+		return c.JSON(deps)
+	}
+}
+
+// TaskDependencyAddHandler makes a task wait on the task in blockerId, and
+// returns the task's dependencies.
+func TaskDependencyAddHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		id, err := paramID(c)
+		if err != nil {
+			return sendQueryError(c, err)
+		}
+		var body taskDependencyBody
+		if err := c.Bind().Body(&body); err != nil {
+			return sendStoreError(c, fiber.StatusBadRequest, "Error parsing body", err)
+		}
+		if body.BlockerID == 0 {
+			return sendQueryError(c, &fieldError{Field: "blockerId", Message: "blockerId is required"})
+		}
+		deps, err := database.AddTaskDependency(requestDB(c, db), id, body.BlockerID)
+		if err != nil {
+			return sendStoreError(c, fiber.StatusInternalServerError, "Error adding task dependency", err)
+		}
+		return c.Status(fiber.StatusCreated).JSON(deps)
+	}
+}
+
+// TaskDependencyDeleteHandler stops a task waiting on another.
+func TaskDependencyDeleteHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		id, err := paramID(c)
+		if err != nil {
+			return sendQueryError(c, err)
+		}
+		blockerID, err := strconv.ParseUint(c.Params("blockerId"), 10, 32)
+		if err != nil || blockerID == 0 {
+			return sendQueryError(c, &fieldError{Field: "blockerId", Message: "invalid blockerId format"})
+		}
+		if err := database.RemoveTaskDependency(requestDB(c, db), id, uint(blockerID)); err != nil {
+			return sendStoreError(c, fiber.StatusInternalServerError, "Error removing task dependency", err)
+		}
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+}
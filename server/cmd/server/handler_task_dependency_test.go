@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/masoncfrancis/homelogger/server/internal/database"
+	"github.com/masoncfrancis/homelogger/server/internal/models"
+)
+
+func TestTaskSubtasksAndDependencies(t *testing.T) {
+	db := openTestDB(t)
+	app := newTestApp(t, newDBProvider(db))
+
+	job, err := database.AddTask(db, &models.Task{Label: "Replace water heater"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, body := postJSON(t, app, "/api/v2/tasks", map[string]interface{}{"label": "Drain tank", "parentId": job.ID, "estimatedCost": 40}, "")
+	if resp.StatusCode != fiber.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", resp.StatusCode, body)
+	}
+	var step models.Task
+	if err := json.Unmarshal(body, &step); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, body = postJSON(t, app, fmt.Sprintf("/api/v2/tasks/%d/dependencies", job.ID), map[string]interface{}{"blockerId": step.ID}, "")
+	if resp.StatusCode != fiber.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", resp.StatusCode, body)
+	}
+	resp, body = doWithToken(t, app, "GET", "/api/task/dashboard", nil, "")
+	var tasks []models.Task
+	if err := json.Unmarshal(body, &tasks); err != nil {
+		t.Fatalf("decode %s: %v", body, err)
+	}
+	if len(tasks) != 1 || tasks[0].ID != step.ID {
+		t.Fatalf("expected only the step on the dashboard, got %s", body)
+	}
+
+	resp, body = doWithToken(t, app, "PUT", fmt.Sprintf("/api/task/complete/%d", job.ID), map[string]interface{}{"completionDate": "2026-03-01"}, "")
+	if resp.StatusCode != fiber.StatusConflict {
+		t.Fatalf("expected 409 completing a blocked task, got %d: %s", resp.StatusCode, body)
+	}
+	resp, body = doWithToken(t, app, "DELETE", fmt.Sprintf("/api/task/dependencies/delete/%d/%d", job.ID, step.ID), nil, "")
+	if resp.StatusCode != fiber.StatusNoContent {
+		t.Fatalf("expected 204, got %d: %s", resp.StatusCode, body)
+	}
+	resp, body = postJSON(t, app, fmt.Sprintf("/api/v2/tasks/%d/complete", job.ID), map[string]interface{}{"completionDate": "2026-03-01"}, "")
+	if resp.StatusCode != fiber.StatusConflict {
+		t.Fatalf("expected 409 completing a task with an open subtask, got %d: %s", resp.StatusCode, body)
+	}
+
+	resp, body = doWithToken(t, app, "GET", fmt.Sprintf("/api/v2/tasks/%d/subtasks", job.ID), nil, "")
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", resp.StatusCode, body)
+	}
+	var rollup database.TaskRollup
+	if err := json.Unmarshal(body, &rollup); err != nil {
+		t.Fatal(err)
+	}
+	if rollup.Total != 1 || rollup.Completed != 0 || rollup.EstimatedCost != 40 {
+		t.Fatalf("unexpected roll-up %s", body)
+	}
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/masoncfrancis/homelogger/server/internal/database"
+	"github.com/masoncfrancis/homelogger/server/internal/models"
+	"gorm.io/gorm"
+)
+
+type taskPackApplyBody struct {
+	PropertyID  uint   `json:"propertyId"`
+	ApplianceID uint   `json:"applianceId"`
+	SpaceID     uint   `json:"spaceId"`
+	Date        string `json:"date"`
+}
+
+// checkTaskPackPriorities rejects templates with a priority tasks do not
+// have. prefix is put before the field name, for packs inside a file.
+func checkTaskPackPriorities(pack *models.TaskPack, prefix string) *fieldError {
+	for i, t := range pack.Tasks {
+		if !slices.Contains(taskPriorities, t.Priority) {
+			return &fieldError{Field: fmt.Sprintf("%stasks[%d].priority", prefix, i), Message: "priority must be one of low, medium, high or critical"}
+		}
+	}
+	return nil
+}
+
+// TaskPackListHandler lists the task packs, built-in and saved. applianceType
+// or space keep the packs with a task for it; applianceId keeps the packs for
+// that appliance's type.
+func TaskPackListHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		filter := database.TaskPackFilter{ApplianceType: c.Query("applianceType"), Space: c.Query("space")}
+		applianceID, err := queryUint(c, "applianceId")
+		if err != nil {
+			return sendQueryError(c, err)
+		}
+		if applianceID != 0 {
+			appliance, err := database.GetAppliance(db(), applianceID)
+			if err != nil {
+				return sendStoreError(c, fiber.StatusNotFound, "Appliance not found", err)
+			}
+			filter.ApplianceType = appliance.Type
+		}
+		packs, err := database.ListTaskPacks(db(), filter)
+		if err != nil {
+			return sendListError(c, "task packs", err)
+		}
+		return c.JSON(packs)
+	}
+}
+
+// TaskPackGetHandler returns a single task pack by key.
+func TaskPackGetHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		pack, err := database.GetTaskPack(db(), c.Params("key"))
+		if err != nil {
+			return sendStoreError(c, fiber.StatusNotFound, "Task pack not found", err)
+		}
+		return c.JSON(pack)
+	}
+}
+
+// TaskPackAddHandler saves a new task pack. Giving it a built-in pack's key
+// replaces that pack.
+func TaskPackAddHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		var pack models.TaskPack
+		if err := c.Bind().Body(&pack); err != nil {
+			return sendStoreError(c, fiber.StatusBadRequest, "Error parsing body", err)
+		}
+		if err := checkTaskPackPriorities(&pack, ""); err != nil {
+			return sendQueryError(c, err)
+		}
+		created, err := database.AddTaskPack(requestDB(c, db), &pack)
+		if err != nil {
+			return sendStoreError(c, fiber.StatusInternalServerError, "Error adding task pack", err)
+		}
+		return c.Status(fiber.StatusCreated).JSON(created)
+	}
+}
+
+// TaskPackUpdateHandler replaces a task pack's name, description and tasks.
+// Updating a built-in pack saves an edited copy in its place.
+func TaskPackUpdateHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		var pack models.TaskPack
+		if err := c.Bind().Body(&pack); err != nil {
+			return sendStoreError(c, fiber.StatusBadRequest, "Error parsing body", err)
+		}
+		if err := checkTaskPackPriorities(&pack, ""); err != nil {
+			return sendQueryError(c, err)
+		}
+		updated, err := database.UpdateTaskPack(requestDB(c, db), c.Params("key"), &pack)
+		if err != nil {
+			return sendStoreError(c, fiber.StatusInternalServerError, "Error updating task pack", err)
+		}
+		return c.JSON(updated)
+	}
+}
+
+// TaskPackDeleteHandler deletes a saved task pack. Deleting the replacement
+// of a built-in pack brings the built-in one back.
+func TaskPackDeleteHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		if err := database.DeleteTaskPack(requestDB(c, db), c.Params("key")); err != nil {
+			return sendStoreError(c, fiber.StatusInternalServerError, "Error deleting task pack", err)
+		}
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+}
+
+// TaskPackExportHandler downloads task packs as a JSON file: those named in
+// keys (comma-separated), or all of them.
+func TaskPackExportHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		var keys []string
+		for _, key := range strings.Split(c.Query("keys"), ",") {
+			if key = strings.TrimSpace(key); key != "" {
+				keys = append(keys, key)
+			}
+		}
+		file, err := database.ExportTaskPacks(db(), keys)
+		if err != nil {
+			return sendStoreError(c, fiber.StatusInternalServerError, "Error exporting task packs", err)
+		}
+		c.Set("Content-Disposition", "attachment; filename=task-packs.json")
+		return c.JSON(file)
+	}
+}
+
+// TaskPackImportHandler saves the packs of an exported file, replacing packs
+// with the same keys, and returns them.
+func TaskPackImportHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		var file models.TaskPackFile
+		if err := c.Bind().Body(&file); err != nil {
+			return sendStoreError(c, fiber.StatusBadRequest, "Error parsing body", err)
+		}
+		for i := range file.Packs {
+			if err := checkTaskPackPriorities(&file.Packs[i], fmt.Sprintf("packs[%d].", i)); err != nil {
+				return sendQueryError(c, err)
+			}
+		}
+		packs, err := database.ImportTaskPacks(requestDB(c, db), &file)
+		if err != nil {
+			return sendStoreError(c, fiber.StatusInternalServerError, "Error importing task packs", err)
+		}
+		return c.JSON(packs)
+	}
+}
+
+// TaskPackApplyHandler creates the tasks of a pack on an appliance, in a
+// space, or across a property, owned by the current user, and returns them.
+// The body is optional: with none the pack goes across the default property,
+// counting due dates from today.
+func TaskPackApplyHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		var body taskPackApplyBody
+		if len(c.Body()) > 0 {
+			if err := c.Bind().Body(&body); err != nil {
+				return sendStoreError(c, fiber.StatusBadRequest, "Error parsing body", err)
+			}
+		}
+		if body.ApplianceID != 0 && body.SpaceID != 0 {
+			return sendQueryError(c, &fieldError{Field: "spaceId", Message: "give applianceId or spaceId, not both"})
+		}
+		if body.Date == "" {
+			body.Date = time.Now().Format("2006-01-02")
+		}
+		target := database.TaskPackTarget{
+			PropertyID:  body.PropertyID,
+			ApplianceID: body.ApplianceID,
+			SpaceID:     body.SpaceID,
+			UserID:      requestUserID(c, "1"),
+			Date:        body.Date,
+		}
+		tasks, err := database.ApplyTaskPack(requestDB(c, db), c.Params("key"), target)
+		if err != nil {
+			return sendStoreError(c, fiber.StatusInternalServerError, "Error applying task pack", err)
+		}
+		return c.Status(fiber.StatusCreated).JSON(tasks)
+	}
+}
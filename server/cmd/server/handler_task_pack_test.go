@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/masoncfrancis/homelogger/server/internal/database"
+	"github.com/masoncfrancis/homelogger/server/internal/models"
+)
+
+func TestTaskPackHandlers(t *testing.T) {
+	db := openTestDB(t)
+	app := newTestApp(t, newDBProvider(db))
+
+	appliance, err := database.AddAppliance(db, &models.Appliance{ApplianceName: "Furnace", Type: "Furnace"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, body := doWithToken(t, app, "GET", fmt.Sprintf("/api/task/packs?applianceId=%d", appliance.ID), nil, "")
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", resp.StatusCode, body)
+	}
+	var packs []models.TaskPack
+	if err := json.Unmarshal(body, &packs); err != nil {
+		t.Fatal(err)
+	}
+	if len(packs) != 1 || packs[0].Key != "furnace" || !packs[0].BuiltIn {
+		t.Fatalf("expected the built-in furnace pack, got %s", body)
+	}
+
+	resp, body = postJSON(t, app, "/api/task/packs/apply/furnace", map[string]interface{}{"applianceId": appliance.ID, "date": "2026-08-01"}, "")
+	if resp.StatusCode != fiber.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", resp.StatusCode, body)
+	}
+	var tasks []models.Task
+	if err := json.Unmarshal(body, &tasks); err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 2 || tasks[1].DueDate == nil || *tasks[1].DueDate != "2026-09-15" {
+		t.Fatalf("expected the filter and the tune-up due 2026-09-15, got %s", body)
+	}
+
+	resp, body = postJSON(t, app, "/api/v2/task-packs/import", map[string]interface{}{
+		"version": 1,
+		"packs":   []map[string]interface{}{{"key": "cabin", "name": "Cabin", "tasks": []map[string]interface{}{{"label": "Close up", "priority": "urgent"}}}},
+	}, "")
+	if resp.StatusCode != fiber.StatusBadRequest || !slices.Contains(detailFields(decodeAPIError(t, body)), "packs[0].tasks[0].priority") {
+		t.Fatalf("expected 400 naming the priority, got %d: %s", resp.StatusCode, body)
+	}
+
+	resp, body = doWithToken(t, app, "DELETE", "/api/v2/task-packs/furnace", nil, "")
+	if resp.StatusCode != fiber.StatusConflict {
+		t.Fatalf("expected 409 deleting a built-in pack, got %d: %s", resp.StatusCode, body)
+	}
+	resp, body = postJSON(t, app, "/api/v2/task-packs/nope/apply", nil, "")
+	if resp.StatusCode != fiber.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown pack, got %d: %s", resp.StatusCode, body)
+	}
+}
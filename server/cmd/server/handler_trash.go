@@ -0,0 +1,78 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/masoncfrancis/homelogger/server/internal/database"
+	"gorm.io/gorm"
+)
+
+// trashParams reads the :type and :id route parameters of a trash item.
+func trashParams(c fiber.Ctx) (string, uint, error) {
+	kind := c.Params("type")
+	if !slices.Contains(database.TrashTypes, kind) {
+		return "", 0, &fieldError{Field: "type", Message: "type must be one of " + strings.Join(database.TrashTypes, ", ")}
+	}
+	id, err := paramID(c)
+	return kind, id, err
+}
+
+// TrashListHandler lists the items in the trash, optionally limited to one
+// property.
+func TrashListHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		propertyID, err := queryUint(c, "propertyId")
+		if err != nil {
+			return sendQueryError(c, err)
+		}
+		page, err := database.ListTrash(db(), propertyID)
+		if err != nil {
+			return sendListError(c, "trash", err)
+		}
+		return sendPage(c, page)
+	}
+}
+
+// TrashRestoreHandler restores an item and everything deleted with it.
+func TrashRestoreHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		kind, id, err := trashParams(c)
+		if err != nil {
+			return sendQueryError(c, err)
+		}
+		if err := database.RestoreFromTrash(requestDB(c, db), kind, id); err != nil {
+			return sendStoreError(c, fiber.StatusInternalServerError, "Error restoring "+kind, err)
+		}
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+}
+
+// TrashPurgeHandler deletes an item in the trash, and everything deleted with
+// it, for good.
+func TrashPurgeHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		kind, id, err := trashParams(c)
+		if err != nil {
+			return sendQueryError(c, err)
+		}
+		if err := database.PurgeFromTrash(requestDB(c, db), kind, id); err != nil {
+			return sendStoreError(c, fiber.StatusInternalServerError, "Error purging "+kind, err)
+		}
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+}
+
+// TrashEmptyHandler purges everything in the trash and reports how many
+// records and files went.
+func TrashEmptyHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		purged, err := database.PurgeTrash(requestDB(c, db), time.Time{})
+		if err != nil {
+			return sendStoreError(c, fiber.StatusInternalServerError, "Error emptying trash", err)
+		}
+		return c.JSON(fiber.Map{"purged": purged})
+	}
+}
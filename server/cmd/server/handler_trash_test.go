@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/masoncfrancis/homelogger/server/internal/database"
+	"github.com/masoncfrancis/homelogger/server/internal/models"
+	"gorm.io/gorm"
+)
+
+func TestTrashRestoreAndPurge(t *testing.T) {
+	db := openTestDB(t)
+	app := newTestApp(t, newDBProvider(db))
+
+	appliance, err := database.AddAppliance(db, &models.Appliance{ApplianceName: "Furnace"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	record, err := database.AddMaintenance(db, &models.Maintenance{Description: "Filter", ReferenceType: "Appliance", Date: "2026-01-01", ApplianceID: &appliance.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp, _ := doWithToken(t, app, "DELETE", fmt.Sprintf("/api/v2/appliances/%d", appliance.ID), nil, ""); resp.StatusCode != fiber.StatusNoContent {
+		t.Fatalf("expected 204, got %d", resp.StatusCode)
+	}
+
+	resp, body := doWithToken(t, app, "GET", "/api/v2/trash", nil, "")
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", resp.StatusCode, body)
+	}
+	var page database.Page[database.TrashItem]
+	if err := json.Unmarshal(body, &page); err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 1 || page.Items[0].Type != database.TrashAppliance || page.Items[0].Children != 1 {
+		t.Fatalf("expected the appliance with one child, got %+v", page)
+	}
+
+	// The record went with the appliance, so it cannot come back on its own.
+	resp, body = postJSON(t, app, fmt.Sprintf("/api/v2/trash/maintenance/%d/restore", record.ID), nil, "")
+	if resp.StatusCode != fiber.StatusConflict || decodeAPIError(t, body).Code != codeConflict {
+		t.Fatalf("expected 409, got %d: %s", resp.StatusCode, body)
+	}
+	if resp, _ := postJSON(t, app, "/api/v2/trash/widget/1/restore", nil, ""); resp.StatusCode != fiber.StatusBadRequest {
+		t.Fatalf("expected 400 for an unknown type, got %d", resp.StatusCode)
+	}
+
+	if resp, body := postJSON(t, app, fmt.Sprintf("/api/v2/trash/appliance/%d/restore", appliance.ID), nil, ""); resp.StatusCode != fiber.StatusNoContent {
+		t.Fatalf("expected 204, got %d: %s", resp.StatusCode, body)
+	}
+	if resp, _ := doWithToken(t, app, "GET", fmt.Sprintf("/api/v2/maintenance/%d", record.ID), nil, ""); resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("expected the record to be restored, got %d", resp.StatusCode)
+	}
+
+	if err := database.DeleteAppliance(db, appliance.ID); err != nil {
+		t.Fatal(err)
+	}
+	resp, body = doWithToken(t, app, "DELETE", "/api/trash/purge", nil, "")
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", resp.StatusCode, body)
+	}
+	var purged struct{ Purged int64 }
+	if err := json.Unmarshal(body, &purged); err != nil || purged.Purged != 2 {
+		t.Fatalf("expected two rows purged, got %s", body)
+	}
+	if resp, _ := doWithToken(t, app, "DELETE", fmt.Sprintf("/api/v2/trash/appliance/%d", appliance.ID), nil, ""); resp.StatusCode != fiber.StatusNotFound {
+		t.Fatalf("expected 404 once purged, got %d", resp.StatusCode)
+	}
+}
+
+func TestRecoverFileTrashWith(t *testing.T) {
+	uploads := t.TempDir()
+	unreachable := func() (*gorm.DB, error) { return nil, errors.New("should not connect") }
+	if err := recoverFileTrashWith(unreachable, uploads); err != nil {
+		t.Fatalf("expected no connection without a trash folder, got %v", err)
+	}
+
+	// The uploads' own database, as demo mode leaves it: not the live one.
+	connect := demoConnect(filepath.Join(t.TempDir(), "homelogger.db"))
+	db, err := connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := database.MigrateGorm(db); err != nil {
+		t.Fatal(err)
+	}
+	kept := filepath.Join(uploads, "1")
+	if _, err := database.UploadFile(db, &models.SavedFile{Path: kept, OriginalName: "kept.pdf", UserID: "u1"}); err != nil {
+		t.Fatal(err)
+	}
+	trashDir := filepath.Join(uploads, database.FileTrashDir)
+	if err := os.MkdirAll(trashDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(trashDir, "1"), []byte("content"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := recoverFileTrashWith(connect, uploads); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(kept); err != nil {
+		t.Fatalf("file with a record should be restored: %v", err)
+	}
+}
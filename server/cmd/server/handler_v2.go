@@ -0,0 +1,179 @@
+package main
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"gorm.io/gorm"
+)
+
+// registerV2 mounts the v2 REST API. Resources live at plural nouns, with the
+// verb carried by the method: GET /appliances lists, POST /appliances
+// creates, and GET, PUT and DELETE /appliances/:id read, replace and delete
+// one. Lists return {items, total, nextCursor} and accept the shared paging
+// parameters. Errors use the JSON envelope from apierror.go.
+//
+// Auth, backups and health checks have no v2 routes yet; clients keep using
+// the /api paths for those.
+func registerV2(v2 fiber.Router, db func() *gorm.DB, uploadsBase string, extractor *textExtractor) {
+	v2.Get("/appliances", V2ApplianceListHandler(db))
+	v2.Post("/appliances", V2ApplianceCreateHandler(db))
+	v2.Get("/appliances/:id", V2ApplianceGetHandler(db))
+	v2.Put("/appliances/:id", V2ApplianceUpdateHandler(db))
+	v2.Delete("/appliances/:id", V2ApplianceDeleteHandler(db))
+
+	v2.Get("/maintenance", recordListHandler(db, maintenanceRecords))
+	v2.Post("/maintenance", recordCreateHandler(db, maintenanceRecords))
+	v2.Get("/maintenance/:id", recordGetHandler(db, maintenanceRecords))
+	v2.Put("/maintenance/:id", recordUpdateHandler(db, maintenanceRecords))
+	v2.Delete("/maintenance/:id", recordDeleteHandler(db, maintenanceRecords))
+
+	v2.Get("/repairs", recordListHandler(db, repairRecords))
+	v2.Post("/repairs", recordCreateHandler(db, repairRecords))
+	v2.Get("/repairs/:id", recordGetHandler(db, repairRecords))
+	v2.Put("/repairs/:id", recordUpdateHandler(db, repairRecords))
+	v2.Delete("/repairs/:id", recordDeleteHandler(db, repairRecords))
+
+	v2.Get("/tasks", V2TaskListHandler(db))
+	v2.Post("/tasks", V2TaskCreateHandler(db))
+	v2.Get("/tasks/mine", TaskMineHandler(db))
+	v2.Get("/tasks/:id", V2TaskGetHandler(db))
+	v2.Put("/tasks/:id", V2TaskUpdateHandler(db))
+	v2.Delete("/tasks/:id", V2TaskDeleteHandler(db))
+	v2.Post("/tasks/:id/complete", V2TaskCompleteHandler(db))
+	v2.Post("/tasks/:id/uncomplete", V2TaskUncompleteHandler(db))
+	v2.Post("/tasks/:id/skip", TaskSkipHandler(db))
+	v2.Post("/tasks/:id/snooze", TaskSnoozeHandler(db))
+	v2.Post("/tasks/:id/assign", TaskAssignHandler(db))
+	v2.Get("/tasks/:id/completions", TaskCompletionListHandler(db))
+	v2.Get("/tasks/:id/completions/stats", TaskCompletionStatsHandler(db))
+	v2.Delete("/tasks/:id/completions/:completionId", TaskCompletionUndoHandler(db))
+	v2.Get("/tasks/:id/subtasks", TaskSubtasksHandler(db))
+	v2.Get("/tasks/:id/dependencies", TaskDependencyListHandler(db))
+	v2.Post("/tasks/:id/dependencies", TaskDependencyAddHandler(db))
+	v2.Delete("/tasks/:id/dependencies/:blockerId", TaskDependencyDeleteHandler(db))
+
+	v2.Get("/task-packs", TaskPackListHandler(db))
+	v2.Post("/task-packs", TaskPackAddHandler(db))
+	v2.Get("/task-packs/export", TaskPackExportHandler(db))
+	v2.Post("/task-packs/import", TaskPackImportHandler(db))
+	v2.Get("/task-packs/:key", TaskPackGetHandler(db))
+	v2.Put("/task-packs/:key", TaskPackUpdateHandler(db))
+	v2.Delete("/task-packs/:key", TaskPackDeleteHandler(db))
+	v2.Post("/task-packs/:key/apply", TaskPackApplyHandler(db))
+
+	v2.Get("/meters", MeterListHandler(db))
+	v2.Post("/meters", MeterAddHandler(db))
+	v2.Get("/meters/:id", MeterGetHandler(db))
+	v2.Put("/meters/:id", MeterUpdateHandler(db))
+	v2.Delete("/meters/:id", MeterDeleteHandler(db))
+	v2.Get("/meters/:id/readings", MeterReadingListHandler(db))
+	v2.Post("/meters/:id/readings", MeterReadingAddHandler(db))
+	v2.Delete("/meters/:id/readings/:readingId", MeterReadingDeleteHandler(db))
+
+	v2.Get("/notes", V2NoteListHandler(db))
+	v2.Post("/notes", V2NoteCreateHandler(db))
+	v2.Get("/notes/:id", V2NoteGetHandler(db))
+	v2.Put("/notes/:id", V2NoteUpdateHandler(db))
+	v2.Delete("/notes/:id", V2NoteDeleteHandler(db))
+
+	v2.Get("/files", V2FileListHandler(db))
+	v2.Post("/files", V2FileUploadHandler(db, uploadsBase, extractor))
+	v2.Get("/files/:id", V2FileGetHandler(db))
+	v2.Get("/files/:id/content", V2FileContentHandler(db))
+	v2.Post("/files/:id/attach", V2FileAttachHandler(db))
+	v2.Delete("/files/:id", V2FileDeleteHandler(db))
+
+	// These handlers are shared with v1 and pick the error format by path.
+	v2.Get("/properties", PropertyListHandler(db))
+	v2.Post("/properties", PropertyAddHandler(db))
+	v2.Get("/properties/:id", PropertyGetHandler(db))
+	v2.Put("/properties/:id", PropertyUpdateHandler(db))
+	v2.Delete("/properties/:id", PropertyDeleteHandler(db))
+
+	v2.Get("/spaces", SpaceListHandler(db))
+	v2.Post("/spaces", SpaceAddHandler(db))
+	v2.Get("/spaces/:id", SpaceGetHandler(db))
+	v2.Get("/spaces/:id/files", SpaceFilesHandler(db))
+	v2.Put("/spaces/:id", SpaceUpdateHandler(db))
+	v2.Delete("/spaces/:id", SpaceDeleteHandler(db))
+
+	v2.Get("/locations", LocationListHandler(db))
+	v2.Get("/locations/tree", LocationTreeHandler(db))
+	v2.Post("/locations", LocationAddHandler(db))
+	v2.Get("/locations/:id", LocationGetHandler(db))
+	v2.Get("/locations/:id/appliances", LocationAppliancesHandler(db))
+	v2.Get("/locations/:id/tasks", LocationTasksHandler(db))
+	v2.Get("/locations/:id/notes", LocationNotesHandler(db))
+	v2.Get("/locations/:id/files", LocationFilesHandler(db))
+	v2.Put("/locations/:id", LocationUpdateHandler(db))
+	v2.Delete("/locations/:id", LocationDeleteHandler(db))
+
+	v2.Get("/search", SearchHandler(db))
+
+	v2.Get("/trash", TrashListHandler(db))
+	v2.Post("/trash/:type/:id/restore", TrashRestoreHandler(db))
+	v2.Delete("/trash/:type/:id", TrashPurgeHandler(db))
+	v2.Delete("/trash", TrashEmptyHandler(db))
+
+	v2.Get("/revisions/:type/:id", RevisionListHandler(db))
+	v2.Get("/revisions/:type/:id/diff", RevisionDiffHandler(db))
+	v2.Post("/revisions/:type/:id/:version/revert", RevisionRevertHandler(db))
+
+	v2.Get("/audit", AuditListHandler(db))
+
+	// Without this, unknown v2 paths would fall through to the SPA.
+	v2.All("/*", func(c fiber.Ctx) error {
+		return sendError(c, fiber.StatusNotFound, "No such endpoint: "+c.Method()+" "+c.Path())
+	})
+}
+
+// paramID reads the :id route parameter.
+func paramID(c fiber.Ctx) (uint, error) {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil || id == 0 {
+		return 0, &fieldError{Field: "id", Message: "invalid id format"}
+	}
+	return uint(id), nil
+}
+
+// queryUint reads an optional numeric query parameter; 0 when absent.
+func queryUint(c fiber.Ctx, name string) (uint, error) {
+	raw := c.Query(name)
+	if raw == "" {
+		return 0, nil
+	}
+	id, err := strconv.ParseUint(raw, 10, 32)
+	if err != nil {
+		return 0, &fieldError{Field: name, Message: "invalid " + name + " format"}
+	}
+	return uint(id), nil
+}
+
+// fieldErrors collects every problem with a request body, so a client can
+// fix them all in one round trip.
+type fieldErrors []fieldError
+
+func (e *fieldErrors) add(field, message string) {
+	*e = append(*e, fieldError{Field: field, Message: message})
+}
+
+// requireDate checks that value is a YYYY-MM-DD date. Empty is allowed unless
+// required is set.
+func (e *fieldErrors) requireDate(field, value string, required bool) {
+	if value == "" {
+		if required {
+			e.add(field, field+" is required")
+		}
+		return
+	}
+	if _, err := time.Parse("2006-01-02", value); err != nil {
+		e.add(field, field+" must be a date in YYYY-MM-DD format")
+	}
+}
+
+// send writes the collected problems as a validation_failed error.
+func (e fieldErrors) send(c fiber.Ctx) error {
+	return sendError(c, fiber.StatusBadRequest, "Request has invalid fields", e...)
+}
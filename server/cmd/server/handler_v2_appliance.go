@@ -0,0 +1,143 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/masoncfrancis/homelogger/server/internal/database"
+	"github.com/masoncfrancis/homelogger/server/internal/models"
+	"gorm.io/gorm"
+)
+
+type applianceInput struct {
+	PropertyID    uint   `json:"propertyId"`
+	ApplianceName string `json:"applianceName"`
+	Manufacturer  string `json:"manufacturer"`
+	ModelNumber   string `json:"modelNumber"`
+	SerialNumber  string `json:"serialNumber"`
+	YearPurchased string `json:"yearPurchased"`
+	PurchasePrice string `json:"purchasePrice"`
+	Location      string `json:"location"`
+	LocationID    *uint  `json:"locationId"`
+	Type          string `json:"type"`
+}
+
+func (in *applianceInput) validate() fieldErrors {
+	var errs fieldErrors
+	if strings.TrimSpace(in.ApplianceName) == "" {
+		errs.add("applianceName", "applianceName is required")
+	}
+	return errs
+}
+
+// apply copies the editable fields onto an appliance.
+func (in *applianceInput) apply(a *models.Appliance) {
+	a.ApplianceName = in.ApplianceName
+	a.Manufacturer = in.Manufacturer
+	a.ModelNumber = in.ModelNumber
+	a.SerialNumber = in.SerialNumber
+	a.YearPurchased = in.YearPurchased
+	a.PurchasePrice = in.PurchasePrice
+	a.Location = in.Location
+	a.LocationID = in.LocationID
+	a.Type = in.Type
+}
+
+// V2ApplianceListHandler lists appliances, optionally limited to one property.
+func V2ApplianceListHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		propertyID, err := queryPropertyID(c)
+		if err != nil {
+			return sendQueryError(c, err)
+		}
+		opts, err := queryListOptions(c)
+		if err != nil {
+			return sendQueryError(c, err)
+		}
+		page, err := database.ListAppliances(db(), propertyID, opts)
+		if err != nil {
+			return sendListError(c, "appliances", err)
+		}
+		return sendPage(c, page)
+	}
+}
+
+// V2ApplianceCreateHandler creates an appliance.
+func V2ApplianceCreateHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		var body applianceInput
+		if err := c.Bind().Body(&body); err != nil {
+			return sendError(c, fiber.StatusBadRequest, "Error parsing body: "+err.Error())
+		}
+		if errs := body.validate(); len(errs) > 0 {
+			return errs.send(c)
+		}
+		appliance := &models.Appliance{PropertyID: body.PropertyID}
+		body.apply(appliance)
+		created, err := database.AddAppliance(requestDB(c, db), appliance)
+		if err != nil {
+			return sendStoreError(c, fiber.StatusInternalServerError, "Error adding appliance", err)
+		}
+		return c.Status(fiber.StatusCreated).JSON(created)
+	}
+}
+
+// V2ApplianceGetHandler returns one appliance.
+func V2ApplianceGetHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		id, err := paramID(c)
+		if err != nil {
+			return sendQueryError(c, err)
+		}
+		appliance, err := database.GetAppliance(db(), id)
+		if err != nil {
+			return sendStoreError(c, fiber.StatusInternalServerError, "Appliance not found", err)
+		}
+		return c.JSON(appliance)
+	}
+}
+
+// V2ApplianceUpdateHandler replaces the editable fields of an appliance.
+func V2ApplianceUpdateHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		id, err := paramID(c)
+		if err != nil {
+			return sendQueryError(c, err)
+		}
+		var body applianceInput
+		if err := c.Bind().Body(&body); err != nil {
+			return sendError(c, fiber.StatusBadRequest, "Error parsing body: "+err.Error())
+		}
+		if errs := body.validate(); len(errs) > 0 {
+			return errs.send(c)
+		}
+		appliance, err := database.GetAppliance(db(), id)
+		if err != nil {
+			return sendStoreError(c, fiber.StatusInternalServerError, "Appliance not found", err)
+		}
+		body.apply(appliance)
+		updated, err := database.UpdateAppliance(requestDB(c, db), appliance)
+		if err != nil {
+			return sendStoreError(c, fiber.StatusInternalServerError, "Error updating appliance", err)
+		}
+		return c.JSON(updated)
+	}
+}
+
+// V2ApplianceDeleteHandler moves an appliance, with its records and files, to
+// the trash.
+func V2ApplianceDeleteHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		id, err := paramID(c)
+		if err != nil {
+			return sendQueryError(c, err)
+		}
+		if _, err := database.GetAppliance(db(), id); err != nil {
+			return sendStoreError(c, fiber.StatusInternalServerError, "Appliance not found", err)
+		}
+		if err := database.DeleteAppliance(requestDB(c, db), id); err != nil {
+			return sendStoreError(c, fiber.StatusInternalServerError, "Error deleting appliance", err)
+		}
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/masoncfrancis/homelogger/server/internal/database"
+	"github.com/masoncfrancis/homelogger/server/internal/models"
+	"gorm.io/gorm"
+)
+
+// V2FileListHandler lists file info, filtered by any of maintenanceId,
+// repairId, applianceId and spaceId.
+func V2FileListHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		var filter database.FileFilter
+		for name, dst := range map[string]*uint{
+			"maintenanceId": &filter.MaintenanceID,
+			"repairId":      &filter.RepairID,
+			"applianceId":   &filter.ApplianceID,
+			"spaceId":       &filter.SpaceID,
+		} {
+			id, err := queryUint(c, name)
+			if err != nil {
+				return sendQueryError(c, err)
+			}
+			*dst = id
+		}
+		opts, err := queryListOptions(c)
+		if err != nil {
+			return sendQueryError(c, err)
+		}
+		page, err := database.ListFiles(db(), filter, opts)
+		if err != nil {
+			return sendListError(c, "files", err)
+		}
+		return sendPage(c, page)
+	}
+}
+
+// formUint reads an optional numeric multipart form value.
+func formUint(c fiber.Ctx, name string) (*uint, error) {
+	raw := c.FormValue(name)
+	if raw == "" {
+		return nil, nil
+	}
+	id, err := strconv.ParseUint(raw, 10, 32)
+	if err != nil {
+		return nil, &fieldError{Field: name, Message: "invalid " + name + " format"}
+	}
+	value := uint(id)
+	return &value, nil
+}
+
+// V2FileUploadHandler stores a multipart upload (field "file") under
+// uploadsBase and queues it for text extraction. The optional propertyId,
+// spaceId, spaceType and locationId form values place it like v1 uploads.
+func V2FileUploadHandler(db func() *gorm.DB, uploadsBase string, extractor *textExtractor) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		header, err := c.FormFile("file")
+		if err != nil {
+			return sendError(c, fiber.StatusBadRequest, "A file is required", fieldError{Field: "file", Message: "file is required"})
+		}
+		userID := requestUserID(c, c.FormValue("userID"))
+		if userID == "" {
+			return sendError(c, fiber.StatusBadRequest, "userID is required", fieldError{Field: "userID", Message: "userID is required when auth is disabled"})
+		}
+
+		savedFile := &models.SavedFile{OriginalName: header.Filename, UserID: userID}
+		var errs fieldErrors
+		for name, dst := range map[string]**uint{"spaceId": &savedFile.SpaceID, "locationId": &savedFile.LocationID} {
+			id, err := formUint(c, name)
+			if err != nil {
+				errs.add(name, err.Error())
+			}
+			*dst = id
+		}
+		propertyID, err := formUint(c, "propertyId")
+		if err != nil {
+			errs.add("propertyId", err.Error())
+		} else if propertyID != nil {
+			savedFile.PropertyID = *propertyID
+		}
+		if len(errs) > 0 {
+			return errs.send(c)
+		}
+		if spaceType := c.FormValue("spaceType"); spaceType != "" {
+			savedFile.SpaceType = &spaceType
+		}
+
+		newFile, err := database.UploadFile(requestDB(c, db), savedFile)
+		if err != nil {
+			return sendStoreError(c, fiber.StatusInternalServerError, "Error saving file information", err)
+		}
+		if err := os.MkdirAll(uploadsBase, 0755); err != nil {
+			return sendError(c, fiber.StatusInternalServerError, "Error creating uploads directory: "+err.Error())
+		}
+		newFile.Path = filepath.Join(uploadsBase, strconv.FormatUint(uint64(newFile.ID), 10))
+		if err := c.SaveFile(header, newFile.Path); err != nil {
+			return sendError(c, fiber.StatusInternalServerError, "Error saving file: "+err.Error())
+		}
+		if _, err := database.UpdateFilePath(requestDB(c, db), newFile); err != nil {
+			return sendStoreError(c, fiber.StatusInternalServerError, "Error updating file path", err)
+		}
+		extractor.notify()
+
+		info, err := database.GetFileInfo(db(), newFile.ID)
+		if err != nil {
+			return sendStoreError(c, fiber.StatusInternalServerError, "Error getting file", err)
+		}
+		return c.Status(fiber.StatusCreated).JSON(info)
+	}
+}
+
+// V2FileGetHandler returns a file's info, including any extracted text.
+func V2FileGetHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		id, err := paramID(c)
+		if err != nil {
+			return sendQueryError(c, err)
+		}
+		info, err := database.GetFileInfo(db(), id)
+		if err != nil {
+			return sendStoreError(c, fiber.StatusInternalServerError, "Error getting file", err)
+		}
+		return c.JSON(info)
+	}
+}
+
+// V2FileContentHandler downloads a file under its original name.
+func V2FileContentHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		id, err := paramID(c)
+		if err != nil {
+			return sendQueryError(c, err)
+		}
+		info, err := database.GetFileInfo(db(), id)
+		if err != nil {
+			return sendStoreError(c, fiber.StatusInternalServerError, "Error getting file", err)
+		}
+		path, err := database.GetFilePath(db(), id)
+		if err != nil {
+			return sendStoreError(c, fiber.StatusInternalServerError, "Error getting file", err)
+		}
+		if _, err := os.Stat(path); err != nil {
+			return sendError(c, fiber.StatusNotFound, "File content is missing from the uploads directory")
+		}
+		return c.Download(path, info.OriginalName)
+	}
+}
+
+type fileAttachInput struct {
+	MaintenanceID uint   `json:"maintenanceId"`
+	RepairID      uint   `json:"repairId"`
+	ApplianceID   uint   `json:"applianceId"`
+	SpaceID       uint   `json:"spaceId"`
+	SpaceType     string `json:"spaceType"`
+	LocationID    uint   `json:"locationId"`
+}
+
+// V2FileAttachHandler links a file to the maintenance record, repair,
+// appliance, space or location named in the body, and returns its info.
+func V2FileAttachHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		id, err := paramID(c)
+		if err != nil {
+			return sendQueryError(c, err)
+		}
+		var body fileAttachInput
+		if err := c.Bind().Body(&body); err != nil {
+			return sendError(c, fiber.StatusBadRequest, "Error parsing body: "+err.Error())
+		}
+		if body == (fileAttachInput{}) {
+			return sendError(c, fiber.StatusBadRequest, "Nothing to attach the file to",
+				fieldError{Field: "maintenanceId", Message: "one of maintenanceId, repairId, applianceId, spaceId, spaceType or locationId is required"})
+		}
+		if _, err := database.GetFileInfo(db(), id); err != nil {
+			return sendStoreError(c, fiber.StatusInternalServerError, "Error getting file", err)
+		}
+
+		steps := []struct {
+			set    bool
+			attach func() error
+		}{
+			{body.MaintenanceID != 0, func() error { return database.AttachFileToMaintenance(requestDB(c, db), id, body.MaintenanceID) }},
+			{body.RepairID != 0, func() error { return database.AttachFileToRepair(requestDB(c, db), id, body.RepairID) }},
+			{body.ApplianceID != 0, func() error { return database.AttachFileToAppliance(requestDB(c, db), id, body.ApplianceID) }},
+			{body.SpaceID != 0 || body.SpaceType != "", func() error { return database.AttachFileToSpace(requestDB(c, db), id, body.SpaceID, body.SpaceType) }},
+			{body.LocationID != 0, func() error { return database.AttachFileToLocation(requestDB(c, db), id, body.LocationID) }},
+		}
+		for _, step := range steps {
+			if !step.set {
+				continue
+			}
+			if err := step.attach(); err != nil {
+				return sendStoreError(c, fiber.StatusInternalServerError, "Error attaching file", err)
+			}
+		}
+
+		info, err := database.GetFileInfo(db(), id)
+		if err != nil {
+			return sendStoreError(c, fiber.StatusInternalServerError, "Error getting file", err)
+		}
+		return c.JSON(info)
+	}
+}
+
+// V2FileDeleteHandler moves a file to the trash.
+func V2FileDeleteHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		id, err := paramID(c)
+		if err != nil {
+			return sendQueryError(c, err)
+		}
+		if _, err := database.GetFilePath(db(), id); err != nil {
+			return sendStoreError(c, fiber.StatusInternalServerError, "Error getting file", err)
+		}
+		if err := database.DeleteFile(requestDB(c, db), id); err != nil {
+			return sendStoreError(c, fiber.StatusInternalServerError, "Error deleting file", err)
+		}
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/masoncfrancis/homelogger/server/internal/database"
+	"github.com/masoncfrancis/homelogger/server/internal/models"
+	"gorm.io/gorm"
+)
+
+// noteInput is the body for creating or updating a note. Only the title and
+// body can be changed after a note is created.
+type noteInput struct {
+	PropertyID  uint    `json:"propertyId"`
+	Title       string  `json:"title"`
+	Body        string  `json:"body"`
+	ApplianceID *uint   `json:"applianceId"`
+	SpaceID     *uint   `json:"spaceId"`
+	SpaceType   *string `json:"spaceType"`
+	LocationID  *uint   `json:"locationId"`
+}
+
+func (in *noteInput) validate() fieldErrors {
+	var errs fieldErrors
+	if strings.TrimSpace(in.Title) == "" {
+		errs.add("title", "title is required")
+	}
+	return errs
+}
+
+// V2NoteListHandler lists notes, optionally limited to a property and to one
+// appliance or space.
+func V2NoteListHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		propertyID, err := queryPropertyID(c)
+		if err != nil {
+			return sendQueryError(c, err)
+		}
+		applianceID, err := queryUint(c, "applianceId")
+		if err != nil {
+			return sendQueryError(c, err)
+		}
+		opts, err := queryListOptions(c)
+		if err != nil {
+			return sendQueryError(c, err)
+		}
+		spaceID, ok, err := querySpaceID(c, db(), propertyID)
+		if err != nil {
+			return sendQueryError(c, err)
+		}
+		if !ok {
+			return sendPage(c, &database.Page[models.Note]{Items: []models.Note{}})
+		}
+		page, err := database.ListNotes(db(), propertyID, applianceID, spaceID, opts)
+		if err != nil {
+			return sendListError(c, "notes", err)
+		}
+		return sendPage(c, page)
+	}
+}
+
+// V2NoteCreateHandler creates a note.
+func V2NoteCreateHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		var body noteInput
+		if err := c.Bind().Body(&body); err != nil {
+			return sendError(c, fiber.StatusBadRequest, "Error parsing body: "+err.Error())
+		}
+		if errs := body.validate(); len(errs) > 0 {
+			return errs.send(c)
+		}
+		note, err := database.AddNote(requestDB(c, db), &models.Note{
+			PropertyID:  body.PropertyID,
+			Title:       body.Title,
+			Body:        body.Body,
+			ApplianceID: body.ApplianceID,
+			SpaceID:     body.SpaceID,
+			SpaceType:   body.SpaceType,
+			LocationID:  body.LocationID,
+		})
+		if err != nil {
+			return sendStoreError(c, fiber.StatusInternalServerError, "Error adding note", err)
+		}
+		return c.Status(fiber.StatusCreated).JSON(note)
+	}
+}
+
+// V2NoteGetHandler returns one note.
+func V2NoteGetHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		id, err := paramID(c)
+		if err != nil {
+			return sendQueryError(c, err)
+		}
+		note, err := database.GetNote(db(), id)
+		if err != nil {
+			return sendStoreError(c, fiber.StatusInternalServerError, "Error getting note", err)
+		}
+		return c.JSON(note)
+	}
+}
+
+// V2NoteUpdateHandler replaces a note's title and body.
+func V2NoteUpdateHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		id, err := paramID(c)
+		if err != nil {
+			return sendQueryError(c, err)
+		}
+		var body noteInput
+		if err := c.Bind().Body(&body); err != nil {
+			return sendError(c, fiber.StatusBadRequest, "Error parsing body: "+err.Error())
+		}
+		if errs := body.validate(); len(errs) > 0 {
+			return errs.send(c)
+		}
+		note, err := database.UpdateNote(requestDB(c, db), id, body.Title, body.Body)
+		if err != nil {
+			return sendStoreError(c, fiber.StatusInternalServerError, "Error updating note", err)
+		}
+		return c.JSON(note)
+	}
+}
+
+// V2NoteDeleteHandler moves a note to the trash.
+func V2NoteDeleteHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		id, err := paramID(c)
+		if err != nil {
+			return sendQueryError(c, err)
+		}
+		if _, err := database.GetNote(db(), id); err != nil {
+			return sendStoreError(c, fiber.StatusInternalServerError, "Error getting note", err)
+		}
+		if err := database.DeleteNote(requestDB(c, db), id); err != nil {
+			return sendStoreError(c, fiber.StatusInternalServerError, "Error deleting note", err)
+		}
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/masoncfrancis/homelogger/server/internal/database"
+	"github.com/masoncfrancis/homelogger/server/internal/models"
+	"gorm.io/gorm"
+)
+
+// recordInput is the body for creating or updating a maintenance or repair
+// record. A record belongs to an appliance or, failing that, a space; only
+// the description, date, cost and notes can be changed afterwards.
+type recordInput struct {
+	PropertyID    uint    `json:"propertyId"`
+	Description   string  `json:"description"`
+	Date          string  `json:"date"`
+	Cost          float64 `json:"cost"`
+	Notes         string  `json:"notes"`
+	ApplianceID   *uint   `json:"applianceId"`
+	SpaceID       *uint   `json:"spaceId"`
+	SpaceType     string  `json:"spaceType"`
+	AttachmentIDs []uint  `json:"attachmentIds"`
+}
+
+func (in *recordInput) validate(create bool) fieldErrors {
+	var errs fieldErrors
+	if strings.TrimSpace(in.Description) == "" {
+		errs.add("description", "description is required")
+	}
+	errs.requireDate("date", in.Date, true)
+	if in.Cost < 0 {
+		errs.add("cost", "cost must not be negative")
+	}
+	if create && in.referenceType() == "" {
+		errs.add("applianceId", "an applianceId, spaceId or spaceType is required")
+	}
+	return errs
+}
+
+// referenceType derives the v1 reference type from the fields that are set.
+func (in *recordInput) referenceType() string {
+	switch {
+	case in.ApplianceID != nil && *in.ApplianceID != 0:
+		return "Appliance"
+	case (in.SpaceID != nil && *in.SpaceID != 0) || in.SpaceType != "":
+		return "Space"
+	}
+	return ""
+}
+
+// recordStore adapts the maintenance and repair functions of the database
+// package, which have the same shape, to one set of handlers.
+type recordStore[T any] struct {
+	name   string
+	list   func(db *gorm.DB, propertyID, applianceID uint, referenceType string, spaceID uint, opts database.ListOptions) (*database.Page[T], error)
+	add    func(db *gorm.DB, in *recordInput) (*T, error)
+	get    func(db *gorm.DB, id uint) (*T, error)
+	update func(db *gorm.DB, id uint, description, date string, cost float64, notes string) (*T, error)
+	delete func(db *gorm.DB, id uint) error
+}
+
+var maintenanceRecords = recordStore[models.Maintenance]{
+	name: "maintenance record",
+	list: database.ListMaintenances,
+	add: func(db *gorm.DB, in *recordInput) (*models.Maintenance, error) {
+		m, err := database.AddMaintenance(db, &models.Maintenance{
+			PropertyID:    in.PropertyID,
+			Description:   in.Description,
+			Date:          in.Date,
+			Cost:          in.Cost,
+			Notes:         in.Notes,
+			ApplianceID:   in.ApplianceID,
+			SpaceID:       in.SpaceID,
+			SpaceType:     in.SpaceType,
+			ReferenceType: in.referenceType(),
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, fid := range in.AttachmentIDs {
+			_ = database.AttachFileToMaintenance(db, fid, m.ID)
+		}
+		return m, nil
+	},
+	get:    database.GetMaintenance,
+	update: database.UpdateMaintenance,
+	delete: database.DeleteMaintenance,
+}
+
+var repairRecords = recordStore[models.Repair]{
+	name: "repair record",
+	list: database.ListRepairs,
+	add: func(db *gorm.DB, in *recordInput) (*models.Repair, error) {
+		r, err := database.AddRepair(db, &models.Repair{
+			PropertyID:    in.PropertyID,
+			Description:   in.Description,
+			Date:          in.Date,
+			Cost:          in.Cost,
+			Notes:         in.Notes,
+			ApplianceID:   in.ApplianceID,
+			SpaceID:       in.SpaceID,
+			SpaceType:     in.SpaceType,
+			ReferenceType: in.referenceType(),
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, fid := range in.AttachmentIDs {
+			_ = database.AttachFileToRepair(db, fid, r.ID)
+		}
+		return r, nil
+	},
+	get:    database.GetRepair,
+	update: database.UpdateRepair,
+	delete: database.DeleteRepair,
+}
+
+// recordListHandler lists records, optionally limited to a property and to
+// one appliance (applianceId) or space (spaceId or spaceType).
+func recordListHandler[T any](db func() *gorm.DB, store recordStore[T]) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		propertyID, err := queryPropertyID(c)
+		if err != nil {
+			return sendQueryError(c, err)
+		}
+		applianceID, err := queryUint(c, "applianceId")
+		if err != nil {
+			return sendQueryError(c, err)
+		}
+		opts, err := queryListOptions(c)
+		if err != nil {
+			return sendQueryError(c, err)
+		}
+		spaceID, ok, err := querySpaceID(c, db(), propertyID)
+		if err != nil {
+			return sendQueryError(c, err)
+		}
+		if !ok {
+			return sendPage(c, &database.Page[T]{Items: []T{}})
+		}
+
+		referenceType := ""
+		switch {
+		case applianceID != 0:
+			referenceType = "Appliance"
+		case spaceID != 0:
+			referenceType = "Space"
+		}
+		page, err := store.list(db(), propertyID, applianceID, referenceType, spaceID, opts)
+		if err != nil {
+			return sendListError(c, store.name+"s", err)
+		}
+		return sendPage(c, page)
+	}
+}
+
+// recordCreateHandler creates a record and attaches any uploaded files.
+func recordCreateHandler[T any](db func() *gorm.DB, store recordStore[T]) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		var body recordInput
+		if err := c.Bind().Body(&body); err != nil {
+			return sendError(c, fiber.StatusBadRequest, "Error parsing body: "+err.Error())
+		}
+		if errs := body.validate(true); len(errs) > 0 {
+			return errs.send(c)
+		}
+		created, err := store.add(requestDB(c, db), &body)
+		if err != nil {
+			return sendStoreError(c, fiber.StatusInternalServerError, "Error adding "+store.name, err)
+		}
+		return c.Status(fiber.StatusCreated).JSON(created)
+	}
+}
+
+// recordGetHandler returns one record.
+func recordGetHandler[T any](db func() *gorm.DB, store recordStore[T]) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		id, err := paramID(c)
+		if err != nil {
+			return sendQueryError(c, err)
+		}
+		record, err := store.get(db(), id)
+		if err != nil {
+			return sendStoreError(c, fiber.StatusInternalServerError, "Error getting "+store.name, err)
+		}
+		return c.JSON(record)
+	}
+}
+
+// recordUpdateHandler replaces a record's description, date, cost and notes.
+func recordUpdateHandler[T any](db func() *gorm.DB, store recordStore[T]) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		id, err := paramID(c)
+		if err != nil {
+			return sendQueryError(c, err)
+		}
+		var body recordInput
+		if err := c.Bind().Body(&body); err != nil {
+			return sendError(c, fiber.StatusBadRequest, "Error parsing body: "+err.Error())
+		}
+		if errs := body.validate(false); len(errs) > 0 {
+			return errs.send(c)
+		}
+		updated, err := store.update(requestDB(c, db), id, body.Description, body.Date, body.Cost, body.Notes)
+		if err != nil {
+			return sendStoreError(c, fiber.StatusInternalServerError, "Error updating "+store.name, err)
+		}
+		return c.JSON(updated)
+	}
+}
+
+// recordDeleteHandler moves a record and its files to the trash.
+func recordDeleteHandler[T any](db func() *gorm.DB, store recordStore[T]) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		id, err := paramID(c)
+		if err != nil {
+			return sendQueryError(c, err)
+		}
+		if _, err := store.get(db(), id); err != nil {
+			return sendStoreError(c, fiber.StatusInternalServerError, "Error getting "+store.name, err)
+		}
+		if err := store.delete(requestDB(c, db), id); err != nil {
+			return sendStoreError(c, fiber.StatusInternalServerError, "Error deleting "+store.name, err)
+		}
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+}
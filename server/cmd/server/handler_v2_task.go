@@ -0,0 +1,324 @@
+package main
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/masoncfrancis/homelogger/server/internal/database"
+	"github.com/masoncfrancis/homelogger/server/internal/models"
+	"github.com/masoncfrancis/homelogger/server/internal/rrule"
+	"gorm.io/gorm"
+)
+
+var (
+	taskPriorities      = []string{"", "low", "medium", "high", "critical"}
+	taskRecurrenceUnits = []string{"days", "weeks", "months", "years"}
+	taskRecurrenceModes = []string{"", "completion_date", "due_date"}
+)
+
+type taskInput struct {
+	PropertyID         uint     `json:"propertyId"`
+	Label              string   `json:"label"`
+	Notes              string   `json:"notes"`
+	Priority           string   `json:"priority"`
+	DueDate            *string  `json:"dueDate"`
+	EstimatedCost      *float64 `json:"estimatedCost"`
+	IsRecurring        bool     `json:"isRecurring"`
+	RecurrenceInterval int      `json:"recurrenceInterval"`
+	RecurrenceUnit     string   `json:"recurrenceUnit"`
+	RecurrenceMode     string   `json:"recurrenceMode"`
+	RRule              *string  `json:"rrule"`
+	ApplianceID        *uint    `json:"applianceId"`
+	SpaceID            *uint    `json:"spaceId"`
+	SpaceType          *string  `json:"spaceType"`
+	LocationID         *uint    `json:"locationId"`
+	MeterID            *uint    `json:"meterId"`
+	MeterInterval      float64  `json:"meterInterval"`
+	MeterDueAt         *float64 `json:"meterDueAt"`
+	ParentID           *uint    `json:"parentId"`
+	AssigneeID         *uint    `json:"assigneeId"`
+}
+
+func (in *taskInput) validate() fieldErrors {
+	var errs fieldErrors
+	if strings.TrimSpace(in.Label) == "" {
+		errs.add("label", "label is required")
+	}
+	if !slices.Contains(taskPriorities, in.Priority) {
+		errs.add("priority", "priority must be low, medium, high or critical")
+	}
+	if in.DueDate != nil {
+		errs.requireDate("dueDate", *in.DueDate, false)
+	}
+	if in.EstimatedCost != nil && *in.EstimatedCost < 0 {
+		errs.add("estimatedCost", "estimatedCost must not be negative")
+	}
+	if in.hasRule() {
+		if _, err := rrule.Parse(*in.RRule); err != nil {
+			errs.add("rrule", "invalid rrule: "+err.Error())
+		}
+	} else if in.IsRecurring {
+		if in.RecurrenceInterval < 1 {
+			errs.add("recurrenceInterval", "recurrenceInterval must be at least 1 for a recurring task")
+		}
+		if !slices.Contains(taskRecurrenceUnits, in.RecurrenceUnit) {
+			errs.add("recurrenceUnit", "recurrenceUnit must be days, weeks, months or years")
+		}
+	}
+	if in.IsRecurring || in.hasRule() {
+		if !slices.Contains(taskRecurrenceModes, in.RecurrenceMode) {
+			errs.add("recurrenceMode", "recurrenceMode must be completion_date or due_date")
+		}
+	}
+	if in.MeterInterval < 0 {
+		errs.add("meterInterval", "meterInterval must not be negative")
+	}
+	if in.MeterDueAt != nil && *in.MeterDueAt < 0 {
+		errs.add("meterDueAt", "meterDueAt must not be negative")
+	}
+	return errs
+}
+
+// hasRule reports whether the input gives a recurrence rule.
+func (in *taskInput) hasRule() bool {
+	return in.RRule != nil && *in.RRule != ""
+}
+
+// apply copies the editable fields onto a task. Without an rrule the task's
+// rule is kept while the interval fields are unchanged, so clients that only
+// know the interval do not wipe a rule out; otherwise it is written afresh
+// from the interval.
+func (in *taskInput) apply(t *models.Task) {
+	switch {
+	case in.RRule != nil:
+		t.RRule = *in.RRule
+	case !in.IsRecurring || !t.IsRecurring || in.RecurrenceInterval != t.RecurrenceInterval || in.RecurrenceUnit != t.RecurrenceUnit:
+		t.RRule = ""
+	}
+	t.Label = in.Label
+	t.Notes = in.Notes
+	t.Priority = in.Priority
+	if !sameDate(t.DueDate, in.DueDate) {
+		// Rescheduled by hand: the new date is the occurrence's own.
+		t.SnoozedFrom = nil
+	}
+	t.DueDate = in.DueDate
+	t.EstimatedCost = in.EstimatedCost
+	t.IsRecurring = in.IsRecurring || in.hasRule()
+	t.RecurrenceInterval = in.RecurrenceInterval
+	t.RecurrenceUnit = in.RecurrenceUnit
+	t.RecurrenceMode = in.RecurrenceMode
+	t.ApplianceID = in.ApplianceID
+	t.SpaceID = in.SpaceID
+	t.SpaceType = in.SpaceType
+	t.LocationID = in.LocationID
+	in.applyMeter(t)
+	// Like meterId: left out keeps the parent, 0 makes the task stand alone.
+	if in.ParentID != nil {
+		t.ParentID = in.ParentID
+		if *in.ParentID == 0 {
+			t.ParentID = nil
+		}
+	}
+	// And the assignee: left out keeps it, 0 assigns the task to no one.
+	if in.AssigneeID != nil {
+		t.AssigneeID = in.AssigneeID
+		if *in.AssigneeID == 0 {
+			t.AssigneeID = nil
+		}
+	}
+}
+
+// sameDate reports whether two optional dates are the same day.
+func sameDate(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// applyMeter copies the usage trigger onto a task. Without a meterId the
+// task's meter is kept, so clients that do not know about meters leave it be;
+// meterId 0 takes the task off its meter. A threshold left out on the same
+// meter and interval keeps the one the task has.
+func (in *taskInput) applyMeter(t *models.Task) {
+	switch {
+	case in.MeterID == nil:
+		return
+	case *in.MeterID == 0:
+		t.MeterID, t.MeterInterval, t.MeterDueAt = nil, 0, nil
+		return
+	}
+	same := t.MeterID != nil && *t.MeterID == *in.MeterID && t.MeterInterval == in.MeterInterval
+	if in.MeterDueAt != nil || !same {
+		t.MeterDueAt = in.MeterDueAt
+	}
+	t.MeterID = in.MeterID
+	t.MeterInterval = in.MeterInterval
+}
+
+// V2TaskListHandler lists tasks by due date. Without applianceId, spaceId or
+// spaceType it lists every task of the property; completed tasks are left out
+// unless includeCompleted=true.
+func V2TaskListHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		propertyID, err := queryPropertyID(c)
+		if err != nil {
+			return sendQueryError(c, err)
+		}
+		applianceID, err := queryUint(c, "applianceId")
+		if err != nil {
+			return sendQueryError(c, err)
+		}
+		opts, err := queryListOptions(c)
+		if err != nil {
+			return sendQueryError(c, err)
+		}
+		spaceID, ok, err := querySpaceID(c, db(), propertyID)
+		if err != nil {
+			return sendQueryError(c, err)
+		}
+		if !ok {
+			return sendPage(c, &database.Page[models.Task]{Items: []models.Task{}})
+		}
+		includeCompleted := fiber.Query[bool](c, "includeCompleted", false)
+
+		var page *database.Page[models.Task]
+		if applianceID == 0 && spaceID == 0 {
+			page, err = database.ListAllTasks(db(), propertyID, includeCompleted, opts)
+		} else {
+			page, err = database.ListTasks(db(), propertyID, applianceID, spaceID, includeCompleted, opts)
+		}
+		if err != nil {
+			return sendListError(c, "tasks", err)
+		}
+		return sendPage(c, page)
+	}
+}
+
+// V2TaskCreateHandler creates a task owned by the current user.
+func V2TaskCreateHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		var body taskInput
+		if err := c.Bind().Body(&body); err != nil {
+			return sendError(c, fiber.StatusBadRequest, "Error parsing body: "+err.Error())
+		}
+		if errs := body.validate(); len(errs) > 0 {
+			return errs.send(c)
+		}
+		task := &models.Task{PropertyID: body.PropertyID, UserID: requestUserID(c, "1")}
+		body.apply(task)
+		created, err := database.AddTask(requestDB(c, db), task)
+		if err != nil {
+			return sendStoreError(c, fiber.StatusInternalServerError, "Error adding task", err)
+		}
+		return c.Status(fiber.StatusCreated).JSON(created)
+	}
+}
+
+// V2TaskGetHandler returns one task.
+func V2TaskGetHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		id, err := paramID(c)
+		if err != nil {
+			return sendQueryError(c, err)
+		}
+		task, err := database.GetTask(db(), id)
+		if err != nil {
+			return sendStoreError(c, fiber.StatusInternalServerError, "Error getting task", err)
+		}
+		return c.JSON(task)
+	}
+}
+
+// V2TaskUpdateHandler replaces the editable fields of a task.
+func V2TaskUpdateHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		id, err := paramID(c)
+		if err != nil {
+			return sendQueryError(c, err)
+		}
+		var body taskInput
+		if err := c.Bind().Body(&body); err != nil {
+			return sendError(c, fiber.StatusBadRequest, "Error parsing body: "+err.Error())
+		}
+		if errs := body.validate(); len(errs) > 0 {
+			return errs.send(c)
+		}
+		task, err := database.GetTask(db(), id)
+		if err != nil {
+			return sendStoreError(c, fiber.StatusInternalServerError, "Error getting task", err)
+		}
+		body.apply(task)
+		updated, err := database.UpdateTask(requestDB(c, db), task)
+		if err != nil {
+			return sendStoreError(c, fiber.StatusInternalServerError, "Error updating task", err)
+		}
+		return c.JSON(updated)
+	}
+}
+
+// V2TaskDeleteHandler moves a task to the trash.
+func V2TaskDeleteHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		id, err := paramID(c)
+		if err != nil {
+			return sendQueryError(c, err)
+		}
+		if _, err := database.GetTask(db(), id); err != nil {
+			return sendStoreError(c, fiber.StatusInternalServerError, "Error getting task", err)
+		}
+		if err := database.DeleteTask(requestDB(c, db), id); err != nil {
+			return sendStoreError(c, fiber.StatusInternalServerError, "Error deleting task", err)
+		}
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+}
+
+// V2TaskCompleteHandler completes a task, advancing recurring tasks to their
+// next due date, and optionally logs a maintenance or repair record.
+func V2TaskCompleteHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		id, err := paramID(c)
+		if err != nil {
+			return sendQueryError(c, err)
+		}
+		var body taskCompletion
+		if err := c.Bind().Body(&body); err != nil {
+			return sendError(c, fiber.StatusBadRequest, "Error parsing body: "+err.Error())
+		}
+		var errs fieldErrors
+		errs.requireDate("completionDate", body.CompletionDate, true)
+		if body.RecordType != "" && body.RecordType != "maintenance" && body.RecordType != "repair" {
+			errs.add("recordType", "recordType must be maintenance or repair")
+		}
+		if body.Cost < 0 {
+			errs.add("cost", "cost must not be negative")
+		}
+		if len(errs) > 0 {
+			return errs.send(c)
+		}
+		task, err := completeTask(requestDB(c, db), id, body)
+		if err != nil {
+			return sendStoreError(c, fiber.StatusInternalServerError, "Error completing task", err)
+		}
+		return c.JSON(task)
+	}
+}
+
+// V2TaskUncompleteHandler reopens a completed task, or undoes the last advance
+// of a recurring one.
+func V2TaskUncompleteHandler(db func() *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		id, err := paramID(c)
+		if err != nil {
+			return sendQueryError(c, err)
+		}
+		task, err := database.UncompleteTask(requestDB(c, db), id)
+		if err != nil {
+			return sendStoreError(c, fiber.StatusInternalServerError, "Error reopening task", err)
+		}
+		return c.JSON(task)
+	}
+}
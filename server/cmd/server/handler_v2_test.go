@@ -0,0 +1,199 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/masoncfrancis/homelogger/server/internal/database"
+	"github.com/masoncfrancis/homelogger/server/internal/models"
+	"gorm.io/gorm"
+)
+
+func createV2TestApp(t *testing.T, db *gorm.DB) *fiber.App {
+	t.Helper()
+	dbFn := func() *gorm.DB { return db }
+	app := fiber.New()
+	api := app.Group("/api")
+	registerV2(api.Group("/v2"), dbFn, t.TempDir(), &textExtractor{wake: make(chan struct{}, 1)})
+	api.Get("/spaces/:id", SpaceGetHandler(dbFn))
+	return app
+}
+
+// decodeAPIError reads a v2 error envelope.
+func decodeAPIError(t *testing.T, body []byte) apiError {
+	t.Helper()
+	var env errorEnvelope
+	if err := json.Unmarshal(body, &env); err != nil {
+		t.Fatalf("expected JSON error envelope, got %q: %v", body, err)
+	}
+	return env.Error
+}
+
+func detailFields(e apiError) []string {
+	var fields []string
+	for _, d := range e.Details {
+		fields = append(fields, d.Field)
+	}
+	return fields
+}
+
+func TestV2NotFoundUsesEnvelope(t *testing.T) {
+	app := createV2TestApp(t, openTestDB(t))
+
+	for _, path := range []string{"/api/v2/appliances/999", "/api/v2/tasks/999", "/api/v2/no-such-thing"} {
+		resp, body := doWithToken(t, app, "GET", path, nil, "")
+		if resp.StatusCode != fiber.StatusNotFound {
+			t.Fatalf("GET %s: expected 404, got %d", path, resp.StatusCode)
+		}
+		if !strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+			t.Fatalf("GET %s: expected JSON, got %q", path, resp.Header.Get("Content-Type"))
+		}
+		if e := decodeAPIError(t, body); e.Code != codeNotFound || e.Message == "" {
+			t.Fatalf("GET %s: unexpected error %+v", path, e)
+		}
+	}
+}
+
+func TestV2ValidationListsEveryField(t *testing.T) {
+	app := createV2TestApp(t, openTestDB(t))
+
+	resp, body := postJSON(t, app, "/api/v2/tasks", map[string]interface{}{
+		"label":    " ",
+		"priority": "urgent",
+		"dueDate":  "next week",
+	}, "")
+	if resp.StatusCode != fiber.StatusBadRequest {
+		t.Fatalf("expected 400, got %d: %s", resp.StatusCode, body)
+	}
+	e := decodeAPIError(t, body)
+	if e.Code != codeValidationFailed {
+		t.Fatalf("expected %s, got %+v", codeValidationFailed, e)
+	}
+	fields := detailFields(e)
+	for _, want := range []string{"label", "priority", "dueDate"} {
+		if !slices.Contains(fields, want) {
+			t.Fatalf("expected a detail for %s, got %v", want, fields)
+		}
+	}
+
+	// Validation from the database package is reported against its field.
+	resp, body = postJSON(t, app, "/api/v2/spaces", map[string]interface{}{"name": ""}, "")
+	if resp.StatusCode != fiber.StatusBadRequest {
+		t.Fatalf("expected 400, got %d: %s", resp.StatusCode, body)
+	}
+	if fields := detailFields(decodeAPIError(t, body)); !slices.Contains(fields, "name") {
+		t.Fatalf("expected a detail for name, got %v", fields)
+	}
+
+	resp, body = doWithToken(t, app, "GET", "/api/v2/notes?limit=many", nil, "")
+	if resp.StatusCode != fiber.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", resp.StatusCode)
+	}
+	if fields := detailFields(decodeAPIError(t, body)); !slices.Contains(fields, "limit") {
+		t.Fatalf("expected a detail for limit, got %v", fields)
+	}
+}
+
+func TestV2ApplianceLifecycle(t *testing.T) {
+	app := createV2TestApp(t, openTestDB(t))
+
+	resp, body := postJSON(t, app, "/api/v2/appliances", map[string]interface{}{"applianceName": "Dishwasher"}, "")
+	if resp.StatusCode != fiber.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", resp.StatusCode, body)
+	}
+	var created models.Appliance
+	if err := json.Unmarshal(body, &created); err != nil || created.ID == 0 {
+		t.Fatalf("unexpected appliance %s: %v", body, err)
+	}
+
+	resp, body = doWithToken(t, app, "GET", "/api/v2/appliances", nil, "")
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	var page database.Page[models.Appliance]
+	if err := json.Unmarshal(body, &page); err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if page.Total != 1 || len(page.Items) != 1 || page.Items[0].ApplianceName != "Dishwasher" {
+		t.Fatalf("unexpected page %+v", page)
+	}
+
+	path := fmt.Sprintf("/api/v2/appliances/%d", created.ID)
+	if resp, _ := doWithToken(t, app, "DELETE", path, nil, ""); resp.StatusCode != fiber.StatusNoContent {
+		t.Fatalf("expected 204, got %d", resp.StatusCode)
+	}
+	if resp, _ := doWithToken(t, app, "GET", path, nil, ""); resp.StatusCode != fiber.StatusNotFound {
+		t.Fatalf("expected 404 after delete, got %d", resp.StatusCode)
+	}
+}
+
+func TestV2TaskCompleteAddsRecord(t *testing.T) {
+	db := openTestDB(t)
+	app := createV2TestApp(t, db)
+
+	resp, body := postJSON(t, app, "/api/v2/tasks", map[string]interface{}{"label": "Replace filter", "priority": "low"}, "")
+	if resp.StatusCode != fiber.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", resp.StatusCode, body)
+	}
+	var task models.Task
+	_ = json.Unmarshal(body, &task)
+
+	path := fmt.Sprintf("/api/v2/tasks/%d/complete", task.ID)
+	resp, body = postJSON(t, app, path, map[string]interface{}{}, "")
+	if resp.StatusCode != fiber.StatusBadRequest {
+		t.Fatalf("expected 400 without completionDate, got %d", resp.StatusCode)
+	}
+	if fields := detailFields(decodeAPIError(t, body)); !slices.Contains(fields, "completionDate") {
+		t.Fatalf("expected a detail for completionDate, got %v", fields)
+	}
+
+	today := time.Now().Format("2006-01-02")
+	resp, body = postJSON(t, app, path, map[string]interface{}{
+		"completionDate": today,
+		"createRecord":   true,
+		"recordType":     "maintenance",
+		"cost":           12.5,
+	}, "")
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", resp.StatusCode, body)
+	}
+	var records []models.Maintenance
+	db.Find(&records)
+	if len(records) != 1 || records[0].Date != today || records[0].Cost != 12.5 {
+		t.Fatalf("expected one maintenance record, got %+v", records)
+	}
+}
+
+func TestV1KeepsPlainTextErrors(t *testing.T) {
+	app := createV2TestApp(t, openTestDB(t))
+
+	resp, body := doWithToken(t, app, "GET", "/api/spaces/999", nil, "")
+	if resp.StatusCode != fiber.StatusNotFound {
+		t.Fatalf("expected 404, got %d", resp.StatusCode)
+	}
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") || strings.HasPrefix(string(body), "{") {
+		t.Fatalf("expected a plain-text v1 error, got %q", body)
+	}
+}
+
+func TestV2MiddlewareRejectionsUseEnvelope(t *testing.T) {
+	db := openTestDB(t)
+	app := fiber.New()
+	app.Use(AuthMiddleware(func() *gorm.DB { return db }, authConfig{Enabled: true, SessionTTL: time.Hour}))
+	registerV2(app.Group("/api/v2"), func() *gorm.DB { return db }, t.TempDir(), &textExtractor{wake: make(chan struct{}, 1)})
+
+	for _, path := range []string{"/api/v2/tasks", "/API/V2/tasks"} {
+		resp, body := doWithToken(t, app, "GET", path, nil, "")
+		if resp.StatusCode != fiber.StatusUnauthorized {
+			t.Fatalf("GET %s: expected 401, got %d", path, resp.StatusCode)
+		}
+		if e := decodeAPIError(t, body); e.Code != codeUnauthorized {
+			t.Fatalf("GET %s: unexpected error %+v", path, e)
+		}
+	}
+}
@@ -5,7 +5,10 @@ import (
 	"encoding/json"
 	"net/http/httptest"
 	"strconv"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/gofiber/fiber/v3"
 	"github.com/masoncfrancis/homelogger/server/internal/database"
@@ -19,40 +22,40 @@ func openTestDB(t *testing.T) *gorm.DB {
 	return database.TestDB(t)
 }
 
-// createApp registers a minimal set of handlers using the provided DB.
-func createApp(db *gorm.DB) *fiber.App {
+// newTestApp builds the whole server, with every route and middleware, on the
+// given DB. Auth is disabled and uploads go to a temporary directory.
+func newTestApp(t *testing.T, dbs *dbProvider) *fiber.App {
+	t.Helper()
 	app := fiber.New()
-
-	app.Get("/api/health", func(c fiber.Ctx) error {
-		sqlDB, err := db.DB()
-		dbStatus := "ok"
-		if err != nil {
-			dbStatus = "error: " + err.Error()
-		} else if err := sqlDB.Ping(); err != nil {
-			dbStatus = "error: " + err.Error()
-		}
-		return c.Status(fiber.StatusOK).JSON(fiber.Map{"db": dbStatus})
+	registerRoutes(app, deps{
+		DB:          dbs,
+		UploadsRoot: t.TempDir(),
+		Extractor:   &textExtractor{dbs: dbs, wake: make(chan struct{}, 1)},
+		Importing:   &atomic.Bool{},
+		BackupMu:    &sync.Mutex{},
 	})
+	return app
+}
 
-	app.Get("/api/appliances", func(c fiber.Ctx) error {
-		apps, err := database.GetAppliances(db)
-		if err != nil {
-			return c.Status(fiber.StatusInternalServerError).SendString("err")
-		}
-		return c.JSON(apps)
+// newAuthTestApp is newTestApp with auth enabled.
+func newAuthTestApp(t *testing.T, dbs *dbProvider) *fiber.App {
+	t.Helper()
+	app := fiber.New()
+	registerRoutes(app, deps{
+		DB:          dbs,
+		UploadsRoot: t.TempDir(),
+		Auth:        authConfig{Enabled: true, SessionTTL: time.Hour},
+		Extractor:   &textExtractor{dbs: dbs, wake: make(chan struct{}, 1)},
+		Importing:   &atomic.Bool{},
+		BackupMu:    &sync.Mutex{},
 	})
+	return app
+}
 
-	app.Post("/api/appliances/add", func(c fiber.Ctx) error {
-		var body models.Appliance
-		if err := c.Bind().Body(&body); err != nil {
-			return c.Status(fiber.StatusBadRequest).SendString("bad")
-		}
-		a, err := database.AddAppliance(db, &body)
-		if err != nil {
-			return c.Status(fiber.StatusInternalServerError).SendString("err")
-		}
-		return c.JSON(a)
-	})
+// createTodoApp serves the legacy todo functions. The server itself no longer
+// has todo routes (todos are migrated to tasks at startup).
+func createTodoApp(db *gorm.DB) *fiber.App {
+	app := fiber.New()
 
 	app.Post("/api/todo/add", func(c fiber.Ctx) error {
 		var body struct {
@@ -71,7 +74,7 @@ func createApp(db *gorm.DB) *fiber.App {
 	})
 
 	app.Get("/api/todo", func(c fiber.Ctx) error {
-		todos, err := database.GetTodos(db, 0, "")
+		todos, err := database.GetTodos(db, "1", 0, "")
 		if err != nil {
 			return c.Status(fiber.StatusInternalServerError).SendString("err")
 		}
@@ -83,7 +86,7 @@ func createApp(db *gorm.DB) *fiber.App {
 
 func TestApplianceEndpoints(t *testing.T) {
 	db := openTestDB(t)
-	app := createApp(db)
+	app := newTestApp(t, newDBProvider(db))
 
 	// initially empty
 	req := httptest.NewRequest("GET", "/api/appliances", nil)
@@ -112,7 +115,7 @@ func TestApplianceEndpoints(t *testing.T) {
 
 func TestTodoEndpoints(t *testing.T) {
 	db := openTestDB(t)
-	app := createApp(db)
+	app := createTodoApp(db)
 
 	// add todo
 	payload := map[string]interface{}{"label": "t1", "checked": false, "userid": "1"}
@@ -132,138 +135,9 @@ func TestTodoEndpoints(t *testing.T) {
 	}
 }
 
-func createAppWithMaintenanceRepair(db *gorm.DB) *fiber.App {
-	app := fiber.New()
-
-	app.Post("/api/maintenance/add", maintenanceAddHandler(db))
-	app.Put("/api/maintenance/update/:id", maintenanceUpdateHandler(db))
-	app.Delete("/api/maintenance/delete/:id", maintenanceDeleteHandler(db))
-
-	app.Post("/api/repair/add", repairAddHandler(db))
-	app.Put("/api/repair/update/:id", repairUpdateHandler(db))
-	app.Delete("/api/repair/delete/:id", repairDeleteHandler(db))
-
-	return app
-}
-
-// parseIDParam parses a numeric :id param and returns it as uint
-func parseIDParam(c fiber.Ctx) (uint, error) {
-	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
-	if err != nil {
-		return 0, err
-	}
-	return uint(id), nil
-}
-
-func maintenanceAddHandler(db *gorm.DB) func(c fiber.Ctx) error {
-	return func(c fiber.Ctx) error {
-		var body struct {
-			models.Maintenance
-			AttachmentIDs []uint `json:"attachmentIds"`
-		}
-		if err := c.Bind().Body(&body); err != nil {
-			return c.Status(fiber.StatusBadRequest).SendString("bad")
-		}
-		m, err := database.AddMaintenance(db, &body.Maintenance)
-		if err != nil {
-			return c.Status(fiber.StatusInternalServerError).SendString("err")
-		}
-		return c.Status(fiber.StatusCreated).JSON(m)
-	}
-}
-
-func maintenanceUpdateHandler(db *gorm.DB) func(c fiber.Ctx) error {
-	return func(c fiber.Ctx) error {
-		id, err := parseIDParam(c)
-		if err != nil {
-			return c.Status(fiber.StatusBadRequest).SendString("invalid id")
-		}
-		var body struct {
-			Description string  `json:"description"`
-			Date        string  `json:"date"`
-			Cost        float64 `json:"cost"`
-			Notes       string  `json:"notes"`
-		}
-		if err := c.Bind().Body(&body); err != nil {
-			return c.Status(fiber.StatusBadRequest).SendString("bad")
-		}
-		updated, err := database.UpdateMaintenance(db, id, body.Description, body.Date, body.Cost, body.Notes)
-		if err != nil {
-			return c.Status(fiber.StatusInternalServerError).SendString("err")
-		}
-		return c.JSON(updated)
-	}
-}
-
-func maintenanceDeleteHandler(db *gorm.DB) func(c fiber.Ctx) error {
-	return func(c fiber.Ctx) error {
-		id, err := parseIDParam(c)
-		if err != nil {
-			return c.Status(fiber.StatusBadRequest).SendString("invalid id")
-		}
-		if err := database.DeleteMaintenance(db, id); err != nil {
-			return c.Status(fiber.StatusInternalServerError).SendString("err")
-		}
-		return c.SendStatus(fiber.StatusNoContent)
-	}
-}
-
-func repairAddHandler(db *gorm.DB) func(c fiber.Ctx) error {
-	return func(c fiber.Ctx) error {
-		var body struct {
-			models.Repair
-			AttachmentIDs []uint `json:"attachmentIds"`
-		}
-		if err := c.Bind().Body(&body); err != nil {
-			return c.Status(fiber.StatusBadRequest).SendString("bad")
-		}
-		r, err := database.AddRepair(db, &body.Repair)
-		if err != nil {
-			return c.Status(fiber.StatusInternalServerError).SendString("err")
-		}
-		return c.Status(fiber.StatusCreated).JSON(r)
-	}
-}
-
-func repairUpdateHandler(db *gorm.DB) func(c fiber.Ctx) error {
-	return func(c fiber.Ctx) error {
-		id, err := parseIDParam(c)
-		if err != nil {
-			return c.Status(fiber.StatusBadRequest).SendString("invalid id")
-		}
-		var body struct {
-			Description string  `json:"description"`
-			Date        string  `json:"date"`
-			Cost        float64 `json:"cost"`
-			Notes       string  `json:"notes"`
-		}
-		if err := c.Bind().Body(&body); err != nil {
-			return c.Status(fiber.StatusBadRequest).SendString("bad")
-		}
-		updated, err := database.UpdateRepair(db, id, body.Description, body.Date, body.Cost, body.Notes)
-		if err != nil {
-			return c.Status(fiber.StatusInternalServerError).SendString("err")
-		}
-		return c.JSON(updated)
-	}
-}
-
-func repairDeleteHandler(db *gorm.DB) func(c fiber.Ctx) error {
-	return func(c fiber.Ctx) error {
-		id, err := parseIDParam(c)
-		if err != nil {
-			return c.Status(fiber.StatusBadRequest).SendString("invalid id")
-		}
-		if err := database.DeleteRepair(db, id); err != nil {
-			return c.Status(fiber.StatusInternalServerError).SendString("err")
-		}
-		return c.SendStatus(fiber.StatusNoContent)
-	}
-}
-
 func TestMaintenanceEndpoints(t *testing.T) {
 	db := openTestDB(t)
-	app := createAppWithMaintenanceRepair(db)
+	app := newTestApp(t, newDBProvider(db))
 
 	// Add a maintenance record
 	payload := map[string]interface{}{
@@ -331,7 +205,7 @@ func TestMaintenanceEndpoints(t *testing.T) {
 
 func TestRepairEndpoints(t *testing.T) {
 	db := openTestDB(t)
-	app := createAppWithMaintenanceRepair(db)
+	app := newTestApp(t, newDBProvider(db))
 
 	// Add a repair record
 	payload := map[string]interface{}{
@@ -396,3 +270,22 @@ func TestRepairEndpoints(t *testing.T) {
 		t.Fatalf("expected 500 on update of deleted record, got %d", resp.StatusCode)
 	}
 }
+
+// The demo reset replaces the connection; every route must see the new one.
+func TestRoutesFollowSwappedDB(t *testing.T) {
+	first, second := openTestDB(t), openTestDB(t)
+	dbs := newDBProvider(first)
+	app := newTestApp(t, dbs)
+
+	if _, err := database.AddAppliance(second, &models.Appliance{ApplianceName: "Boiler"}); err != nil {
+		t.Fatal(err)
+	}
+	_ = dbs.Swap(func(*gorm.DB) (*gorm.DB, error) { return second, nil })
+
+	for _, path := range []string{"/api/appliances", "/api/v2/appliances"} {
+		resp, body := doWithToken(t, app, "GET", path, nil, "")
+		if resp.StatusCode != fiber.StatusOK || !bytes.Contains(body, []byte("Boiler")) {
+			t.Fatalf("GET %s: expected the swapped DB's appliance, got %d %s", path, resp.StatusCode, body)
+		}
+	}
+}
@@ -37,7 +37,7 @@ func createTestApp(cfg testAppConfig) *fiber.App {
 		return c.JSON(fiber.Map{"status": "ok", "importing": cfg.importing.Load()})
 	})
 
-	api.Post("/backup/import", ImportBackupHandler(cfg.db, cfg.importing, cfg.backupMu))
+	api.Post("/backup/import", ImportBackupHandler(func() *gorm.DB { return cfg.db }, cfg.importing, cfg.backupMu))
 
 	api.Get("/appliances", func(c fiber.Ctx) error {
 		var apps []models.Appliance
@@ -4,11 +4,15 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strconv"
 	"strings"
 	"sync/atomic"
 	"time"
 
 	"github.com/gofiber/fiber/v3"
+	"github.com/masoncfrancis/homelogger/server/internal/database"
+	"github.com/masoncfrancis/homelogger/server/internal/models"
+	"gorm.io/gorm"
 )
 
 type logWriter struct {
@@ -96,14 +100,212 @@ func requestLogger(w io.Writer) fiber.Handler {
 
 func ImportLockMiddleware(importing *atomic.Bool) fiber.Handler {
 	return func(c fiber.Ctx) error {
-		if importing.Load() && strings.HasPrefix(c.Path(), "/api/") {
-			if c.Path() != "/api/health" && c.Path() != "/api/backup/import" {
-				return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
-					"status":  "busy",
-					"message": "Server is restoring a backup. Please wait...",
-				})
+		path := requestPath(c)
+		if importing.Load() && strings.HasPrefix(path, "/api/") {
+			if path != "/api/health" && path != "/api/backup/import" {
+				return rejectRequest(c, fiber.StatusServiceUnavailable, "busy", "Server is restoring a backup. Please wait...")
 			}
 		}
 		return c.Next()
 	}
 }
+
+const (
+	sessionCookieName = "homelogger_session"
+	localsUserKey     = "user"
+	localsAPITokenKey = "apiToken"
+)
+
+// publicAPIPaths are reachable without a session even when auth is enabled.
+var publicAPIPaths = map[string]bool{
+	"/api/health":       true,
+	"/api/openapi.json": true,
+	"/api/auth/login":   true,
+	"/api/auth/setup":   true,
+	// OIDC single sign-on; the callback starts the session itself.
+	"/api/auth/oidc":          true,
+	"/api/auth/oidc/login":    true,
+	"/api/auth/oidc/callback": true,
+}
+
+// requestToken returns the session or API token from the Authorization header,
+// or the session token from the cookie.
+func requestToken(c fiber.Ctx) string {
+	if h := c.Get(fiber.HeaderAuthorization); h != "" {
+		if token, ok := strings.CutPrefix(h, "Bearer "); ok {
+			return strings.TrimSpace(token)
+		}
+	}
+	return c.Cookies(sessionCookieName)
+}
+
+// requestPath returns the request path in lower case. Routes match regardless
+// of case, so the access checks on paths must too.
+func requestPath(c fiber.Ctx) string {
+	return strings.ToLower(c.Path())
+}
+
+// AuthMiddleware resolves the request's session or personal API token to a
+// user and stores it in c.Locals. When auth is enabled, API calls without a
+// valid session or token get a 401. Non-API paths (the SPA) are always served.
+func AuthMiddleware(db func() *gorm.DB, cfg authConfig) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		path := requestPath(c)
+		if !strings.HasPrefix(path, "/api/") {
+			return c.Next()
+		}
+
+		if token := requestToken(c); strings.HasPrefix(token, models.APITokenPrefix) {
+			if user, apiToken, err := database.GetAPITokenUser(db(), token); err == nil {
+				c.Locals(localsUserKey, user)
+				c.Locals(localsAPITokenKey, apiToken)
+			}
+		} else if token != "" {
+			if user, err := database.GetSessionUser(db(), token); err == nil {
+				c.Locals(localsUserKey, user)
+			}
+		}
+
+		// Changes made during the request are audited under the user's name
+		if user := currentUser(c); user != nil {
+			c.SetContext(database.WithActorUser(c.Context(), user))
+		}
+
+		if !cfg.Enabled || publicAPIPaths[path] || currentUser(c) != nil {
+			return c.Next()
+		}
+
+		return rejectRequest(c, fiber.StatusUnauthorized, "unauthorized", "Authentication required")
+	}
+}
+
+// currentUser returns the authenticated user for the request, or nil.
+func currentUser(c fiber.Ctx) *models.User {
+	user, _ := c.Locals(localsUserKey).(*models.User)
+	return user
+}
+
+// requestUserID returns the authenticated user's ID as stored in UserID columns.
+// With auth disabled there is no user, so fallback is returned instead.
+func requestUserID(c fiber.Ctx, fallback string) string {
+	if user := currentUser(c); user != nil {
+		return strconv.FormatUint(uint64(user.ID), 10)
+	}
+	return fallback
+}
+
+// ownerOnlyPaths are API paths reserved for household owners.
+var ownerOnlyPaths = []string{
+	"/api/backup/download",
+	"/api/backup/import",
+	"/api/auth/users",
+	"/api/audit",
+	"/api/v2/audit",
+}
+
+// anyRolePaths may be called by every authenticated role regardless of method.
+// Personal API tokens are self-service, so viewers can manage their own read-only tokens.
+var anyRolePaths = map[string]bool{
+	"/api/auth/login":  true,
+	"/api/auth/logout": true,
+	"/api/auth/setup":  true,
+	"/api/auth/tokens": true,
+}
+
+// requiredRole returns the least privileged role allowed to make this request.
+func requiredRole(method, path string) string {
+	for _, p := range ownerOnlyPaths {
+		if path == p || strings.HasPrefix(path, p+"/") {
+			return models.RoleOwner
+		}
+	}
+	if anyRolePaths[path] || strings.HasPrefix(path, "/api/auth/tokens/") {
+		return models.RoleViewer
+	}
+	switch method {
+	case fiber.MethodGet, fiber.MethodHead, fiber.MethodOptions:
+		return models.RoleViewer
+	default:
+		return models.RoleMember
+	}
+}
+
+var roleRank = map[string]int{
+	models.RoleViewer: 1,
+	models.RoleMember: 2,
+	models.RoleOwner:  3,
+}
+
+// scopeResources maps API path prefixes to the token scope resource that covers them.
+var scopeResources = map[string]string{
+	"/api/task":        "tasks",
+	"/api/maintenance": "maintenance",
+	"/api/repair":      "repairs",
+	"/api/appliances":  "appliances",
+	"/api/notes":       "notes",
+	"/api/files":       "files",
+	"/api/meters":      "meters",
+
+	"/api/v2/tasks":       "tasks",
+	"/api/v2/task-packs":  "tasks",
+	"/api/v2/maintenance": "maintenance",
+	"/api/v2/repairs":     "repairs",
+	"/api/v2/appliances":  "appliances",
+	"/api/v2/notes":       "notes",
+	"/api/v2/files":       "files",
+	"/api/v2/meters":      "meters",
+}
+
+// pathResource returns the scope resource for an API path, or "" when only "*" covers it.
+func pathResource(path string) string {
+	for prefix, resource := range scopeResources {
+		if path == prefix || strings.HasPrefix(path, prefix+"/") {
+			return resource
+		}
+	}
+	return ""
+}
+
+// tokenAllows reports whether any of a personal API token's scopes covers the request.
+func tokenAllows(scopes []string, method, path string) bool {
+	readOnly := method == fiber.MethodGet || method == fiber.MethodHead || method == fiber.MethodOptions
+	resource := pathResource(path)
+	for _, scope := range scopes {
+		scopeResource, isRead := strings.CutSuffix(scope, ":read")
+		if isRead && !readOnly {
+			continue
+		}
+		if scopeResource == "*" || (resource != "" && scopeResource == resource) {
+			return true
+		}
+	}
+	return false
+}
+
+// currentAPIToken returns the personal API token used for the request, or nil for sessions.
+func currentAPIToken(c fiber.Ctx) *models.APIToken {
+	token, _ := c.Locals(localsAPITokenKey).(*models.APIToken)
+	return token
+}
+
+// RoleMiddleware enforces household roles on API routes: owners may do
+// anything, members may create and edit records, viewers may only read.
+// Requests made with a personal API token are further limited to its scopes.
+// It runs after AuthMiddleware; requests without a user (auth disabled or a
+// public path) are not restricted here.
+func RoleMiddleware() fiber.Handler {
+	return func(c fiber.Ctx) error {
+		user := currentUser(c)
+		path := requestPath(c)
+		if user == nil || !strings.HasPrefix(path, "/api/") || publicAPIPaths[path] {
+			return c.Next()
+		}
+		if roleRank[user.Role] < roleRank[requiredRole(c.Method(), path)] {
+			return rejectRequest(c, fiber.StatusForbidden, "forbidden", "Your role does not allow this action")
+		}
+		if apiToken := currentAPIToken(c); apiToken != nil && !tokenAllows(apiToken.ScopeList(), c.Method(), path) {
+			return rejectRequest(c, fiber.StatusForbidden, "forbidden", "API token scope does not allow this action")
+		}
+		return c.Next()
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	"github.com/gofiber/fiber/v3"
+	"github.com/masoncfrancis/homelogger/server/internal/models"
 )
 
 func TestImportLockMiddleware(t *testing.T) {
@@ -49,6 +50,23 @@ func TestImportLockMiddleware(t *testing.T) {
 		}
 	})
 
+	t.Run("importing blocks api routes in any letter case", func(t *testing.T) {
+		var importing atomic.Bool
+		importing.Store(true)
+		app := fiber.New()
+		app.Use(ImportLockMiddleware(&importing))
+
+		app.Post("/api/appliances/add", func(c fiber.Ctx) error {
+			return c.SendString("added")
+		})
+
+		req := httptest.NewRequest("POST", "/API/Appliances/add", nil)
+		resp, _ := app.Test(req)
+		if resp.StatusCode != fiber.StatusServiceUnavailable {
+			t.Errorf("expected 503, got %d", resp.StatusCode)
+		}
+	})
+
 	t.Run("health bypasses import lock", func(t *testing.T) {
 		var importing atomic.Bool
 		importing.Store(true)
@@ -102,3 +120,53 @@ func TestImportLockMiddleware(t *testing.T) {
 }
 
 
+
+func TestRequiredRole(t *testing.T) {
+	cases := []struct {
+		method, path, want string
+	}{
+		{"GET", "/api/appliances", models.RoleViewer},
+		{"POST", "/api/appliances/add", models.RoleMember},
+		{"PUT", "/api/task/complete/1", models.RoleMember},
+		{"DELETE", "/api/files/3", models.RoleMember},
+		{"GET", "/api/backup/download", models.RoleOwner},
+		{"POST", "/api/backup/import", models.RoleOwner},
+		{"PUT", "/api/auth/users/2/role", models.RoleOwner},
+		{"POST", "/api/auth/logout", models.RoleViewer},
+	}
+	for _, tc := range cases {
+		if got := requiredRole(tc.method, tc.path); got != tc.want {
+			t.Errorf("requiredRole(%s %s) = %q, want %q", tc.method, tc.path, got, tc.want)
+		}
+	}
+}
+
+func TestTokenAllows(t *testing.T) {
+	cases := []struct {
+		scopes       []string
+		method, path string
+		want         bool
+	}{
+		{[]string{"*"}, "DELETE", "/api/appliances/delete/1", true},
+		{[]string{"*:read"}, "GET", "/api/appliances", true},
+		{[]string{"*:read"}, "POST", "/api/task/add", false},
+		{[]string{"tasks"}, "PUT", "/api/task/complete/4", true},
+		{[]string{"tasks"}, "POST", "/api/task/packs/apply/furnace", true},
+		{[]string{"tasks"}, "POST", "/api/v2/task-packs/furnace/apply", true},
+		{[]string{"tasks:read"}, "GET", "/api/v2/task-packs", true},
+		{[]string{"tasks"}, "GET", "/api/appliances", false},
+		{[]string{"tasks"}, "GET", "/api/auth/tokens", false},
+		{[]string{"tasks", "maintenance"}, "POST", "/api/maintenance/add", true},
+		{[]string{"files:read"}, "POST", "/api/files/upload", false},
+		{[]string{"files"}, "POST", "/api/files/upload", true},
+		{[]string{"meters"}, "POST", "/api/meters/readings/add/1", true},
+		{[]string{"meters"}, "POST", "/api/v2/meters/1/readings", true},
+		{[]string{"meters:read"}, "POST", "/api/v2/meters/1/readings", false},
+		{[]string{"meters"}, "GET", "/api/notes", false},
+	}
+	for _, tc := range cases {
+		if got := tokenAllows(tc.scopes, tc.method, tc.path); got != tc.want {
+			t.Errorf("tokenAllows(%v, %s %s) = %v, want %v", tc.scopes, tc.method, tc.path, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,324 @@
+package main
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/masoncfrancis/homelogger/server/internal/database"
+	"github.com/masoncfrancis/homelogger/server/internal/models"
+	"github.com/masoncfrancis/homelogger/server/internal/sso"
+	"gorm.io/gorm"
+)
+
+const mockClientID = "homelogger-test"
+
+// mockIssuer is a minimal OpenID provider: discovery, JWKS and a token
+// endpoint that signs an ID token for whatever subject the test chose.
+type mockIssuer struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu        sync.Mutex
+	subject   string
+	claims    map[string]interface{}
+	nonce     string
+	challenge string
+}
+
+func newMockIssuer(t *testing.T) *mockIssuer {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	m := &mockIssuer{key: key}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                                m.server.URL,
+			"authorization_endpoint":                m.server.URL + "/authorize",
+			"token_endpoint":                        m.server.URL + "/token",
+			"jwks_uri":                              m.server.URL + "/jwks",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"alg": "RS256",
+				"use": "sig",
+				"kid": "test",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+		if base64.RawURLEncoding.EncodeToString(sum[:]) != m.challenge {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+
+		claims := map[string]interface{}{
+			"iss":   m.server.URL,
+			"sub":   m.subject,
+			"aud":   mockClientID,
+			"exp":   time.Now().Add(time.Hour).Unix(),
+			"iat":   time.Now().Unix(),
+			"nonce": m.nonce,
+		}
+		for k, v := range m.claims {
+			claims[k] = v
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "access",
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+			"id_token":     m.sign(t, claims),
+		})
+	})
+	m.server = httptest.NewServer(mux)
+	t.Cleanup(m.server.Close)
+	return m
+}
+
+func (m *mockIssuer) sign(t *testing.T, claims map[string]interface{}) string {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": "test", "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, m.key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Errorf("sign id_token: %v", err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+// authorize plays the user logging in at the issuer: it records the nonce and
+// PKCE challenge from the authorization URL and returns the callback query.
+func (m *mockIssuer) authorize(t *testing.T, location, subject string, claims map[string]interface{}) string {
+	t.Helper()
+	u, err := url.Parse(location)
+	if err != nil || !strings.HasPrefix(location, m.server.URL+"/authorize") {
+		t.Fatalf("expected redirect to mock issuer, got %q", location)
+	}
+	q := u.Query()
+	if q.Get("code_challenge_method") != "S256" {
+		t.Fatalf("expected PKCE S256, got %q", q.Get("code_challenge_method"))
+	}
+
+	m.mu.Lock()
+	m.subject = subject
+	m.claims = claims
+	m.nonce = q.Get("nonce")
+	m.challenge = q.Get("code_challenge")
+	m.mu.Unlock()
+
+	return "/api/auth/oidc/callback?code=test-code&state=" + url.QueryEscape(q.Get("state"))
+}
+
+func createOIDCTestApp(db *gorm.DB, cfg sso.Config) *fiber.App {
+	authCfg := authConfig{Enabled: true, SessionTTL: time.Hour}
+	dbFn := func() *gorm.DB { return db }
+	provider := sso.NewProvider(cfg)
+
+	app := fiber.New()
+	app.Use(AuthMiddleware(dbFn, authCfg))
+	api := app.Group("/api")
+	api.Get("/auth/me", AuthMeHandler())
+	api.Get("/auth/oidc", OIDCConfigHandler(provider))
+	api.Get("/auth/oidc/login", OIDCLoginHandler(provider, authCfg))
+	api.Get("/auth/oidc/callback", OIDCCallbackHandler(dbFn, provider, authCfg))
+	return app
+}
+
+// cookieValue returns the named cookie from a response's Set-Cookie headers.
+func cookieValue(resp *http.Response, name string) string {
+	for _, c := range resp.Cookies() {
+		if c.Name == name {
+			return c.Value
+		}
+	}
+	return ""
+}
+
+// oidcLogin runs the redirect, issuer and callback steps and returns the callback response.
+func oidcLogin(t *testing.T, app *fiber.App, issuer *mockIssuer, sessionToken, subject string, claims map[string]interface{}, link bool) *http.Response {
+	t.Helper()
+	path := "/api/auth/oidc/login"
+	if link {
+		path += "?link=true"
+	}
+	resp, _ := doWithToken(t, app, "GET", path, nil, sessionToken)
+	if resp.StatusCode != fiber.StatusSeeOther {
+		t.Fatalf("expected redirect to issuer, got %d", resp.StatusCode)
+	}
+	flowCookie := cookieValue(resp, oidcCookieName)
+	if flowCookie == "" {
+		t.Fatal("expected OIDC flow cookie")
+	}
+
+	callback := issuer.authorize(t, resp.Header.Get("Location"), subject, claims)
+	req := httptest.NewRequest("GET", callback, nil)
+	req.AddCookie(&http.Cookie{Name: oidcCookieName, Value: flowCookie})
+	if sessionToken != "" {
+		req.Header.Set("Authorization", "Bearer "+sessionToken)
+	}
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("callback: %v", err)
+	}
+	return resp
+}
+
+func TestOIDCLoginAutoProvision(t *testing.T) {
+	db := openTestDB(t)
+	issuer := newMockIssuer(t)
+	app := createOIDCTestApp(db, sso.Config{
+		IssuerURL:         issuer.server.URL,
+		ClientID:          mockClientID,
+		RedirectURL:       "http://homelogger.test/api/auth/oidc/callback",
+		Scopes:            []string{"openid"},
+		UsernameClaim:     "preferred_username",
+		AutoProvision:     true,
+		DefaultRole:       models.RoleViewer,
+		ProviderName:      "Mock",
+		PostLoginRedirect: "/",
+	})
+
+	if status, body := getWithToken(t, app, "/api/auth/oidc", ""); status != fiber.StatusOK || !strings.Contains(body, `"enabled":true`) {
+		t.Fatalf("expected OIDC to be advertised, got %d %s", status, body)
+	}
+
+	// First SSO user becomes the owner
+	resp := oidcLogin(t, app, issuer, "", "sub-alice", map[string]interface{}{"preferred_username": "alice", "name": "Alice"}, false)
+	if resp.StatusCode != fiber.StatusSeeOther || resp.Header.Get("Location") != "/" {
+		t.Fatalf("expected redirect home after login, got %d %q", resp.StatusCode, resp.Header.Get("Location"))
+	}
+	token := cookieValue(resp, sessionCookieName)
+	if token == "" {
+		t.Fatal("expected session cookie after OIDC login")
+	}
+	status, body := getWithToken(t, app, "/api/auth/me", token)
+	if status != fiber.StatusOK || !strings.Contains(body, `"username":"alice"`) || !strings.Contains(body, `"role":"owner"`) {
+		t.Fatalf("expected alice as owner, got %d %s", status, body)
+	}
+
+	events, err := database.ListAuditEvents(db, database.AuditFilter{EntityType: database.AuditUser}, database.ListOptions{})
+	if err != nil || events.Total != 1 || events.Items[0].Actor != database.SystemActor {
+		t.Fatalf("expected the provisioning audited as the server, got %+v, %v", events, err)
+	}
+
+	// Later users get the default role; a taken username gets a suffix
+	resp = oidcLogin(t, app, issuer, "", "sub-other-alice", map[string]interface{}{"preferred_username": "alice"}, false)
+	status, body = getWithToken(t, app, "/api/auth/me", cookieValue(resp, sessionCookieName))
+	if status != fiber.StatusOK || !strings.Contains(body, `"username":"alice2"`) || !strings.Contains(body, `"role":"viewer"`) {
+		t.Fatalf("expected alice2 as viewer, got %d %s", status, body)
+	}
+
+	// The same subject logs back into the same account
+	oidcLogin(t, app, issuer, "", "sub-alice", map[string]interface{}{"preferred_username": "renamed"}, false)
+	if count, _ := database.CountUsers(db); count != 2 {
+		t.Fatalf("expected 2 users, got %d", count)
+	}
+
+	// SSO-only accounts cannot use password login
+	if _, err := database.AuthenticateUser(db, "alice", ""); err == nil {
+		t.Fatal("expected password login to fail for SSO-only account")
+	}
+}
+
+func TestOIDCLoginRequiresLinkedAccount(t *testing.T) {
+	db := openTestDB(t)
+	issuer := newMockIssuer(t)
+	app := createOIDCTestApp(db, sso.Config{
+		IssuerURL:         issuer.server.URL,
+		ClientID:          mockClientID,
+		RedirectURL:       "http://homelogger.test/api/auth/oidc/callback",
+		Scopes:            []string{"openid"},
+		DefaultRole:       models.RoleMember,
+		PostLoginRedirect: "/",
+	})
+
+	user, err := database.CreateUser(db, "bob", "", "correct-horse", models.RoleOwner)
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	// Unknown subject without auto-provisioning
+	resp := oidcLogin(t, app, issuer, "", "sub-bob", nil, false)
+	if resp.StatusCode != fiber.StatusForbidden {
+		t.Fatalf("expected 403 for unlinked identity, got %d", resp.StatusCode)
+	}
+
+	// Link while logged in with a password session
+	token, _, err := database.CreateSession(db, user.ID, time.Hour)
+	if err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	resp = oidcLogin(t, app, issuer, token, "sub-bob", nil, true)
+	if resp.StatusCode != fiber.StatusSeeOther {
+		t.Fatalf("expected redirect after linking, got %d", resp.StatusCode)
+	}
+
+	// Now the identity logs in as bob
+	resp = oidcLogin(t, app, issuer, "", "sub-bob", nil, false)
+	status, body := getWithToken(t, app, "/api/auth/me", cookieValue(resp, sessionCookieName))
+	if status != fiber.StatusOK || !strings.Contains(body, `"username":"bob"`) {
+		t.Fatalf("expected to log in as bob, got %d %s", status, body)
+	}
+}
+
+func TestOIDCCallbackRejectsBadState(t *testing.T) {
+	db := openTestDB(t)
+	issuer := newMockIssuer(t)
+	app := createOIDCTestApp(db, sso.Config{
+		IssuerURL:   issuer.server.URL,
+		ClientID:    mockClientID,
+		RedirectURL: "http://homelogger.test/api/auth/oidc/callback",
+		Scopes:      []string{"openid"},
+	})
+
+	req := httptest.NewRequest("GET", "/api/auth/oidc/callback?code=x&state=forged", nil)
+	req.AddCookie(&http.Cookie{Name: oidcCookieName, Value: oidcFlow{State: "real", Nonce: "n", Verifier: "v"}.encode()})
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("callback: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusBadRequest {
+		t.Fatalf("expected 400 for mismatched state, got %d", resp.StatusCode)
+	}
+}
+
+func TestOIDCDisabled(t *testing.T) {
+	db := openTestDB(t)
+	app := createOIDCTestApp(db, sso.Config{})
+
+	if status, body := getWithToken(t, app, "/api/auth/oidc", ""); status != fiber.StatusOK || !strings.Contains(body, `"enabled":false`) {
+		t.Fatalf("expected OIDC disabled, got %d %s", status, body)
+	}
+	if status, _ := getWithToken(t, app, "/api/auth/oidc/login", ""); status != fiber.StatusNotFound {
+		t.Fatalf("expected 404 when OIDC is not configured, got %d", status)
+	}
+}
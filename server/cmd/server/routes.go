@@ -0,0 +1,206 @@
+package main
+
+import (
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/gofiber/fiber/v3/middleware/static"
+	"github.com/masoncfrancis/homelogger/server/internal/sso"
+)
+
+// deps is what the routes share. Handlers are given DB.Get and call it on
+// every request rather than keeping a *gorm.DB, so a new connection (after a
+// demo reset) reaches all of them.
+type deps struct {
+	DB          *dbProvider
+	UploadsRoot string // holds every stored upload; backups archive all of it
+	DemoMode    bool   // demo uploads go to their own folder under UploadsRoot
+
+	Auth      authConfig
+	OIDC      *sso.Provider
+	Extractor *textExtractor
+	Importing *atomic.Bool // set while a backup import runs
+	BackupMu  *sync.Mutex  // serializes backup downloads and imports
+}
+
+// uploadsDir is where new uploads are stored.
+func (d deps) uploadsDir() string {
+	if d.DemoMode {
+		return filepath.Join(d.UploadsRoot, "demo-uploads")
+	}
+	return d.UploadsRoot
+}
+
+// registerRoutes installs the DB-swap, import-lock, auth and role middleware and then
+// every route: the v2 API, the original /api routes (v1) and the SPA.
+func registerRoutes(app *fiber.App, d deps) {
+	db := d.DB.Get
+
+	// Holds off a DB swap until in-flight requests finish
+	app.Use(d.DB.Middleware())
+	// Blocks all non-critical API calls during backup import
+	app.Use(ImportLockMiddleware(d.Importing))
+	// Resolves the session and, when AUTH_ENABLED, rejects anonymous API calls
+	app.Use(AuthMiddleware(db, d.Auth))
+	// Owners, members and read-only viewers
+	app.Use(RoleMiddleware())
+
+	api := app.Group("/api")
+
+	// Versioned REST API with JSON errors. Everything else registered on api
+	// below is v1 and keeps its original paths and plain-text errors.
+	registerV2(api.Group("/v2"), db, d.uploadsDir(), d.Extractor)
+
+	api.Get("/health", HealthHandler(db, d.DemoMode, d.Importing))
+
+	// API description, generated clients fetch this
+	api.Get("/openapi.json", OpenAPIHandler())
+
+	// Auth
+	api.Post("/auth/setup", AuthSetupHandler(db, d.Auth))
+	api.Post("/auth/login", AuthLoginHandler(db, d.Auth))
+	api.Post("/auth/logout", AuthLogoutHandler(db))
+	api.Get("/auth/me", AuthMeHandler())
+	api.Get("/auth/users", AuthListUsersHandler(db))
+	api.Post("/auth/users", AuthCreateUserHandler(db))
+	api.Put("/auth/users/:id/role", AuthUpdateRoleHandler(db))
+	api.Get("/auth/tokens", APITokenListHandler(db))
+	api.Post("/auth/tokens", APITokenCreateHandler(db))
+	api.Delete("/auth/tokens/:id", APITokenRevokeHandler(db))
+	api.Get("/auth/oidc", OIDCConfigHandler(d.OIDC))
+	api.Get("/auth/oidc/login", OIDCLoginHandler(d.OIDC, d.Auth))
+	api.Get("/auth/oidc/callback", OIDCCallbackHandler(db, d.OIDC, d.Auth))
+
+	// Properties
+	api.Get("/properties", PropertyListHandler(db))
+	api.Post("/properties/add", PropertyAddHandler(db))
+	api.Get("/properties/:id", PropertyGetHandler(db))
+	api.Put("/properties/update/:id", PropertyUpdateHandler(db))
+	api.Delete("/properties/delete/:id", PropertyDeleteHandler(db))
+
+	// Spaces
+	api.Get("/spaces", SpaceListHandler(db))
+	api.Post("/spaces/add", SpaceAddHandler(db))
+	api.Get("/spaces/:id", SpaceGetHandler(db))
+	api.Get("/spaces/:id/files", SpaceFilesHandler(db))
+	api.Put("/spaces/update/:id", SpaceUpdateHandler(db))
+	api.Delete("/spaces/delete/:id", SpaceDeleteHandler(db))
+
+	// Full-text search across appliances, maintenance, repairs, tasks, notes and file names
+	api.Get("/search", SearchHandler(db))
+
+	// Locations (floor → room → zone) and everything under a subtree
+	api.Get("/locations", LocationListHandler(db))
+	api.Get("/locations/tree", LocationTreeHandler(db))
+	api.Post("/locations/add", LocationAddHandler(db))
+	api.Get("/locations/:id", LocationGetHandler(db))
+	api.Get("/locations/:id/appliances", LocationAppliancesHandler(db))
+	api.Get("/locations/:id/tasks", LocationTasksHandler(db))
+	api.Get("/locations/:id/notes", LocationNotesHandler(db))
+	api.Get("/locations/:id/files", LocationFilesHandler(db))
+	api.Put("/locations/update/:id", LocationUpdateHandler(db))
+	api.Delete("/locations/delete/:id", LocationDeleteHandler(db))
+
+	// Appliances
+	api.Get("/appliances", ApplianceListHandler(db))
+	api.Post("/appliances/add", ApplianceAddHandler(db))
+	api.Put("/appliances/update/:id", ApplianceUpdateHandler(db))
+	api.Get("/appliances/:id", ApplianceGetHandler(db))
+	api.Delete("/appliances/delete/:id", ApplianceDeleteHandler(db))
+
+	// Maintenance
+	api.Get("/maintenance", MaintenanceListHandler(db))
+	api.Post("/maintenance/add", MaintenanceAddHandler(db))
+	api.Get("/maintenance/:id", MaintenanceGetHandler(db))
+	api.Delete("/maintenance/delete/:id", MaintenanceDeleteHandler(db))
+	api.Put("/maintenance/update/:id", MaintenanceUpdateHandler(db))
+
+	// Repairs
+	api.Get("/repair", RepairListHandler(db))
+	api.Post("/repair/add", RepairAddHandler(db))
+	api.Get("/repair/:id", RepairGetHandler(db))
+	api.Delete("/repair/delete/:id", RepairDeleteHandler(db))
+	api.Put("/repair/update/:id", RepairUpdateHandler(db))
+
+	// Files
+	api.Post("/files/upload", FileUploadHandler(db, d.uploadsDir(), d.Extractor))
+	api.Get("/files/info/:id", FileInfoHandler(db))
+	api.Get("/files/maintenance/:id", MaintenanceFilesHandler(db))
+	api.Get("/files/repair/:id", RepairFilesHandler(db))
+	api.Get("/files/download/:id", FileDownloadHandler(db))
+	api.Get("/files/appliance/:id", ApplianceFilesHandler(db))
+	api.Get("/files/space/:spaceType", SpaceTypeFilesHandler(db))
+	api.Post("/files/attach", FileAttachHandler(db))
+	api.Delete("/files/:id", FileDeleteHandler(db))
+
+	// Notes
+	api.Get("/notes", NoteListHandler(db))
+	api.Post("/notes/add", NoteAddHandler(db))
+	api.Get("/notes/:id", NoteGetHandler(db))
+	api.Put("/notes/update/:id", NoteUpdateHandler(db))
+	api.Delete("/notes/delete/:id", NoteDeleteHandler(db))
+
+	// Tasks
+	api.Get("/task", TaskListHandler(db))
+	api.Get("/task/dashboard", TaskDashboardHandler(db))
+	api.Get("/task/mine", TaskMineHandler(db))
+	api.Post("/task/add", TaskAddHandler(db))
+	api.Get("/task/packs", TaskPackListHandler(db))
+	api.Get("/task/packs/export", TaskPackExportHandler(db))
+	api.Post("/task/packs/import", TaskPackImportHandler(db))
+	api.Post("/task/packs/add", TaskPackAddHandler(db))
+	api.Get("/task/packs/:key", TaskPackGetHandler(db))
+	api.Put("/task/packs/update/:key", TaskPackUpdateHandler(db))
+	api.Delete("/task/packs/delete/:key", TaskPackDeleteHandler(db))
+	api.Post("/task/packs/apply/:key", TaskPackApplyHandler(db))
+	api.Get("/task/:id", TaskGetHandler(db))
+	api.Put("/task/update/:id", TaskUpdateHandler(db))
+	api.Put("/task/complete/:id", TaskCompleteHandler(db))
+	api.Put("/task/uncomplete/:id", TaskUncompleteHandler(db))
+	api.Put("/task/skip/:id", TaskSkipHandler(db))
+	api.Put("/task/snooze/:id", TaskSnoozeHandler(db))
+	api.Put("/task/assign/:id", TaskAssignHandler(db))
+	api.Delete("/task/delete/:id", TaskDeleteHandler(db))
+	api.Get("/task/completions/:id", TaskCompletionListHandler(db))
+	api.Get("/task/completions/stats/:id", TaskCompletionStatsHandler(db))
+	api.Delete("/task/completions/undo/:id/:completionId", TaskCompletionUndoHandler(db))
+	api.Get("/task/subtasks/:id", TaskSubtasksHandler(db))
+	api.Get("/task/dependencies/:id", TaskDependencyListHandler(db))
+	api.Post("/task/dependencies/add/:id", TaskDependencyAddHandler(db))
+	api.Delete("/task/dependencies/delete/:id/:blockerId", TaskDependencyDeleteHandler(db))
+
+	// Meters on appliances and their readings, for usage-based tasks
+	api.Get("/meters", MeterListHandler(db))
+	api.Post("/meters/add", MeterAddHandler(db))
+	api.Get("/meters/:id", MeterGetHandler(db))
+	api.Put("/meters/update/:id", MeterUpdateHandler(db))
+	api.Delete("/meters/delete/:id", MeterDeleteHandler(db))
+	api.Get("/meters/readings/:id", MeterReadingListHandler(db))
+	api.Post("/meters/readings/add/:id", MeterReadingAddHandler(db))
+	api.Delete("/meters/readings/delete/:id/:readingId", MeterReadingDeleteHandler(db))
+
+	// Trash: deleted items can be restored until purged
+	api.Get("/trash", TrashListHandler(db))
+	api.Put("/trash/restore/:type/:id", TrashRestoreHandler(db))
+	api.Delete("/trash/purge/:type/:id", TrashPurgeHandler(db))
+	api.Delete("/trash/purge", TrashEmptyHandler(db))
+
+	// Earlier versions of notes, tasks and maintenance and repair records
+	api.Get("/revisions/:type/:id", RevisionListHandler(db))
+	api.Get("/revisions/:type/:id/diff", RevisionDiffHandler(db))
+	api.Put("/revisions/:type/:id/revert/:version", RevisionRevertHandler(db))
+
+	// Audit log of every change, newest first
+	api.Get("/audit", AuditListHandler(db))
+
+	// Backups. Import replaces all data: drop tables → migrate → insert
+	api.Get("/backup/download", BackupDownloadHandler(db, d.UploadsRoot, d.BackupMu))
+	api.Post("/backup/import", ImportBackupHandler(db, d.Importing, d.BackupMu))
+
+	// Serve static SPA files with client-side routing fallback
+	app.Get("/*", static.New("./static"), func(c fiber.Ctx) error {
+		return c.SendFile("./static/index.html")
+	})
+}
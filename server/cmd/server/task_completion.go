@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/masoncfrancis/homelogger/server/internal/database"
+	"github.com/masoncfrancis/homelogger/server/internal/models"
+	"gorm.io/gorm"
+)
+
+// taskCompletion is the body for completing a task. With CreateRecord set, a
+// maintenance record (or a repair, when RecordType is "repair") is logged
+// against the task's appliance or space. Cost and Notes go into the task's
+// completion history either way.
+type taskCompletion struct {
+	CompletionDate string  `json:"completionDate"`
+	CreateRecord   bool    `json:"createRecord"`
+	RecordType     string  `json:"recordType"`
+	Description    string  `json:"description"`
+	Cost           float64 `json:"cost"`
+	Notes          string  `json:"notes"`
+}
+
+// completeTask marks a task complete and logs the optional record, in one
+// transaction.
+func completeTask(db *gorm.DB, id uint, body taskCompletion) (*models.Task, error) {
+	var task *models.Task
+	err := db.Transaction(func(tx *gorm.DB) error {
+		current, err := database.GetTask(tx, id)
+		if err != nil {
+			return err
+		}
+		completion := database.TaskCompletionInput{Date: body.CompletionDate, Cost: body.Cost, Notes: body.Notes}
+		if body.CreateRecord {
+			if err := logTaskRecord(tx, current, body, &completion); err != nil {
+				return err
+			}
+		}
+		task, err = database.CompleteTaskWith(tx, id, completion)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+// logTaskRecord logs the maintenance or repair record for a completed task and
+// links it to the completion.
+func logTaskRecord(tx *gorm.DB, task *models.Task, body taskCompletion, completion *database.TaskCompletionInput) error {
+	description := body.Description
+	if description == "" {
+		description = task.Label
+	}
+
+	// Determine reference type from the task
+	refType := "Space"
+	spaceType := ""
+	var applianceId, spaceID *uint
+	if task.ApplianceID != nil {
+		refType = "Appliance"
+		applianceId = task.ApplianceID
+	} else if task.SpaceID != nil {
+		spaceID = task.SpaceID
+	} else if task.SpaceType != nil {
+		spaceType = *task.SpaceType
+	}
+
+	if body.RecordType == "repair" {
+		repair := &models.Repair{
+			Description:   description,
+			Date:          body.CompletionDate,
+			Cost:          body.Cost,
+			PropertyID:    task.PropertyID,
+			SpaceID:       spaceID,
+			SpaceType:     spaceType,
+			ReferenceType: refType,
+			ApplianceID:   applianceId,
+		}
+		if _, err := database.AddRepair(tx, repair); err != nil {
+			return fmt.Errorf("creating repair record: %w", err)
+		}
+		completion.RepairID = &repair.ID
+		return nil
+	}
+
+	maintenance := &models.Maintenance{
+		Description:   description,
+		Date:          body.CompletionDate,
+		Cost:          body.Cost,
+		PropertyID:    task.PropertyID,
+		SpaceID:       spaceID,
+		SpaceType:     spaceType,
+		ReferenceType: refType,
+		ApplianceID:   applianceId,
+	}
+	if _, err := database.AddMaintenance(tx, maintenance); err != nil {
+		return fmt.Errorf("creating maintenance record: %w", err)
+	}
+	completion.MaintenanceID = &maintenance.ID
+	return nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/masoncfrancis/homelogger/server/internal/database"
+	"gorm.io/gorm"
+)
+
+// defaultTrashRetention is how long deleted items stay in the trash when
+// TRASH_RETENTION_DAYS is not set.
+const defaultTrashRetention = 30 * 24 * time.Hour
+
+// trashPurgeInterval is how often the purger looks for expired items.
+const trashPurgeInterval = time.Hour
+
+// trashRetentionFromEnv reads TRASH_RETENTION_DAYS. Zero keeps deleted items
+// until they are purged by hand.
+func trashRetentionFromEnv() time.Duration {
+	raw := strings.TrimSpace(os.Getenv("TRASH_RETENTION_DAYS"))
+	if raw == "" {
+		return defaultTrashRetention
+	}
+	days, err := strconv.Atoi(raw)
+	if err != nil || days < 0 {
+		fmt.Printf("Warning: invalid TRASH_RETENTION_DAYS %q, using %s\n", raw, defaultTrashRetention)
+		return defaultTrashRetention
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// recoverFileTrashWith puts back or removes the files a crash left in the
+// trash folder of uploadsDir, checked against the database connect opens. The
+// connection is only opened when there is something to recover, and closed
+// afterwards.
+func recoverFileTrashWith(connect func() (*gorm.DB, error), uploadsDir string) error {
+	if _, err := os.Stat(filepath.Join(uploadsDir, database.FileTrashDir)); os.IsNotExist(err) {
+		return nil
+	}
+	db, err := connect()
+	if err != nil {
+		return err
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		defer sqlDB.Close()
+	}
+	return database.RecoverFileTrash(db, uploadsDir)
+}
+
+// startTrashPurger purges items that have been in the trash longer than
+// retention, now and then every trashPurgeInterval. A zero retention
+// disables it.
+func startTrashPurger(dbs *dbProvider, retention time.Duration) {
+	if retention <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(trashPurgeInterval)
+		defer ticker.Stop()
+		for {
+			var n int64
+			err := dbs.Do(func(db *gorm.DB) error {
+				var err error
+				n, err = database.PurgeTrash(systemDB(db), time.Now().Add(-retention))
+				return err
+			})
+			if err != nil {
+				fmt.Printf("Trash purge failed: %v\n", err)
+			} else if n > 0 {
+				fmt.Printf("Purged %d expired item(s) from the trash\n", n)
+			}
+			<-ticker.C
+		}
+	}()
+}
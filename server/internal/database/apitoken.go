@@ -0,0 +1,101 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/masoncfrancis/homelogger/server/internal/models"
+	"gorm.io/gorm"
+)
+
+// ErrInvalidAPIToken is returned when an API token is unknown, revoked or expired.
+var ErrInvalidAPIToken = errors.New("API token invalid, revoked or expired")
+
+// lastUsedResolution limits how often a token's last-used timestamp is written.
+const lastUsedResolution = time.Minute
+
+// CreateAPIToken creates a personal API token for the user and returns the raw token.
+// The raw token is only returned here; the database keeps its hash.
+func CreateAPIToken(db *gorm.DB, userID uint, name string, scopes []string, expiresAt *time.Time) (string, *models.APIToken, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", nil, fmt.Errorf("name is required")
+	}
+	if len(scopes) == 0 {
+		return "", nil, fmt.Errorf("at least one scope is required")
+	}
+	for _, s := range scopes {
+		if !models.ValidTokenScope(s) {
+			return "", nil, fmt.Errorf("invalid scope %q", s)
+		}
+	}
+
+	random, _, err := newToken()
+	if err != nil {
+		return "", nil, fmt.Errorf("generate token: %w", err)
+	}
+	token := models.APITokenPrefix + random
+
+	apiToken := &models.APIToken{
+		UserID:    userID,
+		Name:      name,
+		TokenHash: hashToken(token),
+		Prefix:    token[:len(models.APITokenPrefix)+6],
+		Scopes:    strings.Join(scopes, ","),
+		ExpiresAt: expiresAt,
+	}
+	if err := createAudited(db, AuditAPIToken, apiToken); err != nil {
+		return "", nil, err
+	}
+	return token, apiToken, nil
+}
+
+// ListAPITokens returns the user's tokens, newest first.
+func ListAPITokens(db *gorm.DB, userID uint) ([]models.APIToken, error) {
+	var tokens []models.APIToken
+	if err := db.Where("user_id = ?", userID).Order("created_at DESC").Find(&tokens).Error; err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// RevokeAPIToken revokes one of the user's tokens.
+func RevokeAPIToken(db *gorm.DB, userID, id uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var own int64
+		if err := tx.Model(&models.APIToken{}).Where("id = ? AND user_id = ?", id, userID).Count(&own).Error; err != nil {
+			return err
+		}
+		if own == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return deleteAudited[models.APIToken](tx, AuditAPIToken, id)
+	})
+}
+
+// GetAPITokenUser resolves a raw API token to its user and records the use.
+func GetAPITokenUser(db *gorm.DB, token string) (*models.User, *models.APIToken, error) {
+	var apiToken models.APIToken
+	if err := db.Preload("User").Where("token_hash = ?", hashToken(token)).First(&apiToken).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrInvalidAPIToken
+		}
+		return nil, nil, err
+	}
+	now := time.Now()
+	if apiToken.ExpiresAt != nil && now.After(*apiToken.ExpiresAt) {
+		return nil, nil, ErrInvalidAPIToken
+	}
+	if apiToken.User.ID == 0 {
+		return nil, nil, ErrInvalidAPIToken
+	}
+
+	if apiToken.LastUsedAt == nil || now.Sub(*apiToken.LastUsedAt) > lastUsedResolution {
+		if err := db.Model(&apiToken).UpdateColumn("last_used_at", now).Error; err == nil {
+			apiToken.LastUsedAt = &now
+		}
+	}
+	return &apiToken.User, &apiToken, nil
+}
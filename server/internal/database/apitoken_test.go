@@ -0,0 +1,78 @@
+package database
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/masoncfrancis/homelogger/server/internal/models"
+)
+
+func TestAPITokenLifecycle(t *testing.T) {
+	db := TestDB(t)
+
+	user, err := CreateUser(db, "alice", "", "correct-horse", models.RoleOwner)
+	if err != nil {
+		t.Fatalf("CreateUser error: %v", err)
+	}
+
+	if _, _, err := CreateAPIToken(db, user.ID, "cron", []string{"everything"}, nil); err == nil {
+		t.Fatal("expected error for invalid scope")
+	}
+	if _, _, err := CreateAPIToken(db, user.ID, "", []string{"tasks"}, nil); err == nil {
+		t.Fatal("expected error for missing name")
+	}
+
+	raw, created, err := CreateAPIToken(db, user.ID, "Home Assistant", []string{"tasks", "maintenance:read"}, nil)
+	if err != nil {
+		t.Fatalf("CreateAPIToken error: %v", err)
+	}
+	if !strings.HasPrefix(raw, models.APITokenPrefix) {
+		t.Fatalf("expected token prefix %q, got %q", models.APITokenPrefix, raw)
+	}
+	if created.TokenHash == raw || !strings.HasPrefix(raw, created.Prefix) {
+		t.Fatalf("expected hashed token with display prefix, got hash=%q prefix=%q", created.TokenHash, created.Prefix)
+	}
+
+	got, apiToken, err := GetAPITokenUser(db, raw)
+	if err != nil {
+		t.Fatalf("GetAPITokenUser error: %v", err)
+	}
+	if got.ID != user.ID {
+		t.Fatalf("expected user %d, got %d", user.ID, got.ID)
+	}
+	if apiToken.LastUsedAt == nil {
+		t.Fatal("expected last-used timestamp to be recorded")
+	}
+	if len(apiToken.ScopeList()) != 2 {
+		t.Fatalf("expected 2 scopes, got %v", apiToken.ScopeList())
+	}
+
+	tokens, err := ListAPITokens(db, user.ID)
+	if err != nil {
+		t.Fatalf("ListAPITokens error: %v", err)
+	}
+	if len(tokens) != 1 || tokens[0].LastUsedAt == nil {
+		t.Fatalf("expected 1 listed token with last-used set, got %+v", tokens)
+	}
+
+	if err := RevokeAPIToken(db, user.ID+1, created.ID); err == nil {
+		t.Fatal("expected error revoking another user's token")
+	}
+	if err := RevokeAPIToken(db, user.ID, created.ID); err != nil {
+		t.Fatalf("RevokeAPIToken error: %v", err)
+	}
+	if _, _, err := GetAPITokenUser(db, raw); !errors.Is(err, ErrInvalidAPIToken) {
+		t.Fatalf("expected ErrInvalidAPIToken after revoke, got %v", err)
+	}
+
+	past := time.Now().Add(-time.Hour)
+	expired, _, err := CreateAPIToken(db, user.ID, "old", []string{"*"}, &past)
+	if err != nil {
+		t.Fatalf("CreateAPIToken error: %v", err)
+	}
+	if _, _, err := GetAPITokenUser(db, expired); !errors.Is(err, ErrInvalidAPIToken) {
+		t.Fatalf("expected ErrInvalidAPIToken for expired token, got %v", err)
+	}
+}
@@ -5,22 +5,46 @@ import (
 	"gorm.io/gorm"
 )
 
-// GetAppliances gets all appliances
-func GetAppliances(db *gorm.DB) ([]models.Appliance, error) {
-	var appliances []models.Appliance
-	result := db.Find(&appliances)
-	if result.Error != nil {
-		return []models.Appliance{}, result.Error
+var applianceListSpec = listSpec{
+	sorts: map[string]string{
+		"id":            "id",
+		"applianceName": "appliance_name",
+		"manufacturer":  "manufacturer",
+		"yearPurchased": "year_purchased",
+		"createdAt":     "created_at",
+		"updatedAt":     "updated_at",
+	},
+	defaultSort:  "id",
+	defaultOrder: "asc",
+}
+
+// GetAppliances gets all appliances of a property. Pass propertyID=0 for every property.
+func GetAppliances(db *gorm.DB, propertyID uint) ([]models.Appliance, error) {
+	page, err := ListAppliances(db, propertyID, ListOptions{})
+	if err != nil {
+		return []models.Appliance{}, err
 	}
 
-	return appliances, nil
+	return page.Items, nil
+}
+
+// ListAppliances returns a page of the appliances of a property. Pass propertyID=0 for every property.
+func ListAppliances(db *gorm.DB, propertyID uint, opts ListOptions) (*Page[models.Appliance], error) {
+	return paginate[models.Appliance](db.Model(&models.Appliance{}).Scopes(propertyScope(propertyID)), applianceListSpec, opts)
 }
 
 // AddAppliance creates a new appliance
 func AddAppliance(db *gorm.DB, appliance *models.Appliance) (*models.Appliance, error) {
-	result := db.Create(appliance)
-	if result.Error != nil {
-		return nil, result.Error
+	propertyID, err := resolvePropertyID(db, appliance.PropertyID, nil, nil, appliance.LocationID)
+	if err != nil {
+		return nil, err
+	}
+	appliance.PropertyID = propertyID
+	if err := resolveLocation(db, propertyID, &appliance.LocationID); err != nil {
+		return nil, err
+	}
+	if err := createAudited(db, TrashAppliance, appliance); err != nil {
+		return nil, err
 	}
 
 	return appliance, nil
@@ -28,9 +52,15 @@ func AddAppliance(db *gorm.DB, appliance *models.Appliance) (*models.Appliance,
 
 // UpdateAppliance updates an appliance, saving the changes to an existing appliance by its ID
 func UpdateAppliance(db *gorm.DB, appliance *models.Appliance) (*models.Appliance, error) {
-	result := db.Save(appliance)
-	if result.Error != nil {
-		return nil, result.Error
+	before, err := GetAppliance(db, appliance.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := resolveLocation(db, appliance.PropertyID, &appliance.LocationID); err != nil {
+		return nil, err
+	}
+	if err := saveAudited(db, TrashAppliance, before, appliance); err != nil {
+		return nil, err
 	}
 
 	return appliance, nil
@@ -47,50 +77,8 @@ func GetAppliance(db *gorm.DB, id uint) (*models.Appliance, error) {
 	return &appliance, nil
 }
 
-// DeleteAppliance deletes an appliance by ID
+// DeleteAppliance moves an appliance to the trash, with its maintenance and
+// repair records and every file attached to any of them, in one transaction
 func DeleteAppliance(db *gorm.DB, id uint) error {
-	// Delete files attached directly to the appliance
-	if err := DeleteFilesByAppliance(db, id); err != nil {
-		return err
-	}
-
-	// For each maintenance linked to this appliance, delete its files
-	var maintenances []models.Maintenance
-	if r := db.Select("id").Where("appliance_id = ?", id).Find(&maintenances); r.Error != nil {
-		return r.Error
-	}
-	for _, m := range maintenances {
-		if err := DeleteFilesByMaintenance(db, m.ID); err != nil {
-			return err
-		}
-	}
-
-	// Delete maintenance rows for this appliance
-	if err := db.Where("appliance_id = ?", id).Delete(&models.Maintenance{}).Error; err != nil {
-		return err
-	}
-
-	// For each repair linked to this appliance, delete its files
-	var repairs []models.Repair
-	if r := db.Select("id").Where("appliance_id = ?", id).Find(&repairs); r.Error != nil {
-		return r.Error
-	}
-	for _, rp := range repairs {
-		if err := DeleteFilesByRepair(db, rp.ID); err != nil {
-			return err
-		}
-	}
-
-	// Delete repair rows for this appliance
-	if err := db.Where("appliance_id = ?", id).Delete(&models.Repair{}).Error; err != nil {
-		return err
-	}
-
-	// Finally delete the appliance
-	result := db.Where("id = ?", id).Delete(&models.Appliance{})
-	if result.Error != nil {
-		return result.Error
-	}
-
-	return nil
+	return moveToTrash(db, TrashAppliance, "id = ?", id)
 }
@@ -0,0 +1,349 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"time"
+
+	"github.com/masoncfrancis/homelogger/server/internal/models"
+	"gorm.io/gorm"
+)
+
+// Every create, update and delete made through this package appends an
+// AuditEvent in the same transaction as the change. The actor is read from
+// the context of the *gorm.DB the change is made with (see WithActor).
+// Sessions, API token use and text extraction are bookkeeping rather than
+// changes to the household's records and are not audited.
+
+// Audit actions.
+const (
+	AuditCreate  = "create"
+	AuditUpdate  = "update"
+	AuditDelete  = "delete"  // moved to the trash, or deleted outright
+	AuditRestore = "restore" // brought back from the trash
+	AuditPurge   = "purge"   // removed from the trash for good
+	AuditImport  = "import"  // every record replaced by a backup
+	AuditReset   = "reset"   // demo data reseeded
+)
+
+// Audited entity types besides the trash types (TrashAppliance and so on).
+const (
+	AuditProperty = "property"
+	AuditSpace    = "space"
+	AuditLocation = "location"
+	AuditTodo     = "todo"
+	AuditUser     = "user"
+	AuditAPIToken = "apiToken"
+	AuditBackup   = "backup"
+	AuditDemo     = "demo"
+)
+
+// SystemActor is the actor recorded for changes the server makes on its own,
+// such as demo resets and purging expired trash.
+const SystemActor = "system"
+
+type actorKey struct{}
+
+type actorUserKey struct{}
+
+// WithActor returns a context under which changes are audited as made by
+// actor. Use it through db.WithContext.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// WithActorUser is WithActor for a signed-in user: changes are audited under
+// their username, and work they do, such as completing a task, is credited
+// to their account.
+func WithActorUser(ctx context.Context, user *models.User) context.Context {
+	return context.WithValue(WithActor(ctx, user.Username), actorUserKey{}, user.ID)
+}
+
+// actorUserID returns the ID of the user db's context acts for, or nil when
+// there is none (auth disabled, or a change the server makes itself).
+func actorUserID(db *gorm.DB) *uint {
+	if db.Statement.Context == nil {
+		return nil
+	}
+	id, ok := db.Statement.Context.Value(actorUserKey{}).(uint)
+	if !ok {
+		return nil
+	}
+	return &id
+}
+
+// auditActor returns the actor of db's context, or "" when there is none
+// (auth disabled).
+func auditActor(db *gorm.DB) string {
+	if db.Statement.Context == nil {
+		return ""
+	}
+	actor, _ := db.Statement.Context.Value(actorKey{}).(string)
+	return actor
+}
+
+// auditSkipFields are left out of the changes: IDs and timestamps are on the
+// event itself, extracted text is derived from the file and completers' names
+// from their user IDs.
+var auditSkipFields = map[string]bool{
+	"id": true, "ID": true,
+	"CreatedAt": true, "UpdatedAt": true, "DeletedAt": true,
+	"createdAt": true, "updatedAt": true, "deletedAt": true,
+	"extractedText": true, "textStatus": true, "lastUsedAt": true,
+	"lastCompletedBy": true, "completedBy": true,
+}
+
+// auditSnapshot flattens a record to its JSON fields. Nested objects (loaded
+// associations) are dropped. A nil record gives a nil snapshot.
+func auditSnapshot(record any) (map[string]any, error) {
+	if record == nil {
+		return nil, nil
+	}
+	if v := reflect.ValueOf(record); v.Kind() == reflect.Pointer && v.IsNil() {
+		return nil, nil
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// snapshotUint reads a numeric field of a snapshot, such as its ID.
+func snapshotUint(fields map[string]any, name string) uint {
+	n, _ := fields[name].(float64)
+	return uint(n)
+}
+
+// auditDiff returns the audited fields that differ between two snapshots.
+func auditDiff(before, after map[string]any) models.AuditChanges {
+	changes := models.AuditChanges{}
+	add := func(name string) {
+		if auditSkipFields[name] {
+			return
+		}
+		prev, next := before[name], after[name]
+		if _, nested := prev.(map[string]any); nested {
+			return
+		}
+		if _, nested := next.(map[string]any); nested {
+			return
+		}
+		if !reflect.DeepEqual(prev, next) {
+			changes[name] = models.AuditChange{Old: prev, New: next}
+		}
+	}
+	for name := range before {
+		add(name)
+	}
+	for name := range after {
+		if _, seen := before[name]; !seen {
+			add(name)
+		}
+	}
+	return changes
+}
+
+// audit appends an event for a change to one record. before is nil for a
+// create and after is nil for a delete; an update that changed no audited
+// field is not recorded. Creates and updates of records that keep revisions
+// also save the new version.
+func audit(tx *gorm.DB, action, entityType string, before, after any) error {
+	prev, err := auditSnapshot(before)
+	if err != nil {
+		return fmt.Errorf("audit %s: %w", entityType, err)
+	}
+	next, err := auditSnapshot(after)
+	if err != nil {
+		return fmt.Errorf("audit %s: %w", entityType, err)
+	}
+	changes := auditDiff(prev, next)
+	if action == AuditUpdate && len(changes) == 0 {
+		return nil
+	}
+	record := next
+	if record == nil {
+		record = prev
+	}
+	id, propertyID := snapshotUint(record, "id"), snapshotUint(record, "propertyId")
+	if entityType == AuditProperty {
+		propertyID = id
+	}
+	if (action == AuditCreate || action == AuditUpdate) && slices.Contains(RevisionTypes, entityType) {
+		if err := saveRevision(tx, entityType, id, prev, next); err != nil {
+			return err
+		}
+	}
+	return LogAuditEvent(tx, action, entityType, id, propertyID, changes)
+}
+
+// LogAuditEvent appends an event that is not a change to a single record,
+// such as a backup import or a demo reset.
+func LogAuditEvent(db *gorm.DB, action, entityType string, entityID, propertyID uint, changes models.AuditChanges) error {
+	event := &models.AuditEvent{
+		CreatedAt:  time.Now().UTC(),
+		Actor:      auditActor(db),
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   entityID,
+		PropertyID: propertyID,
+		Changes:    changes,
+	}
+	if err := db.Session(&gorm.Session{NewDB: true}).Create(event).Error; err != nil {
+		return fmt.Errorf("audit %s: %w", entityType, err)
+	}
+	return nil
+}
+
+// createAudited inserts record and audits its creation, in one transaction.
+func createAudited(db *gorm.DB, entityType string, record any) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(record).Error; err != nil {
+			return err
+		}
+		return audit(tx, AuditCreate, entityType, nil, record)
+	})
+}
+
+// saveAudited saves record, which was loaded as before and then changed, and
+// audits the fields that changed, in one transaction.
+func saveAudited(db *gorm.DB, entityType string, before, record any) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(record).Error; err != nil {
+			return err
+		}
+		return audit(tx, AuditUpdate, entityType, before, record)
+	})
+}
+
+// updateAudited runs update against the row of T with the given ID and
+// audits the fields that changed, reloading the row before and after.
+func updateAudited[T any](db *gorm.DB, entityType string, id uint, update func(tx *gorm.DB) error) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var before, after T
+		if err := tx.Where("id = ?", id).First(&before).Error; err != nil {
+			return err
+		}
+		if err := update(tx); err != nil {
+			return err
+		}
+		if err := tx.Where("id = ?", id).First(&after).Error; err != nil {
+			return err
+		}
+		return audit(tx, AuditUpdate, entityType, &before, &after)
+	})
+}
+
+// deleteAudited deletes the row of T with the given ID and audits it, in one
+// transaction.
+func deleteAudited[T any](db *gorm.DB, entityType string, id uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var record T
+		if err := tx.Where("id = ?", id).First(&record).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("id = ?", id).Delete(&record).Error; err != nil {
+			return err
+		}
+		return audit(tx, AuditDelete, entityType, &record, nil)
+	})
+}
+
+// AuditFilter narrows ListAuditEvents. Zero fields do not filter.
+type AuditFilter struct {
+	PropertyID uint
+	Actor      string
+	Action     string
+	EntityType string
+	EntityID   uint
+}
+
+var auditListSpec = listSpec{
+	sorts: map[string]string{
+		"id":        "id",
+		"createdAt": "created_at",
+	},
+	defaultSort:  "id",
+	defaultOrder: "desc",
+}
+
+// ListAuditEvents returns a page of the audit log, newest first by default.
+// opts.DateFrom and opts.DateTo (YYYY-MM-DD, inclusive) filter on the day
+// the event was recorded, in UTC.
+func ListAuditEvents(db *gorm.DB, filter AuditFilter, opts ListOptions) (*Page[models.AuditEvent], error) {
+	query := db.Model(&models.AuditEvent{})
+	if filter.PropertyID != 0 {
+		query = query.Where("property_id = ?", filter.PropertyID)
+	}
+	if filter.Actor != "" {
+		query = query.Where("actor = ?", filter.Actor)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.EntityType != "" {
+		query = query.Where("entity_type = ?", filter.EntityType)
+	}
+	if filter.EntityID != 0 {
+		query = query.Where("entity_id = ?", filter.EntityID)
+	}
+
+	// created_at is a timestamp, so the days are turned into a half-open
+	// range rather than compared as strings like the other lists' dates.
+	for _, bound := range []struct {
+		date, op string
+		days     int
+	}{{opts.DateFrom, ">=", 0}, {opts.DateTo, "<", 1}} {
+		if bound.date == "" {
+			continue
+		}
+		day, err := time.Parse("2006-01-02", bound.date)
+		if err != nil {
+			return nil, invalidList("dates must be YYYY-MM-DD")
+		}
+		query = query.Where("created_at "+bound.op+" ?", day.AddDate(0, 0, bound.days))
+	}
+	opts.DateFrom, opts.DateTo = "", ""
+
+	return paginate[models.AuditEvent](query, auditListSpec, opts)
+}
+
+// auditAppendOnlyDDL returns the statements that make audit_events reject
+// updates and deletes.
+func auditAppendOnlyDDL(dialect string) []string {
+	switch dialect {
+	case dialectSQLite:
+		return []string{
+			"CREATE TRIGGER IF NOT EXISTS audit_events_no_update BEFORE UPDATE ON audit_events BEGIN SELECT RAISE(ABORT, 'audit_events is append-only'); END",
+			"CREATE TRIGGER IF NOT EXISTS audit_events_no_delete BEFORE DELETE ON audit_events BEGIN SELECT RAISE(ABORT, 'audit_events is append-only'); END",
+		}
+	case dialectPostgres:
+		return []string{
+			`CREATE OR REPLACE FUNCTION audit_events_append_only() RETURNS trigger AS $$
+BEGIN
+	RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql`,
+			"DROP TRIGGER IF EXISTS audit_events_append_only ON audit_events",
+			"CREATE TRIGGER audit_events_append_only BEFORE UPDATE OR DELETE ON audit_events FOR EACH ROW EXECUTE FUNCTION audit_events_append_only()",
+		}
+	}
+	return nil
+}
+
+// ensureAuditLog makes the audit_events table append-only.
+func ensureAuditLog(db *gorm.DB) error {
+	for _, stmt := range auditAppendOnlyDDL(db.Dialector.Name()) {
+		if err := db.Exec(stmt).Error; err != nil {
+			return fmt.Errorf("audit log: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/masoncfrancis/homelogger/server/internal/models"
+)
+
+func TestAuditRecordsChangesAndIsAppendOnly(t *testing.T) {
+	db := TestDB(t)
+	alex := db.WithContext(WithActor(context.Background(), "alex"))
+
+	appliance, err := AddAppliance(alex, &models.Appliance{ApplianceName: "Furnace"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	record, err := AddMaintenance(alex, &models.Maintenance{Description: "Filter", ReferenceType: "Appliance", Date: "2026-01-01", Cost: 20, ApplianceID: &appliance.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := UpdateMaintenance(alex, record.ID, "Filter", "2026-01-01", 25, ""); err != nil {
+		t.Fatal(err)
+	}
+	// Saving the same values again is not a change.
+	if _, err := UpdateMaintenance(alex, record.ID, "Filter", "2026-01-01", 25, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	page, err := ListAuditEvents(db, AuditFilter{EntityType: TrashMaintenance, EntityID: record.ID, Action: AuditUpdate}, ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 1 {
+		t.Fatalf("expected one update, got %+v", page.Items)
+	}
+	update := page.Items[0]
+	if update.Actor != "alex" || len(update.Changes) != 1 || update.Changes["cost"].Old != 20.0 || update.Changes["cost"].New != 25.0 {
+		t.Fatalf("expected alex's cost change, got %+v", update)
+	}
+
+	// Deleting the appliance takes its record with it, and restoring brings both back.
+	if err := DeleteAppliance(db, appliance.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := RestoreFromTrash(db, TrashAppliance, appliance.ID); err != nil {
+		t.Fatal(err)
+	}
+	for _, action := range []string{AuditDelete, AuditRestore} {
+		page, err := ListAuditEvents(db, AuditFilter{Action: action}, ListOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if page.Total != 2 {
+			t.Fatalf("expected %s events for the appliance and its record, got %+v", action, page.Items)
+		}
+		if page.Items[0].Actor != "" {
+			t.Fatalf("expected no actor without a context, got %q", page.Items[0].Actor)
+		}
+	}
+
+	if err := db.Model(&models.AuditEvent{}).Where("id = ?", update.ID).Update("actor", "someone").Error; err == nil {
+		t.Fatal("expected updating an audit event to fail")
+	}
+	if err := db.Where("id = ?", update.ID).Delete(&models.AuditEvent{}).Error; err == nil {
+		t.Fatal("expected deleting an audit event to fail")
+	}
+}
@@ -12,13 +12,24 @@ const BackupVersion = "1.0"
 
 // ExportToJSON fetches all data and returns a typed BackupPayload.
 // Works with any GORM dialect — no raw SQL, no dialect-specific logic.
+// Items in the trash are included, so a restore keeps them restorable.
 func ExportToJSON(db *gorm.DB, dbType string) (*models.BackupPayload, error) {
+	db = db.Unscoped().Session(&gorm.Session{})
 	payload := &models.BackupPayload{
 		Version:      BackupVersion,
 		ExportedAt:   time.Now().UTC(),
 		DatabaseType: dbType,
 	}
 
+	if err := db.Find(&payload.Entities.Properties).Error; err != nil {
+		return nil, fmt.Errorf("fetch Property: %w", err)
+	}
+	if err := db.Find(&payload.Entities.Spaces).Error; err != nil {
+		return nil, fmt.Errorf("fetch Space: %w", err)
+	}
+	if err := db.Find(&payload.Entities.Locations).Error; err != nil {
+		return nil, fmt.Errorf("fetch Location: %w", err)
+	}
 	if err := db.Find(&payload.Entities.Appliances).Error; err != nil {
 		return nil, fmt.Errorf("fetch Appliance: %w", err)
 	}
@@ -40,6 +51,24 @@ func ExportToJSON(db *gorm.DB, dbType string) (*models.BackupPayload, error) {
 	if err := db.Find(&payload.Entities.Todos).Error; err != nil {
 		return nil, fmt.Errorf("fetch Todo: %w", err)
 	}
+	if err := db.Find(&payload.Entities.TaskCompletions).Error; err != nil {
+		return nil, fmt.Errorf("fetch TaskCompletion: %w", err)
+	}
+	if err := db.Find(&payload.Entities.Meters).Error; err != nil {
+		return nil, fmt.Errorf("fetch Meter: %w", err)
+	}
+	if err := db.Find(&payload.Entities.MeterReadings).Error; err != nil {
+		return nil, fmt.Errorf("fetch MeterReading: %w", err)
+	}
+	if err := db.Find(&payload.Entities.TaskPacks).Error; err != nil {
+		return nil, fmt.Errorf("fetch TaskPack: %w", err)
+	}
+	if err := db.Find(&payload.Entities.TaskDependencies).Error; err != nil {
+		return nil, fmt.Errorf("fetch TaskDependency: %w", err)
+	}
+	if err := db.Find(&payload.Entities.Revisions).Error; err != nil {
+		return nil, fmt.Errorf("fetch Revision: %w", err)
+	}
 
 	return payload, nil
 }
@@ -41,7 +41,7 @@ func TestApplianceCRUD(t *testing.T) {
     }
 
     // GetAll
-    list, err := GetAppliances(db)
+    list, err := GetAppliances(db, 0)
     if err != nil {
         t.Fatalf("GetAppliances error: %v", err)
     }
@@ -123,9 +123,9 @@ func TestFileAttachAndDelete(t *testing.T) {
         t.Fatalf("DeleteFilesByAppliance error: %v", err)
     }
 
-    // file should be removed from disk
-    if _, err := os.Stat(fp); !os.IsNotExist(err) {
-        t.Fatalf("expected file to be removed from disk")
+    // file stays on disk until it is purged from the trash
+    if _, err := os.Stat(fp); err != nil {
+        t.Fatalf("expected file to stay on disk: %v", err)
     }
 
     // DB should have zero files for appliance
@@ -136,5 +136,12 @@ func TestFileAttachAndDelete(t *testing.T) {
     if len(filesAfter) != 0 {
         t.Fatalf("expected 0 files after delete, got %d", len(filesAfter))
     }
-}
 
+    // purging removes it from disk
+    if err := PurgeFromTrash(db, TrashFile, uploaded.ID); err != nil {
+        t.Fatalf("PurgeFromTrash error: %v", err)
+    }
+    if _, err := os.Stat(fp); !os.IsNotExist(err) {
+        t.Fatalf("expected file to be removed from disk")
+    }
+}
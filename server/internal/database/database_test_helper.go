@@ -78,6 +78,7 @@ func postgresTestDSN() string {
 
 func resetPostgresTestSchema(db *gorm.DB) error {
     tables := []string{
+        "search_index",
         "todo_task_migrations",
         "tasks",
         "notes",
@@ -85,7 +86,20 @@ func resetPostgresTestSchema(db *gorm.DB) error {
         "repairs",
         "maintenances",
         "appliances",
+        "locations",
+        "spaces",
+        "properties",
         "todos",
+        "api_tokens",
+        "sessions",
+        "users",
+        "audit_events",
+        "revisions",
+        "task_completions",
+        "meters",
+        "meter_readings",
+        "task_packs",
+        "task_dependencies",
     }
 
     for _, table := range tables {
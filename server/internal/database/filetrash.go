@@ -0,0 +1,132 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/masoncfrancis/homelogger/server/internal/models"
+	"gorm.io/gorm"
+)
+
+// FileTrashDir is the folder, inside each uploads directory, that holds files
+// whose records are being purged. Backups skip it.
+const FileTrashDir = ".trash"
+
+// fileTrash moves uploaded files aside while a purge runs, so that removing
+// them from disk can wait until the records are gone for good.
+type fileTrash struct {
+	moved []trashedFile
+}
+
+type trashedFile struct {
+	path, trashPath string
+}
+
+// add moves the file at path into the trash folder next to it. A file that is
+// already missing is skipped.
+func (t *fileTrash) add(path string) error {
+	if path == "" {
+		return nil
+	}
+	dir := filepath.Join(filepath.Dir(path), FileTrashDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("create trash folder: %w", err)
+	}
+	trashPath := filepath.Join(dir, filepath.Base(path))
+	if err := os.Rename(path, trashPath); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("move %s to trash: %w", path, err)
+	}
+	t.moved = append(t.moved, trashedFile{path: path, trashPath: trashPath})
+	return nil
+}
+
+// restore puts every trashed file back where it was.
+func (t *fileTrash) restore() error {
+	var errs []error
+	for _, f := range t.moved {
+		if err := os.Rename(f.trashPath, f.path); err != nil {
+			errs = append(errs, fmt.Errorf("restore %s from trash: %w", f.path, err))
+		}
+	}
+	t.moved = nil
+	return errors.Join(errs...)
+}
+
+// purge removes the trashed files for good. A file left behind is cleaned
+// up by RecoverFileTrash on the next start.
+func (t *fileTrash) purge() {
+	for _, f := range t.moved {
+		_ = os.Remove(f.trashPath)
+	}
+	t.moved = nil
+}
+
+// trashFilesWhere trashes the files matching the condition, deleted or not,
+// and hard-deletes their rows. It returns how many rows it deleted.
+func (t *fileTrash) trashFilesWhere(tx *gorm.DB, query string, args ...any) (int64, error) {
+	var files []models.SavedFile
+	if err := tx.Unscoped().Select("id", "path").Where(query, args...).Find(&files).Error; err != nil {
+		return 0, err
+	}
+	for _, f := range files {
+		if err := t.add(f.Path); err != nil {
+			return 0, err
+		}
+	}
+	result := tx.Unscoped().Where(query, args...).Delete(&models.SavedFile{})
+	return result.RowsAffected, result.Error
+}
+
+// deleteWithFiles runs fn in a transaction. Files fn trashes are removed from
+// disk only after the commit; if fn or the commit fails they are put back, so
+// the database and the uploads folder are both left as they were.
+func deleteWithFiles(db *gorm.DB, fn func(tx *gorm.DB, trash *fileTrash) error) error {
+	trash := &fileTrash{}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		return fn(tx, trash)
+	})
+	if err != nil {
+		return errors.Join(err, trash.restore())
+	}
+	trash.purge()
+	return nil
+}
+
+// RecoverFileTrash empties the trash folder of uploadsDir after a crash: a
+// file whose record still exists is moved back, any other file is removed.
+func RecoverFileTrash(db *gorm.DB, uploadsDir string) error {
+	dir := filepath.Join(uploadsDir, FileTrashDir)
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		trashPath := filepath.Join(dir, e.Name())
+		path := filepath.Join(uploadsDir, e.Name())
+		var refs int64
+		if err := db.Unscoped().Model(&models.SavedFile{}).Where("path = ?", path).Count(&refs).Error; err != nil {
+			return err
+		}
+		if refs > 0 {
+			err = os.Rename(trashPath, path)
+		} else {
+			err = os.Remove(trashPath)
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,146 @@
+package database
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/masoncfrancis/homelogger/server/internal/models"
+	"gorm.io/gorm"
+)
+
+// applianceWithFiles creates an appliance with one maintenance and one repair
+// record, each with a file on disk, plus a file on the appliance itself.
+func applianceWithFiles(t *testing.T, db *gorm.DB, dir string) (*models.Appliance, []string) {
+	t.Helper()
+	appliance, err := AddAppliance(db, &models.Appliance{ApplianceName: "Furnace"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := AddMaintenance(db, &models.Maintenance{Description: "Filter", ReferenceType: "Appliance", Date: "2026-01-01", ApplianceID: &appliance.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := AddRepair(db, &models.Repair{Description: "Igniter", ReferenceType: "Appliance", Date: "2026-02-01", ApplianceID: &appliance.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var paths []string
+	for i, link := range []models.SavedFile{{ApplianceID: &appliance.ID}, {MaintenanceID: &m.ID}, {RepairID: &r.ID}} {
+		path := filepath.Join(dir, string(rune('a'+i)))
+		if err := os.WriteFile(path, []byte("content"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		link.Path, link.OriginalName, link.UserID = path, "receipt.pdf", "u1"
+		if _, err := UploadFile(db, &link); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+	return appliance, paths
+}
+
+func TestPurgeFailureKeepsRecordsAndFiles(t *testing.T) {
+	db := TestDB(t)
+	dir := t.TempDir()
+	appliance, paths := applianceWithFiles(t, db, dir)
+	if err := DeleteAppliance(db, appliance.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	// Fail the last statement, after the files have been trashed and the
+	// maintenance and repair rows deleted.
+	boom := errors.New("boom")
+	err := db.Callback().Raw().Before("gorm:raw").Register("test:fail_appliance_purge", func(tx *gorm.DB) {
+		if strings.HasPrefix(tx.Statement.SQL.String(), "DELETE FROM appliances") {
+			_ = tx.AddError(boom)
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := PurgeFromTrash(db, TrashAppliance, appliance.ID); !errors.Is(err, boom) {
+		t.Fatalf("expected the purge to fail, got %v", err)
+	}
+	for _, path := range paths {
+		if _, err := os.Stat(path); err != nil {
+			t.Fatalf("file %s should be back in place: %v", path, err)
+		}
+	}
+	for table, want := range map[string]int64{"appliances": 1, "maintenances": 1, "repairs": 1, "saved_files": 3} {
+		var n int64
+		db.Table(table).Where("deleted_at IS NOT NULL").Count(&n)
+		if n != want {
+			t.Fatalf("%s: expected %d rows in the trash after the failed purge, got %d", table, want, n)
+		}
+	}
+}
+
+func TestDeleteApplianceKeepsFilesUntilPurge(t *testing.T) {
+	db := TestDB(t)
+	dir := t.TempDir()
+	appliance, paths := applianceWithFiles(t, db, dir)
+
+	if err := DeleteAppliance(db, appliance.ID); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range paths {
+		if _, err := os.Stat(path); err != nil {
+			t.Fatalf("file %s should stay until purged: %v", path, err)
+		}
+	}
+
+	if err := PurgeFromTrash(db, TrashAppliance, appliance.ID); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range paths {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Fatalf("file %s should be gone", path)
+		}
+	}
+	if entries, _ := os.ReadDir(filepath.Join(dir, FileTrashDir)); len(entries) != 0 {
+		t.Fatalf("expected an empty trash folder, got %d entries", len(entries))
+	}
+	for _, table := range []string{"appliances", "maintenances", "repairs", "saved_files"} {
+		var n int64
+		db.Table(table).Count(&n)
+		if n != 0 {
+			t.Fatalf("%s: expected the rows to be purged, got %d", table, n)
+		}
+	}
+}
+
+func TestRecoverFileTrash(t *testing.T) {
+	db := TestDB(t)
+	dir := t.TempDir()
+	kept := filepath.Join(dir, "1")
+	if _, err := UploadFile(db, &models.SavedFile{Path: kept, OriginalName: "kept.pdf", UserID: "u1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// As a crash would leave them: one file whose record survived, one whose
+	// record was deleted.
+	trashDir := filepath.Join(dir, FileTrashDir)
+	if err := os.MkdirAll(trashDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"1", "2"} {
+		if err := os.WriteFile(filepath.Join(trashDir, name), []byte("content"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := RecoverFileTrash(db, dir); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(kept); err != nil {
+		t.Fatalf("file with a record should be restored: %v", err)
+	}
+	if entries, _ := os.ReadDir(trashDir); len(entries) != 0 {
+		t.Fatalf("expected an empty trash folder, got %d entries", len(entries))
+	}
+}
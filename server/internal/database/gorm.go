@@ -231,7 +231,7 @@ func ConnectGorm() (*gorm.DB, error) {
 
 // MigrateGorm migrates the database
 func MigrateGorm(db *gorm.DB) error {
-	err := db.AutoMigrate(&models.Todo{}, &models.Appliance{}, &models.Maintenance{}, &models.Repair{}, &models.SavedFile{}, &models.Note{}, &models.Task{})
+	err := db.AutoMigrate(&models.Property{}, &models.Space{}, &models.Location{}, &models.Todo{}, &models.Appliance{}, &models.Maintenance{}, &models.Repair{}, &models.SavedFile{}, &models.Note{}, &models.Task{}, &models.User{}, &models.Session{}, &models.APIToken{}, &models.AuditEvent{}, &models.Revision{}, &models.TaskCompletion{}, &models.Meter{}, &models.MeterReading{}, &models.TaskPack{}, &models.TaskDependency{})
 	if err != nil {
 		return err
 	}
@@ -248,5 +248,35 @@ func MigrateGorm(db *gorm.DB) error {
 		}
 	}
 
+	// Records created before multi-property support have property_id = 0; give them the default property.
+	if err := EnsureDefaultProperty(db); err != nil {
+		return err
+	}
+
+	// Records created before spaces were user-defined only carry a space_type string; link them to a space.
+	if err := MigrateSpaceTypes(db); err != nil {
+		return err
+	}
+
+	// Recurring tasks created before recurrence rules only carry an interval; write their rule.
+	if err := MigrateTaskRecurrence(db); err != nil {
+		return err
+	}
+
+	// Accounts created before roles existed default to member; make sure someone owns the household.
+	if err := EnsureOwner(db); err != nil {
+		return err
+	}
+
+	// The audit log only ever grows; triggers reject updates and deletes.
+	if err := ensureAuditLog(db); err != nil {
+		return err
+	}
+
+	// Full-text search is kept in sync by triggers; rebuild it in case records changed without them.
+	if err := EnsureSearchIndex(db); err != nil {
+		return err
+	}
+
 	return nil
 }
@@ -6,6 +6,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"slices"
 	"time"
 
 	"github.com/masoncfrancis/homelogger/server/internal/models"
@@ -14,13 +15,23 @@ import (
 
 // tableDropOrder lists tables in reverse FK dependency order for safe drops.
 // note: hard-coded list mirrors MigrateGorm — update both together.
+// users, sessions and api_tokens are deliberately absent so a restore never logs everyone out.
 var tableDropOrder = []string{
+	"revisions",
+	"task_dependencies",
+	"task_completions",
 	"tasks",
+	"meter_readings",
+	"meters",
+	"task_packs",
 	"notes",
 	"saved_files",
 	"repairs",
 	"maintenances",
 	"appliances",
+	"locations",
+	"spaces",
+	"properties",
 	"todos",
 	"todo_task_migrations",
 }
@@ -45,12 +56,21 @@ func dropAllTables(db *gorm.DB) error {
 // todo_task_migrations uses BIGINT PRIMARY KEY (no sequence), so it's excluded.
 // note: Postgres only — sequences don't exist in SQLite.
 var tablesWithSequences = []string{
+	"revisions",
+	"task_dependencies",
+	"task_completions",
 	"tasks",
+	"meter_readings",
+	"meters",
+	"task_packs",
 	"notes",
 	"saved_files",
 	"repairs",
 	"maintenances",
 	"appliances",
+	"locations",
+	"spaces",
+	"properties",
 	"todos",
 }
 
@@ -140,7 +160,45 @@ func validatePayload(payload *models.BackupPayload) error {
 	}
 
 	seenIDs := make(map[uint]bool)
+	for i, e := range payload.Entities.Properties {
+		if e.Name == "" {
+			return fmt.Errorf("property[%d].name: must not be empty", i)
+		}
+		if e.ID != 0 {
+			if seenIDs[e.ID] {
+				return fmt.Errorf("duplicate property ID: %d", e.ID)
+			}
+			seenIDs[e.ID] = true
+		}
+	}
 
+	seenIDs = make(map[uint]bool)
+	for i, e := range payload.Entities.Spaces {
+		if e.Name == "" {
+			return fmt.Errorf("space[%d].name: must not be empty", i)
+		}
+		if e.ID != 0 {
+			if seenIDs[e.ID] {
+				return fmt.Errorf("duplicate space ID: %d", e.ID)
+			}
+			seenIDs[e.ID] = true
+		}
+	}
+
+	seenIDs = make(map[uint]bool)
+	for i, e := range payload.Entities.Locations {
+		if e.Name == "" {
+			return fmt.Errorf("location[%d].name: must not be empty", i)
+		}
+		if e.ID != 0 {
+			if seenIDs[e.ID] {
+				return fmt.Errorf("duplicate location ID: %d", e.ID)
+			}
+			seenIDs[e.ID] = true
+		}
+	}
+
+	seenIDs = make(map[uint]bool)
 	for i, e := range payload.Entities.Appliances {
 		if e.ApplianceName == "" {
 			return fmt.Errorf("appliance[%d].applianceName: must not be empty", i)
@@ -237,6 +295,91 @@ func validatePayload(payload *models.BackupPayload) error {
 		}
 	}
 
+	seenIDs = make(map[uint]bool)
+	for i, e := range payload.Entities.TaskCompletions {
+		if e.CompletionDate == "" {
+			return fmt.Errorf("taskCompletion[%d].completionDate: must not be empty", i)
+		}
+		if e.ID != 0 {
+			if seenIDs[e.ID] {
+				return fmt.Errorf("duplicate taskCompletion ID: %d", e.ID)
+			}
+			seenIDs[e.ID] = true
+		}
+	}
+
+	seenIDs = make(map[uint]bool)
+	for i, e := range payload.Entities.Meters {
+		if e.Name == "" {
+			return fmt.Errorf("meter[%d].name: must not be empty", i)
+		}
+		if e.ID != 0 {
+			if seenIDs[e.ID] {
+				return fmt.Errorf("duplicate meter ID: %d", e.ID)
+			}
+			seenIDs[e.ID] = true
+		}
+	}
+
+	seenIDs = make(map[uint]bool)
+	for i, e := range payload.Entities.MeterReadings {
+		if e.ReadAt == "" {
+			return fmt.Errorf("meterReading[%d].readAt: must not be empty", i)
+		}
+		if e.ID != 0 {
+			if seenIDs[e.ID] {
+				return fmt.Errorf("duplicate meterReading ID: %d", e.ID)
+			}
+			seenIDs[e.ID] = true
+		}
+	}
+
+	seenKeys := make(map[string]bool)
+	for i, e := range payload.Entities.TaskPacks {
+		if e.Key == "" {
+			return fmt.Errorf("taskPack[%d].key: must not be empty", i)
+		}
+		if seenKeys[e.Key] {
+			return fmt.Errorf("duplicate taskPack key: %s", e.Key)
+		}
+		seenKeys[e.Key] = true
+	}
+
+	seenIDs = make(map[uint]bool)
+	for i, e := range payload.Entities.TaskDependencies {
+		if e.TaskID == e.BlockerID {
+			return fmt.Errorf("taskDependency[%d]: a task cannot wait on itself", i)
+		}
+		if e.ID != 0 {
+			if seenIDs[e.ID] {
+				return fmt.Errorf("duplicate taskDependency ID: %d", e.ID)
+			}
+			seenIDs[e.ID] = true
+		}
+	}
+
+	seenIDs = make(map[uint]bool)
+	seenVersions := make(map[string]bool)
+	for i, e := range payload.Entities.Revisions {
+		if !slices.Contains(RevisionTypes, e.EntityType) {
+			return fmt.Errorf("revision[%d].entityType: unknown type %q", i, e.EntityType)
+		}
+		if e.Version < 1 {
+			return fmt.Errorf("revision[%d].version: must be at least 1", i)
+		}
+		version := fmt.Sprintf("%s %d %d", e.EntityType, e.EntityID, e.Version)
+		if seenVersions[version] {
+			return fmt.Errorf("duplicate revision: version %d of %s %d", e.Version, e.EntityType, e.EntityID)
+		}
+		seenVersions[version] = true
+		if e.ID != 0 {
+			if seenIDs[e.ID] {
+				return fmt.Errorf("duplicate revision ID: %d", e.ID)
+			}
+			seenIDs[e.ID] = true
+		}
+	}
+
 	return nil
 }
 
@@ -315,9 +458,38 @@ func ImportFromJSON(db *gorm.DB, payload *models.BackupPayload, uploadsDir strin
 			return nil
 		}
 
+		// MigrateGorm created a default property with its spaces; backups that carry their own replace them.
+		if len(payload.Entities.Properties) > 0 || len(payload.Entities.Spaces) > 0 {
+			if err := tx.Exec("DELETE FROM spaces").Error; err != nil {
+				return fmt.Errorf("clear default spaces: %w", err)
+			}
+		}
+		if len(payload.Entities.Properties) > 0 {
+			if err := tx.Exec("DELETE FROM properties").Error; err != nil {
+				return fmt.Errorf("clear default property: %w", err)
+			}
+		}
+		if err := insertEach("Property", func(i int) error { return tx.Create(&payload.Entities.Properties[i]).Error }, len(payload.Entities.Properties)); err != nil {
+			return err
+		}
+		if err := insertEach("Space", func(i int) error { return tx.Create(&payload.Entities.Spaces[i]).Error }, len(payload.Entities.Spaces)); err != nil {
+			return err
+		}
+		if err := insertEach("Location", func(i int) error { return tx.Create(&payload.Entities.Locations[i]).Error }, len(payload.Entities.Locations)); err != nil {
+			return err
+		}
 		if err := insertEach("Appliance", func(i int) error { return tx.Create(&payload.Entities.Appliances[i]).Error }, len(payload.Entities.Appliances)); err != nil {
 			return err
 		}
+		if err := insertEach("Meter", func(i int) error { return tx.Create(&payload.Entities.Meters[i]).Error }, len(payload.Entities.Meters)); err != nil {
+			return err
+		}
+		if err := insertEach("MeterReading", func(i int) error { return tx.Create(&payload.Entities.MeterReadings[i]).Error }, len(payload.Entities.MeterReadings)); err != nil {
+			return err
+		}
+		if err := insertEach("TaskPack", func(i int) error { return tx.Create(&payload.Entities.TaskPacks[i]).Error }, len(payload.Entities.TaskPacks)); err != nil {
+			return err
+		}
 		if err := insertEach("Todo", func(i int) error { return tx.Create(&payload.Entities.Todos[i]).Error }, len(payload.Entities.Todos)); err != nil {
 			return err
 		}
@@ -336,6 +508,32 @@ func ImportFromJSON(db *gorm.DB, payload *models.BackupPayload, uploadsDir strin
 		if err := insertEach("Task", func(i int) error { return tx.Create(&payload.Entities.Tasks[i]).Error }, len(payload.Entities.Tasks)); err != nil {
 			return err
 		}
+		if err := insertEach("TaskCompletion", func(i int) error { return tx.Create(&payload.Entities.TaskCompletions[i]).Error }, len(payload.Entities.TaskCompletions)); err != nil {
+			return err
+		}
+		if err := insertEach("TaskDependency", func(i int) error { return tx.Create(&payload.Entities.TaskDependencies[i]).Error }, len(payload.Entities.TaskDependencies)); err != nil {
+			return err
+		}
+		if err := insertEach("Revision", func(i int) error { return tx.Create(&payload.Entities.Revisions[i]).Error }, len(payload.Entities.Revisions)); err != nil {
+			return err
+		}
+
+		// Backups from before multi-property support carry no property; put everything in the default one.
+		if err := EnsureDefaultProperty(tx); err != nil {
+			return err
+		}
+		// Backups from before spaces were user-defined only carry space_type strings.
+		if err := MigrateSpaceTypes(tx); err != nil {
+			return err
+		}
+		// Backups from before recurrence rules only carry a task's interval.
+		if err := MigrateTaskRecurrence(tx); err != nil {
+			return err
+		}
+		// Meters are filed under their appliance's property.
+		if err := tx.Exec("UPDATE meters SET property_id = (SELECT property_id FROM appliances WHERE appliances.id = meters.appliance_id)").Error; err != nil {
+			return fmt.Errorf("file meters: %w", err)
+		}
 
 		// 4. Resync Postgres sequences — inserting explicit IDs doesn't advance them
 		if err := resetPostgresSequences(tx); err != nil {
@@ -376,7 +574,11 @@ func ImportFromJSON(db *gorm.DB, payload *models.BackupPayload, uploadsDir strin
 			}
 		}
 
-		return nil
+		// 7. The audit log survives the import; note what replaced the records.
+		return LogAuditEvent(tx, AuditImport, AuditBackup, 0, 0, models.AuditChanges{
+			"importId": {New: importID},
+			"inserted": {New: result.Inserted},
+		})
 	})
 
 	if err != nil {
@@ -251,6 +251,18 @@ func TestValidatePayload_Duplicates(t *testing.T) {
 			t.Fatal("expected error for duplicate task IDs")
 		}
 	})
+
+	t.Run("duplicate revision versions", func(t *testing.T) {
+		p := validMinimalPayload()
+		p.Entities.Revisions = []models.Revision{
+			{EntityType: "note", EntityID: 1, Version: 1},
+			{EntityType: "note", EntityID: 1, Version: 1},
+		}
+		err := validatePayload(p)
+		if err == nil {
+			t.Fatal("expected error for duplicate revision versions")
+		}
+	})
 }
 
 func TestValidateUploads(t *testing.T) {
@@ -32,6 +32,24 @@ func ConvertLegacyDB(dbPath string) (*models.BackupPayload, error) {
 
 	migrator := oldDB.Migrator()
 
+	if migrator.HasTable("properties") {
+		if err := oldDB.Unscoped().Find(&payload.Entities.Properties).Error; err != nil {
+			return nil, fmt.Errorf("read properties: %w", err)
+		}
+	}
+
+	if migrator.HasTable("spaces") {
+		if err := oldDB.Unscoped().Find(&payload.Entities.Spaces).Error; err != nil {
+			return nil, fmt.Errorf("read spaces: %w", err)
+		}
+	}
+
+	if migrator.HasTable("locations") {
+		if err := oldDB.Unscoped().Find(&payload.Entities.Locations).Error; err != nil {
+			return nil, fmt.Errorf("read locations: %w", err)
+		}
+	}
+
 	if migrator.HasTable("appliances") {
 		if err := oldDB.Unscoped().Find(&payload.Entities.Appliances).Error; err != nil {
 			return nil, fmt.Errorf("read appliances: %w", err)
@@ -84,6 +102,60 @@ func ConvertLegacyDB(dbPath string) (*models.BackupPayload, error) {
 }
 
 func SanitizeFKs(payload *models.BackupPayload) {
+	validPropertyIDs := make(map[uint]struct{}, len(payload.Entities.Properties))
+	for _, p := range payload.Entities.Properties {
+		validPropertyIDs[p.ID] = struct{}{}
+	}
+	// Unknown property IDs are zeroed; the import assigns those records to the default property.
+	sanitizeProperty := func(id *uint) {
+		if _, ok := validPropertyIDs[*id]; !ok {
+			*id = 0
+		}
+	}
+	for i := range payload.Entities.Spaces {
+		sanitizeProperty(&payload.Entities.Spaces[i].PropertyID)
+	}
+	for i := range payload.Entities.Locations {
+		sanitizeProperty(&payload.Entities.Locations[i].PropertyID)
+	}
+	for i := range payload.Entities.Appliances {
+		sanitizeProperty(&payload.Entities.Appliances[i].PropertyID)
+	}
+
+	validLocationIDs := make(map[uint]struct{}, len(payload.Entities.Locations))
+	for _, l := range payload.Entities.Locations {
+		validLocationIDs[l.ID] = struct{}{}
+	}
+	// Unknown locations are cleared; records stay in their property without one.
+	sanitizeLocation := func(id **uint) {
+		if *id == nil {
+			return
+		}
+		if _, ok := validLocationIDs[**id]; !ok {
+			*id = nil
+		}
+	}
+	for i := range payload.Entities.Locations {
+		sanitizeLocation(&payload.Entities.Locations[i].ParentID)
+	}
+	for i := range payload.Entities.Appliances {
+		sanitizeLocation(&payload.Entities.Appliances[i].LocationID)
+	}
+
+	validSpaceIDs := make(map[uint]struct{}, len(payload.Entities.Spaces))
+	for _, s := range payload.Entities.Spaces {
+		validSpaceIDs[s.ID] = struct{}{}
+	}
+	// Unknown space IDs are cleared; the import relinks those records by their space_type name.
+	sanitizeSpace := func(id **uint) {
+		if *id == nil {
+			return
+		}
+		if _, ok := validSpaceIDs[**id]; !ok {
+			*id = nil
+		}
+	}
+
 	validApplianceIDs := make(map[uint]struct{}, len(payload.Entities.Appliances))
 	for _, a := range payload.Entities.Appliances {
 		validApplianceIDs[a.ID] = struct{}{}
@@ -106,6 +178,8 @@ func SanitizeFKs(payload *models.BackupPayload) {
 
 	for i := range payload.Entities.Maintenance {
 		m := &payload.Entities.Maintenance[i]
+		sanitizeProperty(&m.PropertyID)
+		sanitizeSpace(&m.SpaceID)
 		if m.ApplianceID != nil {
 			if _, ok := validApplianceIDs[*m.ApplianceID]; !ok {
 				m.ApplianceID = nil
@@ -120,6 +194,8 @@ func SanitizeFKs(payload *models.BackupPayload) {
 
 	for i := range payload.Entities.Repairs {
 		r := &payload.Entities.Repairs[i]
+		sanitizeProperty(&r.PropertyID)
+		sanitizeSpace(&r.SpaceID)
 		if r.ApplianceID != nil {
 			if _, ok := validApplianceIDs[*r.ApplianceID]; !ok {
 				r.ApplianceID = nil
@@ -134,6 +210,9 @@ func SanitizeFKs(payload *models.BackupPayload) {
 
 	for i := range payload.Entities.SavedFiles {
 		f := &payload.Entities.SavedFiles[i]
+		sanitizeProperty(&f.PropertyID)
+		sanitizeSpace(&f.SpaceID)
+		sanitizeLocation(&f.LocationID)
 		if f.ApplianceID != nil {
 			if _, ok := validApplianceIDs[*f.ApplianceID]; !ok {
 				f.ApplianceID = nil
@@ -153,6 +232,9 @@ func SanitizeFKs(payload *models.BackupPayload) {
 
 	for i := range payload.Entities.Notes {
 		n := &payload.Entities.Notes[i]
+		sanitizeProperty(&n.PropertyID)
+		sanitizeSpace(&n.SpaceID)
+		sanitizeLocation(&n.LocationID)
 		if n.ApplianceID != nil {
 			if _, ok := validApplianceIDs[*n.ApplianceID]; !ok {
 				n.ApplianceID = nil
@@ -160,12 +242,99 @@ func SanitizeFKs(payload *models.BackupPayload) {
 		}
 	}
 
+	// Meters of appliances not in the backup are dropped, with their readings.
+	validMeterIDs := make(map[uint]struct{}, len(payload.Entities.Meters))
+	meters := payload.Entities.Meters[:0]
+	for _, m := range payload.Entities.Meters {
+		if _, ok := validApplianceIDs[m.ApplianceID]; !ok {
+			continue
+		}
+		validMeterIDs[m.ID] = struct{}{}
+		meters = append(meters, m)
+	}
+	payload.Entities.Meters = meters
+	readings := payload.Entities.MeterReadings[:0]
+	for _, r := range payload.Entities.MeterReadings {
+		if _, ok := validMeterIDs[r.MeterID]; ok {
+			readings = append(readings, r)
+		}
+	}
+	payload.Entities.MeterReadings = readings
+
+	validTaskIDs := make(map[uint]struct{}, len(payload.Entities.Tasks))
 	for i := range payload.Entities.Tasks {
 		t := &payload.Entities.Tasks[i]
+		validTaskIDs[t.ID] = struct{}{}
+		sanitizeProperty(&t.PropertyID)
+		sanitizeSpace(&t.SpaceID)
+		sanitizeLocation(&t.LocationID)
 		if t.ApplianceID != nil {
 			if _, ok := validApplianceIDs[*t.ApplianceID]; !ok {
 				t.ApplianceID = nil
 			}
 		}
+		if t.MeterID != nil {
+			if _, ok := validMeterIDs[*t.MeterID]; !ok {
+				t.MeterID, t.MeterInterval, t.MeterDueAt = nil, 0, nil
+			}
+		}
+	}
+	for i := range payload.Entities.Tasks {
+		t := &payload.Entities.Tasks[i]
+		if t.ParentID != nil {
+			if _, ok := validTaskIDs[*t.ParentID]; !ok {
+				t.ParentID = nil
+			}
+		}
+	}
+
+	// Dependencies between tasks not both in the backup are dropped.
+	dependencies := payload.Entities.TaskDependencies[:0]
+	for _, d := range payload.Entities.TaskDependencies {
+		_, okTask := validTaskIDs[d.TaskID]
+		_, okBlocker := validTaskIDs[d.BlockerID]
+		if okTask && okBlocker {
+			dependencies = append(dependencies, d)
+		}
+	}
+	payload.Entities.TaskDependencies = dependencies
+
+	// Completions of tasks not in the backup are dropped; unknown records are cleared.
+	completions := payload.Entities.TaskCompletions[:0]
+	for _, c := range payload.Entities.TaskCompletions {
+		if _, ok := validTaskIDs[c.TaskID]; !ok {
+			continue
+		}
+		if c.MaintenanceID != nil {
+			if _, ok := validMaintenanceIDs[*c.MaintenanceID]; !ok {
+				c.MaintenanceID = nil
+			}
+		}
+		if c.RepairID != nil {
+			if _, ok := validRepairIDs[*c.RepairID]; !ok {
+				c.RepairID = nil
+			}
+		}
+		completions = append(completions, c)
+	}
+	payload.Entities.TaskCompletions = completions
+
+	// Revisions of records not in the backup are dropped.
+	validNoteIDs := make(map[uint]struct{}, len(payload.Entities.Notes))
+	for _, n := range payload.Entities.Notes {
+		validNoteIDs[n.ID] = struct{}{}
+	}
+	validRevisionIDs := map[string]map[uint]struct{}{
+		TrashNote:        validNoteIDs,
+		TrashTask:        validTaskIDs,
+		TrashMaintenance: validMaintenanceIDs,
+		TrashRepair:      validRepairIDs,
+	}
+	revisions := payload.Entities.Revisions[:0]
+	for _, r := range payload.Entities.Revisions {
+		if _, ok := validRevisionIDs[r.EntityType][r.EntityID]; ok {
+			revisions = append(revisions, r)
+		}
 	}
+	payload.Entities.Revisions = revisions
 }
@@ -0,0 +1,242 @@
+package database
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// ErrInvalidListOptions is wrapped by errors about paging, sort or filter
+// options a list does not support.
+var ErrInvalidListOptions = errors.New("invalid list options")
+
+// MaxListLimit is the largest page a list returns.
+const MaxListLimit = 500
+
+// ListOptions is the paging, sorting and filtering contract shared by every
+// list. The zero value returns everything in the list's default order.
+type ListOptions struct {
+	// Limit is the page size; 0 means no limit.
+	Limit int
+	// Offset skips rows. Use either Offset or Cursor.
+	Offset int
+	// Cursor continues after the last row of a previous page (Page.NextCursor).
+	Cursor string
+	// Sort is a field name from the list's whitelist, e.g. "date" or "cost".
+	Sort string
+	// Order is "asc" or "desc"; empty uses the list's default.
+	Order string
+	// DateFrom and DateTo (YYYY-MM-DD, inclusive) filter on Date or DueDate.
+	DateFrom string
+	DateTo   string
+	// CostMin and CostMax (inclusive) filter on Cost or EstimatedCost.
+	CostMin *float64
+	CostMax *float64
+	// AssigneeID, when not 0, keeps the rows assigned to that user.
+	AssigneeID uint
+}
+
+// Page is one page of a list. NextCursor is empty on the last page.
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	Total      int64  `json:"total"`
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+// listSpec describes what a list may be sorted and filtered by. sorts maps API
+// field names to columns; dateColumn, costColumn and assigneeColumn are empty
+// when the list has no such filter.
+type listSpec struct {
+	sorts          map[string]string
+	defaultSort    string
+	defaultOrder   string
+	dateColumn     string
+	costColumn     string
+	assigneeColumn string
+}
+
+// listSchemaCache caches the parsed models paginate reads sort values from.
+var listSchemaCache sync.Map
+
+// listCursor is the decoded form of Page.NextCursor: the sort value and ID of
+// the last row returned.
+type listCursor struct {
+	Sort  string          `json:"s"`
+	Order string          `json:"o"`
+	Value json.RawMessage `json:"v"`
+	ID    uint            `json:"id"`
+}
+
+func invalidList(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", ErrInvalidListOptions, fmt.Sprintf(format, args...))
+}
+
+func validDate(s string) bool {
+	_, err := time.Parse("2006-01-02", s)
+	return err == nil
+}
+
+// paginate applies opts to query, which must already have its model and
+// filters set, and returns the matching page together with the total count.
+// Rows are ordered by the sort column then ID; nulls sort last either way.
+func paginate[T any](query *gorm.DB, spec listSpec, opts ListOptions) (*Page[T], error) {
+	sortName := opts.Sort
+	if sortName == "" {
+		sortName = spec.defaultSort
+	}
+	column, ok := spec.sorts[sortName]
+	if !ok {
+		return nil, invalidList("sort must be one of %s", strings.Join(slices.Sorted(maps.Keys(spec.sorts)), ", "))
+	}
+	order := strings.ToLower(opts.Order)
+	if order == "" {
+		order = spec.defaultOrder
+	}
+	if order != "asc" && order != "desc" {
+		return nil, invalidList("order must be asc or desc")
+	}
+	if opts.Limit < 0 || opts.Offset < 0 {
+		return nil, invalidList("limit and offset must not be negative")
+	}
+	if opts.Offset > 0 && opts.Cursor != "" {
+		return nil, invalidList("use either offset or cursor, not both")
+	}
+	limit := min(opts.Limit, MaxListLimit)
+
+	if opts.DateFrom != "" || opts.DateTo != "" {
+		if spec.dateColumn == "" {
+			return nil, invalidList("this list has no date to filter on")
+		}
+		if (opts.DateFrom != "" && !validDate(opts.DateFrom)) || (opts.DateTo != "" && !validDate(opts.DateTo)) {
+			return nil, invalidList("dates must be YYYY-MM-DD")
+		}
+		if opts.DateFrom != "" {
+			query = query.Where(spec.dateColumn+" >= ?", opts.DateFrom)
+		}
+		if opts.DateTo != "" {
+			query = query.Where(spec.dateColumn+" <= ?", opts.DateTo)
+		}
+	}
+	if opts.CostMin != nil || opts.CostMax != nil {
+		if spec.costColumn == "" {
+			return nil, invalidList("this list has no cost to filter on")
+		}
+		if opts.CostMin != nil {
+			query = query.Where(spec.costColumn+" >= ?", *opts.CostMin)
+		}
+		if opts.CostMax != nil {
+			query = query.Where(spec.costColumn+" <= ?", *opts.CostMax)
+		}
+	}
+	if opts.AssigneeID != 0 {
+		if spec.assigneeColumn == "" {
+			return nil, invalidList("this list has no assignee to filter on")
+		}
+		query = query.Where(spec.assigneeColumn+" = ?", opts.AssigneeID)
+	}
+
+	page := &Page[T]{Items: []T{}}
+	if err := query.Session(&gorm.Session{}).Count(&page.Total).Error; err != nil {
+		return nil, err
+	}
+
+	// The schema gives the Go type of the sort field, to read and write cursors.
+	var model T
+	sch, err := schema.Parse(&model, &listSchemaCache, query.NamingStrategy)
+	if err != nil {
+		return nil, err
+	}
+	field := sch.LookUpField(column)
+	idField := sch.LookUpField("id")
+	if field == nil || idField == nil {
+		return nil, fmt.Errorf("list %s: unknown column %s", sch.Table, column)
+	}
+	nullable := field.FieldType.Kind() == reflect.Pointer
+	cmp, dir := ">", "ASC"
+	if order == "desc" {
+		cmp, dir = "<", "DESC"
+	}
+
+	if opts.Cursor != "" {
+		cur, err := decodeCursor(opts.Cursor)
+		if err != nil || cur.Sort != sortName || cur.Order != order {
+			return nil, invalidList("cursor is invalid or was made for a different sort")
+		}
+		value := reflect.New(field.FieldType)
+		if err := json.Unmarshal(cur.Value, value.Interface()); err != nil {
+			return nil, invalidList("cursor is invalid")
+		}
+		v := value.Elem()
+		switch {
+		case column == "id":
+			query = query.Where("id "+cmp+" ?", cur.ID)
+		case nullable && v.IsNil():
+			query = query.Where(column+" IS NULL AND id "+cmp+" ?", cur.ID)
+		default:
+			if nullable {
+				v = v.Elem()
+			}
+			keyset := fmt.Sprintf("(%[1]s %[2]s ? OR (%[1]s = ? AND id %[2]s ?))", column, cmp)
+			if nullable {
+				keyset = fmt.Sprintf("(%s IS NULL OR %s)", column, keyset)
+			}
+			query = query.Where(keyset, v.Interface(), v.Interface(), cur.ID)
+		}
+	}
+
+	if nullable {
+		query = query.Order(fmt.Sprintf("CASE WHEN %s IS NULL THEN 1 ELSE 0 END", column))
+	}
+	if column != "id" {
+		query = query.Order(column + " " + dir)
+	}
+	query = query.Order("id " + dir)
+	if limit > 0 {
+		query = query.Limit(limit + 1)
+	}
+	if opts.Offset > 0 {
+		query = query.Offset(opts.Offset)
+	}
+	if err := query.Find(&page.Items).Error; err != nil {
+		return nil, err
+	}
+
+	if limit > 0 && len(page.Items) > limit {
+		page.Items = page.Items[:limit]
+		last := reflect.ValueOf(&page.Items[limit-1]).Elem()
+		value, _ := field.ValueOf(context.Background(), last)
+		id, _ := idField.ValueOf(context.Background(), last)
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		page.NextCursor = encodeCursor(listCursor{Sort: sortName, Order: order, Value: raw, ID: id.(uint)})
+	}
+	return page, nil
+}
+
+func encodeCursor(cur listCursor) string {
+	data, _ := json.Marshal(cur)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(s string) (listCursor, error) {
+	var cur listCursor
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return cur, err
+	}
+	err = json.Unmarshal(data, &cur)
+	return cur, err
+}
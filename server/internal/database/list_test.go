@@ -0,0 +1,136 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/masoncfrancis/homelogger/server/internal/models"
+)
+
+func TestListMaintenancesPaging(t *testing.T) {
+	db := TestDB(t)
+	appliance, err := AddAppliance(db, &models.Appliance{ApplianceName: "Furnace"})
+	if err != nil {
+		t.Fatalf("AddAppliance error: %v", err)
+	}
+	costs := []float64{120, 45, 300, 45, 80}
+	for i, cost := range costs {
+		m := &models.Maintenance{
+			Description:   fmt.Sprintf("Service %d", i+1),
+			Date:          fmt.Sprintf("2026-0%d-15", i+1),
+			Cost:          cost,
+			ApplianceID:   &appliance.ID,
+			ReferenceType: "Appliance",
+		}
+		if _, err := AddMaintenance(db, m); err != nil {
+			t.Fatalf("AddMaintenance error: %v", err)
+		}
+	}
+
+	// Walk the list by cost, two at a time.
+	var got []float64
+	opts := ListOptions{Limit: 2, Sort: "cost", Order: "desc"}
+	for pages := 0; ; pages++ {
+		if pages > len(costs) {
+			t.Fatalf("cursor paging did not terminate")
+		}
+		page, err := ListMaintenances(db, 0, appliance.ID, "Appliance", 0, opts)
+		if err != nil {
+			t.Fatalf("ListMaintenances error: %v", err)
+		}
+		if page.Total != int64(len(costs)) {
+			t.Fatalf("expected total %d, got %d", len(costs), page.Total)
+		}
+		for _, m := range page.Items {
+			got = append(got, m.Cost)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		opts.Cursor = page.NextCursor
+	}
+	want := []float64{300, 120, 80, 45, 45}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("expected costs %v, got %v", want, got)
+	}
+
+	// Offset without a limit skips rows and returns the rest.
+	page, err := ListMaintenances(db, 0, appliance.ID, "Appliance", 0, ListOptions{Offset: 3})
+	if err != nil {
+		t.Fatalf("ListMaintenances with offset error: %v", err)
+	}
+	if len(page.Items) != 2 || page.Items[0].Description != "Service 4" || page.NextCursor != "" {
+		t.Fatalf("unexpected offset page: %+v", page)
+	}
+
+	// Date and cost filters are inclusive and narrow the total.
+	minCost, maxCost := 50.0, 150.0
+	page, err = ListMaintenances(db, 0, appliance.ID, "Appliance", 0, ListOptions{DateFrom: "2026-02-15", DateTo: "2026-05-15", CostMin: &minCost, CostMax: &maxCost})
+	if err != nil {
+		t.Fatalf("ListMaintenances with filters error: %v", err)
+	}
+	if page.Total != 1 || len(page.Items) != 1 || page.Items[0].Description != "Service 5" {
+		t.Fatalf("unexpected filtered page: %+v", page)
+	}
+}
+
+func TestListTasksCursorWithUndatedTasks(t *testing.T) {
+	db := TestDB(t)
+	dates := []*string{nil, strPtr("2026-03-01"), nil, strPtr("2026-01-01"), strPtr("2026-03-01")}
+	for i, d := range dates {
+		if _, err := AddTask(db, &models.Task{Label: fmt.Sprintf("Task %d", i+1), DueDate: d, UserID: "1"}); err != nil {
+			t.Fatalf("AddTask error: %v", err)
+		}
+	}
+
+	var labels []string
+	opts := ListOptions{Limit: 2}
+	for {
+		page, err := ListAllTasks(db, 0, false, opts)
+		if err != nil {
+			t.Fatalf("ListAllTasks error: %v", err)
+		}
+		for _, task := range page.Items {
+			labels = append(labels, task.Label)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		opts.Cursor = page.NextCursor
+	}
+	want := []string{"Task 4", "Task 2", "Task 5", "Task 1", "Task 3"}
+	if fmt.Sprint(labels) != fmt.Sprint(want) {
+		t.Fatalf("expected %v, got %v", want, labels)
+	}
+}
+
+func TestListOptionsValidation(t *testing.T) {
+	db := TestDB(t)
+	page, err := ListNotes(db, 0, 0, 0, ListOptions{Limit: 1})
+	if err != nil {
+		t.Fatalf("ListNotes error: %v", err)
+	}
+	cost := 10.0
+	cases := map[string]ListOptions{
+		"unknown sort":       {Sort: "body"},
+		"bad order":          {Order: "sideways"},
+		"negative limit":     {Limit: -1},
+		"offset and cursor":  {Offset: 1, Cursor: "abc"},
+		"garbled cursor":     {Cursor: "not a cursor"},
+		"no date on notes":   {DateFrom: "2026-01-01"},
+		"no cost on notes":   {CostMin: &cost},
+		"cursor of new sort": {Cursor: encodeCursor(listCursor{Sort: "title", Order: "asc"})},
+	}
+	for name, opts := range cases {
+		if _, err := ListNotes(db, 0, 0, 0, opts); !errors.Is(err, ErrInvalidListOptions) {
+			t.Errorf("%s: expected ErrInvalidListOptions, got %v", name, err)
+		}
+	}
+	if _, err := ListTasks(db, 0, 0, 0, false, ListOptions{DateFrom: "01/02/2026"}); !errors.Is(err, ErrInvalidListOptions) {
+		t.Errorf("expected a malformed date to be rejected, got %v", err)
+	}
+	if page.Items == nil {
+		t.Errorf("expected an empty list to encode as [], got nil")
+	}
+}
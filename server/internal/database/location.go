@@ -0,0 +1,369 @@
+package database
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/masoncfrancis/homelogger/server/internal/models"
+	"gorm.io/gorm"
+)
+
+// ErrLocationInUse is returned when deleting a location that still has
+// child locations or records.
+var ErrLocationInUse = errors.New("location still has sub-locations or records; move or delete them first")
+
+// locationRank orders the location kinds from the top of the tree down.
+var locationRank = map[string]int{
+	models.LocationFloor: 1,
+	models.LocationRoom:  2,
+	models.LocationZone:  3,
+}
+
+// locationTables are the tables carrying a location_id column.
+var locationTables = []string{
+	"appliances",
+	"tasks",
+	"notes",
+	"saved_files",
+}
+
+// LocationNode is a location with its children, as returned by GetLocationTree.
+type LocationNode struct {
+	models.Location
+	Children []LocationNode `json:"children"`
+}
+
+// ApplianceHistory is an appliance together with its maintenance and repair records.
+type ApplianceHistory struct {
+	models.Appliance
+	Maintenance []models.Maintenance `json:"maintenance"`
+	Repairs     []models.Repair      `json:"repairs"`
+}
+
+// GetLocations returns the locations of a property ordered by ID. Pass
+// propertyID=0 for every property.
+func GetLocations(db *gorm.DB, propertyID uint) ([]models.Location, error) {
+	var locations []models.Location
+	if err := db.Scopes(propertyScope(propertyID)).Order("id ASC").Find(&locations).Error; err != nil {
+		return nil, err
+	}
+	return locations, nil
+}
+
+// GetLocation returns a single location by ID.
+func GetLocation(db *gorm.DB, id uint) (*models.Location, error) {
+	var location models.Location
+	if err := db.Where("id = ?", id).First(&location).Error; err != nil {
+		return nil, err
+	}
+	return &location, nil
+}
+
+// GetLocationTree returns the location trees of a property, with floors (and
+// any other parentless locations) at the top.
+func GetLocationTree(db *gorm.DB, propertyID uint) ([]LocationNode, error) {
+	locations, err := GetLocations(db, propertyID)
+	if err != nil {
+		return nil, err
+	}
+	children := make(map[uint][]models.Location)
+	var roots []models.Location
+	for _, l := range locations {
+		if l.ParentID == nil {
+			roots = append(roots, l)
+			continue
+		}
+		children[*l.ParentID] = append(children[*l.ParentID], l)
+	}
+	var build func(l models.Location) LocationNode
+	build = func(l models.Location) LocationNode {
+		node := LocationNode{Location: l, Children: []LocationNode{}}
+		for _, child := range children[l.ID] {
+			node.Children = append(node.Children, build(child))
+		}
+		return node
+	}
+	tree := make([]LocationNode, 0, len(roots))
+	for _, root := range roots {
+		tree = append(tree, build(root))
+	}
+	return tree, nil
+}
+
+// LocationSubtreeIDs returns the ID of a location followed by the IDs of
+// every location below it.
+func LocationSubtreeIDs(db *gorm.DB, id uint) ([]uint, error) {
+	root, err := GetLocation(db, id)
+	if err != nil {
+		return nil, err
+	}
+	locations, err := GetLocations(db, root.PropertyID)
+	if err != nil {
+		return nil, err
+	}
+	children := make(map[uint][]uint)
+	for _, l := range locations {
+		if l.ParentID != nil {
+			children[*l.ParentID] = append(children[*l.ParentID], l.ID)
+		}
+	}
+	ids := []uint{root.ID}
+	for i := 0; i < len(ids); i++ {
+		ids = append(ids, children[ids[i]]...)
+	}
+	return ids, nil
+}
+
+// validateLocation checks a location's name, kind and parent. The parent must
+// be in the same property and of an earlier kind.
+func validateLocation(db *gorm.DB, location *models.Location) error {
+	location.Name = strings.TrimSpace(location.Name)
+	if location.Name == "" {
+		return invalidField("name", "name is required")
+	}
+	rank, ok := locationRank[location.Kind]
+	if !ok {
+		return invalidField("kind", "kind must be %s, %s or %s", models.LocationFloor, models.LocationRoom, models.LocationZone)
+	}
+	if location.ParentID != nil && *location.ParentID == 0 {
+		location.ParentID = nil
+	}
+	if location.ParentID == nil {
+		return nil
+	}
+	parent, err := GetLocation(db, *location.ParentID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return invalidField("parentId", "parent location %d does not exist", *location.ParentID)
+		}
+		return err
+	}
+	if parent.PropertyID != location.PropertyID {
+		return invalidField("parentId", "parent location %d belongs to another property", parent.ID)
+	}
+	if locationRank[parent.Kind] >= rank {
+		return invalidField("parentId", "a %s cannot be inside a %s", location.Kind, parent.Kind)
+	}
+	return nil
+}
+
+// AddLocation creates a location. Without a propertyID it goes into the
+// parent's property, or the default property.
+func AddLocation(db *gorm.DB, location *models.Location) (*models.Location, error) {
+	location.ID = 0
+	propertyID, err := resolvePropertyID(db, location.PropertyID, nil, nil, location.ParentID)
+	if err != nil {
+		return nil, err
+	}
+	location.PropertyID = propertyID
+	if err := validateLocation(db, location); err != nil {
+		return nil, err
+	}
+	if err := createAudited(db, AuditLocation, location); err != nil {
+		return nil, err
+	}
+	return location, nil
+}
+
+// UpdateLocation renames, re-kinds or moves a location within its property.
+// An empty kind keeps the current one. Moving a location moves its whole subtree.
+func UpdateLocation(db *gorm.DB, id uint, name, kind string, parentID *uint) (*models.Location, error) {
+	location, err := GetLocation(db, id)
+	if err != nil {
+		return nil, err
+	}
+	before := *location
+	location.Name = name
+	if kind != "" {
+		location.Kind = kind
+	}
+	location.ParentID = parentID
+	if err := validateLocation(db, location); err != nil {
+		return nil, err
+	}
+
+	subtree, err := LocationSubtreeIDs(db, id)
+	if err != nil {
+		return nil, err
+	}
+	if location.ParentID != nil {
+		for _, sub := range subtree {
+			if sub == *location.ParentID {
+				return nil, invalidField("parentId", "a location cannot be moved inside itself")
+			}
+		}
+	}
+	var children []models.Location
+	if err := db.Where("parent_id = ?", id).Find(&children).Error; err != nil {
+		return nil, err
+	}
+	for _, child := range children {
+		if locationRank[child.Kind] <= locationRank[location.Kind] {
+			return nil, invalidField("kind", "a %s cannot be inside a %s", child.Kind, location.Kind)
+		}
+	}
+
+	if err := saveAudited(db, AuditLocation, &before, location); err != nil {
+		return nil, err
+	}
+	return location, nil
+}
+
+// DeleteLocation deletes a location with no sub-locations and no records.
+func DeleteLocation(db *gorm.DB, id uint) error {
+	if _, err := GetLocation(db, id); err != nil {
+		return err
+	}
+	var children int64
+	if err := db.Model(&models.Location{}).Where("parent_id = ?", id).Count(&children).Error; err != nil {
+		return err
+	}
+	if children > 0 {
+		return ErrLocationInUse
+	}
+	for _, table := range locationTables {
+		var inUse int64
+		if err := db.Table(table).Where("location_id = ? AND deleted_at IS NULL", id).Count(&inUse).Error; err != nil {
+			return err
+		}
+		if inUse > 0 {
+			return ErrLocationInUse
+		}
+	}
+	return deleteAudited[models.Location](db, AuditLocation, id)
+}
+
+// resolveLocation checks that a record's location exists in the record's
+// property. A zero locationID is cleared.
+func resolveLocation(db *gorm.DB, propertyID uint, locationID **uint) error {
+	if *locationID == nil {
+		return nil
+	}
+	if **locationID == 0 {
+		*locationID = nil
+		return nil
+	}
+	location, err := GetLocation(db, **locationID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return invalidField("locationId", "location %d does not exist", **locationID)
+		}
+		return err
+	}
+	if location.PropertyID != propertyID {
+		return invalidField("locationId", "location %d belongs to another property", location.ID)
+	}
+	return nil
+}
+
+// inLocationSubtree matches rows placed directly in one of ids, or attached
+// to an appliance placed there.
+func inLocationSubtree(ids []uint) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("location_id IN ? OR appliance_id IN (?)", ids,
+			db.Session(&gorm.Session{NewDB: true}).Model(&models.Appliance{}).Select("id").Where("location_id IN ?", ids))
+	}
+}
+
+// GetAppliancesInLocation returns the appliances placed anywhere under a
+// location. With withHistory, each appliance carries its maintenance and
+// repair records.
+func GetAppliancesInLocation(db *gorm.DB, id uint, withHistory bool) ([]ApplianceHistory, error) {
+	ids, err := LocationSubtreeIDs(db, id)
+	if err != nil {
+		return nil, err
+	}
+	var appliances []models.Appliance
+	if err := db.Where("location_id IN ?", ids).Order("id ASC").Find(&appliances).Error; err != nil {
+		return nil, err
+	}
+
+	result := make([]ApplianceHistory, 0, len(appliances))
+	byID := make(map[uint]int, len(appliances))
+	applianceIDs := make([]uint, 0, len(appliances))
+	for i, a := range appliances {
+		result = append(result, ApplianceHistory{Appliance: a, Maintenance: []models.Maintenance{}, Repairs: []models.Repair{}})
+		byID[a.ID] = i
+		applianceIDs = append(applianceIDs, a.ID)
+	}
+	if !withHistory || len(applianceIDs) == 0 {
+		return result, nil
+	}
+
+	var maintenances []models.Maintenance
+	if err := db.Where("appliance_id IN ?", applianceIDs).Order("date DESC, id DESC").Find(&maintenances).Error; err != nil {
+		return nil, err
+	}
+	for _, m := range maintenances {
+		i := byID[*m.ApplianceID]
+		result[i].Maintenance = append(result[i].Maintenance, m)
+	}
+	var repairs []models.Repair
+	if err := db.Where("appliance_id IN ?", applianceIDs).Order("date DESC, id DESC").Find(&repairs).Error; err != nil {
+		return nil, err
+	}
+	for _, r := range repairs {
+		i := byID[*r.ApplianceID]
+		result[i].Repairs = append(result[i].Repairs, r)
+	}
+	return result, nil
+}
+
+// GetTasksInLocation returns the tasks under a location, including tasks on
+// appliances placed there. Set includeCompleted=true to include tasks where
+// checked=true.
+func GetTasksInLocation(db *gorm.DB, id uint, includeCompleted bool) ([]models.Task, error) {
+	ids, err := LocationSubtreeIDs(db, id)
+	if err != nil {
+		return nil, err
+	}
+	query := db.Model(&models.Task{}).Scopes(inLocationSubtree(ids))
+	if !includeCompleted {
+		query = query.Where("checked = ?", false)
+	}
+	var tasks []models.Task
+	result := query.
+		Order("CASE WHEN due_date IS NULL THEN 1 ELSE 0 END, due_date ASC, created_at ASC").
+		Find(&tasks)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if err := nameCompleters(db, tasks); err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+// GetNotesInLocation returns the notes under a location, including notes on
+// appliances placed there.
+func GetNotesInLocation(db *gorm.DB, id uint) ([]models.Note, error) {
+	ids, err := LocationSubtreeIDs(db, id)
+	if err != nil {
+		return nil, err
+	}
+	var notes []models.Note
+	if err := db.Scopes(inLocationSubtree(ids)).Order("id ASC").Find(&notes).Error; err != nil {
+		return nil, err
+	}
+	return notes, nil
+}
+
+// GetFilesInLocation returns file info for files under a location, including
+// files attached to appliances placed there.
+func GetFilesInLocation(db *gorm.DB, id uint) ([]FileInfoResponse, error) {
+	ids, err := LocationSubtreeIDs(db, id)
+	if err != nil {
+		return nil, err
+	}
+	var files []models.SavedFile
+	result := db.Select("id", "original_name", "user_id").Scopes(inLocationSubtree(ids)).Order("id ASC").Find(&files)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	resp := make([]FileInfoResponse, 0, len(files))
+	for _, f := range files {
+		resp = append(resp, FileInfoResponse{ID: f.ID, OriginalName: f.OriginalName, UserID: f.UserID})
+	}
+	return resp, nil
+}
@@ -0,0 +1,209 @@
+package database
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/masoncfrancis/homelogger/server/internal/models"
+)
+
+func TestLocationTree(t *testing.T) {
+	db := TestDB(t)
+
+	upstairs, err := AddLocation(db, &models.Location{Kind: models.LocationFloor, Name: "Second floor"})
+	if err != nil {
+		t.Fatalf("AddLocation error: %v", err)
+	}
+	bedroom, err := AddLocation(db, &models.Location{Kind: models.LocationRoom, Name: "Bedroom", ParentID: &upstairs.ID})
+	if err != nil {
+		t.Fatalf("AddLocation error: %v", err)
+	}
+	if _, err := AddLocation(db, &models.Location{Kind: models.LocationZone, Name: "Closet", ParentID: &bedroom.ID}); err != nil {
+		t.Fatalf("AddLocation error: %v", err)
+	}
+	if bedroom.PropertyID != upstairs.PropertyID {
+		t.Fatalf("expected the room to inherit the floor's property, got %d", bedroom.PropertyID)
+	}
+
+	// A floor cannot sit inside a room, and kinds must be known
+	if _, err := AddLocation(db, &models.Location{Kind: models.LocationFloor, Name: "Loft", ParentID: &bedroom.ID}); err == nil {
+		t.Fatal("expected error for a floor inside a room")
+	}
+	if _, err := AddLocation(db, &models.Location{Kind: "wing", Name: "East"}); err == nil {
+		t.Fatal("expected error for an unknown kind")
+	}
+
+	tree, err := GetLocationTree(db, 0)
+	if err != nil {
+		t.Fatalf("GetLocationTree error: %v", err)
+	}
+	if len(tree) != 1 || len(tree[0].Children) != 1 || len(tree[0].Children[0].Children) != 1 {
+		t.Fatalf("unexpected tree: %+v", tree)
+	}
+	if tree[0].Children[0].Children[0].Name != "Closet" {
+		t.Fatalf("expected the closet at the bottom, got %+v", tree[0].Children[0].Children[0])
+	}
+}
+
+func TestMoveLocation(t *testing.T) {
+	db := TestDB(t)
+
+	ground, _ := AddLocation(db, &models.Location{Kind: models.LocationFloor, Name: "Ground floor"})
+	upstairs, _ := AddLocation(db, &models.Location{Kind: models.LocationFloor, Name: "Second floor"})
+	kitchen, _ := AddLocation(db, &models.Location{Kind: models.LocationRoom, Name: "Kitchen", ParentID: &ground.ID})
+	pantry, _ := AddLocation(db, &models.Location{Kind: models.LocationZone, Name: "Pantry", ParentID: &kitchen.ID})
+
+	moved, err := UpdateLocation(db, kitchen.ID, "Kitchenette", "", &upstairs.ID)
+	if err != nil {
+		t.Fatalf("UpdateLocation error: %v", err)
+	}
+	if moved.Name != "Kitchenette" || moved.Kind != models.LocationRoom || *moved.ParentID != upstairs.ID {
+		t.Fatalf("unexpected location after move: %+v", moved)
+	}
+	ids, _ := LocationSubtreeIDs(db, upstairs.ID)
+	if len(ids) != 3 {
+		t.Fatalf("expected the pantry to move with the kitchen, got %v", ids)
+	}
+
+	// A location cannot move into its own subtree
+	if _, err := UpdateLocation(db, kitchen.ID, "Kitchenette", models.LocationRoom, &pantry.ID); err == nil {
+		t.Fatal("expected error moving a location inside itself")
+	}
+	// Turning the kitchen into a zone would leave the pantry ranked above it
+	if _, err := UpdateLocation(db, kitchen.ID, "Kitchenette", models.LocationZone, &upstairs.ID); err == nil {
+		t.Fatal("expected error for a child ranked above its parent")
+	}
+}
+
+func TestDeleteLocation(t *testing.T) {
+	db := TestDB(t)
+
+	basement, _ := AddLocation(db, &models.Location{Kind: models.LocationFloor, Name: "Basement"})
+	utility, _ := AddLocation(db, &models.Location{Kind: models.LocationRoom, Name: "Utility", ParentID: &basement.ID})
+	note, err := AddNote(db, &models.Note{Title: "Shutoff", Body: "Main valve by the stairs", LocationID: &utility.ID})
+	if err != nil {
+		t.Fatalf("AddNote error: %v", err)
+	}
+
+	if err := DeleteLocation(db, basement.ID); !errors.Is(err, ErrLocationInUse) {
+		t.Fatalf("expected ErrLocationInUse for a location with children, got %v", err)
+	}
+	if err := DeleteLocation(db, utility.ID); !errors.Is(err, ErrLocationInUse) {
+		t.Fatalf("expected ErrLocationInUse for a location with records, got %v", err)
+	}
+
+	if err := DeleteNote(db, note.ID); err != nil {
+		t.Fatalf("DeleteNote error: %v", err)
+	}
+	if err := DeleteLocation(db, utility.ID); err != nil {
+		t.Fatalf("DeleteLocation error: %v", err)
+	}
+	if err := DeleteLocation(db, basement.ID); err != nil {
+		t.Fatalf("DeleteLocation error: %v", err)
+	}
+}
+
+func TestLocationSubtreeQueries(t *testing.T) {
+	db := TestDB(t)
+
+	basement, _ := AddLocation(db, &models.Location{Kind: models.LocationFloor, Name: "Basement"})
+	utility, _ := AddLocation(db, &models.Location{Kind: models.LocationRoom, Name: "Utility", ParentID: &basement.ID})
+	upstairs, _ := AddLocation(db, &models.Location{Kind: models.LocationFloor, Name: "Second floor"})
+
+	heater := &models.Appliance{ApplianceName: "Water heater", LocationID: &utility.ID}
+	if _, err := AddAppliance(db, heater); err != nil {
+		t.Fatalf("AddAppliance error: %v", err)
+	}
+	if _, err := AddAppliance(db, &models.Appliance{ApplianceName: "Dehumidifier", LocationID: &upstairs.ID}); err != nil {
+		t.Fatalf("AddAppliance error: %v", err)
+	}
+	if _, err := AddRepair(db, &models.Repair{Description: "Replace anode", Date: "2026-03-01", ApplianceID: &heater.ID, ReferenceType: "Appliance"}); err != nil {
+		t.Fatalf("AddRepair error: %v", err)
+	}
+
+	// One task on the appliance, one placed directly, one completed
+	if _, err := AddTask(db, &models.Task{Label: "Flush tank", ApplianceID: &heater.ID, UserID: "1"}); err != nil {
+		t.Fatalf("AddTask error: %v", err)
+	}
+	if _, err := AddTask(db, &models.Task{Label: "Check sump pump", LocationID: &basement.ID, UserID: "1"}); err != nil {
+		t.Fatalf("AddTask error: %v", err)
+	}
+	if _, err := AddTask(db, &models.Task{Label: "Seal floor", LocationID: &utility.ID, Checked: true, UserID: "1"}); err != nil {
+		t.Fatalf("AddTask error: %v", err)
+	}
+
+	open, err := GetTasksInLocation(db, basement.ID, false)
+	if err != nil {
+		t.Fatalf("GetTasksInLocation error: %v", err)
+	}
+	if len(open) != 2 {
+		t.Fatalf("expected 2 open tasks in the basement, got %+v", open)
+	}
+	all, _ := GetTasksInLocation(db, basement.ID, true)
+	if len(all) != 3 {
+		t.Fatalf("expected 3 tasks including completed, got %+v", all)
+	}
+	if upstairsTasks, _ := GetTasksInLocation(db, upstairs.ID, true); len(upstairsTasks) != 0 {
+		t.Fatalf("expected no tasks upstairs, got %+v", upstairsTasks)
+	}
+
+	appliances, err := GetAppliancesInLocation(db, basement.ID, true)
+	if err != nil {
+		t.Fatalf("GetAppliancesInLocation error: %v", err)
+	}
+	if len(appliances) != 1 || appliances[0].ID != heater.ID {
+		t.Fatalf("expected only the water heater in the basement, got %+v", appliances)
+	}
+	if len(appliances[0].Repairs) != 1 || len(appliances[0].Maintenance) != 0 {
+		t.Fatalf("expected the heater's repair history, got %+v", appliances[0])
+	}
+
+	if _, err := GetTasksInLocation(db, 9999, false); err == nil {
+		t.Fatal("expected error for an unknown location")
+	}
+}
+
+func TestLocationMustBelongToRecordProperty(t *testing.T) {
+	db := TestDB(t)
+
+	cabin, _ := AddProperty(db, "Cabin", "")
+	loft, _ := AddLocation(db, &models.Location{PropertyID: cabin.ID, Kind: models.LocationFloor, Name: "Loft"})
+	home, _ := DefaultPropertyID(db)
+
+	if _, err := AddTask(db, &models.Task{Label: "Sweep", LocationID: &loft.ID, PropertyID: home, UserID: "1"}); err == nil {
+		t.Fatal("expected error for a location in another property")
+	}
+	task, err := AddTask(db, &models.Task{Label: "Sweep", LocationID: &loft.ID, UserID: "1"})
+	if err != nil {
+		t.Fatalf("AddTask error: %v", err)
+	}
+	if task.PropertyID != cabin.ID {
+		t.Fatalf("expected task in cabin, got property %d", task.PropertyID)
+	}
+}
+
+func TestImportKeepsLocations(t *testing.T) {
+	db := TestDB(t)
+
+	floor, _ := AddLocation(db, &models.Location{Kind: models.LocationFloor, Name: "Ground floor"})
+	room, _ := AddLocation(db, &models.Location{Kind: models.LocationRoom, Name: "Laundry", ParentID: &floor.ID})
+	if _, err := AddAppliance(db, &models.Appliance{ApplianceName: "Washer", LocationID: &room.ID}); err != nil {
+		t.Fatalf("AddAppliance error: %v", err)
+	}
+
+	exported, err := ExportToJSON(db, dialectSQLite)
+	if err != nil {
+		t.Fatalf("ExportToJSON error: %v", err)
+	}
+	if _, err := ImportFromJSON(db, exported, ""); err != nil {
+		t.Fatalf("ImportFromJSON error: %v", err)
+	}
+
+	appliances, err := GetAppliancesInLocation(db, floor.ID, false)
+	if err != nil {
+		t.Fatalf("GetAppliancesInLocation error: %v", err)
+	}
+	if len(appliances) != 1 || appliances[0].ApplianceName != "Washer" {
+		t.Fatalf("expected the washer under the ground floor after import, got %+v", appliances)
+	}
+}
@@ -5,25 +5,43 @@ import (
 	"gorm.io/gorm"
 )
 
-// GetMaintenances gets maintenance records filtered by applianceId, referenceType, and spaceType
-func GetMaintenances(db *gorm.DB, applianceId uint, referenceType, spaceType string) ([]models.Maintenance, error) {
-	var maintenances []models.Maintenance
-	if referenceType == "Space" {
-		result := db.Where("reference_type = ? AND space_type = ?", referenceType, spaceType).Find(&maintenances)
-		if result.Error != nil {
-			return []models.Maintenance{}, result.Error
-		}
-
-		return maintenances, nil
-	} else {
-		result := db.Where("appliance_id = ? AND reference_type = ?", applianceId, referenceType).Find(&maintenances)
-		if result.Error != nil {
-			return []models.Maintenance{}, result.Error
-		}
+var maintenanceListSpec = listSpec{
+	sorts: map[string]string{
+		"id":          "id",
+		"date":        "date",
+		"cost":        "cost",
+		"description": "description",
+		"createdAt":   "created_at",
+	},
+	defaultSort:  "id",
+	defaultOrder: "asc",
+	dateColumn:   "date",
+	costColumn:   "cost",
+}
 
-		return maintenances, nil
+// GetMaintenances gets maintenance records filtered by propertyID, applianceId, referenceType, and spaceID.
+// Pass propertyID=0 for every property and referenceType="" for records of
+// every appliance and space.
+func GetMaintenances(db *gorm.DB, propertyID uint, applianceId uint, referenceType string, spaceID uint) ([]models.Maintenance, error) {
+	page, err := ListMaintenances(db, propertyID, applianceId, referenceType, spaceID, ListOptions{})
+	if err != nil {
+		return []models.Maintenance{}, err
 	}
 
+	return page.Items, nil
+}
+
+// ListMaintenances returns a page of maintenance records, filtered like GetMaintenances.
+func ListMaintenances(db *gorm.DB, propertyID uint, applianceId uint, referenceType string, spaceID uint, opts ListOptions) (*Page[models.Maintenance], error) {
+	query := db.Model(&models.Maintenance{}).Scopes(propertyScope(propertyID))
+	switch referenceType {
+	case "":
+	case "Space":
+		query = query.Where("reference_type = ? AND space_id = ?", referenceType, spaceID)
+	default:
+		query = query.Where("appliance_id = ? AND reference_type = ?", applianceId, referenceType)
+	}
+	return paginate[models.Maintenance](query, maintenanceListSpec, opts)
 }
 
 // AddMaintenance creates a new maintenance record
@@ -32,9 +50,22 @@ func AddMaintenance(db *gorm.DB, maintenance *models.Maintenance) (*models.Maint
 	if maintenance.ApplianceID != nil && *maintenance.ApplianceID == 0 {
 		maintenance.ApplianceID = nil
 	}
-	result := db.Create(maintenance)
-	if result.Error != nil {
-		return nil, result.Error
+	propertyID, err := resolvePropertyID(db, maintenance.PropertyID, maintenance.ApplianceID, maintenance.SpaceID, nil)
+	if err != nil {
+		return nil, err
+	}
+	maintenance.PropertyID = propertyID
+	space, err := resolveSpace(db, propertyID, maintenance.SpaceID, maintenance.SpaceType)
+	if err != nil {
+		return nil, err
+	}
+	maintenance.SpaceID = nil
+	if space != nil {
+		maintenance.SpaceID = &space.ID
+		maintenance.SpaceType = space.Name
+	}
+	if err := createAudited(db, TrashMaintenance, maintenance); err != nil {
+		return nil, err
 	}
 
 	return maintenance, nil
@@ -57,28 +88,19 @@ func UpdateMaintenance(db *gorm.DB, id uint, description, date string, cost floa
 	if err != nil {
 		return nil, err
 	}
+	before := *maintenance
 	maintenance.Description = description
 	maintenance.Date = date
 	maintenance.Cost = cost
 	maintenance.Notes = notes
-	result := db.Save(maintenance)
-	if result.Error != nil {
-		return nil, result.Error
+	if err := saveAudited(db, TrashMaintenance, &before, maintenance); err != nil {
+		return nil, err
 	}
 	return maintenance, nil
 }
 
-// DeleteMaintenance deletes a maintenance record by ID
+// DeleteMaintenance moves a maintenance record to the trash together with its
+// files, in one transaction
 func DeleteMaintenance(db *gorm.DB, id uint) error {
-	// Remove associated files (disk + DB)
-	if err := DeleteFilesByMaintenance(db, id); err != nil {
-		return err
-	}
-
-	result := db.Where("id = ?", id).Delete(&models.Maintenance{})
-	if result.Error != nil {
-		return result.Error
-	}
-
-	return nil
+	return moveToTrash(db, TrashMaintenance, "id = ?", id)
 }
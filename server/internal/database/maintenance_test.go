@@ -42,11 +42,18 @@ func TestAddGetDeleteMaintenance(t *testing.T) {
         t.Fatalf("UploadFile failed: %v", err)
     }
 
-    // Delete maintenance should remove files and DB row
+    // Delete maintenance moves it and its files to the trash; the file stays
+    // on disk until purged
     if err := DeleteMaintenance(db, added.ID); err != nil {
         t.Fatalf("DeleteMaintenance failed: %v", err)
     }
+    if _, err := os.Stat(path); err != nil {
+        t.Fatalf("expected maintenance file kept until purge, stat error: %v", err)
+    }
 
+    if err := PurgeFromTrash(db, TrashMaintenance, added.ID); err != nil {
+        t.Fatalf("PurgeFromTrash failed: %v", err)
+    }
     if _, err := os.Stat(path); !os.IsNotExist(err) {
         t.Fatalf("expected maintenance file removed, stat error: %v", err)
     }
@@ -60,7 +67,7 @@ func TestGetMaintenancesSpaceFilter(t *testing.T) {
         t.Fatalf("AddMaintenance failed: %v", err)
     }
 
-    res, err := GetMaintenances(db, 0, "Space", "Yard")
+    res, err := GetMaintenances(db, 0, 0, "Space", *m.SpaceID)
     if err != nil {
         t.Fatalf("GetMaintenances failed: %v", err)
     }
@@ -0,0 +1,341 @@
+package database
+
+import (
+	"errors"
+	"time"
+
+	"github.com/masoncfrancis/homelogger/server/internal/models"
+	"gorm.io/gorm"
+)
+
+// Audited entity types of meters and their readings.
+const (
+	AuditMeter        = "meter"
+	AuditMeterReading = "meterReading"
+)
+
+var meterListSpec = listSpec{
+	sorts: map[string]string{
+		"id":        "id",
+		"name":      "name",
+		"readAt":    "read_at",
+		"createdAt": "created_at",
+	},
+	defaultSort:  "name",
+	defaultOrder: "asc",
+}
+
+var meterReadingListSpec = listSpec{
+	sorts: map[string]string{
+		"readAt": "read_at",
+		"value":  "value",
+		"id":     "id",
+	},
+	defaultSort:  "readAt",
+	defaultOrder: "desc",
+	dateColumn:   "read_at",
+}
+
+// liveMeters leaves out the meters of appliances in the trash. They come
+// back when the appliance is restored.
+func liveMeters(db *gorm.DB) *gorm.DB {
+	return db.Where("appliance_id IN (SELECT id FROM appliances WHERE deleted_at IS NULL)")
+}
+
+// ListMeters returns a page of the meters of a property, or of one appliance
+// when applianceID is not 0. Pass propertyID=0 for every property.
+func ListMeters(db *gorm.DB, propertyID, applianceID uint, opts ListOptions) (*Page[models.Meter], error) {
+	query := db.Model(&models.Meter{}).Scopes(propertyScope(propertyID), liveMeters)
+	if applianceID != 0 {
+		query = query.Where("appliance_id = ?", applianceID)
+	}
+	return paginate[models.Meter](query, meterListSpec, opts)
+}
+
+// GetMeter returns a single meter by ID.
+func GetMeter(db *gorm.DB, id uint) (*models.Meter, error) {
+	var meter models.Meter
+	if err := db.Scopes(liveMeters).Where("id = ?", id).First(&meter).Error; err != nil {
+		return nil, err
+	}
+	return &meter, nil
+}
+
+// checkMeter validates a meter's name and appliance, and files the meter
+// under the appliance's property.
+func checkMeter(db *gorm.DB, meter *models.Meter) error {
+	if meter.Name == "" {
+		return invalidField("name", "name is required")
+	}
+	appliance, err := GetAppliance(db, meter.ApplianceID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return invalidField("applianceId", "appliance %d does not exist", meter.ApplianceID)
+	}
+	if err != nil {
+		return err
+	}
+	meter.PropertyID = appliance.PropertyID
+	return nil
+}
+
+// AddMeter creates a meter on an appliance. Its reading is set by adding
+// readings.
+func AddMeter(db *gorm.DB, meter *models.Meter) (*models.Meter, error) {
+	if err := checkMeter(db, meter); err != nil {
+		return nil, err
+	}
+	meter.Reading, meter.ReadAt = nil, nil
+	if err := createAudited(db, AuditMeter, meter); err != nil {
+		return nil, err
+	}
+	return meter, nil
+}
+
+// UpdateMeter saves a meter's name, unit and appliance. Its reading is kept.
+func UpdateMeter(db *gorm.DB, meter *models.Meter) (*models.Meter, error) {
+	before, err := GetMeter(db, meter.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkMeter(db, meter); err != nil {
+		return nil, err
+	}
+	meter.CreatedAt = before.CreatedAt
+	meter.Reading, meter.ReadAt = before.Reading, before.ReadAt
+	if err := saveAudited(db, AuditMeter, before, meter); err != nil {
+		return nil, err
+	}
+	return meter, nil
+}
+
+// DeleteMeter deletes a meter and its readings for good. Tasks that came due
+// by the meter keep only their dates.
+func DeleteMeter(db *gorm.DB, id uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if _, err := GetMeter(tx, id); err != nil {
+			return err
+		}
+		var tasks []models.Task
+		if err := tx.Where("meter_id = ?", id).Find(&tasks).Error; err != nil {
+			return err
+		}
+		for i := range tasks {
+			before := tasks[i]
+			tasks[i].MeterID, tasks[i].MeterInterval, tasks[i].MeterDueAt = nil, 0, nil
+			if err := saveAudited(tx, TrashTask, &before, &tasks[i]); err != nil {
+				return err
+			}
+		}
+		if err := tx.Where("meter_id = ?", id).Delete(&models.MeterReading{}).Error; err != nil {
+			return err
+		}
+		return deleteAudited[models.Meter](tx, AuditMeter, id)
+	})
+}
+
+// purgeMeters deletes the meters and readings of appliances purged for good,
+// and unhooks the tasks that used them.
+func purgeMeters(tx *gorm.DB, kind string, ids []uint) error {
+	if kind != TrashAppliance {
+		return nil
+	}
+	var meterIDs []uint
+	if err := tx.Model(&models.Meter{}).Where("appliance_id IN ?", ids).Pluck("id", &meterIDs).Error; err != nil {
+		return err
+	}
+	if len(meterIDs) == 0 {
+		return nil
+	}
+	if err := tx.Model(&models.Task{}).Unscoped().Where("meter_id IN ?", meterIDs).
+		Updates(map[string]any{"meter_id": nil, "meter_interval": 0, "meter_due_at": nil}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("meter_id IN ?", meterIDs).Delete(&models.MeterReading{}).Error; err != nil {
+		return err
+	}
+	return tx.Where("id IN ?", meterIDs).Delete(&models.Meter{}).Error
+}
+
+// ListMeterReadings returns a page of a meter's readings, newest first by
+// default.
+func ListMeterReadings(db *gorm.DB, meterID uint, opts ListOptions) (*Page[models.MeterReading], error) {
+	if _, err := GetMeter(db, meterID); err != nil {
+		return nil, err
+	}
+	query := db.Model(&models.MeterReading{}).Where("meter_id = ?", meterID)
+	return paginate[models.MeterReading](query, meterReadingListSpec, opts)
+}
+
+// AddMeterReading records a reading of a meter. A reading may be backdated
+// but must fit between the readings around it, as meters only count up.
+// Open tasks whose threshold the meter has now reached come due on the day
+// it was first read at or above it.
+func AddMeterReading(db *gorm.DB, meterID uint, reading *models.MeterReading) (*models.MeterReading, error) {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		meter, err := GetMeter(tx, meterID)
+		if err != nil {
+			return err
+		}
+		if _, err := time.Parse("2006-01-02", reading.ReadAt); err != nil {
+			return invalidField("readAt", "readAt must be a date in YYYY-MM-DD format")
+		}
+		if reading.Value < 0 {
+			return invalidField("value", "value must not be negative")
+		}
+
+		var earlier, later models.MeterReading
+		err = tx.Where("meter_id = ? AND read_at <= ?", meterID, reading.ReadAt).Order("read_at DESC, id DESC").First(&earlier).Error
+		if err == nil && reading.Value < earlier.Value {
+			return invalidField("value", "value %g is below the reading of %g on %s", reading.Value, earlier.Value, earlier.ReadAt)
+		} else if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		err = tx.Where("meter_id = ? AND read_at > ?", meterID, reading.ReadAt).Order("read_at ASC, id ASC").First(&later).Error
+		if err == nil && reading.Value > later.Value {
+			return invalidField("value", "value %g is above the reading of %g on %s", reading.Value, later.Value, later.ReadAt)
+		} else if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		reading.ID = 0
+		reading.MeterID = meterID
+		reading.RecordedBy = auditActor(tx)
+		if err := createAudited(tx, AuditMeterReading, reading); err != nil {
+			return err
+		}
+		if err := refreshMeter(tx, meter); err != nil {
+			return err
+		}
+		return markMeterTasksDue(tx, meter)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return reading, nil
+}
+
+// DeleteMeterReading deletes one reading of a meter, such as a typo. Tasks
+// it brought due keep their due date.
+func DeleteMeterReading(db *gorm.DB, meterID, readingID uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		meter, err := GetMeter(tx, meterID)
+		if err != nil {
+			return err
+		}
+		var reading models.MeterReading
+		if err := tx.Where("id = ? AND meter_id = ?", readingID, meterID).First(&reading).Error; err != nil {
+			return err
+		}
+		if err := deleteAudited[models.MeterReading](tx, AuditMeterReading, readingID); err != nil {
+			return err
+		}
+		return refreshMeter(tx, meter)
+	})
+}
+
+// refreshMeter sets a meter's reading to its latest one.
+func refreshMeter(tx *gorm.DB, meter *models.Meter) error {
+	before := *meter
+	meter.Reading, meter.ReadAt = nil, nil
+	var latest models.MeterReading
+	err := tx.Where("meter_id = ?", meter.ID).Order("read_at DESC, id DESC").First(&latest).Error
+	switch {
+	case err == nil:
+		meter.Reading, meter.ReadAt = &latest.Value, &latest.ReadAt
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return err
+	}
+	return saveAudited(tx, AuditMeter, &before, meter)
+}
+
+// markMeterTasksDue brings due the open tasks whose threshold the meter has
+// reached.
+func markMeterTasksDue(tx *gorm.DB, meter *models.Meter) error {
+	if meter.Reading == nil {
+		return nil
+	}
+	var tasks []models.Task
+	if err := tx.Where("meter_id = ? AND checked = ? AND meter_due_at <= ?", meter.ID, false, *meter.Reading).Find(&tasks).Error; err != nil {
+		return err
+	}
+	for i := range tasks {
+		before := tasks[i]
+		if err := dueByMeter(tx, &tasks[i]); err != nil {
+			return err
+		}
+		if err := saveAudited(tx, TrashTask, &before, &tasks[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dueByMeter moves a task's due date up to the day its meter was first read
+// at or above the task's threshold, if that is earlier.
+func dueByMeter(tx *gorm.DB, task *models.Task) error {
+	if task.MeterID == nil || task.MeterDueAt == nil {
+		return nil
+	}
+	var crossed models.MeterReading
+	err := tx.Where("meter_id = ? AND value >= ?", *task.MeterID, *task.MeterDueAt).Order("read_at ASC, id ASC").First(&crossed).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	// Both are YYYY-MM-DD, so they compare as strings.
+	if task.DueDate == nil || *task.DueDate == "" || crossed.ReadAt < *task.DueDate {
+		task.DueDate = &crossed.ReadAt
+	}
+	return nil
+}
+
+// normalizeMeter checks a task's meter and threshold. Without a threshold the
+// first one is the meter's reading now plus the interval.
+func normalizeMeter(db *gorm.DB, task *models.Task) error {
+	if task.MeterID == nil {
+		task.MeterInterval, task.MeterDueAt = 0, nil
+		return nil
+	}
+	meter, err := GetMeter(db, *task.MeterID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return invalidField("meterId", "meter %d does not exist", *task.MeterID)
+	}
+	if err != nil {
+		return err
+	}
+	if meter.PropertyID != task.PropertyID {
+		return invalidField("meterId", "meter %d belongs to another property", meter.ID)
+	}
+	if task.MeterInterval < 0 {
+		return invalidField("meterInterval", "meterInterval must not be negative")
+	}
+	if task.MeterDueAt == nil {
+		if task.MeterInterval == 0 {
+			return invalidField("meterDueAt", "a task with a meter needs a meterDueAt or a meterInterval")
+		}
+		due := task.MeterInterval
+		if meter.Reading != nil {
+			due += *meter.Reading
+		}
+		task.MeterDueAt = &due
+	}
+	return dueByMeter(db, task)
+}
+
+// meterReading returns what a task's meter reads now, or nil when the task
+// has no meter or the meter has no readings.
+func meterReading(tx *gorm.DB, task *models.Task) (*float64, error) {
+	if task.MeterID == nil {
+		return nil, nil
+	}
+	meter, err := GetMeter(tx, *task.MeterID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return meter.Reading, nil
+}
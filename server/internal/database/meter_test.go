@@ -0,0 +1,111 @@
+package database
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/masoncfrancis/homelogger/server/internal/models"
+	"gorm.io/gorm"
+)
+
+func TestMeterReadingsBringTasksDue(t *testing.T) {
+	db := TestDB(t)
+	appliance, err := AddAppliance(db, &models.Appliance{ApplianceName: "Generator"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	meter, err := AddMeter(db, &models.Meter{ApplianceID: appliance.ID, Name: "Runtime", Unit: "hours"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	task, err := AddTask(db, &models.Task{Label: "Change oil", MeterID: &meter.ID, MeterInterval: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task.MeterDueAt == nil || *task.MeterDueAt != 100 || task.DueDate != nil {
+		t.Fatalf("expected the task due at 100 hours and undated, got %+v", task)
+	}
+
+	for _, r := range []models.MeterReading{{Value: 40, ReadAt: "2026-01-10"}, {Value: 105, ReadAt: "2026-02-01"}} {
+		if _, err := AddMeterReading(db, meter.ID, &r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var invalid *ValidationError
+	if _, err := AddMeterReading(db, meter.ID, &models.MeterReading{Value: 30, ReadAt: "2026-01-20"}); !errors.As(err, &invalid) || invalid.Field != "value" {
+		t.Fatalf("expected a reading below an earlier one to be refused, got %v", err)
+	}
+
+	task, _ = GetTask(db, task.ID)
+	if task.DueDate == nil || *task.DueDate != "2026-02-01" {
+		t.Fatalf("expected the task due the day it crossed 100 hours, got %v", task.DueDate)
+	}
+	meter, _ = GetMeter(db, meter.ID)
+	if meter.Reading == nil || *meter.Reading != 105 {
+		t.Fatalf("expected the meter to read 105, got %v", meter.Reading)
+	}
+
+	// Completing starts counting again from the reading now.
+	task, err = CompleteTask(db, task.ID, "2026-02-03")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task.Checked || task.DueDate != nil || *task.MeterDueAt != 205 {
+		t.Fatalf("expected the task open again, due at 205 hours, got %+v", task)
+	}
+	page, err := ListTaskCompletions(db, task.ID, ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Items[0].MeterReading == nil || *page.Items[0].MeterReading != 105 {
+		t.Fatalf("expected the completion to keep the reading, got %+v", page.Items[0])
+	}
+	task, err = UndoTaskCompletion(db, task.ID, page.Items[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *task.MeterDueAt != 100 || *task.DueDate != "2026-02-01" {
+		t.Fatalf("expected the undo to put the threshold back, got %v and %v", *task.MeterDueAt, task.DueDate)
+	}
+}
+
+func TestMeterGoesWithItsAppliance(t *testing.T) {
+	db := TestDB(t)
+	appliance, err := AddAppliance(db, &models.Appliance{ApplianceName: "Water softener"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	meter, err := AddMeter(db, &models.Meter{ApplianceID: appliance.ID, Name: "Water", Unit: "gallons"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := AddMeterReading(db, meter.ID, &models.MeterReading{Value: 500, ReadAt: "2026-03-01"}); err != nil {
+		t.Fatal(err)
+	}
+	task, err := AddTask(db, &models.Task{Label: "Add salt", MeterID: &meter.ID, MeterDueAt: f64Ptr(2000)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := DeleteAppliance(db, appliance.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := GetMeter(db, meter.ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("expected the meter hidden with its appliance, got %v", err)
+	}
+	if err := PurgeFromTrash(db, TrashAppliance, appliance.ID); err != nil {
+		t.Fatal(err)
+	}
+	var left int64
+	db.Model(&models.MeterReading{}).Count(&left)
+	if left != 0 {
+		t.Fatalf("expected the readings purged, %d left", left)
+	}
+	task, err = GetTask(db, task.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task.MeterID != nil || task.MeterDueAt != nil {
+		t.Fatalf("expected the task off its meter, got %+v", task)
+	}
+}
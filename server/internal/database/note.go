@@ -5,39 +5,63 @@ import (
 	"gorm.io/gorm"
 )
 
-// GetNotes returns notes filtered by optional applianceId and spaceType.
-// Pass applianceId=0 and spaceType="" for no filter.
-func GetNotes(db *gorm.DB, applianceId uint, spaceType string) ([]models.Note, error) {
-	var notes []models.Note
-	query := db.Model(&models.Note{})
+var noteListSpec = listSpec{
+	sorts: map[string]string{
+		"id":        "id",
+		"title":     "title",
+		"createdAt": "created_at",
+		"updatedAt": "updated_at",
+	},
+	defaultSort:  "id",
+	defaultOrder: "asc",
+}
+
+// GetNotes returns notes filtered by optional propertyID, applianceId and spaceID.
+// Pass propertyID=0, applianceId=0 and spaceID=0 for no filter.
+func GetNotes(db *gorm.DB, propertyID uint, applianceId uint, spaceID uint) ([]models.Note, error) {
+	page, err := ListNotes(db, propertyID, applianceId, spaceID, ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return page.Items, nil
+}
+
+// ListNotes returns a page of notes, filtered like GetNotes.
+func ListNotes(db *gorm.DB, propertyID uint, applianceId uint, spaceID uint, opts ListOptions) (*Page[models.Note], error) {
+	query := db.Model(&models.Note{}).Scopes(propertyScope(propertyID))
 
 	if applianceId != 0 {
 		query = query.Where("appliance_id = ?", applianceId)
 	}
-	if spaceType != "" {
-		query = query.Where("space_type = ?", spaceType)
+	if spaceID != 0 {
+		query = query.Where("space_id = ?", spaceID)
 	}
 
-	result := query.Find(&notes)
-	if result.Error != nil {
-		return nil, result.Error
-	}
-	return notes, nil
+	return paginate[models.Note](query, noteListSpec, opts)
 }
 
-// AddNote creates and returns a note
-func AddNote(db *gorm.DB, title string, body string, applianceId uint, spaceType string) (models.Note, error) {
-	note := models.Note{Title: title, Body: body}
-	if applianceId != 0 {
-		note.ApplianceID = &applianceId
+// AddNote creates a note. Without a PropertyID it goes into the property of
+// its appliance, space or location, or the default property. The space is
+// given by SpaceID, or by name through SpaceType.
+func AddNote(db *gorm.DB, note *models.Note) (*models.Note, error) {
+	// note: nil out zero ApplianceID — Postgres enforces FK, 0 is not a valid appliance id
+	if note.ApplianceID != nil && *note.ApplianceID == 0 {
+		note.ApplianceID = nil
+	}
+	propertyID, err := resolvePropertyID(db, note.PropertyID, note.ApplianceID, note.SpaceID, note.LocationID)
+	if err != nil {
+		return nil, err
 	}
-	if spaceType != "" {
-		note.SpaceType = &spaceType
+	note.PropertyID = propertyID
+	if err := placeInSpace(db, propertyID, &note.SpaceID, &note.SpaceType); err != nil {
+		return nil, err
+	}
+	if err := resolveLocation(db, propertyID, &note.LocationID); err != nil {
+		return nil, err
 	}
 
-	result := db.Create(&note)
-	if result.Error != nil {
-		return models.Note{}, result.Error
+	if err := createAudited(db, TrashNote, note); err != nil {
+		return nil, err
 	}
 	return note, nil
 }
@@ -58,19 +82,16 @@ func UpdateNote(db *gorm.DB, id uint, title string, body string) (models.Note, e
 	if err := db.First(&note, id).Error; err != nil {
 		return models.Note{}, err
 	}
+	before := note
 	note.Title = title
 	note.Body = body
-	if err := db.Save(&note).Error; err != nil {
+	if err := saveAudited(db, TrashNote, &before, &note); err != nil {
 		return models.Note{}, err
 	}
 	return note, nil
 }
 
-// DeleteNote deletes a note
+// DeleteNote moves a note to the trash
 func DeleteNote(db *gorm.DB, id uint) error {
-	result := db.Where("id = ?", id).Delete(&models.Note{})
-	if result.Error != nil {
-		return result.Error
-	}
-	return nil
+	return moveToTrash(db, TrashNote, "id = ?", id)
 }
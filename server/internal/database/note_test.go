@@ -10,7 +10,7 @@ func TestAddGetUpdateDeleteNote(t *testing.T) {
     db := TestDB(t)
 
     // Add
-    n, err := AddNote(db, "title1", "body1", 0, "")
+    n, err := AddNote(db, &models.Note{Title: "title1", Body: "body1"})
     if err != nil {
         t.Fatalf("AddNote failed: %v", err)
     }
@@ -34,7 +34,7 @@ func TestAddGetUpdateDeleteNote(t *testing.T) {
     }
 
     // GetNotes (no filters)
-    notes, err := GetNotes(db, 0, "")
+    notes, err := GetNotes(db, 0, 0, 0)
     if err != nil {
         t.Fatalf("GetNotes failed: %v", err)
     }
@@ -59,12 +59,12 @@ func TestAddGetUpdateDeleteNote(t *testing.T) {
         t.Fatalf("AddAppliance failed: %v", err)
     }
 
-    n2, err := AddNote(db, "t2", "b2", a.ID, "Kitchen")
+    n2, err := AddNote(db, &models.Note{Title: "t2", Body: "b2", ApplianceID: &a.ID, SpaceType: strPtr("Kitchen")})
     if err != nil {
         t.Fatalf("AddNote with appliance failed: %v", err)
     }
     // Filtered list
-    ns, err := GetNotes(db, a.ID, "Kitchen")
+    ns, err := GetNotes(db, 0, a.ID, *n2.SpaceID)
     if err != nil {
         t.Fatalf("GetNotes filtered failed: %v", err)
     }
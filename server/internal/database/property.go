@@ -0,0 +1,214 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/masoncfrancis/homelogger/server/internal/models"
+	"gorm.io/gorm"
+)
+
+// DefaultPropertyName is used for the property created on first run, which
+// also receives every record that predates multi-property support.
+const DefaultPropertyName = "Home"
+
+// ErrLastProperty is returned when deleting the only remaining property.
+var ErrLastProperty = errors.New("at least one property is required")
+
+// ErrPropertyInUse is returned when deleting a property that still has records.
+var ErrPropertyInUse = errors.New("property still has records; move or delete them first")
+
+// propertyTables are the tables carrying a property_id column.
+var propertyTables = []string{
+	"appliances",
+	"maintenances",
+	"repairs",
+	"tasks",
+	"notes",
+	"saved_files",
+}
+
+// propertyScope limits a query to one property. propertyID=0 means all properties.
+func propertyScope(propertyID uint) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if propertyID == 0 {
+			return db
+		}
+		return db.Where("property_id = ?", propertyID)
+	}
+}
+
+// GetProperties returns every property ordered by ID.
+func GetProperties(db *gorm.DB) ([]models.Property, error) {
+	var properties []models.Property
+	if err := db.Order("id ASC").Find(&properties).Error; err != nil {
+		return nil, err
+	}
+	return properties, nil
+}
+
+// GetProperty returns a single property by ID.
+func GetProperty(db *gorm.DB, id uint) (*models.Property, error) {
+	var property models.Property
+	if err := db.Where("id = ?", id).First(&property).Error; err != nil {
+		return nil, err
+	}
+	return &property, nil
+}
+
+// AddProperty creates a new property.
+func AddProperty(db *gorm.DB, name, address string) (*models.Property, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, invalidField("name", "name is required")
+	}
+	property := &models.Property{Name: name, Address: address}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := createAudited(tx, AuditProperty, property); err != nil {
+			return err
+		}
+		return seedDefaultSpaces(tx, property.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return property, nil
+}
+
+// UpdateProperty changes a property's name and address.
+func UpdateProperty(db *gorm.DB, id uint, name, address string) (*models.Property, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, invalidField("name", "name is required")
+	}
+	property, err := GetProperty(db, id)
+	if err != nil {
+		return nil, err
+	}
+	before := *property
+	property.Name = name
+	property.Address = address
+	if err := saveAudited(db, AuditProperty, &before, property); err != nil {
+		return nil, err
+	}
+	return property, nil
+}
+
+// DeleteProperty deletes an empty property along with its spaces and
+// locations. The last property cannot be deleted.
+func DeleteProperty(db *gorm.DB, id uint) error {
+	if _, err := GetProperty(db, id); err != nil {
+		return err
+	}
+
+	var count int64
+	if err := db.Model(&models.Property{}).Count(&count).Error; err != nil {
+		return err
+	}
+	if count <= 1 {
+		return ErrLastProperty
+	}
+
+	for _, table := range propertyTables {
+		var inUse int64
+		if err := db.Table(table).Where("property_id = ? AND deleted_at IS NULL", id).Count(&inUse).Error; err != nil {
+			return err
+		}
+		if inUse > 0 {
+			return ErrPropertyInUse
+		}
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		var spaceIDs, locationIDs []uint
+		if err := tx.Model(&models.Space{}).Where("property_id = ?", id).Pluck("id", &spaceIDs).Error; err != nil {
+			return err
+		}
+		for _, spaceID := range spaceIDs {
+			if err := deleteAudited[models.Space](tx, AuditSpace, spaceID); err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(&models.Location{}).Where("property_id = ?", id).Pluck("id", &locationIDs).Error; err != nil {
+			return err
+		}
+		for _, locationID := range locationIDs {
+			if err := deleteAudited[models.Location](tx, AuditLocation, locationID); err != nil {
+				return err
+			}
+		}
+		return deleteAudited[models.Property](tx, AuditProperty, id)
+	})
+}
+
+// DefaultPropertyID returns the oldest property's ID, creating the default
+// property if none exists yet.
+func DefaultPropertyID(db *gorm.DB) (uint, error) {
+	var property models.Property
+	result := db.Order("id ASC").Limit(1).Find(&property)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	if result.RowsAffected > 0 {
+		return property.ID, nil
+	}
+
+	created, err := AddProperty(db, DefaultPropertyName, "")
+	if err != nil {
+		return 0, err
+	}
+	return created.ID, nil
+}
+
+// EnsureDefaultProperty makes sure a property exists and assigns every record,
+// space and location without one (property_id = 0) to the default property.
+func EnsureDefaultProperty(db *gorm.DB) error {
+	id, err := DefaultPropertyID(db)
+	if err != nil {
+		return fmt.Errorf("default property: %w", err)
+	}
+	for _, table := range append([]string{"spaces", "locations"}, propertyTables...) {
+		if err := db.Table(table).Where("property_id = ?", 0).Update("property_id", id).Error; err != nil {
+			return fmt.Errorf("assign %s to default property: %w", table, err)
+		}
+	}
+	return nil
+}
+
+// resolvePropertyID returns the property a new record should belong to. An
+// explicit propertyID must exist; otherwise the property of the record's
+// appliance, space or location is used, falling back to the default property.
+func resolvePropertyID(db *gorm.DB, propertyID uint, applianceID, spaceID, locationID *uint) (uint, error) {
+	if propertyID != 0 {
+		if _, err := GetProperty(db, propertyID); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return 0, invalidField("propertyId", "property %d does not exist", propertyID)
+			}
+			return 0, err
+		}
+		return propertyID, nil
+	}
+	parents := []struct {
+		table string
+		id    *uint
+	}{
+		{"appliances", applianceID},
+		{"spaces", spaceID},
+		{"locations", locationID},
+	}
+	for _, parent := range parents {
+		if parent.id == nil || *parent.id == 0 {
+			continue
+		}
+		var inherited uint
+		result := db.Table(parent.table).Select("property_id").Where("id = ? AND deleted_at IS NULL", *parent.id).Limit(1).Scan(&inherited)
+		if result.Error != nil {
+			return 0, result.Error
+		}
+		if result.RowsAffected > 0 && inherited != 0 {
+			return inherited, nil
+		}
+	}
+	return DefaultPropertyID(db)
+}
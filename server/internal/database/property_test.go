@@ -0,0 +1,159 @@
+package database
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/masoncfrancis/homelogger/server/internal/models"
+)
+
+func TestDefaultPropertyAndBackfill(t *testing.T) {
+	db := TestDB(t)
+
+	properties, err := GetProperties(db)
+	if err != nil {
+		t.Fatalf("GetProperties error: %v", err)
+	}
+	if len(properties) != 1 || properties[0].Name != DefaultPropertyName {
+		t.Fatalf("expected the default property after migrate, got %+v", properties)
+	}
+
+	// Rows written before properties existed have property_id = 0
+	legacy := &models.Appliance{ApplianceName: "Old Fridge"}
+	if err := db.Create(legacy).Error; err != nil {
+		t.Fatalf("create appliance: %v", err)
+	}
+	if err := EnsureDefaultProperty(db); err != nil {
+		t.Fatalf("EnsureDefaultProperty error: %v", err)
+	}
+	got, err := GetAppliance(db, legacy.ID)
+	if err != nil {
+		t.Fatalf("GetAppliance error: %v", err)
+	}
+	if got.PropertyID != properties[0].ID {
+		t.Fatalf("expected appliance in default property %d, got %d", properties[0].ID, got.PropertyID)
+	}
+}
+
+func TestPropertyScopedLists(t *testing.T) {
+	db := TestDB(t)
+
+	home, _ := DefaultPropertyID(db)
+	cabin, err := AddProperty(db, "Cabin", "1 Lake Rd")
+	if err != nil {
+		t.Fatalf("AddProperty error: %v", err)
+	}
+
+	if _, err := AddAppliance(db, &models.Appliance{ApplianceName: "Fridge"}); err != nil {
+		t.Fatalf("AddAppliance error: %v", err)
+	}
+	stove, err := AddAppliance(db, &models.Appliance{ApplianceName: "Wood stove", PropertyID: cabin.ID})
+	if err != nil {
+		t.Fatalf("AddAppliance error: %v", err)
+	}
+
+	// Records on an appliance inherit its property
+	m, err := AddMaintenance(db, &models.Maintenance{Description: "Sweep chimney", Date: "2026-01-01", ReferenceType: "Appliance", ApplianceID: uintPtr(stove.ID)})
+	if err != nil {
+		t.Fatalf("AddMaintenance error: %v", err)
+	}
+	if m.PropertyID != cabin.ID {
+		t.Fatalf("expected maintenance in cabin, got property %d", m.PropertyID)
+	}
+
+	if _, err := AddTask(db, &models.Task{Label: "Close up for winter", UserID: "1", PropertyID: cabin.ID}); err != nil {
+		t.Fatalf("AddTask error: %v", err)
+	}
+	if _, err := AddTask(db, &models.Task{Label: "Mow lawn", UserID: "1"}); err != nil {
+		t.Fatalf("AddTask error: %v", err)
+	}
+
+	apps, _ := GetAppliances(db, cabin.ID)
+	if len(apps) != 1 || apps[0].ApplianceName != "Wood stove" {
+		t.Fatalf("expected only the cabin's appliance, got %+v", apps)
+	}
+	if all, _ := GetAppliances(db, 0); len(all) != 2 {
+		t.Fatalf("expected 2 appliances across properties, got %d", len(all))
+	}
+
+	tasks, _ := GetAllTasks(db, home, false)
+	if len(tasks) != 1 || tasks[0].Label != "Mow lawn" {
+		t.Fatalf("expected only the home task, got %+v", tasks)
+	}
+	tasks, _ = GetTasks(db, cabin.ID, 0, 0, false)
+	if len(tasks) != 1 || tasks[0].Label != "Close up for winter" {
+		t.Fatalf("expected only the cabin task, got %+v", tasks)
+	}
+
+	if _, err := AddTask(db, &models.Task{Label: "Nowhere", UserID: "1", PropertyID: 999}); err == nil {
+		t.Fatal("expected error for unknown property")
+	}
+}
+
+func TestDeleteProperty(t *testing.T) {
+	db := TestDB(t)
+
+	home, _ := DefaultPropertyID(db)
+	if err := DeleteProperty(db, home); !errors.Is(err, ErrLastProperty) {
+		t.Fatalf("expected ErrLastProperty, got %v", err)
+	}
+
+	rental, _ := AddProperty(db, "Rental", "")
+	if _, err := AddNote(db, &models.Note{PropertyID: rental.ID, Title: "Tenant", Body: "Lease renews in May"}); err != nil {
+		t.Fatalf("AddNote error: %v", err)
+	}
+	if err := DeleteProperty(db, rental.ID); !errors.Is(err, ErrPropertyInUse) {
+		t.Fatalf("expected ErrPropertyInUse, got %v", err)
+	}
+
+	empty, _ := AddProperty(db, "Empty lot", "")
+	if err := DeleteProperty(db, empty.ID); err != nil {
+		t.Fatalf("DeleteProperty error: %v", err)
+	}
+	if _, err := GetProperty(db, empty.ID); err == nil {
+		t.Fatal("expected property to be gone")
+	}
+}
+
+func TestImportCarriesProperties(t *testing.T) {
+	db := TestDB(t)
+
+	cabin, _ := AddProperty(db, "Cabin", "")
+	if _, err := AddAppliance(db, &models.Appliance{ApplianceName: "Wood stove", PropertyID: cabin.ID}); err != nil {
+		t.Fatalf("AddAppliance error: %v", err)
+	}
+
+	payload, err := ExportToJSON(db, dialectSQLite)
+	if err != nil {
+		t.Fatalf("ExportToJSON error: %v", err)
+	}
+	if len(payload.Entities.Properties) != 2 {
+		t.Fatalf("expected 2 exported properties, got %d", len(payload.Entities.Properties))
+	}
+
+	if _, err := ImportFromJSON(db, payload, ""); err != nil {
+		t.Fatalf("ImportFromJSON error: %v", err)
+	}
+	properties, _ := GetProperties(db)
+	if len(properties) != 2 {
+		t.Fatalf("expected 2 properties after import, got %+v", properties)
+	}
+	apps, _ := GetAppliances(db, cabin.ID)
+	if len(apps) != 1 {
+		t.Fatalf("expected the stove to stay in the cabin, got %+v", apps)
+	}
+
+	// Backups from before multi-property support land in the default property
+	old := validMinimalPayload()
+	if _, err := ImportFromJSON(db, old, ""); err != nil {
+		t.Fatalf("ImportFromJSON error: %v", err)
+	}
+	properties, _ = GetProperties(db)
+	if len(properties) != 1 || properties[0].Name != DefaultPropertyName {
+		t.Fatalf("expected only the default property, got %+v", properties)
+	}
+	apps, _ = GetAppliances(db, properties[0].ID)
+	if len(apps) != 1 {
+		t.Fatalf("expected the imported appliance in the default property, got %+v", apps)
+	}
+}
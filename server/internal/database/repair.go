@@ -5,25 +5,43 @@ import (
 	"gorm.io/gorm"
 )
 
-// GetRepairs gets repair records filtered by applianceId, referenceType, and spaceType
-func GetRepairs(db *gorm.DB, applianceId uint, referenceType, spaceType string) ([]models.Repair, error) {
-	var repairs []models.Repair
-	if referenceType == "Space" {
-		result := db.Where("reference_type = ? AND space_type = ?", referenceType, spaceType).Find(&repairs)
-		if result.Error != nil {
-			return []models.Repair{}, result.Error
-		}
-
-		return repairs, nil
-	} else {
-		result := db.Where("appliance_id = ? AND reference_type = ?", applianceId, referenceType).Find(&repairs)
-		if result.Error != nil {
-			return []models.Repair{}, result.Error
-		}
+var repairListSpec = listSpec{
+	sorts: map[string]string{
+		"id":          "id",
+		"date":        "date",
+		"cost":        "cost",
+		"description": "description",
+		"createdAt":   "created_at",
+	},
+	defaultSort:  "id",
+	defaultOrder: "asc",
+	dateColumn:   "date",
+	costColumn:   "cost",
+}
 
-		return repairs, nil
+// GetRepairs gets repair records filtered by propertyID, applianceId, referenceType, and spaceID.
+// Pass propertyID=0 for every property and referenceType="" for records of
+// every appliance and space.
+func GetRepairs(db *gorm.DB, propertyID uint, applianceId uint, referenceType string, spaceID uint) ([]models.Repair, error) {
+	page, err := ListRepairs(db, propertyID, applianceId, referenceType, spaceID, ListOptions{})
+	if err != nil {
+		return []models.Repair{}, err
 	}
 
+	return page.Items, nil
+}
+
+// ListRepairs returns a page of repair records, filtered like GetRepairs.
+func ListRepairs(db *gorm.DB, propertyID uint, applianceId uint, referenceType string, spaceID uint, opts ListOptions) (*Page[models.Repair], error) {
+	query := db.Model(&models.Repair{}).Scopes(propertyScope(propertyID))
+	switch referenceType {
+	case "":
+	case "Space":
+		query = query.Where("reference_type = ? AND space_id = ?", referenceType, spaceID)
+	default:
+		query = query.Where("appliance_id = ? AND reference_type = ?", applianceId, referenceType)
+	}
+	return paginate[models.Repair](query, repairListSpec, opts)
 }
 
 // AddRepair creates a new repair record
@@ -32,9 +50,22 @@ func AddRepair(db *gorm.DB, repair *models.Repair) (*models.Repair, error) {
 	if repair.ApplianceID != nil && *repair.ApplianceID == 0 {
 		repair.ApplianceID = nil
 	}
-	result := db.Create(repair)
-	if result.Error != nil {
-		return nil, result.Error
+	propertyID, err := resolvePropertyID(db, repair.PropertyID, repair.ApplianceID, repair.SpaceID, nil)
+	if err != nil {
+		return nil, err
+	}
+	repair.PropertyID = propertyID
+	space, err := resolveSpace(db, propertyID, repair.SpaceID, repair.SpaceType)
+	if err != nil {
+		return nil, err
+	}
+	repair.SpaceID = nil
+	if space != nil {
+		repair.SpaceID = &space.ID
+		repair.SpaceType = space.Name
+	}
+	if err := createAudited(db, TrashRepair, repair); err != nil {
+		return nil, err
 	}
 
 	return repair, nil
@@ -57,28 +88,19 @@ func UpdateRepair(db *gorm.DB, id uint, description, date string, cost float64,
 	if err != nil {
 		return nil, err
 	}
+	before := *repair
 	repair.Description = description
 	repair.Date = date
 	repair.Cost = cost
 	repair.Notes = notes
-	result := db.Save(repair)
-	if result.Error != nil {
-		return nil, result.Error
+	if err := saveAudited(db, TrashRepair, &before, repair); err != nil {
+		return nil, err
 	}
 	return repair, nil
 }
 
-// DeleteRepair deletes a repair record by ID
+// DeleteRepair moves a repair record to the trash together with its files, in
+// one transaction
 func DeleteRepair(db *gorm.DB, id uint) error {
-	// Remove associated files (disk + DB)
-	if err := DeleteFilesByRepair(db, id); err != nil {
-		return err
-	}
-
-	result := db.Where("id = ?", id).Delete(&models.Repair{})
-	if result.Error != nil {
-		return result.Error
-	}
-
-	return nil
+	return moveToTrash(db, TrashRepair, "id = ?", id)
 }
@@ -45,7 +45,13 @@ func TestAddGetDeleteRepair(t *testing.T) {
     if err := DeleteRepair(db, added.ID); err != nil {
         t.Fatalf("DeleteRepair failed: %v", err)
     }
+    if _, err := os.Stat(path); err != nil {
+        t.Fatalf("expected repair file kept until purge, stat error: %v", err)
+    }
 
+    if err := PurgeFromTrash(db, TrashRepair, added.ID); err != nil {
+        t.Fatalf("PurgeFromTrash failed: %v", err)
+    }
     if _, err := os.Stat(path); !os.IsNotExist(err) {
         t.Fatalf("expected repair file removed, stat error: %v", err)
     }
@@ -59,7 +65,7 @@ func TestGetRepairsSpaceFilter(t *testing.T) {
         t.Fatalf("AddRepair failed: %v", err)
     }
 
-    res, err := GetRepairs(db, 0, "Space", "Basement")
+    res, err := GetRepairs(db, 0, 0, "Space", *r.SpaceID)
     if err != nil {
         t.Fatalf("GetRepairs failed: %v", err)
     }
@@ -0,0 +1,219 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/masoncfrancis/homelogger/server/internal/models"
+	"gorm.io/gorm"
+)
+
+// Notes, tasks and maintenance and repair records keep their earlier
+// versions. Every create, and every update that changes a field, saves the
+// record's fields as a new Revision in the same transaction as the change and
+// its audit event. A record created before revisions were kept gets its state
+// from before its first update saved as version 1.
+
+// RevisionTypes lists the entity types that keep revisions.
+var RevisionTypes = []string{TrashNote, TrashTask, TrashMaintenance, TrashRepair}
+
+// revisionFields keeps the audited, non-nested fields of a snapshot.
+func revisionFields(snapshot map[string]any) models.RevisionFields {
+	fields := models.RevisionFields{}
+	for name, value := range snapshot {
+		if auditSkipFields[name] {
+			continue
+		}
+		if _, nested := value.(map[string]any); nested {
+			continue
+		}
+		fields[name] = value
+	}
+	return fields
+}
+
+// saveRevision appends the record's state after a change as its next version.
+// before is nil for a create.
+func saveRevision(tx *gorm.DB, entityType string, id uint, before, after map[string]any) error {
+	var latest int
+	if err := tx.Session(&gorm.Session{NewDB: true}).Model(&models.Revision{}).
+		Where("entity_type = ? AND entity_id = ?", entityType, id).
+		Select("COALESCE(MAX(version), 0)").Scan(&latest).Error; err != nil {
+		return fmt.Errorf("revision %s: %w", entityType, err)
+	}
+	add := func(fields map[string]any, actor string) error {
+		latest++
+		revision := &models.Revision{
+			CreatedAt:  time.Now().UTC(),
+			EntityType: entityType,
+			EntityID:   id,
+			Version:    latest,
+			Actor:      actor,
+			Fields:     revisionFields(fields),
+		}
+		if err := tx.Session(&gorm.Session{NewDB: true}).Create(revision).Error; err != nil {
+			return fmt.Errorf("revision %s: %w", entityType, err)
+		}
+		return nil
+	}
+	// Who wrote the version from before revisions were kept is not known.
+	if latest == 0 && before != nil {
+		if err := add(before, ""); err != nil {
+			return err
+		}
+	}
+	return add(after, auditActor(tx))
+}
+
+// purgeRevisions deletes the revisions of records purged for good.
+func purgeRevisions(tx *gorm.DB, entityType string, ids []uint) error {
+	if !slices.Contains(RevisionTypes, entityType) {
+		return nil
+	}
+	return tx.Where("entity_type = ? AND entity_id IN ?", entityType, ids).Delete(&models.Revision{}).Error
+}
+
+var revisionListSpec = listSpec{
+	sorts: map[string]string{
+		"version":   "version",
+		"createdAt": "created_at",
+	},
+	defaultSort:  "version",
+	defaultOrder: "desc",
+}
+
+// revisionRecordExists reports gorm.ErrRecordNotFound for a record that was
+// never created or has been purged. Records in the trash keep their history.
+func revisionRecordExists(db *gorm.DB, entityType string, id uint) error {
+	var count int64
+	if err := db.Table(trashKinds[entityType].table).Where("id = ?", id).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// ListRevisions returns a page of a record's revisions, newest first by
+// default.
+func ListRevisions(db *gorm.DB, entityType string, id uint, opts ListOptions) (*Page[models.Revision], error) {
+	if err := revisionRecordExists(db, entityType, id); err != nil {
+		return nil, err
+	}
+	query := db.Model(&models.Revision{}).Where("entity_type = ? AND entity_id = ?", entityType, id)
+	return paginate[models.Revision](query, revisionListSpec, opts)
+}
+
+// GetRevision returns one version of a record.
+func GetRevision(db *gorm.DB, entityType string, id uint, version int) (*models.Revision, error) {
+	var revision models.Revision
+	if err := db.Where("entity_type = ? AND entity_id = ? AND version = ?", entityType, id, version).
+		First(&revision).Error; err != nil {
+		return nil, err
+	}
+	return &revision, nil
+}
+
+// RevisionDiff is how a record's fields changed from one version to another.
+type RevisionDiff struct {
+	From    int                 `json:"from"`
+	To      int                 `json:"to"`
+	Changes models.AuditChanges `json:"changes"`
+}
+
+// DiffRevisions compares two versions of a record. to=0 compares against the
+// latest version.
+func DiffRevisions(db *gorm.DB, entityType string, id uint, from, to int) (*RevisionDiff, error) {
+	if to == 0 {
+		if err := db.Model(&models.Revision{}).Where("entity_type = ? AND entity_id = ?", entityType, id).
+			Select("COALESCE(MAX(version), 0)").Scan(&to).Error; err != nil {
+			return nil, err
+		}
+	}
+	older, err := GetRevision(db, entityType, id, from)
+	if err != nil {
+		return nil, err
+	}
+	newer, err := GetRevision(db, entityType, id, to)
+	if err != nil {
+		return nil, err
+	}
+	return &RevisionDiff{From: from, To: to, Changes: auditDiff(older.Fields, newer.Fields)}, nil
+}
+
+// revisionReverts put a revision's fields back through the record type's own
+// update, so a revert is audited and saved as a revision like any other
+// change. Only the fields a user edits come back; a revert never moves a
+// record to another property, space or appliance.
+var revisionReverts = map[string]func(tx *gorm.DB, id uint, data []byte) (any, error){
+	TrashNote: func(tx *gorm.DB, id uint, data []byte) (any, error) {
+		var note models.Note
+		if err := json.Unmarshal(data, &note); err != nil {
+			return nil, err
+		}
+		return UpdateNote(tx, id, note.Title, note.Body)
+	},
+	TrashMaintenance: func(tx *gorm.DB, id uint, data []byte) (any, error) {
+		var record models.Maintenance
+		if err := json.Unmarshal(data, &record); err != nil {
+			return nil, err
+		}
+		return UpdateMaintenance(tx, id, record.Description, record.Date, record.Cost, record.Notes)
+	},
+	TrashRepair: func(tx *gorm.DB, id uint, data []byte) (any, error) {
+		var record models.Repair
+		if err := json.Unmarshal(data, &record); err != nil {
+			return nil, err
+		}
+		return UpdateRepair(tx, id, record.Description, record.Date, record.Cost, record.Notes)
+	},
+	TrashTask: func(tx *gorm.DB, id uint, data []byte) (any, error) {
+		current, err := GetTask(tx, id)
+		if err != nil {
+			return nil, err
+		}
+		// Take only what a user edits: completion state, due dates,
+		// assignee, steps and meter thresholds are the task's progress, not
+		// its content. A revision from before rules were kept has none, and
+		// gets one from its interval when saved.
+		var old models.Task
+		if err := json.Unmarshal(data, &old); err != nil {
+			return nil, err
+		}
+		task := *current
+		task.Label, task.Notes, task.Priority, task.EstimatedCost = old.Label, old.Notes, old.Priority, old.EstimatedCost
+		task.IsRecurring, task.RecurrenceInterval, task.RecurrenceUnit, task.RecurrenceMode, task.RRule =
+			old.IsRecurring, old.RecurrenceInterval, old.RecurrenceUnit, old.RecurrenceMode, old.RRule
+		return UpdateTask(tx, &task)
+	},
+}
+
+// RevertToRevision restores a record's editable fields to those of an
+// earlier version and returns the updated record. The revert is itself saved
+// as the newest version.
+func RevertToRevision(db *gorm.DB, entityType string, id uint, version int) (any, error) {
+	var record any
+	err := db.Transaction(func(tx *gorm.DB) error {
+		revision, err := GetRevision(tx, entityType, id, version)
+		if err != nil {
+			return err
+		}
+		data, err := json.Marshal(revision.Fields)
+		if err != nil {
+			return err
+		}
+		revert, ok := revisionReverts[entityType]
+		if !ok {
+			return fmt.Errorf("%s records do not keep revisions", entityType)
+		}
+		record, err = revert(tx, id, data)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return record, nil
+}
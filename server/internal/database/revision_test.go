@@ -0,0 +1,181 @@
+package database
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/masoncfrancis/homelogger/server/internal/models"
+	"gorm.io/gorm"
+)
+
+func TestRevisionsDiffAndRevert(t *testing.T) {
+	db := TestDB(t)
+	note, err := AddNote(db, &models.Note{Title: "Furnace", Body: "Filter is 16x25x1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := UpdateNote(db, note.ID, "Furnace", "Filter is 16x25x4"); err != nil {
+		t.Fatal(err)
+	}
+	// Saving the same values again is not a new version.
+	if _, err := UpdateNote(db, note.ID, "Furnace", "Filter is 16x25x4"); err != nil {
+		t.Fatal(err)
+	}
+
+	page, err := ListRevisions(db, TrashNote, note.ID, ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 2 || page.Items[0].Version != 2 || page.Items[0].Fields["body"] != "Filter is 16x25x4" {
+		t.Fatalf("expected two versions, newest first, got %+v", page.Items)
+	}
+
+	diff, err := DiffRevisions(db, TrashNote, note.ID, 1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff.To != 2 || len(diff.Changes) != 1 || diff.Changes["body"].Old != "Filter is 16x25x1" {
+		t.Fatalf("expected only the body to differ, got %+v", diff)
+	}
+
+	reverted, err := RevertToRevision(db, TrashNote, note.ID, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reverted.(models.Note).Body != "Filter is 16x25x1" {
+		t.Fatalf("expected the first body back, got %+v", reverted)
+	}
+	if latest, err := GetRevision(db, TrashNote, note.ID, 3); err != nil || latest.Fields["body"] != "Filter is 16x25x1" {
+		t.Fatalf("expected the revert saved as version 3, got %+v, %v", latest, err)
+	}
+	if _, err := RevertToRevision(db, TrashNote, note.ID, 9); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("expected a missing version to be not found, got %v", err)
+	}
+}
+
+func TestRevertTaskKeepsProgress(t *testing.T) {
+	db := TestDB(t)
+	alex, err := CreateUser(db, "alex", "Alex", "correct-horse", models.RoleMember)
+	if err != nil {
+		t.Fatal(err)
+	}
+	due := "2026-01-01"
+	task, err := AddTask(db, &models.Task{Label: "Change filter", DueDate: &due, IsRecurring: true, RecurrenceInterval: 1, RecurrenceUnit: "months", RecurrenceMode: "due_date"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	task.Label = "Change furnace filter"
+	if _, err := UpdateTask(db, task); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := AssignTask(db, task.ID, &alex.ID); err != nil {
+		t.Fatal(err)
+	}
+	done, err := CompleteTask(db, task.ID, "2026-01-02")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reverted, err := RevertToRevision(db, TrashTask, task.ID, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := reverted.(*models.Task)
+	if got.Label != "Change filter" {
+		t.Fatalf("expected the first label back, got %q", got.Label)
+	}
+	if got.DueDate == nil || *got.DueDate != *done.DueDate || got.LastCompletedAt == nil || *got.LastCompletedAt != "2026-01-02" {
+		t.Fatalf("expected the completion kept, got %+v", got)
+	}
+	if got.AssigneeID == nil || *got.AssigneeID != alex.ID {
+		t.Fatalf("expected the task still assigned to alex, got %+v", got.AssigneeID)
+	}
+}
+
+func TestRevisionsSurviveBackupAndRestore(t *testing.T) {
+	db := TestDB(t)
+	note, err := AddNote(db, &models.Note{Title: "Water heater", Body: "Anode checked 2024"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := UpdateNote(db, note.ID, "Water heater", "Anode replaced 2026"); err != nil {
+		t.Fatal(err)
+	}
+
+	exported, err := ExportToJSON(db, dialectSQLite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A revision of a record the backup does not hold is dropped.
+	exported.Entities.Revisions = append(exported.Entities.Revisions, models.Revision{EntityType: TrashNote, EntityID: 999, Version: 1})
+	data, err := json.Marshal(exported)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var payload models.BackupPayload
+	if err := json.Unmarshal(data, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ImportFromJSON(db, &payload, ""); err != nil {
+		t.Fatalf("ImportFromJSON error: %v", err)
+	}
+
+	page, err := ListRevisions(db, TrashNote, note.ID, ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 2 || page.Items[1].Fields["body"] != "Anode checked 2024" {
+		t.Fatalf("expected both versions back, got %+v", page.Items)
+	}
+	var orphans int64
+	db.Model(&models.Revision{}).Where("entity_id = ?", 999).Count(&orphans)
+	if orphans != 0 {
+		t.Fatalf("expected the orphaned revision dropped, got %d", orphans)
+	}
+	// History carries on from the restored versions.
+	if _, err := UpdateNote(db, note.ID, "Water heater", "Anode due 2029"); err != nil {
+		t.Fatal(err)
+	}
+	if latest, err := GetRevision(db, TrashNote, note.ID, 3); err != nil || latest.Fields["body"] != "Anode due 2029" {
+		t.Fatalf("expected the next edit saved as version 3, got %+v, %v", latest, err)
+	}
+}
+
+func TestRevisionsOfOlderRecordsAndPurge(t *testing.T) {
+	db := TestDB(t)
+	// A record from before revisions were kept has none until it changes.
+	record := &models.Repair{Description: "Leak", Date: "2026-02-01", Cost: 120}
+	if err := db.Create(record).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := UpdateRepair(db, record.ID, "Leak under sink", "2026-02-01", 120, ""); err != nil {
+		t.Fatal(err)
+	}
+	first, err := GetRevision(db, TrashRepair, record.ID, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Fields["description"] != "Leak" || first.Actor != "" {
+		t.Fatalf("expected the original state as version 1, got %+v", first)
+	}
+
+	if err := DeleteRepair(db, record.ID); err != nil {
+		t.Fatal(err)
+	}
+	if page, err := ListRevisions(db, TrashRepair, record.ID, ListOptions{}); err != nil || page.Total != 2 {
+		t.Fatalf("expected the trashed record to keep its history, got %+v, %v", page, err)
+	}
+	if _, err := PurgeTrash(db, time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	var left int64
+	db.Model(&models.Revision{}).Where("entity_type = ? AND entity_id = ?", TrashRepair, record.ID).Count(&left)
+	if left != 0 {
+		t.Fatalf("expected purging to remove the revisions, %d left", left)
+	}
+	if _, err := ListRevisions(db, TrashRepair, record.ID, ListOptions{}); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("expected a purged record to be not found, got %v", err)
+	}
+}
@@ -1,7 +1,7 @@
 package database
 
 import (
-	"os"
+	"fmt"
 
 	"github.com/masoncfrancis/homelogger/server/internal/models"
 	"gorm.io/gorm"
@@ -9,26 +9,84 @@ import (
 
 // FileInfoResponse represents the response structure for file information
 type FileInfoResponse struct {
-	ID           uint   `json:"id"`
-	OriginalName string `json:"originalName"`
-	UserID       string `json:"userID"`
+	ID            uint   `json:"id"`
+	OriginalName  string `json:"originalName"`
+	UserID        string `json:"userID"`
+	TextStatus    string `json:"textStatus,omitempty"`
+	ExtractedText string `json:"extractedText,omitempty"`
 }
 
-// GetFileInfo gets a file by ID and returns only id, originalName, and userID
+// GetFileInfo gets a file by ID and returns its id, originalName, userID and
+// the text extracted from it
 func GetFileInfo(db *gorm.DB, id uint) (*FileInfoResponse, error) {
 	var file models.SavedFile
-	result := db.Select("id", "original_name", "user_id").Where("id = ?", id).First(&file)
+	result := db.Select("id", "original_name", "user_id", "text_status", "extracted_text").Where("id = ?", id).First(&file)
 	if result.Error != nil {
 		return nil, result.Error
 	}
 
+	status := file.TextStatus
+	if status == "" {
+		status = models.TextStatusPending
+	}
 	return &FileInfoResponse{
-		ID:           file.ID,
-		OriginalName: file.OriginalName,
-		UserID:       file.UserID,
+		ID:            file.ID,
+		OriginalName:  file.OriginalName,
+		UserID:        file.UserID,
+		TextStatus:    status,
+		ExtractedText: file.ExtractedText,
 	}, nil
 }
 
+// FileFilter selects the files ListFiles returns. Pass 0 for no filter.
+type FileFilter struct {
+	MaintenanceID uint
+	RepairID      uint
+	ApplianceID   uint
+	SpaceID       uint
+}
+
+var fileListSpec = listSpec{
+	sorts: map[string]string{
+		"id":           "id",
+		"originalName": "original_name",
+		"createdAt":    "created_at",
+	},
+	defaultSort:  "id",
+	defaultOrder: "asc",
+}
+
+// ListFiles returns a page of file info for the files matching filter.
+func ListFiles(db *gorm.DB, filter FileFilter, opts ListOptions) (*Page[FileInfoResponse], error) {
+	query := db.Model(&models.SavedFile{}).Select("id", "original_name", "user_id", "created_at")
+	if filter.MaintenanceID != 0 {
+		query = query.Where("maintenance_id = ?", filter.MaintenanceID)
+	}
+	if filter.RepairID != 0 {
+		query = query.Where("repair_id = ?", filter.RepairID)
+	}
+	if filter.ApplianceID != 0 {
+		query = query.Where("appliance_id = ?", filter.ApplianceID)
+	}
+	if filter.SpaceID != 0 {
+		query = query.Where("space_id = ?", filter.SpaceID)
+	}
+
+	files, err := paginate[models.SavedFile](query, fileListSpec, opts)
+	if err != nil {
+		return nil, err
+	}
+	resp := &Page[FileInfoResponse]{
+		Items:      make([]FileInfoResponse, 0, len(files.Items)),
+		Total:      files.Total,
+		NextCursor: files.NextCursor,
+	}
+	for _, f := range files.Items {
+		resp.Items = append(resp.Items, FileInfoResponse{ID: f.ID, OriginalName: f.OriginalName, UserID: f.UserID})
+	}
+	return resp, nil
+}
+
 // GetFilePath retrieves the file path of a file by its ID
 func GetFilePath(db *gorm.DB, id uint) (string, error) {
 	var file models.SavedFile
@@ -43,9 +101,21 @@ func GetFilePath(db *gorm.DB, id uint) (string, error) {
 func UploadFile(db *gorm.DB, file *models.SavedFile) (*models.SavedFile, error) {
 	// Ensure the ID is not manually set
 	file.ID = 0
-	result := db.Create(file)
-	if result.Error != nil {
-		return nil, result.Error
+	propertyID, err := resolvePropertyID(db, file.PropertyID, file.ApplianceID, file.SpaceID, file.LocationID)
+	if err != nil {
+		return nil, err
+	}
+	file.PropertyID = propertyID
+	if err := placeInSpace(db, propertyID, &file.SpaceID, &file.SpaceType); err != nil {
+		return nil, err
+	}
+	if err := resolveLocation(db, propertyID, &file.LocationID); err != nil {
+		return nil, err
+	}
+	file.TextStatus = models.TextStatusPending
+	file.ExtractedText = ""
+	if err := createAudited(db, TrashFile, file); err != nil {
+		return nil, err
 	}
 
 	return file, nil
@@ -53,199 +123,160 @@ func UploadFile(db *gorm.DB, file *models.SavedFile) (*models.SavedFile, error)
 
 // UpdateFilePath updates the file path of an existing file
 func UpdateFilePath(db *gorm.DB, file *models.SavedFile) (*models.SavedFile, error) {
-	result := db.Model(&models.SavedFile{}).Where("id = ?", file.ID).Update("path", file.Path)
-	if result.Error != nil {
-		return nil, result.Error
+	err := updateAudited[models.SavedFile](db, TrashFile, file.ID, func(tx *gorm.DB) error {
+		return tx.Model(&models.SavedFile{}).Where("id = ?", file.ID).Update("path", file.Path).Error
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return file, nil
 }
 
-// AttachFileToMaintenance sets the maintenance_id for a saved file
-func AttachFileToMaintenance(db *gorm.DB, fileID uint, maintenanceID uint) error {
-	result := db.Model(&models.SavedFile{}).Where("id = ?", fileID).Update("maintenance_id", maintenanceID)
+// GetFilesPendingText returns the stored files whose text has not been
+// extracted yet, oldest first.
+func GetFilesPendingText(db *gorm.DB) ([]models.SavedFile, error) {
+	var files []models.SavedFile
+	result := db.Select("id", "path", "original_name").
+		Where("text_status IN ? AND path <> ''", []string{"", models.TextStatusPending}).
+		Order("id ASC").
+		Find(&files)
 	if result.Error != nil {
-		return result.Error
+		return nil, result.Error
 	}
-	return nil
+	return files, nil
 }
 
-// AttachFileToRepair sets the repair_id for a saved file
-func AttachFileToRepair(db *gorm.DB, fileID uint, repairID uint) error {
-	result := db.Model(&models.SavedFile{}).Where("id = ?", fileID).Update("repair_id", repairID)
+// SetExtractedText records the outcome of text extraction for a file. The
+// search index picks the text up through its triggers.
+func SetExtractedText(db *gorm.DB, id uint, status string, text string) error {
+	result := db.Model(&models.SavedFile{}).Where("id = ?", id).Updates(map[string]any{
+		"text_status":    status,
+		"extracted_text": text,
+	})
 	if result.Error != nil {
 		return result.Error
 	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
 	return nil
 }
 
-// DeleteFile deletes the file record by ID
+// AttachFileToMaintenance sets the maintenance_id for a saved file
+func AttachFileToMaintenance(db *gorm.DB, fileID uint, maintenanceID uint) error {
+	return updateAudited[models.SavedFile](db, TrashFile, fileID, func(tx *gorm.DB) error {
+		return tx.Model(&models.SavedFile{}).Where("id = ?", fileID).Update("maintenance_id", maintenanceID).Error
+	})
+}
+
+// AttachFileToRepair sets the repair_id for a saved file
+func AttachFileToRepair(db *gorm.DB, fileID uint, repairID uint) error {
+	return updateAudited[models.SavedFile](db, TrashFile, fileID, func(tx *gorm.DB) error {
+		return tx.Model(&models.SavedFile{}).Where("id = ?", fileID).Update("repair_id", repairID).Error
+	})
+}
+
+// DeleteFile moves a file to the trash. Its content stays on disk until the
+// file is purged.
 func DeleteFile(db *gorm.DB, id uint) error {
-	result := db.Unscoped().Where("id = ?", id).Delete(&models.SavedFile{})
-	if result.Error != nil {
-		return result.Error
-	}
-	return nil
+	return moveToTrash(db, TrashFile, "id = ?", id)
 }
 
 // GetFilesByMaintenance returns file info for files attached to a maintenance record
 func GetFilesByMaintenance(db *gorm.DB, maintenanceID uint) ([]FileInfoResponse, error) {
-	var files []models.SavedFile
-	result := db.Select("id", "original_name", "user_id").Where("maintenance_id = ?", maintenanceID).Find(&files)
-	if result.Error != nil {
-		return nil, result.Error
-	}
-
-	resp := make([]FileInfoResponse, 0, len(files))
-	for _, f := range files {
-		resp = append(resp, FileInfoResponse{ID: f.ID, OriginalName: f.OriginalName, UserID: f.UserID})
+	page, err := ListFiles(db, FileFilter{MaintenanceID: maintenanceID}, ListOptions{})
+	if err != nil {
+		return nil, err
 	}
-	return resp, nil
+	return page.Items, nil
 }
 
 // GetFilesByRepair returns file info for files attached to a repair record
 func GetFilesByRepair(db *gorm.DB, repairID uint) ([]FileInfoResponse, error) {
-	var files []models.SavedFile
-	result := db.Select("id", "original_name", "user_id").Where("repair_id = ?", repairID).Find(&files)
-	if result.Error != nil {
-		return nil, result.Error
+	page, err := ListFiles(db, FileFilter{RepairID: repairID}, ListOptions{})
+	if err != nil {
+		return nil, err
 	}
-
-	resp := make([]FileInfoResponse, 0, len(files))
-	for _, f := range files {
-		resp = append(resp, FileInfoResponse{ID: f.ID, OriginalName: f.OriginalName, UserID: f.UserID})
-	}
-	return resp, nil
+	return page.Items, nil
 }
 
-// DeleteFilesByMaintenance removes files on disk and deletes their DB rows for a maintenance record
+// DeleteFilesByMaintenance moves the files attached to a maintenance record to the trash
 func DeleteFilesByMaintenance(db *gorm.DB, maintenanceID uint) error {
-	var files []models.SavedFile
-	result := db.Select("id", "path").Where("maintenance_id = ?", maintenanceID).Find(&files)
-	if result.Error != nil {
-		return result.Error
-	}
-
-	for _, f := range files {
-		if f.Path != "" {
-			_ = os.Remove(f.Path)
-		}
-	}
-
-	// Delete DB rows (hard delete)
-	if err := db.Unscoped().Where("maintenance_id = ?", maintenanceID).Delete(&models.SavedFile{}).Error; err != nil {
-		return err
-	}
-	return nil
+	return moveToTrash(db, TrashFile, "maintenance_id = ?", maintenanceID)
 }
 
-// DeleteFilesByRepair removes files on disk and deletes their DB rows for a repair record
+// DeleteFilesByRepair moves the files attached to a repair record to the trash
 func DeleteFilesByRepair(db *gorm.DB, repairID uint) error {
-	var files []models.SavedFile
-	result := db.Select("id", "path").Where("repair_id = ?", repairID).Find(&files)
-	if result.Error != nil {
-		return result.Error
-	}
-
-	for _, f := range files {
-		if f.Path != "" {
-			_ = os.Remove(f.Path)
-		}
-	}
-
-	// Delete DB rows (hard delete)
-	if err := db.Unscoped().Where("repair_id = ?", repairID).Delete(&models.SavedFile{}).Error; err != nil {
-		return err
-	}
-	return nil
+	return moveToTrash(db, TrashFile, "repair_id = ?", repairID)
 }
 
-// DeleteFilesByAppliance removes files on disk and deletes their DB rows for an appliance
+// DeleteFilesByAppliance moves the files attached to an appliance to the trash
 func DeleteFilesByAppliance(db *gorm.DB, applianceID uint) error {
-	var files []models.SavedFile
-	result := db.Select("id", "path").Where("appliance_id = ?", applianceID).Find(&files)
-	if result.Error != nil {
-		return result.Error
-	}
-
-	for _, f := range files {
-		if f.Path != "" {
-			_ = os.Remove(f.Path)
-		}
-	}
-
-	// Delete DB rows (hard delete)
-	if err := db.Unscoped().Where("appliance_id = ?", applianceID).Delete(&models.SavedFile{}).Error; err != nil {
-		return err
-	}
-	return nil
+	return moveToTrash(db, TrashFile, "appliance_id = ?", applianceID)
 }
 
 // GetFilesByAppliance returns file info for files attached to an appliance
 func GetFilesByAppliance(db *gorm.DB, applianceID uint) ([]FileInfoResponse, error) {
-	var files []models.SavedFile
-	result := db.Select("id", "original_name", "user_id").Where("appliance_id = ?", applianceID).Find(&files)
-	if result.Error != nil {
-		return nil, result.Error
-	}
-
-	resp := make([]FileInfoResponse, 0, len(files))
-	for _, f := range files {
-		resp = append(resp, FileInfoResponse{ID: f.ID, OriginalName: f.OriginalName, UserID: f.UserID})
+	page, err := ListFiles(db, FileFilter{ApplianceID: applianceID}, ListOptions{})
+	if err != nil {
+		return nil, err
 	}
-	return resp, nil
+	return page.Items, nil
 }
 
 // AttachFileToAppliance sets the appliance_id for a saved file
 func AttachFileToAppliance(db *gorm.DB, fileID uint, applianceID uint) error {
-	result := db.Model(&models.SavedFile{}).Where("id = ?", fileID).Update("appliance_id", applianceID)
-	if result.Error != nil {
-		return result.Error
-	}
-	return nil
+	return updateAudited[models.SavedFile](db, TrashFile, fileID, func(tx *gorm.DB) error {
+		return tx.Model(&models.SavedFile{}).Where("id = ?", fileID).Update("appliance_id", applianceID).Error
+	})
 }
 
-// AttachFileToSpace sets the space_type for a saved file
-func AttachFileToSpace(db *gorm.DB, fileID uint, spaceType string) error {
-	result := db.Model(&models.SavedFile{}).Where("id = ?", fileID).Update("space_type", spaceType)
-	if result.Error != nil {
-		return result.Error
+// AttachFileToSpace links a saved file to a space, given by spaceID or by
+// name through spaceType. The space must belong to the file's property.
+func AttachFileToSpace(db *gorm.DB, fileID uint, spaceID uint, spaceType string) error {
+	var file models.SavedFile
+	if err := db.Select("id", "property_id").Where("id = ?", fileID).First(&file).Error; err != nil {
+		return err
 	}
-	return nil
-}
-
-// GetFilesBySpace returns file info for files attached to a space type
-func GetFilesBySpace(db *gorm.DB, spaceType string) ([]FileInfoResponse, error) {
-	var files []models.SavedFile
-	result := db.Select("id", "original_name", "user_id").Where("space_type = ?", spaceType).Find(&files)
-	if result.Error != nil {
-		return nil, result.Error
+	space, err := resolveSpace(db, file.PropertyID, &spaceID, spaceType)
+	if err != nil {
+		return err
 	}
-
-	resp := make([]FileInfoResponse, 0, len(files))
-	for _, f := range files {
-		resp = append(resp, FileInfoResponse{ID: f.ID, OriginalName: f.OriginalName, UserID: f.UserID})
+	if space == nil {
+		return fmt.Errorf("space is required")
 	}
-	return resp, nil
+	return updateAudited[models.SavedFile](db, TrashFile, fileID, func(tx *gorm.DB) error {
+		return tx.Model(&models.SavedFile{}).Where("id = ?", fileID).
+			Updates(map[string]any{"space_id": space.ID, "space_type": space.Name}).Error
+	})
 }
 
-// DeleteFilesBySpace removes files on disk and deletes their DB rows for a space type
-func DeleteFilesBySpace(db *gorm.DB, spaceType string) error {
-	var files []models.SavedFile
-	result := db.Select("id", "path").Where("space_type = ?", spaceType).Find(&files)
-	if result.Error != nil {
-		return result.Error
+// AttachFileToLocation places a saved file in a location of the file's property.
+func AttachFileToLocation(db *gorm.DB, fileID uint, locationID uint) error {
+	var file models.SavedFile
+	if err := db.Select("id", "property_id").Where("id = ?", fileID).First(&file).Error; err != nil {
+		return err
 	}
-
-	for _, f := range files {
-		if f.Path != "" {
-			_ = os.Remove(f.Path)
-		}
+	location := &locationID
+	if err := resolveLocation(db, file.PropertyID, &location); err != nil {
+		return err
 	}
+	return updateAudited[models.SavedFile](db, TrashFile, fileID, func(tx *gorm.DB) error {
+		return tx.Model(&models.SavedFile{}).Where("id = ?", fileID).Update("location_id", locationID).Error
+	})
+}
 
-	// Delete DB rows (hard delete)
-	if err := db.Unscoped().Where("space_type = ?", spaceType).Delete(&models.SavedFile{}).Error; err != nil {
-		return err
+// GetFilesBySpace returns file info for files attached to a space.
+func GetFilesBySpace(db *gorm.DB, spaceID uint) ([]FileInfoResponse, error) {
+	page, err := ListFiles(db, FileFilter{SpaceID: spaceID}, ListOptions{})
+	if err != nil {
+		return nil, err
 	}
-	return nil
+	return page.Items, nil
+}
+
+// DeleteFilesBySpace moves the files attached to a space to the trash
+func DeleteFilesBySpace(db *gorm.DB, spaceID uint) error {
+	return moveToTrash(db, TrashFile, "space_id = ?", spaceID)
 }
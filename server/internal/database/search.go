@@ -0,0 +1,238 @@
+package database
+
+import (
+	"fmt"
+	"html"
+	"strings"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+// searchTable holds one row per searchable record. On SQLite it is an FTS5
+// virtual table; on Postgres a plain table with a weighted tsvector column.
+// Triggers on the source tables keep it in sync.
+const searchTable = "search_index"
+
+// maxSearchTerms caps how many words of a query are used.
+const maxSearchTerms = 16
+
+// searchSource describes how one table feeds the search index. The title
+// column is weighted above the body columns when ranking. Triggers are
+// recreated on every migration, so changing a source takes effect on restart.
+type searchSource struct {
+	kind  string
+	table string
+	title string
+	body  []string
+}
+
+var searchSources = []searchSource{
+	{kind: "appliance", table: "appliances", title: "appliance_name", body: []string{"manufacturer", "model_number", "serial_number"}},
+	{kind: "maintenance", table: "maintenances", title: "description", body: []string{"notes"}},
+	{kind: "repair", table: "repairs", title: "description", body: []string{"notes"}},
+	{kind: "task", table: "tasks", title: "label", body: []string{"notes"}},
+	{kind: "note", table: "notes", title: "title", body: []string{"body"}},
+	{kind: "file", table: "saved_files", title: "original_name", body: []string{"extracted_text"}},
+}
+
+// snippetStart and snippetEnd surround matched words in the snippets the
+// database builds. They are control characters so that they survive HTML
+// escaping and cannot be confused with markup in the indexed text.
+const (
+	snippetStart = "\x02"
+	snippetEnd   = "\x03"
+)
+
+// snippetMarks turns the database's match markers into <mark> tags.
+var snippetMarks = strings.NewReplacer(snippetStart, "<mark>", snippetEnd, "</mark>")
+
+// SearchResult is one ranked match. Snippet is HTML: the text is escaped and
+// matched words are wrapped in <mark> tags.
+type SearchResult struct {
+	Type       string  `json:"type" gorm:"column:kind"`
+	ID         uint    `json:"id" gorm:"column:record_id"`
+	PropertyID uint    `json:"propertyId" gorm:"column:property_id"`
+	Title      string  `json:"title" gorm:"column:title"`
+	Snippet    string  `json:"snippet" gorm:"column:snippet"`
+	Rank       float64 `json:"rank" gorm:"column:rank"`
+}
+
+// titleExpr and bodyExpr return the SQL for a source's indexed text, reading
+// columns through prefix (e.g. "NEW.") inside triggers.
+func (s searchSource) titleExpr(prefix string) string {
+	return fmt.Sprintf("COALESCE(%s%s, '')", prefix, s.title)
+}
+
+func (s searchSource) bodyExpr(prefix string) string {
+	if len(s.body) == 0 {
+		return "''"
+	}
+	parts := make([]string, 0, len(s.body))
+	for _, col := range s.body {
+		parts = append(parts, fmt.Sprintf("COALESCE(%s%s, '')", prefix, col))
+	}
+	return strings.Join(parts, " || ' ' || ")
+}
+
+// EnsureSearchIndex creates the search table and its sync triggers, then
+// rebuilds the index from the live (not soft-deleted) records.
+func EnsureSearchIndex(db *gorm.DB) error {
+	var stmts []string
+	switch db.Dialector.Name() {
+	case dialectSQLite:
+		stmts = sqliteSearchDDL()
+	case dialectPostgres:
+		stmts = postgresSearchDDL()
+	default:
+		return fmt.Errorf("unsupported database dialect: %s", db.Dialector.Name())
+	}
+	for _, stmt := range stmts {
+		if err := db.Exec(stmt).Error; err != nil {
+			return fmt.Errorf("search index: %w", err)
+		}
+	}
+	return RebuildSearchIndex(db)
+}
+
+// RebuildSearchIndex repopulates the search index from scratch.
+func RebuildSearchIndex(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM " + searchTable).Error; err != nil {
+			return err
+		}
+		for _, s := range searchSources {
+			sql := fmt.Sprintf(
+				"INSERT INTO %s (kind, record_id, property_id, title, body) SELECT '%s', id, property_id, %s, %s FROM %s WHERE deleted_at IS NULL",
+				searchTable, s.kind, s.titleExpr(""), s.bodyExpr(""), s.table,
+			)
+			if err := tx.Exec(sql).Error; err != nil {
+				return fmt.Errorf("index %s: %w", s.table, err)
+			}
+		}
+		return nil
+	})
+}
+
+func sqliteSearchDDL() []string {
+	stmts := []string{
+		"CREATE VIRTUAL TABLE IF NOT EXISTS " + searchTable + " USING fts5(kind UNINDEXED, record_id UNINDEXED, property_id UNINDEXED, title, body, tokenize = 'porter unicode61 remove_diacritics 2')",
+	}
+	for _, s := range searchSources {
+		insert := fmt.Sprintf(
+			"INSERT INTO %s (kind, record_id, property_id, title, body) SELECT '%s', NEW.id, NEW.property_id, %s, %s WHERE NEW.deleted_at IS NULL;",
+			searchTable, s.kind, s.titleExpr("NEW."), s.bodyExpr("NEW."),
+		)
+		remove := fmt.Sprintf("DELETE FROM %s WHERE kind = '%s' AND record_id = OLD.id;", searchTable, s.kind)
+		stmts = append(stmts,
+			fmt.Sprintf("DROP TRIGGER IF EXISTS search_%s_insert", s.table),
+			fmt.Sprintf("DROP TRIGGER IF EXISTS search_%s_update", s.table),
+			fmt.Sprintf("DROP TRIGGER IF EXISTS search_%s_delete", s.table),
+			fmt.Sprintf("CREATE TRIGGER search_%s_insert AFTER INSERT ON %s BEGIN %s END", s.table, s.table, insert),
+			fmt.Sprintf("CREATE TRIGGER search_%s_update AFTER UPDATE ON %s BEGIN %s %s END", s.table, s.table, remove, insert),
+			fmt.Sprintf("CREATE TRIGGER search_%s_delete AFTER DELETE ON %s BEGIN %s END", s.table, s.table, remove),
+		)
+	}
+	return stmts
+}
+
+func postgresSearchDDL() []string {
+	stmts := []string{
+		`CREATE TABLE IF NOT EXISTS ` + searchTable + ` (
+			kind        TEXT NOT NULL,
+			record_id   BIGINT NOT NULL,
+			property_id BIGINT NOT NULL DEFAULT 0,
+			title       TEXT NOT NULL DEFAULT '',
+			body        TEXT NOT NULL DEFAULT '',
+			document    tsvector GENERATED ALWAYS AS (
+				setweight(to_tsvector('english', title), 'A') || setweight(to_tsvector('english', body), 'B')
+			) STORED,
+			PRIMARY KEY (kind, record_id)
+		)`,
+		"CREATE INDEX IF NOT EXISTS idx_search_index_document ON " + searchTable + " USING GIN (document)",
+	}
+	for _, s := range searchSources {
+		stmts = append(stmts,
+			fmt.Sprintf(`CREATE OR REPLACE FUNCTION search_sync_%[1]s() RETURNS trigger AS $$
+BEGIN
+	IF TG_OP <> 'INSERT' THEN
+		DELETE FROM %[2]s WHERE kind = '%[3]s' AND record_id = OLD.id;
+	END IF;
+	IF TG_OP <> 'DELETE' THEN
+		IF NEW.deleted_at IS NULL THEN
+			INSERT INTO %[2]s (kind, record_id, property_id, title, body) VALUES ('%[3]s', NEW.id, NEW.property_id, %[4]s, %[5]s);
+		END IF;
+	END IF;
+	RETURN NULL;
+END
+$$ LANGUAGE plpgsql`, s.table, searchTable, s.kind, s.titleExpr("NEW."), s.bodyExpr("NEW.")),
+			fmt.Sprintf("DROP TRIGGER IF EXISTS search_sync ON %s", s.table),
+			fmt.Sprintf("CREATE TRIGGER search_sync AFTER INSERT OR UPDATE OR DELETE ON %[1]s FOR EACH ROW EXECUTE FUNCTION search_sync_%[1]s()", s.table),
+		)
+	}
+	return stmts
+}
+
+// searchTerms splits a free-text query into words, dropping punctuation so
+// user input can never be read as query syntax.
+func searchTerms(q string) []string {
+	terms := strings.FieldsFunc(strings.ToLower(q), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(terms) > maxSearchTerms {
+		terms = terms[:maxSearchTerms]
+	}
+	return terms
+}
+
+// Search returns the records matching every word of q, best match first.
+// Words match as prefixes, so "furn" finds "furnace". Pass propertyID=0 to
+// search every property.
+func Search(db *gorm.DB, propertyID uint, q string, limit int) ([]SearchResult, error) {
+	terms := searchTerms(q)
+	results := []SearchResult{}
+	if len(terms) == 0 {
+		return results, nil
+	}
+
+	var sql string
+	var args []any
+	switch db.Dialector.Name() {
+	case dialectSQLite:
+		quoted := make([]string, len(terms))
+		for i, t := range terms {
+			quoted[i] = `"` + t + `"*`
+		}
+		sql = `SELECT kind, record_id, property_id, title,
+				snippet(` + searchTable + `, -1, char(2), char(3), '…', 16) AS snippet,
+				-bm25(` + searchTable + `, 0, 0, 0, 10.0, 1.0) AS rank
+			FROM ` + searchTable + ` WHERE ` + searchTable + ` MATCH ?`
+		args = append(args, strings.Join(quoted, " "))
+	case dialectPostgres:
+		prefixed := make([]string, len(terms))
+		for i, t := range terms {
+			prefixed[i] = t + ":*"
+		}
+		sql = `SELECT kind, record_id, property_id, title,
+				ts_headline('english', title || ' ' || body, query, 'StartSel=' || chr(2) || ', StopSel=' || chr(3) || ', MaxWords=24, MinWords=8') AS snippet,
+				ts_rank(document, query) AS rank
+			FROM ` + searchTable + `, to_tsquery('english', ?) AS query WHERE document @@ query`
+		args = append(args, strings.Join(prefixed, " & "))
+	default:
+		return nil, fmt.Errorf("unsupported database dialect: %s", db.Dialector.Name())
+	}
+	if propertyID != 0 {
+		sql += " AND property_id = ?"
+		args = append(args, propertyID)
+	}
+	sql += " ORDER BY rank DESC, kind, record_id LIMIT ?"
+	args = append(args, limit)
+
+	if err := db.Raw(sql, args...).Scan(&results).Error; err != nil {
+		return nil, err
+	}
+	for i := range results {
+		results[i].Snippet = snippetMarks.Replace(html.EscapeString(results[i].Snippet))
+	}
+	return results, nil
+}
@@ -0,0 +1,160 @@
+package database
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/masoncfrancis/homelogger/server/internal/models"
+)
+
+func TestSearchCoversRecordTypes(t *testing.T) {
+	db := TestDB(t)
+
+	furnace := &models.Appliance{ApplianceName: "Furnace", Manufacturer: "Lennox", ModelNumber: "EL296V", SerialNumber: "5819K"}
+	if _, err := AddAppliance(db, furnace); err != nil {
+		t.Fatalf("AddAppliance error: %v", err)
+	}
+	if _, err := AddMaintenance(db, &models.Maintenance{Description: "Annual tune-up", Date: "2026-01-10", Notes: "Replaced the flame sensor on the furnace", ApplianceID: &furnace.ID, ReferenceType: "Appliance"}); err != nil {
+		t.Fatalf("AddMaintenance error: %v", err)
+	}
+	if _, err := AddRepair(db, &models.Repair{Description: "Igniter replaced", Date: "2026-02-03", Notes: "Cracked hot surface igniter", ApplianceID: &furnace.ID, ReferenceType: "Appliance"}); err != nil {
+		t.Fatalf("AddRepair error: %v", err)
+	}
+	if _, err := AddTask(db, &models.Task{Label: "Order filters", Notes: "16x25x1 for the furnace", UserID: "1"}); err != nil {
+		t.Fatalf("AddTask error: %v", err)
+	}
+	if _, err := AddNote(db, &models.Note{Title: "Thermostat", Body: "Schedule set to 68 during the day"}); err != nil {
+		t.Fatalf("AddNote error: %v", err)
+	}
+	if _, err := UploadFile(db, &models.SavedFile{OriginalName: "lennox-manual.pdf", Path: "./data/uploads/x", UserID: "1"}); err != nil {
+		t.Fatalf("UploadFile error: %v", err)
+	}
+
+	results, err := Search(db, 0, "furnace", 20)
+	if err != nil {
+		t.Fatalf("Search error: %v", err)
+	}
+	kinds := map[string]bool{}
+	for _, r := range results {
+		kinds[r.Type] = true
+	}
+	for _, want := range []string{"appliance", "maintenance", "task"} {
+		if !kinds[want] {
+			t.Fatalf("expected a %s match for furnace, got %+v", want, results)
+		}
+	}
+	// The title match ranks above the body matches
+	if results[0].Type != "appliance" || results[0].ID != furnace.ID {
+		t.Fatalf("expected the furnace appliance first, got %+v", results[0])
+	}
+
+	// Serial numbers, prefixes and file names are searchable
+	if r, _ := Search(db, 0, "5819k", 20); len(r) != 1 || r[0].Type != "appliance" {
+		t.Fatalf("expected the serial number to match, got %+v", r)
+	}
+	if r, _ := Search(db, 0, "igni", 20); len(r) != 1 || r[0].Type != "repair" {
+		t.Fatalf("expected a prefix match on the repair, got %+v", r)
+	}
+	if r, _ := Search(db, 0, "lennox manual", 20); len(r) != 1 || r[0].Type != "file" {
+		t.Fatalf("expected every word to match the file, got %+v", r)
+	}
+
+	r, _ := Search(db, 0, "thermostat schedule", 20)
+	if len(r) != 1 || !strings.Contains(r[0].Snippet, "<mark>") {
+		t.Fatalf("expected a highlighted snippet, got %+v", r)
+	}
+}
+
+func TestSearchEscapesSnippets(t *testing.T) {
+	db := TestDB(t)
+
+	if _, err := AddNote(db, &models.Note{Title: "Sump pump", Body: `<img src=x onerror="alert(1)"> check the <mark>float</mark> switch`}); err != nil {
+		t.Fatalf("AddNote error: %v", err)
+	}
+	r, err := Search(db, 0, "float", 20)
+	if err != nil || len(r) != 1 {
+		t.Fatalf("expected one match, got %+v, %v", r, err)
+	}
+	snippet := r[0].Snippet
+	if strings.Contains(snippet, "<img") || !strings.Contains(snippet, "&lt;img") {
+		t.Fatalf("expected the note's markup escaped, got %q", snippet)
+	}
+	if strings.Count(snippet, "<mark>") != 1 || !strings.Contains(snippet, "&lt;mark&gt;<mark>float</mark>&lt;/mark&gt;") {
+		t.Fatalf("expected only the match marked, got %q", snippet)
+	}
+}
+
+func TestSearchFollowsWrites(t *testing.T) {
+	db := TestDB(t)
+
+	note, err := AddNote(db, &models.Note{Title: "Water shutoff", Body: "Valve behind the dryer"})
+	if err != nil {
+		t.Fatalf("AddNote error: %v", err)
+	}
+	if r, _ := Search(db, 0, "dryer", 20); len(r) != 1 {
+		t.Fatalf("expected the new note to be indexed, got %+v", r)
+	}
+
+	if _, err := UpdateNote(db, note.ID, "Water shutoff", "Valve under the stairs"); err != nil {
+		t.Fatalf("UpdateNote error: %v", err)
+	}
+	if r, _ := Search(db, 0, "dryer", 20); len(r) != 0 {
+		t.Fatalf("expected the old body to be gone from the index, got %+v", r)
+	}
+	if r, _ := Search(db, 0, "stairs", 20); len(r) != 1 || r[0].ID != note.ID {
+		t.Fatalf("expected the updated body to be indexed, got %+v", r)
+	}
+
+	if err := DeleteNote(db, note.ID); err != nil {
+		t.Fatalf("DeleteNote error: %v", err)
+	}
+	if r, _ := Search(db, 0, "stairs", 20); len(r) != 0 {
+		t.Fatalf("expected the deleted note to leave the index, got %+v", r)
+	}
+}
+
+func TestSearchByProperty(t *testing.T) {
+	db := TestDB(t)
+
+	cabin, _ := AddProperty(db, "Cabin", "")
+	if _, err := AddTask(db, &models.Task{Label: "Drain pipes", PropertyID: cabin.ID, UserID: "1"}); err != nil {
+		t.Fatalf("AddTask error: %v", err)
+	}
+	if _, err := AddTask(db, &models.Task{Label: "Drain water heater", UserID: "1"}); err != nil {
+		t.Fatalf("AddTask error: %v", err)
+	}
+
+	if r, _ := Search(db, 0, "drain", 20); len(r) != 2 {
+		t.Fatalf("expected matches in both properties, got %+v", r)
+	}
+	r, _ := Search(db, cabin.ID, "drain", 20)
+	if len(r) != 1 || r[0].PropertyID != cabin.ID {
+		t.Fatalf("expected only the cabin task, got %+v", r)
+	}
+
+	// Query syntax in user input is treated as plain words
+	if _, err := Search(db, 0, `drain" OR NEAR(`, 20); err != nil {
+		t.Fatalf("expected punctuation to be ignored, got %v", err)
+	}
+	if r, _ := Search(db, 0, "  ", 20); len(r) != 0 {
+		t.Fatalf("expected no results for an empty query, got %+v", r)
+	}
+}
+
+func TestSearchIndexSurvivesImport(t *testing.T) {
+	db := TestDB(t)
+
+	if _, err := AddNote(db, &models.Note{Title: "Roof", Body: "Shingles replaced in 2019"}); err != nil {
+		t.Fatalf("AddNote error: %v", err)
+	}
+	exported, err := ExportToJSON(db, dialectSQLite)
+	if err != nil {
+		t.Fatalf("ExportToJSON error: %v", err)
+	}
+	if _, err := ImportFromJSON(db, exported, ""); err != nil {
+		t.Fatalf("ImportFromJSON error: %v", err)
+	}
+	if r, _ := Search(db, 0, "shingles", 20); len(r) != 1 {
+		t.Fatalf("expected the imported note to be searchable, got %+v", r)
+	}
+}
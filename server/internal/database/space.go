@@ -0,0 +1,262 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/masoncfrancis/homelogger/server/internal/models"
+	"gorm.io/gorm"
+)
+
+// DefaultSpaceNames are the spaces every new property starts with. They match
+// the space types the client offered before spaces were user-defined.
+var DefaultSpaceNames = []string{
+	"BuildingExterior",
+	"BuildingInterior",
+	"Electrical",
+	"HVAC",
+	"Plumbing",
+	"Yard",
+}
+
+// ErrDuplicateSpace is returned when a property already has a space with the same name.
+var ErrDuplicateSpace = errors.New("a space with that name already exists")
+
+// ErrSpaceInUse is returned when deleting a space that still has records.
+var ErrSpaceInUse = errors.New("space still has records; move or delete them first")
+
+// spaceTables are the tables carrying a space_id column. Each also keeps the
+// space's name in space_type for older clients.
+var spaceTables = []string{
+	"maintenances",
+	"repairs",
+	"tasks",
+	"notes",
+	"saved_files",
+}
+
+// GetSpaces returns the spaces of a property ordered by name. Pass
+// propertyID=0 for every property.
+func GetSpaces(db *gorm.DB, propertyID uint) ([]models.Space, error) {
+	var spaces []models.Space
+	if err := db.Scopes(propertyScope(propertyID)).Order("name ASC, id ASC").Find(&spaces).Error; err != nil {
+		return nil, err
+	}
+	return spaces, nil
+}
+
+// GetSpace returns a single space by ID.
+func GetSpace(db *gorm.DB, id uint) (*models.Space, error) {
+	var space models.Space
+	if err := db.Where("id = ?", id).First(&space).Error; err != nil {
+		return nil, err
+	}
+	return &space, nil
+}
+
+// GetSpaceByName looks up a space by name (case-insensitive) within a
+// property. Pass propertyID=0 for the default property.
+func GetSpaceByName(db *gorm.DB, propertyID uint, name string) (*models.Space, error) {
+	if propertyID == 0 {
+		id, err := DefaultPropertyID(db)
+		if err != nil {
+			return nil, err
+		}
+		propertyID = id
+	}
+	var space models.Space
+	err := db.Where("property_id = ? AND LOWER(name) = LOWER(?)", propertyID, strings.TrimSpace(name)).
+		Order("id ASC").First(&space).Error
+	if err != nil {
+		return nil, err
+	}
+	return &space, nil
+}
+
+// AddSpace creates a space in a property. Pass propertyID=0 for the default property.
+func AddSpace(db *gorm.DB, propertyID uint, name string) (*models.Space, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, invalidField("name", "name is required")
+	}
+	propertyID, err := resolvePropertyID(db, propertyID, nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := GetSpaceByName(db, propertyID, name); err == nil {
+		return nil, ErrDuplicateSpace
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	space := &models.Space{PropertyID: propertyID, Name: name}
+	if err := createAudited(db, AuditSpace, space); err != nil {
+		return nil, err
+	}
+	return space, nil
+}
+
+// RenameSpace renames a space. Records keep pointing at the same space ID;
+// their space_type copy of the name is updated in the same transaction.
+func RenameSpace(db *gorm.DB, id uint, name string) (*models.Space, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, invalidField("name", "name is required")
+	}
+	space, err := GetSpace(db, id)
+	if err != nil {
+		return nil, err
+	}
+	if existing, err := GetSpaceByName(db, space.PropertyID, name); err == nil && existing.ID != id {
+		return nil, ErrDuplicateSpace
+	} else if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		before := *space
+		space.Name = name
+		if err := saveAudited(tx, AuditSpace, &before, space); err != nil {
+			return err
+		}
+		for _, table := range spaceTables {
+			if err := tx.Table(table).Where("space_id = ?", id).Update("space_type", name).Error; err != nil {
+				return fmt.Errorf("rename space in %s: %w", table, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return space, nil
+}
+
+// DeleteSpace deletes a space that no longer has any records. The check and
+// the delete run in one transaction.
+func DeleteSpace(db *gorm.DB, id uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if _, err := GetSpace(tx, id); err != nil {
+			return err
+		}
+		for _, table := range spaceTables {
+			var inUse int64
+			if err := tx.Table(table).Where("space_id = ? AND deleted_at IS NULL", id).Count(&inUse).Error; err != nil {
+				return err
+			}
+			if inUse > 0 {
+				return ErrSpaceInUse
+			}
+		}
+		return deleteAudited[models.Space](tx, AuditSpace, id)
+	})
+}
+
+// seedDefaultSpaces creates DefaultSpaceNames in a new property.
+func seedDefaultSpaces(db *gorm.DB, propertyID uint) error {
+	for _, name := range DefaultSpaceNames {
+		if err := createAudited(db, AuditSpace, &models.Space{PropertyID: propertyID, Name: name}); err != nil {
+			return fmt.Errorf("create space %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// ensureSpace returns the named space of a property, creating it if needed.
+func ensureSpace(db *gorm.DB, propertyID uint, name string) (*models.Space, error) {
+	if propertyID == 0 {
+		id, err := DefaultPropertyID(db)
+		if err != nil {
+			return nil, err
+		}
+		propertyID = id
+	}
+	space, err := GetSpaceByName(db, propertyID, name)
+	if err == nil {
+		return space, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	space = &models.Space{PropertyID: propertyID, Name: strings.TrimSpace(name)}
+	if err := createAudited(db, AuditSpace, space); err != nil {
+		return nil, err
+	}
+	return space, nil
+}
+
+// resolveSpace returns the space a record in propertyID should point to. An
+// explicit spaceID must exist in that property; otherwise the space is looked
+// up by name and created on first use, so clients that still send space type
+// strings keep working. Returns nil when the record has no space.
+func resolveSpace(db *gorm.DB, propertyID uint, spaceID *uint, name string) (*models.Space, error) {
+	if spaceID != nil && *spaceID != 0 {
+		space, err := GetSpace(db, *spaceID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, invalidField("spaceId", "space %d does not exist", *spaceID)
+			}
+			return nil, err
+		}
+		if propertyID != 0 && space.PropertyID != propertyID {
+			return nil, invalidField("spaceId", "space %d belongs to another property", *spaceID)
+		}
+		return space, nil
+	}
+	if strings.TrimSpace(name) == "" {
+		return nil, nil
+	}
+	return ensureSpace(db, propertyID, name)
+}
+
+// placeInSpace resolves the space of a record whose space_type is nullable
+// and stores the result back into spaceID and spaceType.
+func placeInSpace(db *gorm.DB, propertyID uint, spaceID **uint, spaceType **string) error {
+	name := ""
+	if *spaceType != nil {
+		name = **spaceType
+	}
+	space, err := resolveSpace(db, propertyID, *spaceID, name)
+	if err != nil {
+		return err
+	}
+	*spaceID, *spaceType = nil, nil
+	if space != nil {
+		*spaceID = &space.ID
+		*spaceType = &space.Name
+	}
+	return nil
+}
+
+// MigrateSpaceTypes links records that only carry a space_type string to a
+// space of their property, creating the space if needed. Rows that already
+// have a space_id are left alone, so it is safe to call on every startup.
+func MigrateSpaceTypes(db *gorm.DB) error {
+	type pending struct {
+		PropertyID uint
+		SpaceType  string
+	}
+	for _, table := range spaceTables {
+		var rows []pending
+		err := db.Table(table).
+			Distinct("property_id", "space_type").
+			Where("space_id IS NULL AND space_type IS NOT NULL AND space_type <> ''").
+			Scan(&rows).Error
+		if err != nil {
+			return fmt.Errorf("query %s space types: %w", table, err)
+		}
+		for _, row := range rows {
+			space, err := ensureSpace(db, row.PropertyID, row.SpaceType)
+			if err != nil {
+				return fmt.Errorf("create space %q: %w", row.SpaceType, err)
+			}
+			err = db.Table(table).
+				Where("space_id IS NULL AND property_id = ? AND space_type = ?", row.PropertyID, row.SpaceType).
+				Updates(map[string]any{"space_id": space.ID, "space_type": space.Name}).Error
+			if err != nil {
+				return fmt.Errorf("link %s to space %q: %w", table, row.SpaceType, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,211 @@
+package database
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/masoncfrancis/homelogger/server/internal/models"
+)
+
+func TestNewPropertyGetsDefaultSpaces(t *testing.T) {
+	db := TestDB(t)
+
+	home, _ := DefaultPropertyID(db)
+	spaces, err := GetSpaces(db, home)
+	if err != nil {
+		t.Fatalf("GetSpaces error: %v", err)
+	}
+	if len(spaces) != len(DefaultSpaceNames) {
+		t.Fatalf("expected %d default spaces, got %+v", len(DefaultSpaceNames), spaces)
+	}
+
+	garage, err := AddSpace(db, home, "Garage")
+	if err != nil {
+		t.Fatalf("AddSpace error: %v", err)
+	}
+	if garage.PropertyID != home {
+		t.Fatalf("expected garage in property %d, got %d", home, garage.PropertyID)
+	}
+	if _, err := AddSpace(db, home, "garage"); !errors.Is(err, ErrDuplicateSpace) {
+		t.Fatalf("expected ErrDuplicateSpace, got %v", err)
+	}
+
+	// Other properties may reuse the name
+	cabin, _ := AddProperty(db, "Cabin", "")
+	if _, err := AddSpace(db, cabin.ID, "Garage"); err != nil {
+		t.Fatalf("AddSpace in second property error: %v", err)
+	}
+}
+
+func TestRenameSpaceKeepsRecords(t *testing.T) {
+	db := TestDB(t)
+
+	home, _ := DefaultPropertyID(db)
+	pool, err := AddSpace(db, home, "Pool")
+	if err != nil {
+		t.Fatalf("AddSpace error: %v", err)
+	}
+	task, err := AddTask(db, &models.Task{Label: "Test chlorine", SpaceID: &pool.ID, UserID: "1"})
+	if err != nil {
+		t.Fatalf("AddTask error: %v", err)
+	}
+	if task.SpaceType == nil || *task.SpaceType != "Pool" {
+		t.Fatalf("expected spaceType to mirror the space name, got %v", task.SpaceType)
+	}
+	m, err := AddMaintenance(db, &models.Maintenance{Description: "Replace pump seal", Date: "2026-05-01", ReferenceType: "Space", SpaceID: &pool.ID})
+	if err != nil {
+		t.Fatalf("AddMaintenance error: %v", err)
+	}
+
+	if _, err := RenameSpace(db, pool.ID, "Swimming pool"); err != nil {
+		t.Fatalf("RenameSpace error: %v", err)
+	}
+
+	tasks, _ := GetTasks(db, 0, 0, pool.ID, false)
+	if len(tasks) != 1 || *tasks[0].SpaceType != "Swimming pool" {
+		t.Fatalf("expected the task to follow the rename, got %+v", tasks)
+	}
+	maint, _ := GetMaintenances(db, 0, 0, "Space", pool.ID)
+	if len(maint) != 1 || maint[0].ID != m.ID || maint[0].SpaceType != "Swimming pool" {
+		t.Fatalf("expected the maintenance to follow the rename, got %+v", maint)
+	}
+
+	if _, err := RenameSpace(db, pool.ID, "HVAC"); !errors.Is(err, ErrDuplicateSpace) {
+		t.Fatalf("expected ErrDuplicateSpace, got %v", err)
+	}
+}
+
+func TestDeleteSpace(t *testing.T) {
+	db := TestDB(t)
+
+	attic, _ := AddSpace(db, 0, "Attic")
+	note, err := AddNote(db, &models.Note{Title: "Insulation", Body: "R-38 blown in", SpaceID: &attic.ID})
+	if err != nil {
+		t.Fatalf("AddNote error: %v", err)
+	}
+	if err := DeleteSpace(db, attic.ID); !errors.Is(err, ErrSpaceInUse) {
+		t.Fatalf("expected ErrSpaceInUse, got %v", err)
+	}
+
+	if err := DeleteNote(db, note.ID); err != nil {
+		t.Fatalf("DeleteNote error: %v", err)
+	}
+	if err := DeleteSpace(db, attic.ID); err != nil {
+		t.Fatalf("DeleteSpace error: %v", err)
+	}
+	if _, err := GetSpace(db, attic.ID); err == nil {
+		t.Fatal("expected space to be gone")
+	}
+}
+
+func TestSpaceMustBelongToRecordProperty(t *testing.T) {
+	db := TestDB(t)
+
+	cabin, _ := AddProperty(db, "Cabin", "")
+	dock, _ := AddSpace(db, cabin.ID, "Dock")
+	home, _ := DefaultPropertyID(db)
+
+	if _, err := AddTask(db, &models.Task{Label: "Stain dock", SpaceID: &dock.ID, PropertyID: home, UserID: "1"}); err == nil {
+		t.Fatal("expected error for a space in another property")
+	}
+
+	// Without a property the record follows its space
+	task, err := AddTask(db, &models.Task{Label: "Stain dock", SpaceID: &dock.ID, UserID: "1"})
+	if err != nil {
+		t.Fatalf("AddTask error: %v", err)
+	}
+	if task.PropertyID != cabin.ID {
+		t.Fatalf("expected task in cabin, got property %d", task.PropertyID)
+	}
+}
+
+func TestSpaceValidationNamesField(t *testing.T) {
+	db := TestDB(t)
+	home, _ := DefaultPropertyID(db)
+
+	_, err := AddSpace(db, home, "  ")
+	var verr *ValidationError
+	if !errors.As(err, &verr) || verr.Field != "name" {
+		t.Fatalf("expected a name ValidationError, got %v", err)
+	}
+	missing := uint(9999)
+	_, err = AddTask(db, &models.Task{Label: "Sweep", SpaceID: &missing, UserID: "1"})
+	if !errors.As(err, &verr) || verr.Field != "spaceId" {
+		t.Fatalf("expected a spaceId ValidationError, got %v", err)
+	}
+}
+
+func TestMigrateSpaceTypes(t *testing.T) {
+	db := TestDB(t)
+
+	// Rows written before spaces were user-defined only carry a space_type string
+	legacy := []any{
+		&models.Repair{Description: "Fix door", Date: "2026-01-02", ReferenceType: "Space", SpaceType: "Garage"},
+		&models.Note{Title: "Door code", SpaceType: strPtr("Garage")},
+		&models.Task{Label: "Skim leaves", SpaceType: strPtr("Pool"), UserID: "1"},
+		&models.Task{Label: "Change filter", SpaceType: strPtr("HVAC"), UserID: "1"},
+	}
+	for _, row := range legacy {
+		if err := db.Create(row).Error; err != nil {
+			t.Fatalf("create legacy row: %v", err)
+		}
+	}
+	if err := EnsureDefaultProperty(db); err != nil {
+		t.Fatalf("EnsureDefaultProperty error: %v", err)
+	}
+	if err := MigrateSpaceTypes(db); err != nil {
+		t.Fatalf("MigrateSpaceTypes error: %v", err)
+	}
+
+	home, _ := DefaultPropertyID(db)
+	garage, err := GetSpaceByName(db, home, "Garage")
+	if err != nil {
+		t.Fatalf("expected a Garage space: %v", err)
+	}
+	if repairs, _ := GetRepairs(db, 0, 0, "Space", garage.ID); len(repairs) != 1 {
+		t.Fatalf("expected the repair linked to Garage, got %+v", repairs)
+	}
+	if notes, _ := GetNotes(db, 0, 0, garage.ID); len(notes) != 1 {
+		t.Fatalf("expected the note linked to Garage, got %+v", notes)
+	}
+
+	// Existing spaces are reused rather than duplicated
+	hvac, _ := GetSpaceByName(db, home, "HVAC")
+	if tasks, _ := GetTasks(db, 0, 0, hvac.ID, false); len(tasks) != 1 {
+		t.Fatalf("expected the task linked to the default HVAC space, got %+v", tasks)
+	}
+	spaces, _ := GetSpaces(db, home)
+	if len(spaces) != len(DefaultSpaceNames)+2 {
+		t.Fatalf("expected Garage and Pool added to the defaults, got %+v", spaces)
+	}
+}
+
+func TestImportLinksSpaceTypes(t *testing.T) {
+	db := TestDB(t)
+
+	payload := validMinimalPayload()
+	payload.Entities.Notes = []models.Note{{Title: "Shelving", SpaceType: strPtr("Attic")}}
+	if _, err := ImportFromJSON(db, payload, ""); err != nil {
+		t.Fatalf("ImportFromJSON error: %v", err)
+	}
+
+	attic, err := GetSpaceByName(db, 0, "Attic")
+	if err != nil {
+		t.Fatalf("expected an Attic space after import: %v", err)
+	}
+	if notes, _ := GetNotes(db, 0, 0, attic.ID); len(notes) != 1 {
+		t.Fatalf("expected the imported note linked to Attic, got %+v", notes)
+	}
+
+	// Exported spaces survive a round trip with their IDs
+	exported, err := ExportToJSON(db, dialectSQLite)
+	if err != nil {
+		t.Fatalf("ExportToJSON error: %v", err)
+	}
+	if _, err := ImportFromJSON(db, exported, ""); err != nil {
+		t.Fatalf("ImportFromJSON error: %v", err)
+	}
+	if notes, _ := GetNotes(db, 0, 0, attic.ID); len(notes) != 1 {
+		t.Fatalf("expected the note still linked to Attic, got %+v", notes)
+	}
+}
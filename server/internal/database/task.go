@@ -1,19 +1,53 @@
 package database
 
 import (
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/masoncfrancis/homelogger/server/internal/models"
+	"github.com/masoncfrancis/homelogger/server/internal/rrule"
 	"gorm.io/gorm"
 )
 
-// GetTasks returns tasks filtered by optional applianceId and spaceType.
-// Pass applianceId=0 and spaceType="" to get tasks with no filter.
+// ErrTaskNotRecurring is returned when skipping an occurrence of a one-off task.
+var ErrTaskNotRecurring = errors.New("only recurring tasks have occurrences to skip")
+
+// ErrTaskClosed is returned when skipping or snoozing a completed task.
+var ErrTaskClosed = errors.New("the task is completed")
+
+var taskListSpec = listSpec{
+	sorts: map[string]string{
+		"dueDate":       "due_date",
+		"id":            "id",
+		"label":         "label",
+		"priority":      "priority",
+		"estimatedCost": "estimated_cost",
+		"createdAt":     "created_at",
+	},
+	defaultSort:    "dueDate",
+	defaultOrder:   "asc",
+	dateColumn:     "due_date",
+	costColumn:     "estimated_cost",
+	assigneeColumn: "assignee_id",
+}
+
+// GetTasks returns tasks filtered by optional propertyID, applianceId and spaceID.
+// Pass applianceId=0 and spaceID=0 to get tasks with no filter, and
+// propertyID=0 for every property.
 // Set includeCompleted=true to include tasks where Checked=true.
-func GetTasks(db *gorm.DB, applianceId uint, spaceType string, includeCompleted bool) ([]models.Task, error) {
-	var tasks []models.Task
-	query := db.Model(&models.Task{})
+func GetTasks(db *gorm.DB, propertyID uint, applianceId uint, spaceID uint, includeCompleted bool) ([]models.Task, error) {
+	page, err := ListTasks(db, propertyID, applianceId, spaceID, includeCompleted, ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return page.Items, nil
+}
+
+// ListTasks returns a page of tasks, filtered like GetTasks. By default tasks
+// are ordered by due date, with undated tasks last.
+func ListTasks(db *gorm.DB, propertyID uint, applianceId uint, spaceID uint, includeCompleted bool, opts ListOptions) (*Page[models.Task], error) {
+	query := db.Model(&models.Task{}).Scopes(propertyScope(propertyID))
 
 	if !includeCompleted {
 		query = query.Where("checked = ?", false)
@@ -21,41 +55,57 @@ func GetTasks(db *gorm.DB, applianceId uint, spaceType string, includeCompleted
 
 	if applianceId != 0 {
 		query = query.Where("appliance_id = ?", applianceId)
-	} else if spaceType != "" {
-		query = query.Where("space_type = ?", spaceType)
+	} else if spaceID != 0 {
+		query = query.Where("space_id = ?", spaceID)
 	} else {
 		// Global (no appliance, no space)
-		query = query.Where("appliance_id IS NULL AND space_type IS NULL")
+		query = query.Where("appliance_id IS NULL AND space_id IS NULL")
 	}
 
-	result := query.Order("due_date ASC, created_at ASC").Find(&tasks)
-	if result.Error != nil {
-		return nil, result.Error
-	}
-	return tasks, nil
+	return paginateTasks(db, query, opts)
 }
 
 // GetAllActiveTasks returns all incomplete tasks across all spaces and appliances,
-// ordered by due date ascending (nulls last). Used for the dashboard.
-func GetAllActiveTasks(db *gorm.DB) ([]models.Task, error) {
-	return GetAllTasks(db, false)
+// ordered by due date ascending (nulls last). Used for the dashboard. Tasks
+// waiting on an open task are left out until it is completed.
+// Pass propertyID=0 for every property.
+func GetAllActiveTasks(db *gorm.DB, propertyID uint) ([]models.Task, error) {
+	return GetAllTasks(db, propertyID, false)
 }
 
-// GetAllTasks returns tasks across all spaces and appliances.
-// Pass includeCompleted=true to include tasks where checked=true.
-func GetAllTasks(db *gorm.DB, includeCompleted bool) ([]models.Task, error) {
-	var tasks []models.Task
-	query := db.Model(&models.Task{})
+// GetAllTasks returns tasks across all spaces and appliances of a property.
+// Pass propertyID=0 for every property and includeCompleted=true to include
+// tasks where checked=true.
+func GetAllTasks(db *gorm.DB, propertyID uint, includeCompleted bool) ([]models.Task, error) {
+	page, err := ListAllTasks(db, propertyID, includeCompleted, ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return page.Items, nil
+}
+
+// ListAllTasks returns a page of tasks across all spaces and appliances of a
+// property, filtered like GetAllTasks. Without includeCompleted it lists the
+// tasks that can be done now, leaving out those waiting on an open task.
+func ListAllTasks(db *gorm.DB, propertyID uint, includeCompleted bool, opts ListOptions) (*Page[models.Task], error) {
+	query := db.Model(&models.Task{}).Scopes(propertyScope(propertyID))
 	if !includeCompleted {
-		query = query.Where("checked = ?", false)
+		query = query.Where("checked = ?", false).Scopes(unblockedTasks)
 	}
-	result := query.
-		Order("CASE WHEN due_date IS NULL THEN 1 ELSE 0 END, due_date ASC, created_at ASC").
-		Find(&tasks)
-	if result.Error != nil {
-		return nil, result.Error
+	return paginateTasks(db, query, opts)
+}
+
+// paginateTasks returns a page of the tasks query finds, with the names of
+// who last completed them.
+func paginateTasks(db *gorm.DB, query *gorm.DB, opts ListOptions) (*Page[models.Task], error) {
+	page, err := paginate[models.Task](query, taskListSpec, opts)
+	if err != nil {
+		return nil, err
 	}
-	return tasks, nil
+	if err := nameCompleters(db, page.Items); err != nil {
+		return nil, err
+	}
+	return page, nil
 }
 
 // GetTask returns a single task by ID.
@@ -65,23 +115,71 @@ func GetTask(db *gorm.DB, id uint) (*models.Task, error) {
 	if result.Error != nil {
 		return nil, result.Error
 	}
+	if err := nameCompleter(db, &task); err != nil {
+		return nil, err
+	}
 	return &task, nil
 }
 
 // AddTask creates a new task record.
 func AddTask(db *gorm.DB, task *models.Task) (*models.Task, error) {
-	result := db.Create(task)
-	if result.Error != nil {
-		return nil, result.Error
+	if err := checkTaskLinks(db, task); err != nil {
+		return nil, err
+	}
+	if err := checkAssignee(db, task.AssigneeID); err != nil {
+		return nil, err
+	}
+	propertyID, err := resolvePropertyID(db, task.PropertyID, task.ApplianceID, task.SpaceID, task.LocationID)
+	if err != nil {
+		return nil, err
+	}
+	task.PropertyID = propertyID
+	if err := placeInSpace(db, propertyID, &task.SpaceID, &task.SpaceType); err != nil {
+		return nil, err
+	}
+	if err := resolveLocation(db, propertyID, &task.LocationID); err != nil {
+		return nil, err
+	}
+	if err := normalizeRecurrence(task); err != nil {
+		return nil, err
+	}
+	anchorSeries(nil, task)
+	if err := normalizeMeter(db, task); err != nil {
+		return nil, err
+	}
+	if err := createAudited(db, TrashTask, task); err != nil {
+		return nil, err
 	}
 	return task, nil
 }
 
 // UpdateTask saves all fields of an existing task.
 func UpdateTask(db *gorm.DB, task *models.Task) (*models.Task, error) {
-	result := db.Save(task)
-	if result.Error != nil {
-		return nil, result.Error
+	before, err := GetTask(db, task.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkTaskLinks(db, task); err != nil {
+		return nil, err
+	}
+	if err := checkAssignee(db, task.AssigneeID); err != nil {
+		return nil, err
+	}
+	if err := placeInSpace(db, task.PropertyID, &task.SpaceID, &task.SpaceType); err != nil {
+		return nil, err
+	}
+	if err := resolveLocation(db, task.PropertyID, &task.LocationID); err != nil {
+		return nil, err
+	}
+	if err := normalizeRecurrence(task); err != nil {
+		return nil, err
+	}
+	anchorSeries(before, task)
+	if err := normalizeMeter(db, task); err != nil {
+		return nil, err
+	}
+	if err := saveAudited(db, TrashTask, before, task); err != nil {
+		return nil, err
 	}
 	return task, nil
 }
@@ -89,81 +187,344 @@ func UpdateTask(db *gorm.DB, task *models.Task) (*models.Task, error) {
 // CompleteTask marks a task complete and, for recurring tasks, advances the due date.
 // completionDate must be in YYYY-MM-DD format.
 func CompleteTask(db *gorm.DB, id uint, completionDate string) (*models.Task, error) {
-	task, err := GetTask(db, id)
-	if err != nil {
-		return nil, err
-	}
-
-	task.LastCompletedAt = &completionDate
+	return CompleteTaskWith(db, id, TaskCompletionInput{Date: completionDate})
+}
 
-	if !task.IsRecurring {
-		task.Checked = true
-	} else {
-		// Determine the base date for advancing the schedule
-		baseDate := completionDate
-		if task.RecurrenceMode == "due_date" && task.DueDate != nil && *task.DueDate != "" {
-			baseDate = *task.DueDate
+// CompleteTaskWith completes a task like CompleteTask and records the
+// completion in the task's history, in one transaction.
+func CompleteTaskWith(db *gorm.DB, id uint, input TaskCompletionInput) (*models.Task, error) {
+	var task *models.Task
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		task, err = GetTask(tx, id)
+		if err != nil {
+			return err
+		}
+		if err := checkCompletable(tx, task); err != nil {
+			return err
 		}
 
-		nextDue, err := advanceDate(baseDate, task.RecurrenceUnit, task.RecurrenceInterval)
+		before := *task
+		task.LastCompletedAt = &input.Date
+		task.LastCompletedByID = actorUserID(tx)
+		if err := nameCompleter(tx, task); err != nil {
+			return err
+		}
+		reading, err := meterReading(tx, task)
 		if err != nil {
-			return nil, fmt.Errorf("error computing next due date: %w", err)
+			return err
 		}
-		task.DueDate = &nextDue
-	}
+		byUsage := task.MeterID != nil && task.MeterInterval > 0
 
-	result := db.Save(task)
-	if result.Error != nil {
-		return nil, result.Error
+		switch {
+		case task.IsRecurring:
+			// Determine the base date for advancing the schedule
+			baseDate := input.Date
+			if scheduled := scheduledDate(task); task.RecurrenceMode == "due_date" && scheduled != "" {
+				baseDate = scheduled
+			}
+
+			nextDue, ok, err := nextOccurrence(tx, task, baseDate, 1)
+			if err != nil {
+				return fmt.Errorf("error computing next due date: %w", err)
+			}
+			switch {
+			case ok:
+				task.DueDate = &nextDue
+			case byUsage:
+				task.DueDate = nil
+			default:
+				// The rule's UNTIL or COUNT has been reached.
+				task.Checked = true
+			}
+		case byUsage:
+			// Due again once the meter reaches the next threshold.
+			task.DueDate = nil
+		default:
+			task.Checked = true
+		}
+		task.SnoozedFrom = nil
+		if byUsage {
+			// Usage is counted afresh from the reading now.
+			next := task.MeterInterval
+			if reading != nil {
+				next += *reading
+			}
+			task.MeterDueAt = &next
+		}
+
+		if err := saveAudited(tx, TrashTask, &before, task); err != nil {
+			return err
+		}
+		if !task.Checked {
+			// The next occurrence starts over with every step to do.
+			if err := reopenSubtasks(tx, task.ID); err != nil {
+				return err
+			}
+		}
+		return recordCompletion(tx, &before, input, reading)
+	})
+	if err != nil {
+		return nil, err
 	}
 	return task, nil
 }
 
-// UncompleteTask marks a non-recurring task as incomplete. No-op for recurring tasks.
+// UncompleteTask reopens a completed task: a one-off task, or a recurring one
+// whose schedule has ended. Its latest completion is dropped from the
+// history. For an open recurring or usage-based task it undoes the last
+// completion, skip or snooze instead (see UndoTaskCompletion). No-op for
+// other open tasks.
 func UncompleteTask(db *gorm.DB, id uint) (*models.Task, error) {
 	task, err := GetTask(db, id)
 	if err != nil {
 		return nil, err
 	}
 
-	if !task.IsRecurring {
+	if !task.Checked && (task.IsRecurring || task.MeterID != nil && task.MeterInterval > 0) {
+		var latest models.TaskCompletion
+		err := db.Where("task_id = ?", id).Order("id DESC").First(&latest).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return task, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return UndoTaskCompletion(db, id, latest.ID)
+	}
+	if task.Checked {
+		before := *task
 		task.Checked = false
-		result := db.Save(task)
-		if result.Error != nil {
-			return nil, result.Error
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := saveAudited(tx, TrashTask, &before, task); err != nil {
+				return err
+			}
+			return forgetLatestCompletion(tx, id)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return task, nil
+}
+
+// SkipTaskOccurrence moves a recurring task on to its next occurrence without
+// completing it, and records the skip, made on date, in the task's history.
+// The series carries on from the day the skipped occurrence was due, or from
+// date when the task has no due date.
+func SkipTaskOccurrence(db *gorm.DB, id uint, date string) (*models.Task, error) {
+	if _, err := time.Parse("2006-01-02", date); err != nil {
+		return nil, invalidField("date", "date must be in YYYY-MM-DD format")
+	}
+	var task *models.Task
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		task, err = GetTask(tx, id)
+		if err != nil {
+			return err
+		}
+		if !task.IsRecurring {
+			return ErrTaskNotRecurring
+		}
+		if task.Checked {
+			return ErrTaskClosed
+		}
+
+		before := *task
+		base := date
+		if scheduled := scheduledDate(task); scheduled != "" {
+			base = scheduled
+		}
+		next, ok, err := nextOccurrence(tx, task, base, 1)
+		if err != nil {
+			return fmt.Errorf("error computing next due date: %w", err)
+		}
+		if ok {
+			task.DueDate = &next
+		} else {
+			// Skipping the last occurrence ends the series.
+			task.Checked = true
+		}
+		task.SnoozedFrom = nil
+
+		if err := saveAudited(tx, TrashTask, &before, task); err != nil {
+			return err
 		}
+		return recordCompletion(tx, &before, TaskCompletionInput{Action: TaskActionSkip, Date: date}, nil)
+	})
+	if err != nil {
+		return nil, err
 	}
 	return task, nil
 }
 
-// DeleteTask deletes a task by ID.
+// SnoozeTask puts a task's due date off by days and records the snooze, made
+// on date, in the task's history. A recurring task's series is not shifted:
+// its next occurrence still follows the day this one was first due.
+func SnoozeTask(db *gorm.DB, id uint, days int, date string) (*models.Task, error) {
+	if days < 1 {
+		return nil, invalidField("days", "days must be at least 1")
+	}
+	if _, err := time.Parse("2006-01-02", date); err != nil {
+		return nil, invalidField("date", "date must be in YYYY-MM-DD format")
+	}
+	var task *models.Task
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		task, err = GetTask(tx, id)
+		if err != nil {
+			return err
+		}
+		if task.Checked {
+			return ErrTaskClosed
+		}
+		if task.DueDate == nil || *task.DueDate == "" {
+			return invalidField("dueDate", "the task has no due date to snooze")
+		}
+		due, err := time.Parse("2006-01-02", *task.DueDate)
+		if err != nil {
+			return invalidField("dueDate", "the task's due date is not in YYYY-MM-DD format")
+		}
+
+		before := *task
+		if task.SnoozedFrom == nil {
+			from := *task.DueDate
+			task.SnoozedFrom = &from
+		}
+		snoozed := due.AddDate(0, 0, days).Format("2006-01-02")
+		task.DueDate = &snoozed
+
+		if err := saveAudited(tx, TrashTask, &before, task); err != nil {
+			return err
+		}
+		return recordCompletion(tx, &before, TaskCompletionInput{Action: TaskActionSnooze, Date: date}, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+// scheduledDate is the day a task's current occurrence is due by its
+// schedule, before any snooze; "" when it has no due date.
+func scheduledDate(task *models.Task) string {
+	if task.SnoozedFrom != nil && *task.SnoozedFrom != "" {
+		return *task.SnoozedFrom
+	}
+	if task.DueDate != nil {
+		return *task.DueDate
+	}
+	return ""
+}
+
+// DeleteTask moves a task to the trash.
 func DeleteTask(db *gorm.DB, id uint) error {
-	result := db.Where("id = ?", id).Delete(&models.Task{})
-	return result.Error
+	return moveToTrash(db, TrashTask, "id = ?", id)
 }
 
-// advanceDate adds the given interval in the given unit to a YYYY-MM-DD date string.
-func advanceDate(base, unit string, interval int) (string, error) {
-	t, err := time.Parse("2006-01-02", base)
+// normalizeRecurrence keeps a task's rule and its older recurrence fields in
+// step. A recurring task without a rule gets one written from its interval; a
+// rule is stored in canonical form and its FREQ and INTERVAL copied back.
+func normalizeRecurrence(task *models.Task) error {
+	if task.RRule == "" {
+		if task.IsRecurring {
+			task.RRule = rrule.FromInterval(task.RecurrenceInterval, task.RecurrenceUnit)
+		}
+		return nil
+	}
+	rule, err := rrule.Parse(task.RRule)
 	if err != nil {
-		return "", err
-	}
-
-	var next time.Time
-	switch unit {
-	case "days":
-		next = t.AddDate(0, 0, interval)
-	case "weeks":
-		next = t.AddDate(0, 0, interval*7)
-	case "months":
-		next = t.AddDate(0, interval, 0)
-	case "years":
-		next = t.AddDate(interval, 0, 0)
+		return invalidField("rrule", "invalid rrule: %v", err)
+	}
+	task.RRule = rule.String()
+	task.IsRecurring = true
+	task.RecurrenceInterval = rule.Interval
+	task.RecurrenceUnit = map[rrule.Frequency]string{rrule.Daily: "days", rrule.Weekly: "weeks", rrule.Monthly: "months", rrule.Yearly: "years"}[rule.Freq]
+	return nil
+}
+
+// anchorSeries starts a task's series over when its rule is set or changed,
+// from its due date; without one, the series starts at its next completion.
+// The start is the server's to keep, so whatever the client sent is ignored.
+// before is nil for a new task.
+func anchorSeries(before, task *models.Task) {
+	switch {
+	case task.RRule == "":
+		task.RRuleStart = nil
+	case before != nil && before.RRule == task.RRule:
+		task.RRuleStart = before.RRuleStart
+	case task.DueDate != nil && *task.DueDate != "":
+		start := *task.DueDate
+		task.RRuleStart = &start
 	default:
-		next = t.AddDate(0, interval, 0)
+		task.RRuleStart = nil
 	}
+}
 
-	return next.Format("2006-01-02"), nil
+// nextOccurrence returns the day, after base (YYYY-MM-DD), on which a
+// recurring task next falls due. The series is expanded from the task's
+// start, which is set to base if it has none yet, except that a rule that
+// only steps by its interval starts over from each completion for tasks
+// scheduled from their completion. occurrences is how many occurrences the
+// caller is about to use up, for rules with a COUNT; it reports false once
+// the rule's UNTIL or COUNT is reached.
+func nextOccurrence(tx *gorm.DB, task *models.Task, base string, occurrences int64) (string, bool, error) {
+	rule, err := rrule.Parse(task.RRule)
+	if err != nil {
+		return "", false, err
+	}
+	if task.RRuleStart == nil {
+		start := base
+		task.RRuleStart = &start
+	}
+	from := *task.RRuleStart
+	if task.RecurrenceMode != "due_date" && !rule.Calendar() {
+		from = base
+	}
+	start, err := time.Parse("2006-01-02", from)
+	if err != nil {
+		return "", false, err
+	}
+	day, err := time.Parse("2006-01-02", base)
+	if err != nil {
+		return "", false, err
+	}
+	next, ok := rule.Next(start, day)
+	if !ok {
+		return "", false, nil
+	}
+	if rule.Count > 0 {
+		var done int64
+		// Snoozes put an occurrence off; completions and skips use one up,
+		// counted from the start of the series.
+		err := tx.Model(&models.TaskCompletion{}).
+			Where("task_id = ? AND action <> ? AND COALESCE(due_date, completion_date) >= ?", task.ID, TaskActionSnooze, *task.RRuleStart).
+			Count(&done).Error
+		if err != nil {
+			return "", false, err
+		}
+		if done+occurrences >= int64(rule.Count) {
+			return "", false, nil
+		}
+	}
+	return next.Format("2006-01-02"), true, nil
+}
+
+// MigrateTaskRecurrence writes a rule for recurring tasks from before rules
+// existed, from their interval and unit. Tasks that have a rule are left
+// alone, so it is safe to call on every startup.
+func MigrateTaskRecurrence(db *gorm.DB) error {
+	var tasks []models.Task
+	if err := db.Unscoped().Where("is_recurring = ? AND (rrule IS NULL OR rrule = '')", true).Find(&tasks).Error; err != nil {
+		return err
+	}
+	for _, task := range tasks {
+		rule := rrule.FromInterval(task.RecurrenceInterval, task.RecurrenceUnit)
+		if err := db.Model(&models.Task{}).Unscoped().Where("id = ?", task.ID).UpdateColumn("rrule", rule).Error; err != nil {
+			return fmt.Errorf("task %d: %w", task.ID, err)
+		}
+	}
+	return nil
 }
 
 // MigrateTodosToTasks copies any rows from the todos table that have not yet
@@ -185,12 +546,21 @@ func MigrateTodosToTasks(db *gorm.DB) error {
 	`).Scan(&todos).Error; err != nil {
 		return fmt.Errorf("query pending todos: %w", err)
 	}
+	if len(todos) == 0 {
+		return nil
+	}
+
+	propertyID, err := DefaultPropertyID(db)
+	if err != nil {
+		return fmt.Errorf("default property: %w", err)
+	}
 
 	for _, t := range todos {
 		task := &models.Task{
-			Label:   t.Label,
-			Checked: t.Checked,
-			UserID:  t.UserID,
+			PropertyID: propertyID,
+			Label:      t.Label,
+			Checked:    t.Checked,
+			UserID:     t.UserID,
 		}
 		if t.ApplianceID != nil {
 			aid := *t.ApplianceID
@@ -200,6 +570,10 @@ func MigrateTodosToTasks(db *gorm.DB) error {
 			st := *t.SpaceType
 			task.SpaceType = &st
 		}
+		if err := placeInSpace(db, propertyID, &task.SpaceID, &task.SpaceType); err != nil {
+			fmt.Printf("MigrateTodosToTasks: skipping todo %d: %v\n", t.ID, err)
+			continue
+		}
 
 		if err := db.Create(task).Error; err != nil {
 			fmt.Printf("MigrateTodosToTasks: skipping todo %d: %v\n", t.ID, err)
@@ -0,0 +1,114 @@
+package database
+
+import (
+	"errors"
+
+	"github.com/masoncfrancis/homelogger/server/internal/models"
+	"gorm.io/gorm"
+)
+
+// checkAssignee validates that a task's assignee is a household member.
+func checkAssignee(db *gorm.DB, assigneeID *uint) error {
+	if assigneeID == nil {
+		return nil
+	}
+	_, err := GetUser(db, *assigneeID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return invalidField("assigneeId", "user %d does not exist", *assigneeID)
+	}
+	return err
+}
+
+// AssignTask assigns a task to a household member, or to no one when
+// assigneeID is nil.
+func AssignTask(db *gorm.DB, id uint, assigneeID *uint) (*models.Task, error) {
+	var task *models.Task
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		task, err = GetTask(tx, id)
+		if err != nil {
+			return err
+		}
+		if err := checkAssignee(tx, assigneeID); err != nil {
+			return err
+		}
+		before := *task
+		task.AssigneeID = assigneeID
+		return saveAudited(tx, TrashTask, &before, task)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+// userNames returns the names of the given users: their display names, or
+// usernames when they have none. Deleted accounts keep their names.
+func userNames(db *gorm.DB, ids []uint) (map[uint]string, error) {
+	names := map[uint]string{}
+	if len(ids) == 0 {
+		return names, nil
+	}
+	var users []models.User
+	if err := db.Unscoped().Where("id IN ?", ids).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	for _, user := range users {
+		names[user.ID] = user.DisplayName
+		if user.DisplayName == "" {
+			names[user.ID] = user.Username
+		}
+	}
+	return names, nil
+}
+
+// nameCompleters fills in the name of whoever last completed each task.
+func nameCompleters(db *gorm.DB, tasks []models.Task) error {
+	var ids []uint
+	for _, task := range tasks {
+		if task.LastCompletedByID != nil {
+			ids = append(ids, *task.LastCompletedByID)
+		}
+	}
+	names, err := userNames(db, ids)
+	if err != nil {
+		return err
+	}
+	for i := range tasks {
+		tasks[i].LastCompletedBy = ""
+		if id := tasks[i].LastCompletedByID; id != nil {
+			tasks[i].LastCompletedBy = names[*id]
+		}
+	}
+	return nil
+}
+
+// nameCompleter is nameCompleters for a single task.
+func nameCompleter(db *gorm.DB, task *models.Task) error {
+	tasks := []models.Task{*task}
+	if err := nameCompleters(db, tasks); err != nil {
+		return err
+	}
+	task.LastCompletedBy = tasks[0].LastCompletedBy
+	return nil
+}
+
+// nameCompletionUsers fills in the name of whoever made each completion.
+func nameCompletionUsers(db *gorm.DB, completions []models.TaskCompletion) error {
+	var ids []uint
+	for _, completion := range completions {
+		if completion.CompletedByID != nil {
+			ids = append(ids, *completion.CompletedByID)
+		}
+	}
+	names, err := userNames(db, ids)
+	if err != nil {
+		return err
+	}
+	for i := range completions {
+		if id := completions[i].CompletedByID; id != nil {
+			completions[i].CompletedBy = names[*id]
+		}
+	}
+	return nil
+}
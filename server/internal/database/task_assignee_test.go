@@ -0,0 +1,90 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/masoncfrancis/homelogger/server/internal/models"
+)
+
+func TestAssignTasks(t *testing.T) {
+	db := TestDB(t)
+	alice, err := CreateUser(db, "alice", "Alice", "correct-horse", models.RoleOwner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bob, err := CreateUser(db, "bob", "Bob", "battery-staple", models.RoleMember)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var invalid *ValidationError
+	if _, err := AddTask(db, &models.Task{Label: "Mow", AssigneeID: uintPtr(999)}); !errors.As(err, &invalid) || invalid.Field != "assigneeId" {
+		t.Fatalf("expected an unknown assignee to be refused, got %v", err)
+	}
+	filter, err := AddTask(db, &models.Task{Label: "Change furnace filter", AssigneeID: &alice.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := AddTask(db, &models.Task{Label: "Clean gutters"}); err != nil {
+		t.Fatal(err)
+	}
+
+	page, err := ListAllTasks(db, 0, false, ListOptions{AssigneeID: alice.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 1 || page.Items[0].ID != filter.ID {
+		t.Fatalf("expected only alice's task, got %+v", page.Items)
+	}
+
+	if filter, err = AssignTask(db, filter.ID, &bob.ID); err != nil || filter.AssigneeID == nil || *filter.AssigneeID != bob.ID {
+		t.Fatalf("expected the task reassigned to bob, got %+v, %v", filter, err)
+	}
+	if page, err = ListAllTasks(db, 0, false, ListOptions{AssigneeID: alice.ID}); err != nil || page.Total != 0 {
+		t.Fatalf("expected alice to have no tasks left, got %+v, %v", page, err)
+	}
+	if _, err := AssignTask(db, filter.ID, uintPtr(999)); !errors.As(err, &invalid) || invalid.Field != "assigneeId" {
+		t.Fatalf("expected an unknown assignee to be refused, got %v", err)
+	}
+
+	// Alice completes bob's task; the task remembers who did it and who it
+	// was assigned to.
+	asAlice := db.WithContext(WithActorUser(context.Background(), alice))
+	done, err := CompleteTask(asAlice, filter.ID, "2026-03-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if done.LastCompletedByID == nil || *done.LastCompletedByID != alice.ID || done.LastCompletedBy != "Alice" || done.AssigneeID == nil || *done.AssigneeID != bob.ID {
+		t.Fatalf("expected completed by alice and still assigned to bob, got %+v", done)
+	}
+	completions, err := ListTaskCompletions(db, filter.ID, ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	completion := completions.Items[0]
+	if completion.CompletedByID == nil || *completion.CompletedByID != alice.ID || completion.CompletedBy != "Alice" || completion.AssigneeID == nil || *completion.AssigneeID != bob.ID {
+		t.Fatalf("unexpected completion %+v", completion)
+	}
+
+	// The name is looked up when the task is read, so it follows renames.
+	if err := db.Model(&models.User{}).Where("id = ?", alice.ID).Update("display_name", "Alice B.").Error; err != nil {
+		t.Fatal(err)
+	}
+	if reread, err := GetTask(db, filter.ID); err != nil || reread.LastCompletedBy != "Alice B." {
+		t.Fatalf("expected the completer's current name, got %+v, %v", reread, err)
+	}
+
+	undone, err := UndoTaskCompletion(db, filter.ID, completion.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if undone.LastCompletedByID != nil || undone.LastCompletedBy != "" {
+		t.Fatalf("expected undo to forget who completed it, got %+v", undone)
+	}
+
+	if filter, err = AssignTask(db, filter.ID, nil); err != nil || filter.AssigneeID != nil {
+		t.Fatalf("expected the task unassigned, got %+v, %v", filter, err)
+	}
+}
@@ -0,0 +1,238 @@
+package database
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/masoncfrancis/homelogger/server/internal/models"
+	"gorm.io/gorm"
+)
+
+// AuditTaskCompletion is the audited entity type of a task completion.
+const AuditTaskCompletion = "taskCompletion"
+
+// Actions recorded in a task's history.
+const (
+	TaskActionComplete = "complete"
+	TaskActionSkip     = "skip"   // an occurrence passed over without doing it
+	TaskActionSnooze   = "snooze" // an occurrence put off by some days
+)
+
+// TaskCompletionInput is one completion of a task: the day it was done, what
+// it cost, and the maintenance or repair record logged for it, if any. Action
+// is TaskActionComplete when empty.
+type TaskCompletionInput struct {
+	Action        string
+	Date          string
+	Cost          float64
+	Notes         string
+	MaintenanceID *uint
+	RepairID      *uint
+}
+
+// recordCompletion adds a completion of the task, as it was before being
+// completed, to its history. reading is what the task's meter read then.
+func recordCompletion(tx *gorm.DB, before *models.Task, input TaskCompletionInput, reading *float64) error {
+	action := input.Action
+	if action == "" {
+		action = TaskActionComplete
+	}
+	completion := &models.TaskCompletion{
+		TaskID:                    before.ID,
+		Action:                    action,
+		CompletionDate:            input.Date,
+		DueDate:                   before.DueDate,
+		CompletedByID:             actorUserID(tx),
+		Cost:                      input.Cost,
+		Notes:                     input.Notes,
+		MaintenanceID:             input.MaintenanceID,
+		RepairID:                  input.RepairID,
+		PreviousLastCompletedAt:   before.LastCompletedAt,
+		PreviousChecked:           before.Checked,
+		PreviousSnoozedFrom:       before.SnoozedFrom,
+		MeterReading:              reading,
+		PreviousMeterDueAt:        before.MeterDueAt,
+		AssigneeID:                before.AssigneeID,
+		PreviousLastCompletedByID: before.LastCompletedByID,
+	}
+	return createAudited(tx, AuditTaskCompletion, completion)
+}
+
+// forgetLatestCompletion drops a task's latest completion from its history,
+// if it has any.
+func forgetLatestCompletion(tx *gorm.DB, taskID uint) error {
+	var latest models.TaskCompletion
+	err := tx.Where("task_id = ?", taskID).Order("id DESC").First(&latest).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return deleteAudited[models.TaskCompletion](tx, AuditTaskCompletion, latest.ID)
+}
+
+// purgeTaskCompletions deletes the history of tasks purged for good.
+func purgeTaskCompletions(tx *gorm.DB, kind string, ids []uint) error {
+	if kind != TrashTask {
+		return nil
+	}
+	return tx.Where("task_id IN ?", ids).Delete(&models.TaskCompletion{}).Error
+}
+
+var taskCompletionListSpec = listSpec{
+	sorts: map[string]string{
+		"completionDate": "completion_date",
+		"id":             "id",
+		"cost":           "cost",
+	},
+	defaultSort:  "completionDate",
+	defaultOrder: "desc",
+	dateColumn:   "completion_date",
+	costColumn:   "cost",
+}
+
+// ListTaskCompletions returns a page of a task's completions, newest first by
+// default.
+func ListTaskCompletions(db *gorm.DB, taskID uint, opts ListOptions) (*Page[models.TaskCompletion], error) {
+	if _, err := GetTask(db, taskID); err != nil {
+		return nil, err
+	}
+	query := db.Model(&models.TaskCompletion{}).Where("task_id = ?", taskID)
+	page, err := paginate[models.TaskCompletion](query, taskCompletionListSpec, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := nameCompletionUsers(db, page.Items); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// UndoTaskCompletion removes a completion, skip or snooze from a task's
+// history and moves the maintenance or repair record logged for it to the
+// trash. Undoing the latest entry also puts the task's due date, last
+// completion, checked state and meter threshold back as they were; an earlier
+// entry is only taken out of the history.
+func UndoTaskCompletion(db *gorm.DB, taskID, completionID uint) (*models.Task, error) {
+	var task *models.Task
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var completion models.TaskCompletion
+		if err := tx.Where("id = ? AND task_id = ?", completionID, taskID).First(&completion).Error; err != nil {
+			return err
+		}
+		var err error
+		task, err = GetTask(tx, taskID)
+		if err != nil {
+			return err
+		}
+
+		var next models.TaskCompletion
+		err = tx.Where("task_id = ? AND id > ?", taskID, completionID).Order("id ASC").First(&next).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			before := *task
+			task.DueDate = completion.DueDate
+			task.LastCompletedAt = completion.PreviousLastCompletedAt
+			task.LastCompletedByID = completion.PreviousLastCompletedByID
+			if err := nameCompleter(tx, task); err != nil {
+				return err
+			}
+			task.Checked = completion.PreviousChecked
+			task.SnoozedFrom = completion.PreviousSnoozedFrom
+			if task.MeterID != nil {
+				task.MeterDueAt = completion.PreviousMeterDueAt
+			}
+			if err := saveAudited(tx, TrashTask, &before, task); err != nil {
+				return err
+			}
+		case err != nil:
+			return err
+		default:
+			// The next completion now follows the one before this.
+			err := tx.Model(&next).Updates(map[string]any{
+				"previous_last_completed_at":    completion.PreviousLastCompletedAt,
+				"previous_last_completed_by_id": completion.PreviousLastCompletedByID,
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		if completion.MaintenanceID != nil {
+			if err := DeleteMaintenance(tx, *completion.MaintenanceID); err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+		}
+		if completion.RepairID != nil {
+			if err := DeleteRepair(tx, *completion.RepairID); err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+		}
+		return deleteAudited[models.TaskCompletion](tx, AuditTaskCompletion, completionID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+// TaskCompletionStats summarizes a task's completion history.
+type TaskCompletionStats struct {
+	Completions    int     `json:"completions"`
+	FirstCompleted *string `json:"firstCompleted"`
+	LastCompleted  *string `json:"lastCompleted"`
+	TotalCost      float64 `json:"totalCost"`
+	// AverageIntervalDays is the mean number of days between consecutive
+	// completions; null with fewer than two.
+	AverageIntervalDays *float64 `json:"averageIntervalDays"`
+	// OnTime counts completions done on or before the day they were due, out
+	// of the Scheduled completions that had a due date. OnTimeRate is their
+	// ratio, null when none were scheduled.
+	OnTime     int      `json:"onTime"`
+	Scheduled  int      `json:"scheduled"`
+	OnTimeRate *float64 `json:"onTimeRate"`
+}
+
+// GetTaskCompletionStats returns the completion stats of a task. Skipped and
+// snoozed occurrences are left out.
+func GetTaskCompletionStats(db *gorm.DB, taskID uint) (*TaskCompletionStats, error) {
+	if _, err := GetTask(db, taskID); err != nil {
+		return nil, err
+	}
+	var completions []models.TaskCompletion
+	if err := db.Where("task_id = ? AND action = ?", taskID, TaskActionComplete).Find(&completions).Error; err != nil {
+		return nil, err
+	}
+
+	stats := &TaskCompletionStats{Completions: len(completions)}
+	var days []time.Time
+	for _, c := range completions {
+		stats.TotalCost += c.Cost
+		if c.DueDate != nil && *c.DueDate != "" {
+			stats.Scheduled++
+			// Both are YYYY-MM-DD, so they compare as strings.
+			if c.CompletionDate <= *c.DueDate {
+				stats.OnTime++
+			}
+		}
+		if day, err := time.Parse("2006-01-02", c.CompletionDate); err == nil {
+			days = append(days, day)
+		}
+	}
+	if stats.Scheduled > 0 {
+		rate := float64(stats.OnTime) / float64(stats.Scheduled)
+		stats.OnTimeRate = &rate
+	}
+	if len(days) > 0 {
+		sort.Slice(days, func(i, j int) bool { return days[i].Before(days[j]) })
+		first, last := days[0].Format("2006-01-02"), days[len(days)-1].Format("2006-01-02")
+		stats.FirstCompleted, stats.LastCompleted = &first, &last
+	}
+	if len(days) > 1 {
+		average := days[len(days)-1].Sub(days[0]).Hours() / 24 / float64(len(days)-1)
+		stats.AverageIntervalDays = &average
+	}
+	return stats, nil
+}
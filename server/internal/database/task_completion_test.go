@@ -0,0 +1,174 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/masoncfrancis/homelogger/server/internal/models"
+)
+
+func TestTaskCompletionHistoryAndUndo(t *testing.T) {
+	db := TestDB(t)
+	due := "2026-01-01"
+	task, err := AddTask(db, &models.Task{Label: "Replace HVAC filter", DueDate: &due, IsRecurring: true, RecurrenceInterval: 3, RecurrenceUnit: "months", RecurrenceMode: "due_date"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user, err := CreateUser(db, "alex", "", "correct-horse", models.RoleMember)
+	if err != nil {
+		t.Fatal(err)
+	}
+	alex := db.WithContext(WithActorUser(context.Background(), user))
+	for _, day := range []string{"2025-12-30", "2026-04-10", "2026-07-01"} {
+		if _, err := CompleteTaskWith(alex, task.ID, TaskCompletionInput{Date: day, Cost: 25}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	page, err := ListTaskCompletions(db, task.ID, ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 3 || page.Items[0].CompletionDate != "2026-07-01" || page.Items[0].CompletedBy != "alex" {
+		t.Fatalf("expected three completions by alex, newest first, got %+v", page.Items)
+	}
+
+	stats, err := GetTaskCompletionStats(db, task.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Due 2026-01-01, 04-01 and 07-01: the second was late.
+	if stats.Completions != 3 || stats.TotalCost != 75 || stats.OnTime != 2 || stats.Scheduled != 3 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+	if stats.AverageIntervalDays == nil || *stats.AverageIntervalDays != 91.5 {
+		t.Fatalf("expected a 91.5 day average, got %v", stats.AverageIntervalDays)
+	}
+
+	// Undoing an earlier completion leaves the schedule alone.
+	undone, err := UndoTaskCompletion(db, task.ID, page.Items[1].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *undone.DueDate != "2026-10-01" {
+		t.Fatalf("expected the due date to stay 2026-10-01, got %s", *undone.DueDate)
+	}
+	// Undoing the latest one puts the task back as it was before it.
+	undone, err = UndoTaskCompletion(db, task.ID, page.Items[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *undone.DueDate != "2026-07-01" || *undone.LastCompletedAt != "2025-12-30" {
+		t.Fatalf("expected due 2026-07-01 and last completed 2025-12-30, got %s and %s", *undone.DueDate, *undone.LastCompletedAt)
+	}
+	if page, _ := ListTaskCompletions(db, task.ID, ListOptions{}); page.Total != 1 {
+		t.Fatalf("expected one completion left, got %d", page.Total)
+	}
+}
+
+func TestTaskCompletionsSurviveBackup(t *testing.T) {
+	db := TestDB(t)
+	task, err := AddTask(db, &models.Task{Label: "Flush water heater"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CompleteTaskWith(db, task.ID, TaskCompletionInput{Date: "2026-02-01", Notes: "Lots of sediment"}); err != nil {
+		t.Fatal(err)
+	}
+
+	payload, err := ExportToJSON(db, "sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A completion of a task that is not in the backup is dropped on import.
+	payload.Entities.TaskCompletions = append(payload.Entities.TaskCompletions, models.TaskCompletion{ID: 99, TaskID: 42, CompletionDate: "2026-01-01"})
+	if _, err := ImportFromJSON(db, payload, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	page, err := ListTaskCompletions(db, task.ID, ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 1 || page.Items[0].Notes != "Lots of sediment" {
+		t.Fatalf("expected the completion to come back, got %+v", page.Items)
+	}
+	var all int64
+	db.Model(&models.TaskCompletion{}).Count(&all)
+	if all != 1 {
+		t.Fatalf("expected the orphaned completion to be dropped, got %d rows", all)
+	}
+}
+
+func TestSkipSnoozeAndUndoLastAdvance(t *testing.T) {
+	db := TestDB(t)
+	due := "2026-04-01"
+	task, err := AddTask(db, &models.Task{Label: "Check sump pump", DueDate: &due, RRule: "FREQ=MONTHLY", RecurrenceMode: "due_date"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	task, err = SnoozeTask(db, task.ID, 10, "2026-03-30")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *task.DueDate != "2026-04-11" || task.SnoozedFrom == nil || *task.SnoozedFrom != "2026-04-01" {
+		t.Fatalf("expected the task snoozed to 2026-04-11, got %+v", task)
+	}
+	// The series still runs from the first of the month.
+	task, err = CompleteTask(db, task.ID, "2026-04-10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *task.DueDate != "2026-05-01" || task.SnoozedFrom != nil {
+		t.Fatalf("expected the next occurrence on 2026-05-01, got %+v", task)
+	}
+	task, err = SkipTaskOccurrence(db, task.ID, "2026-04-20")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *task.DueDate != "2026-06-01" || *task.LastCompletedAt != "2026-04-10" {
+		t.Fatalf("expected the skip to move on to 2026-06-01 only, got %+v", task)
+	}
+
+	page, err := ListTaskCompletions(db, task.ID, ListOptions{Sort: "id"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actions []string
+	for _, c := range page.Items {
+		actions = append(actions, c.Action)
+	}
+	if len(actions) != 3 || actions[0] != TaskActionSkip || actions[1] != TaskActionComplete || actions[2] != TaskActionSnooze {
+		t.Fatalf("expected skip, complete and snooze in the history, got %v", actions)
+	}
+	if stats, _ := GetTaskCompletionStats(db, task.ID); stats.Completions != 1 {
+		t.Fatalf("expected skips and snoozes left out of the stats, got %d completions", stats.Completions)
+	}
+
+	// Uncompleting an open recurring task steps back one advance at a time.
+	task, err = UncompleteTask(db, task.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *task.DueDate != "2026-05-01" {
+		t.Fatalf("expected the skip undone, got due %s", *task.DueDate)
+	}
+	task, err = UncompleteTask(db, task.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *task.DueDate != "2026-04-11" || task.SnoozedFrom == nil || *task.SnoozedFrom != "2026-04-01" {
+		t.Fatalf("expected the completion undone back to the snoozed occurrence, got %+v", task)
+	}
+
+	oneOff, err := AddTask(db, &models.Task{Label: "Replace mailbox"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SkipTaskOccurrence(db, oneOff.ID, "2026-04-01"); !errors.Is(err, ErrTaskNotRecurring) {
+		t.Fatalf("expected ErrTaskNotRecurring, got %v", err)
+	}
+}
@@ -0,0 +1,278 @@
+package database
+
+import (
+	"errors"
+
+	"github.com/masoncfrancis/homelogger/server/internal/models"
+	"gorm.io/gorm"
+)
+
+// AuditTaskDependency is the audited entity type of a task dependency.
+const AuditTaskDependency = "taskDependency"
+
+// ErrTaskBlocked is returned when completing a task that waits on an open one.
+var ErrTaskBlocked = errors.New("the task is waiting on tasks that are still open")
+
+// ErrTaskHasOpenSubtasks is returned when completing a task with open steps.
+var ErrTaskHasOpenSubtasks = errors.New("the task has subtasks that are still open")
+
+// openBlockersQuery selects the IDs of tasks waiting on an open, live task.
+const openBlockersQuery = "SELECT d.task_id FROM task_dependencies d JOIN tasks b ON b.id = d.blocker_id WHERE b.checked = ? AND b.deleted_at IS NULL"
+
+// unblockedTasks leaves out tasks waiting on an open task. A blocker in the
+// trash no longer blocks.
+func unblockedTasks(db *gorm.DB) *gorm.DB {
+	return db.Where("id NOT IN ("+openBlockersQuery+")", false)
+}
+
+// repeats reports whether a task comes back after it is completed, by date or
+// by usage, and so is never finished for good.
+func repeats(task *models.Task) bool {
+	return task.IsRecurring || task.RRule != "" || task.MeterID != nil && task.MeterInterval > 0
+}
+
+// checkTaskLinks validates a task's parent, and that a task that other tasks
+// wait on or that has steps of its own stays what they need. A step with no
+// property of its own is filed under its parent's.
+func checkTaskLinks(db *gorm.DB, task *models.Task) error {
+	if task.ID != 0 && repeats(task) {
+		var blocking int64
+		if err := db.Model(&models.TaskDependency{}).Where("blocker_id = ?", task.ID).Count(&blocking).Error; err != nil {
+			return err
+		}
+		if blocking > 0 {
+			return invalidField("isRecurring", "a task other tasks wait on cannot recur")
+		}
+	}
+	if task.ParentID == nil {
+		return nil
+	}
+	if task.ID != 0 && *task.ParentID == task.ID {
+		return invalidField("parentId", "a task cannot be its own subtask")
+	}
+	parent, err := GetTask(db, *task.ParentID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return invalidField("parentId", "task %d does not exist", *task.ParentID)
+	}
+	if err != nil {
+		return err
+	}
+	if parent.ParentID != nil {
+		return invalidField("parentId", "task %d is itself a subtask", parent.ID)
+	}
+	if task.PropertyID == 0 && task.ApplianceID == nil && task.SpaceID == nil && task.LocationID == nil {
+		task.PropertyID = parent.PropertyID
+	}
+	if task.PropertyID != parent.PropertyID {
+		return invalidField("parentId", "task %d belongs to another property", parent.ID)
+	}
+	if repeats(task) {
+		return invalidField("isRecurring", "a subtask cannot recur")
+	}
+	if task.ID != 0 {
+		var steps int64
+		if err := db.Model(&models.Task{}).Where("parent_id = ?", task.ID).Count(&steps).Error; err != nil {
+			return err
+		}
+		if steps > 0 {
+			return invalidField("parentId", "a task with subtasks cannot be a subtask")
+		}
+	}
+	return nil
+}
+
+// checkCompletable refuses to complete a task waiting on an open task or with
+// open steps.
+func checkCompletable(tx *gorm.DB, task *models.Task) error {
+	var blocked int64
+	if err := tx.Model(&models.Task{}).Where("id = ? AND id IN ("+openBlockersQuery+")", task.ID, false).Count(&blocked).Error; err != nil {
+		return err
+	}
+	if blocked > 0 {
+		return ErrTaskBlocked
+	}
+	var open int64
+	if err := tx.Model(&models.Task{}).Where("parent_id = ? AND checked = ?", task.ID, false).Count(&open).Error; err != nil {
+		return err
+	}
+	if open > 0 {
+		return ErrTaskHasOpenSubtasks
+	}
+	return nil
+}
+
+// reopenSubtasks opens the completed steps of a recurring task again, for its
+// next occurrence.
+func reopenSubtasks(tx *gorm.DB, parentID uint) error {
+	var steps []models.Task
+	if err := tx.Where("parent_id = ? AND checked = ?", parentID, true).Find(&steps).Error; err != nil {
+		return err
+	}
+	for i := range steps {
+		before := steps[i]
+		steps[i].Checked = false
+		if err := saveAudited(tx, TrashTask, &before, &steps[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TaskRollup sums up the steps of a task.
+type TaskRollup struct {
+	Subtasks  []models.Task `json:"subtasks"`
+	Total     int           `json:"total"`
+	Completed int           `json:"completed"`
+	// EstimatedCost adds up the steps' estimates; Cost what was spent
+	// completing them.
+	EstimatedCost float64 `json:"estimatedCost"`
+	Cost          float64 `json:"cost"`
+}
+
+// GetTaskRollup returns a task's steps, oldest first, with how many are done
+// and what they cost.
+func GetTaskRollup(db *gorm.DB, id uint) (*TaskRollup, error) {
+	if _, err := GetTask(db, id); err != nil {
+		return nil, err
+	}
+	rollup := &TaskRollup{Subtasks: []models.Task{}}
+	if err := db.Where("parent_id = ?", id).Order("id").Find(&rollup.Subtasks).Error; err != nil {
+		return nil, err
+	}
+	if err := nameCompleters(db, rollup.Subtasks); err != nil {
+		return nil, err
+	}
+	ids := make([]uint, len(rollup.Subtasks))
+	for i, step := range rollup.Subtasks {
+		ids[i] = step.ID
+		if step.Checked {
+			rollup.Completed++
+		}
+		if step.EstimatedCost != nil {
+			rollup.EstimatedCost += *step.EstimatedCost
+		}
+	}
+	rollup.Total = len(rollup.Subtasks)
+	if len(ids) > 0 {
+		err := db.Model(&models.TaskCompletion{}).Where("task_id IN ? AND action = ?", ids, TaskActionComplete).
+			Select("COALESCE(SUM(cost), 0)").Scan(&rollup.Cost).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+	return rollup, nil
+}
+
+// TaskDependencies lists the tasks a task waits on and the tasks waiting on
+// it.
+type TaskDependencies struct {
+	BlockedBy []models.Task `json:"blockedBy"`
+	Blocking  []models.Task `json:"blocking"`
+}
+
+// GetTaskDependencies returns the live tasks a task waits on and that wait on
+// it.
+func GetTaskDependencies(db *gorm.DB, id uint) (*TaskDependencies, error) {
+	if _, err := GetTask(db, id); err != nil {
+		return nil, err
+	}
+	deps := &TaskDependencies{BlockedBy: []models.Task{}, Blocking: []models.Task{}}
+	err := db.Where("id IN (SELECT blocker_id FROM task_dependencies WHERE task_id = ?)", id).Order("id").Find(&deps.BlockedBy).Error
+	if err != nil {
+		return nil, err
+	}
+	err = db.Where("id IN (SELECT task_id FROM task_dependencies WHERE blocker_id = ?)", id).Order("id").Find(&deps.Blocking).Error
+	if err != nil {
+		return nil, err
+	}
+	if err := nameCompleters(db, deps.BlockedBy); err != nil {
+		return nil, err
+	}
+	if err := nameCompleters(db, deps.Blocking); err != nil {
+		return nil, err
+	}
+	return deps, nil
+}
+
+// AddTaskDependency makes a task wait on another until it is completed. The
+// blocker must be a one-off task of the same property, and not already wait
+// on the task, directly or through others. Adding a dependency twice is a
+// no-op.
+func AddTaskDependency(db *gorm.DB, taskID, blockerID uint) (*TaskDependencies, error) {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		task, err := GetTask(tx, taskID)
+		if err != nil {
+			return err
+		}
+		if blockerID == taskID {
+			return invalidField("blockerId", "a task cannot wait on itself")
+		}
+		blocker, err := GetTask(tx, blockerID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return invalidField("blockerId", "task %d does not exist", blockerID)
+		}
+		if err != nil {
+			return err
+		}
+		if blocker.PropertyID != task.PropertyID {
+			return invalidField("blockerId", "task %d belongs to another property", blockerID)
+		}
+		if repeats(blocker) {
+			return invalidField("blockerId", "task %d recurs, so it is never finished", blockerID)
+		}
+
+		var existing int64
+		if err := tx.Model(&models.TaskDependency{}).Where("task_id = ? AND blocker_id = ?", taskID, blockerID).Count(&existing).Error; err != nil {
+			return err
+		}
+		if existing > 0 {
+			return nil
+		}
+		// The blocker must not wait on the task, or neither could be done.
+		seen := map[uint]bool{blockerID: true}
+		for frontier := []uint{blockerID}; len(frontier) > 0; {
+			var next []uint
+			if err := tx.Model(&models.TaskDependency{}).Where("task_id IN ?", frontier).Pluck("blocker_id", &next).Error; err != nil {
+				return err
+			}
+			frontier = frontier[:0]
+			for _, id := range next {
+				if id == taskID {
+					return invalidField("blockerId", "task %d already waits on task %d", blockerID, taskID)
+				}
+				if !seen[id] {
+					seen[id] = true
+					frontier = append(frontier, id)
+				}
+			}
+		}
+		return createAudited(tx, AuditTaskDependency, &models.TaskDependency{TaskID: taskID, BlockerID: blockerID})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return GetTaskDependencies(db, taskID)
+}
+
+// RemoveTaskDependency stops a task waiting on another.
+func RemoveTaskDependency(db *gorm.DB, taskID, blockerID uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var dep models.TaskDependency
+		if err := tx.Where("task_id = ? AND blocker_id = ?", taskID, blockerID).First(&dep).Error; err != nil {
+			return err
+		}
+		return deleteAudited[models.TaskDependency](tx, AuditTaskDependency, dep.ID)
+	})
+}
+
+// purgeTaskLinks deletes the dependencies of tasks purged for good, and turns
+// their steps still in the trash into tasks of their own.
+func purgeTaskLinks(tx *gorm.DB, kind string, ids []uint) error {
+	if kind != TrashTask {
+		return nil
+	}
+	if err := tx.Where("task_id IN ? OR blocker_id IN ?", ids, ids).Delete(&models.TaskDependency{}).Error; err != nil {
+		return err
+	}
+	return tx.Model(&models.Task{}).Unscoped().Where("parent_id IN ?", ids).Update("parent_id", nil).Error
+}
@@ -0,0 +1,160 @@
+package database
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/masoncfrancis/homelogger/server/internal/models"
+)
+
+func TestSubtasksRollUp(t *testing.T) {
+	db := TestDB(t)
+	job, err := AddTask(db, &models.Task{Label: "Replace water heater"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var steps []*models.Task
+	for _, label := range []string{"Shut off gas", "Drain tank", "Install new heater"} {
+		step, err := AddTask(db, &models.Task{Label: label, ParentID: &job.ID, EstimatedCost: f64Ptr(100)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		steps = append(steps, step)
+	}
+	if steps[0].PropertyID != job.PropertyID {
+		t.Fatalf("expected the step filed under the job's property, got %d", steps[0].PropertyID)
+	}
+
+	var invalid *ValidationError
+	if _, err := AddTask(db, &models.Task{Label: "Nested", ParentID: &steps[0].ID}); !errors.As(err, &invalid) || invalid.Field != "parentId" {
+		t.Fatalf("expected a step of a step to be refused, got %v", err)
+	}
+	if _, err := AddTask(db, &models.Task{Label: "Flush", ParentID: &job.ID, IsRecurring: true, RecurrenceInterval: 1, RecurrenceUnit: "years"}); !errors.As(err, &invalid) || invalid.Field != "isRecurring" {
+		t.Fatalf("expected a recurring step to be refused, got %v", err)
+	}
+
+	if _, err := CompleteTask(db, job.ID, "2026-03-01"); !errors.Is(err, ErrTaskHasOpenSubtasks) {
+		t.Fatalf("expected ErrTaskHasOpenSubtasks, got %v", err)
+	}
+	for _, step := range steps {
+		if _, err := CompleteTaskWith(db, step.ID, TaskCompletionInput{Date: "2026-03-01", Cost: 120}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rollup, err := GetTaskRollup(db, job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rollup.Total != 3 || rollup.Completed != 3 || rollup.EstimatedCost != 300 || rollup.Cost != 360 {
+		t.Fatalf("unexpected roll-up %+v", rollup)
+	}
+	if job, err = CompleteTask(db, job.ID, "2026-03-02"); err != nil || !job.Checked {
+		t.Fatalf("expected the job completed once its steps were, got %+v, %v", job, err)
+	}
+
+	// Deleting the job takes its steps to the trash with it.
+	if err := DeleteTask(db, job.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := GetTask(db, steps[0].ID); err == nil {
+		t.Fatal("expected the step in the trash with its job")
+	}
+	if err := RestoreFromTrash(db, TrashTask, job.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := GetTask(db, steps[0].ID); err != nil {
+		t.Fatalf("expected the step restored with its job, got %v", err)
+	}
+}
+
+func TestRecurringTaskReopensSubtasks(t *testing.T) {
+	db := TestDB(t)
+	service, err := AddTask(db, &models.Task{Label: "Service furnace", DueDate: strPtr("2026-10-01"), IsRecurring: true, RecurrenceInterval: 1, RecurrenceUnit: "years", RecurrenceMode: "due_date"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	step, err := AddTask(db, &models.Task{Label: "Replace filter", ParentID: &service.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CompleteTask(db, step.ID, "2026-10-01"); err != nil {
+		t.Fatal(err)
+	}
+	service, err = CompleteTask(db, service.ID, "2026-10-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *service.DueDate != "2027-10-01" {
+		t.Fatalf("expected the service due next year, got %s", *service.DueDate)
+	}
+	if step, _ = GetTask(db, step.ID); step.Checked {
+		t.Fatal("expected the step open again for the next service")
+	}
+}
+
+func TestBlockedTasksHiddenAndRefused(t *testing.T) {
+	db := TestDB(t)
+	drain, err := AddTask(db, &models.Task{Label: "Drain tank"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	haul, err := AddTask(db, &models.Task{Label: "Haul old tank away"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	filter, err := AddTask(db, &models.Task{Label: "Replace filter", IsRecurring: true, RecurrenceInterval: 3, RecurrenceUnit: "months"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deps, err := AddTaskDependency(db, haul.ID, drain.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deps.BlockedBy) != 1 || deps.BlockedBy[0].ID != drain.ID {
+		t.Fatalf("expected haul to wait on drain, got %+v", deps)
+	}
+	var invalid *ValidationError
+	if _, err := AddTaskDependency(db, drain.ID, haul.ID); !errors.As(err, &invalid) || invalid.Field != "blockerId" {
+		t.Fatalf("expected a circular dependency to be refused, got %v", err)
+	}
+	if _, err := AddTaskDependency(db, haul.ID, filter.ID); !errors.As(err, &invalid) || invalid.Field != "blockerId" {
+		t.Fatalf("expected a recurring blocker to be refused, got %v", err)
+	}
+	drain.IsRecurring, drain.RecurrenceInterval, drain.RecurrenceUnit = true, 1, "years"
+	if _, err := UpdateTask(db, drain); !errors.As(err, &invalid) || invalid.Field != "isRecurring" {
+		t.Fatalf("expected a blocker made recurring to be refused, got %v", err)
+	}
+	drain.IsRecurring, drain.RecurrenceInterval, drain.RecurrenceUnit, drain.RRule = false, 0, "", ""
+
+	active, err := GetAllActiveTasks(db, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, task := range active {
+		if task.ID == haul.ID {
+			t.Fatal("expected the blocked task hidden from the active tasks")
+		}
+	}
+	if _, err := CompleteTask(db, haul.ID, "2026-03-01"); !errors.Is(err, ErrTaskBlocked) {
+		t.Fatalf("expected ErrTaskBlocked, got %v", err)
+	}
+
+	if _, err := CompleteTask(db, drain.ID, "2026-03-01"); err != nil {
+		t.Fatal(err)
+	}
+	active, _ = GetAllActiveTasks(db, 0)
+	if len(active) != 2 {
+		t.Fatalf("expected haul and filter active once drain is done, got %d tasks", len(active))
+	}
+	if _, err := CompleteTask(db, haul.ID, "2026-03-02"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := RemoveTaskDependency(db, haul.ID, drain.ID); err != nil {
+		t.Fatal(err)
+	}
+	if deps, _ := GetTaskDependencies(db, drain.ID); len(deps.Blocking) != 0 {
+		t.Fatalf("expected no dependents after removing the dependency, got %+v", deps.Blocking)
+	}
+}
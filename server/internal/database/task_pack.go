@@ -0,0 +1,412 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/masoncfrancis/homelogger/server/internal/models"
+	"github.com/masoncfrancis/homelogger/server/internal/rrule"
+	"gorm.io/gorm"
+)
+
+// AuditTaskPack is the audited entity type of a saved task pack.
+const AuditTaskPack = "taskPack"
+
+// TaskPackFileVersion is the version of the task pack file format written by
+// ExportTaskPacks.
+const TaskPackFileVersion = 1
+
+// ErrDuplicateTaskPack is returned when adding a pack whose key is taken.
+var ErrDuplicateTaskPack = errors.New("a task pack with that key already exists")
+
+// ErrBuiltInTaskPack is returned when deleting a built-in pack.
+var ErrBuiltInTaskPack = errors.New("built-in task packs cannot be deleted")
+
+var taskPackKeyPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// TaskPackFilter narrows ListTaskPacks to packs with a template for an
+// appliance type or a space, matched without regard to case. Zero fields do
+// not filter.
+type TaskPackFilter struct {
+	ApplianceType string
+	Space         string
+}
+
+func (f TaskPackFilter) matches(pack models.TaskPack) bool {
+	fits := func(want string, field func(models.TaskTemplate) string) bool {
+		if want == "" {
+			return true
+		}
+		return slices.ContainsFunc(pack.Tasks, func(t models.TaskTemplate) bool {
+			return strings.EqualFold(field(t), want)
+		})
+	}
+	return fits(f.ApplianceType, func(t models.TaskTemplate) string { return t.ApplianceType }) &&
+		fits(f.Space, func(t models.TaskTemplate) string { return t.Space })
+}
+
+// builtinTaskPack returns a copy of the built-in pack with the key.
+func builtinTaskPack(key string) (models.TaskPack, bool) {
+	for _, pack := range builtinTaskPacks {
+		if pack.Key == key {
+			pack.Tasks = slices.Clone(pack.Tasks)
+			pack.BuiltIn = true
+			return pack, true
+		}
+	}
+	return models.TaskPack{}, false
+}
+
+// ListTaskPacks returns the saved packs and the built-in packs not replaced
+// by one, by name.
+func ListTaskPacks(db *gorm.DB, filter TaskPackFilter) ([]models.TaskPack, error) {
+	var saved []models.TaskPack
+	if err := db.Find(&saved).Error; err != nil {
+		return nil, err
+	}
+	packs := []models.TaskPack{}
+	keys := map[string]bool{}
+	for _, pack := range saved {
+		keys[pack.Key] = true
+		if filter.matches(pack) {
+			packs = append(packs, pack)
+		}
+	}
+	for _, builtin := range builtinTaskPacks {
+		if pack, _ := builtinTaskPack(builtin.Key); !keys[pack.Key] && filter.matches(pack) {
+			packs = append(packs, pack)
+		}
+	}
+	sort.SliceStable(packs, func(i, j int) bool { return packs[i].Name < packs[j].Name })
+	return packs, nil
+}
+
+// GetTaskPack returns the pack with the key: the saved one, or else the
+// built-in one.
+func GetTaskPack(db *gorm.DB, key string) (*models.TaskPack, error) {
+	var pack models.TaskPack
+	err := db.Where("key = ?", key).First(&pack).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		if builtin, ok := builtinTaskPack(key); ok {
+			return &builtin, nil
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &pack, nil
+}
+
+// checkTaskPack validates a pack and stores its templates' recurrence rules
+// in canonical form.
+func checkTaskPack(pack *models.TaskPack) error {
+	if !taskPackKeyPattern.MatchString(pack.Key) {
+		return invalidField("key", "key must be lowercase letters, digits and dashes")
+	}
+	if strings.TrimSpace(pack.Name) == "" {
+		return invalidField("name", "name is required")
+	}
+	if len(pack.Tasks) == 0 {
+		return invalidField("tasks", "a task pack needs at least one task")
+	}
+	for i := range pack.Tasks {
+		t := &pack.Tasks[i]
+		field := fmt.Sprintf("tasks[%d]", i)
+		if strings.TrimSpace(t.Label) == "" {
+			return invalidField(field+".label", "label is required")
+		}
+		if t.DueInDays != nil && *t.DueInDays < 0 {
+			return invalidField(field+".dueInDays", "dueInDays must not be negative")
+		}
+		if t.RRule == "" && t.IsRecurring && t.RecurrenceInterval < 1 {
+			return invalidField(field+".recurrenceInterval", "recurrenceInterval must be at least 1 for a recurring task")
+		}
+		task := templateTask(*t)
+		if err := normalizeRecurrence(&task); err != nil {
+			return invalidField(field+".rrule", "%v", err)
+		}
+		t.RRule, t.IsRecurring = task.RRule, task.IsRecurring
+		t.RecurrenceInterval, t.RecurrenceUnit = task.RecurrenceInterval, task.RecurrenceUnit
+	}
+	return nil
+}
+
+// AddTaskPack saves a new pack. It may take a built-in pack's key, and
+// replaces that pack.
+func AddTaskPack(db *gorm.DB, pack *models.TaskPack) (*models.TaskPack, error) {
+	if err := checkTaskPack(pack); err != nil {
+		return nil, err
+	}
+	var taken int64
+	if err := db.Model(&models.TaskPack{}).Where("key = ?", pack.Key).Count(&taken).Error; err != nil {
+		return nil, err
+	}
+	if taken > 0 {
+		return nil, ErrDuplicateTaskPack
+	}
+	pack.ID, pack.BuiltIn = 0, false
+	if err := createAudited(db, AuditTaskPack, pack); err != nil {
+		return nil, err
+	}
+	return pack, nil
+}
+
+// UpdateTaskPack replaces the pack with the key. Updating a built-in pack
+// saves a copy in its place.
+func UpdateTaskPack(db *gorm.DB, key string, pack *models.TaskPack) (*models.TaskPack, error) {
+	existing, err := GetTaskPack(db, key)
+	if err != nil {
+		return nil, err
+	}
+	pack.Key = key
+	if err := checkTaskPack(pack); err != nil {
+		return nil, err
+	}
+	pack.BuiltIn = false
+	if existing.BuiltIn {
+		pack.ID = 0
+		err = createAudited(db, AuditTaskPack, pack)
+	} else {
+		pack.ID, pack.CreatedAt = existing.ID, existing.CreatedAt
+		err = saveAudited(db, AuditTaskPack, existing, pack)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return pack, nil
+}
+
+// DeleteTaskPack deletes a saved pack for good. A built-in pack it replaced
+// comes back.
+func DeleteTaskPack(db *gorm.DB, key string) error {
+	pack, err := GetTaskPack(db, key)
+	if err != nil {
+		return err
+	}
+	if pack.BuiltIn {
+		return ErrBuiltInTaskPack
+	}
+	return deleteAudited[models.TaskPack](db, AuditTaskPack, pack.ID)
+}
+
+// ExportTaskPacks returns the packs with the given keys, or every pack when
+// no keys are given, in the task pack file format.
+func ExportTaskPacks(db *gorm.DB, keys []string) (*models.TaskPackFile, error) {
+	var packs []models.TaskPack
+	if len(keys) == 0 {
+		var err error
+		if packs, err = ListTaskPacks(db, TaskPackFilter{}); err != nil {
+			return nil, err
+		}
+	}
+	for _, key := range keys {
+		pack, err := GetTaskPack(db, key)
+		if err != nil {
+			return nil, fmt.Errorf("task pack %q: %w", key, err)
+		}
+		packs = append(packs, *pack)
+	}
+	for i := range packs {
+		packs[i].ID, packs[i].BuiltIn = 0, false
+	}
+	return &models.TaskPackFile{Version: TaskPackFileVersion, Packs: packs}, nil
+}
+
+// ImportTaskPacks saves every pack of a task pack file, in one transaction.
+// A pack replaces the pack with the same key, if there is one.
+func ImportTaskPacks(db *gorm.DB, file *models.TaskPackFile) ([]models.TaskPack, error) {
+	if file.Version != TaskPackFileVersion {
+		return nil, invalidField("version", "unsupported task pack file version %d", file.Version)
+	}
+	seen := map[string]bool{}
+	for i := range file.Packs {
+		if err := checkTaskPack(&file.Packs[i]); err != nil {
+			var invalid *ValidationError
+			if errors.As(err, &invalid) {
+				return nil, invalidField(fmt.Sprintf("packs[%d].%s", i, invalid.Field), "%s", invalid.Message)
+			}
+			return nil, err
+		}
+		if seen[file.Packs[i].Key] {
+			return nil, invalidField(fmt.Sprintf("packs[%d].key", i), "key %q appears twice", file.Packs[i].Key)
+		}
+		seen[file.Packs[i].Key] = true
+	}
+
+	saved := make([]models.TaskPack, 0, len(file.Packs))
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for _, pack := range file.Packs {
+			var result *models.TaskPack
+			_, err := GetTaskPack(tx, pack.Key)
+			switch {
+			case err == nil:
+				result, err = UpdateTaskPack(tx, pack.Key, &pack)
+			case errors.Is(err, gorm.ErrRecordNotFound):
+				result, err = AddTaskPack(tx, &pack)
+			}
+			if err != nil {
+				return fmt.Errorf("task pack %q: %w", pack.Key, err)
+			}
+			saved = append(saved, *result)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return saved, nil
+}
+
+// TaskPackTarget is where ApplyTaskPack puts a pack's tasks: on an appliance,
+// in a space, or, with neither, across a property (0 for the default one).
+// Date is the day the pack is applied, which first due dates count from.
+type TaskPackTarget struct {
+	PropertyID  uint
+	ApplianceID uint
+	SpaceID     uint
+	UserID      string
+	Date        string
+}
+
+// ApplyTaskPack creates the tasks of a pack, in one transaction, and returns
+// them. On an appliance, templates for another appliance type are left out;
+// in a space, templates for another space. Across a property, a template for
+// an appliance type goes on each appliance of that type the property has, or
+// is left out when it has none, and a template for a space goes into that
+// space, which is created if needed.
+func ApplyTaskPack(db *gorm.DB, key string, target TaskPackTarget) ([]models.Task, error) {
+	day, err := time.Parse("2006-01-02", target.Date)
+	if err != nil {
+		return nil, invalidField("date", "date must be in YYYY-MM-DD format")
+	}
+	pack, err := GetTaskPack(db, key)
+	if err != nil {
+		return nil, err
+	}
+
+	tasks := []models.Task{}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		add := func(t models.TaskTemplate, placed models.Task) error {
+			task := templateTask(t)
+			task.PropertyID, task.UserID = placed.PropertyID, target.UserID
+			task.ApplianceID, task.SpaceID, task.SpaceType = placed.ApplianceID, placed.SpaceID, placed.SpaceType
+			if err := normalizeRecurrence(&task); err != nil {
+				return err
+			}
+			task.DueDate = firstDue(&task, t, day)
+			if _, err := AddTask(tx, &task); err != nil {
+				return err
+			}
+			tasks = append(tasks, task)
+			return nil
+		}
+
+		switch {
+		case target.ApplianceID != 0:
+			appliance, err := GetAppliance(tx, target.ApplianceID)
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return invalidField("applianceId", "appliance %d does not exist", target.ApplianceID)
+			}
+			if err != nil {
+				return err
+			}
+			for _, t := range pack.Tasks {
+				if t.ApplianceType == "" || strings.EqualFold(t.ApplianceType, appliance.Type) {
+					if err := add(t, models.Task{PropertyID: appliance.PropertyID, ApplianceID: &appliance.ID}); err != nil {
+						return err
+					}
+				}
+			}
+		case target.SpaceID != 0:
+			space, err := GetSpace(tx, target.SpaceID)
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return invalidField("spaceId", "space %d does not exist", target.SpaceID)
+			}
+			if err != nil {
+				return err
+			}
+			for _, t := range pack.Tasks {
+				if t.Space == "" || strings.EqualFold(t.Space, space.Name) {
+					if err := add(t, models.Task{PropertyID: space.PropertyID, SpaceID: &space.ID}); err != nil {
+						return err
+					}
+				}
+			}
+		default:
+			propertyID, err := resolvePropertyID(tx, target.PropertyID, nil, nil, nil)
+			if err != nil {
+				return err
+			}
+			appliances, err := GetAppliances(tx, propertyID)
+			if err != nil {
+				return err
+			}
+			for _, t := range pack.Tasks {
+				if t.ApplianceType == "" {
+					placed := models.Task{PropertyID: propertyID}
+					if t.Space != "" {
+						name := t.Space
+						placed.SpaceType = &name
+					}
+					if err := add(t, placed); err != nil {
+						return err
+					}
+					continue
+				}
+				for _, appliance := range appliances {
+					if strings.EqualFold(t.ApplianceType, appliance.Type) {
+						if err := add(t, models.Task{PropertyID: propertyID, ApplianceID: &appliance.ID}); err != nil {
+							return err
+						}
+					}
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+// templateTask is the task a template describes, not yet placed anywhere.
+func templateTask(t models.TaskTemplate) models.Task {
+	return models.Task{
+		Label:              t.Label,
+		Notes:              t.Notes,
+		Priority:           t.Priority,
+		EstimatedCost:      t.EstimatedCost,
+		IsRecurring:        t.IsRecurring,
+		RecurrenceInterval: t.RecurrenceInterval,
+		RecurrenceUnit:     t.RecurrenceUnit,
+		RecurrenceMode:     t.RecurrenceMode,
+		RRule:              t.RRule,
+	}
+}
+
+// firstDue is the first due date of a task made from a template on day.
+func firstDue(task *models.Task, t models.TaskTemplate, day time.Time) *string {
+	if t.DueInDays != nil {
+		due := day.AddDate(0, 0, *t.DueInDays).Format("2006-01-02")
+		return &due
+	}
+	if !task.IsRecurring {
+		return nil
+	}
+	rule, err := rrule.Parse(task.RRule)
+	if err != nil {
+		return nil
+	}
+	next, ok := rule.Next(day, day)
+	if !ok {
+		return nil
+	}
+	due := next.Format("2006-01-02")
+	return &due
+}
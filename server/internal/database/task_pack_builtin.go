@@ -0,0 +1,104 @@
+package database
+
+import "github.com/masoncfrancis/homelogger/server/internal/models"
+
+func costOf(amount float64) *float64 { return &amount }
+
+// builtinTaskPacks ship with the server. Appliance packs are keyed on
+// Appliance.Type; seasonal packs use the default spaces.
+var builtinTaskPacks = []models.TaskPack{
+	{
+		Key:         "water-heater",
+		Name:        "Water heater",
+		Description: "Yearly upkeep of a tank water heater.",
+		Tasks: models.TaskTemplates{
+			{Label: "Flush water heater tank", Priority: "medium", IsRecurring: true, RecurrenceInterval: 1, RecurrenceUnit: "years", ApplianceType: "Water Heater"},
+			{Label: "Test temperature and pressure relief valve", Priority: "high", IsRecurring: true, RecurrenceInterval: 1, RecurrenceUnit: "years", ApplianceType: "Water Heater"},
+			{Label: "Inspect anode rod", Priority: "low", EstimatedCost: costOf(50), IsRecurring: true, RecurrenceInterval: 3, RecurrenceUnit: "years", ApplianceType: "Water Heater"},
+		},
+	},
+	{
+		Key:         "furnace",
+		Name:        "Furnace",
+		Description: "Filters and a yearly tune-up before the heating season.",
+		Tasks: models.TaskTemplates{
+			{Label: "Replace furnace filter", Priority: "medium", EstimatedCost: costOf(15), IsRecurring: true, RecurrenceInterval: 3, RecurrenceUnit: "months", ApplianceType: "Furnace"},
+			{Label: "Furnace tune-up", Priority: "medium", EstimatedCost: costOf(150), RRule: "FREQ=YEARLY;BYMONTH=9;BYMONTHDAY=15", RecurrenceMode: "due_date", ApplianceType: "Furnace"},
+		},
+	},
+	{
+		Key:         "air-conditioner",
+		Name:        "Air conditioner",
+		Description: "Getting a central air conditioner ready for summer.",
+		Tasks: models.TaskTemplates{
+			{Label: "Clean condenser coils", Priority: "medium", RRule: "FREQ=YEARLY;BYMONTH=4;BYMONTHDAY=15", RecurrenceMode: "due_date", ApplianceType: "Air Conditioner"},
+			{Label: "Clear condensate drain line", Priority: "low", IsRecurring: true, RecurrenceInterval: 6, RecurrenceUnit: "months", ApplianceType: "Air Conditioner"},
+		},
+	},
+	{
+		Key:         "refrigerator",
+		Name:        "Refrigerator",
+		Description: "Coils and water filter.",
+		Tasks: models.TaskTemplates{
+			{Label: "Vacuum refrigerator condenser coils", Priority: "low", IsRecurring: true, RecurrenceInterval: 1, RecurrenceUnit: "years", ApplianceType: "Refrigerator"},
+			{Label: "Replace refrigerator water filter", Priority: "medium", EstimatedCost: costOf(40), IsRecurring: true, RecurrenceInterval: 6, RecurrenceUnit: "months", ApplianceType: "Refrigerator"},
+		},
+	},
+	{
+		Key:         "dryer",
+		Name:        "Clothes dryer",
+		Description: "Keeping lint out of the dryer and its vent.",
+		Tasks: models.TaskTemplates{
+			{Label: "Clean dryer vent duct", Priority: "high", IsRecurring: true, RecurrenceInterval: 1, RecurrenceUnit: "years", ApplianceType: "Dryer"},
+			{Label: "Clean lint trap housing", Priority: "low", IsRecurring: true, RecurrenceInterval: 3, RecurrenceUnit: "months", ApplianceType: "Dryer"},
+		},
+	},
+	{
+		Key:         "dishwasher",
+		Name:        "Dishwasher",
+		Description: "Filter and spray arm cleaning.",
+		Tasks: models.TaskTemplates{
+			{Label: "Clean dishwasher filter", Priority: "low", IsRecurring: true, RecurrenceInterval: 1, RecurrenceUnit: "months", ApplianceType: "Dishwasher"},
+		},
+	},
+	{
+		Key:         "water-softener",
+		Name:        "Water softener",
+		Description: "Salt and brine tank upkeep.",
+		Tasks: models.TaskTemplates{
+			{Label: "Refill softener salt", Priority: "medium", EstimatedCost: costOf(25), IsRecurring: true, RecurrenceInterval: 2, RecurrenceUnit: "months", ApplianceType: "Water Softener"},
+			{Label: "Clean brine tank", Priority: "low", IsRecurring: true, RecurrenceInterval: 1, RecurrenceUnit: "years", ApplianceType: "Water Softener"},
+		},
+	},
+	{
+		Key:         "generator",
+		Name:        "Standby generator",
+		Description: "Exercising and servicing a standby generator.",
+		Tasks: models.TaskTemplates{
+			{Label: "Run generator under load", Priority: "medium", IsRecurring: true, RecurrenceInterval: 1, RecurrenceUnit: "months", ApplianceType: "Generator"},
+			{Label: "Change generator oil and filter", Priority: "medium", EstimatedCost: costOf(60), IsRecurring: true, RecurrenceInterval: 1, RecurrenceUnit: "years", ApplianceType: "Generator"},
+		},
+	},
+	{
+		Key:         "spring-checklist",
+		Name:        "Spring checklist",
+		Description: "Opening the house up after winter.",
+		Tasks: models.TaskTemplates{
+			{Label: "Turn on outdoor faucets", Priority: "medium", RRule: "FREQ=YEARLY;BYMONTH=4;BYMONTHDAY=1", RecurrenceMode: "due_date", Space: "Yard"},
+			{Label: "Inspect roof and flashing", Priority: "medium", RRule: "FREQ=YEARLY;BYMONTH=4;BYMONTHDAY=15", RecurrenceMode: "due_date", Space: "BuildingExterior"},
+			{Label: "Test sump pump", Priority: "high", RRule: "FREQ=YEARLY;BYMONTH=3;BYMONTHDAY=15", RecurrenceMode: "due_date", Space: "Plumbing"},
+			{Label: "Have air conditioning serviced", Priority: "medium", EstimatedCost: costOf(120), RRule: "FREQ=YEARLY;BYMONTH=5;BYMONTHDAY=1", RecurrenceMode: "due_date", Space: "HVAC"},
+		},
+	},
+	{
+		Key:         "fall-checklist",
+		Name:        "Fall checklist",
+		Description: "Winterizing the house before the first freeze.",
+		Tasks: models.TaskTemplates{
+			{Label: "Winterize hose bibs", Priority: "high", RRule: "FREQ=YEARLY;BYMONTH=10;BYMONTHDAY=15", RecurrenceMode: "due_date", Space: "Yard"},
+			{Label: "Blow out sprinkler lines", Priority: "high", EstimatedCost: costOf(90), RRule: "FREQ=YEARLY;BYMONTH=10;BYMONTHDAY=20", RecurrenceMode: "due_date", Space: "Yard"},
+			{Label: "Clean gutters and downspouts", Priority: "medium", RRule: "FREQ=YEARLY;BYMONTH=11;BYMONTHDAY=1", RecurrenceMode: "due_date", Space: "BuildingExterior"},
+			{Label: "Test smoke and carbon monoxide detectors", Priority: "high", RRule: "FREQ=YEARLY;BYMONTH=4,10;BYDAY=2SA", RecurrenceMode: "due_date", Space: "BuildingInterior"},
+		},
+	},
+}
@@ -0,0 +1,124 @@
+package database
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/masoncfrancis/homelogger/server/internal/models"
+)
+
+func TestApplyTaskPack(t *testing.T) {
+	db := TestDB(t)
+	heater, err := AddAppliance(db, &models.Appliance{ApplianceName: "Basement heater", Type: "water heater"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dryer, err := AddAppliance(db, &models.Appliance{ApplianceName: "Dryer", Type: "Dryer"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tasks, err := ApplyTaskPack(db, "water-heater", TaskPackTarget{ApplianceID: heater.ID, Date: "2026-10-01"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 3 {
+		t.Fatalf("expected 3 tasks on the water heater, got %d", len(tasks))
+	}
+	for _, task := range tasks {
+		if task.ApplianceID == nil || *task.ApplianceID != heater.ID || !task.IsRecurring || task.DueDate == nil {
+			t.Fatalf("expected a dated recurring task on the water heater, got %+v", task)
+		}
+	}
+	if *tasks[0].DueDate != "2027-10-01" {
+		t.Fatalf("expected the yearly flush first due a year on, got %s", *tasks[0].DueDate)
+	}
+
+	tasks, err = ApplyTaskPack(db, "water-heater", TaskPackTarget{ApplianceID: dryer.ID, Date: "2026-10-01"})
+	if err != nil || len(tasks) != 0 {
+		t.Fatalf("expected no water heater tasks on a dryer, got %d, %v", len(tasks), err)
+	}
+
+	// Across the property, space tasks go into their spaces.
+	tasks, err = ApplyTaskPack(db, "fall-checklist", TaskPackTarget{Date: "2026-10-01"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 4 {
+		t.Fatalf("expected 4 fall tasks, got %d", len(tasks))
+	}
+	if tasks[0].SpaceID == nil || tasks[0].DueDate == nil || *tasks[0].DueDate != "2026-10-15" {
+		t.Fatalf("expected hose bibs in the yard due 2026-10-15, got %+v", tasks[0])
+	}
+	space, err := GetSpace(db, *tasks[0].SpaceID)
+	if err != nil || space.Name != "Yard" {
+		t.Fatalf("expected the Yard space, got %+v, %v", space, err)
+	}
+
+	if _, err := ApplyTaskPack(db, "no-such-pack", TaskPackTarget{Date: "2026-10-01"}); err == nil {
+		t.Fatal("expected an unknown pack to be refused")
+	}
+}
+
+func TestTaskPackReplaceExportAndImport(t *testing.T) {
+	db := TestDB(t)
+	var invalid *ValidationError
+	if _, err := AddTaskPack(db, &models.TaskPack{Key: "Bad Key", Name: "x", Tasks: models.TaskTemplates{{Label: "x"}}}); !errors.As(err, &invalid) || invalid.Field != "key" {
+		t.Fatalf("expected a bad key to be refused, got %v", err)
+	}
+	if _, err := AddTaskPack(db, &models.TaskPack{Key: "cabin", Name: "Cabin", Tasks: models.TaskTemplates{{Label: "Close up", RRule: "FREQ=SOMETIMES"}}}); !errors.As(err, &invalid) || invalid.Field != "tasks[0].rrule" {
+		t.Fatalf("expected a bad rrule to be refused, got %v", err)
+	}
+
+	// Saving a built-in pack's key replaces it until the saved pack is deleted.
+	week := 7
+	pack, err := UpdateTaskPack(db, "dryer", &models.TaskPack{Name: "Our dryer", Tasks: models.TaskTemplates{{Label: "Clean vent", DueInDays: &week}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pack.BuiltIn || pack.Key != "dryer" {
+		t.Fatalf("expected a saved dryer pack, got %+v", pack)
+	}
+	packs, err := ListTaskPacks(db, TaskPackFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dryers := 0
+	for _, p := range packs {
+		if p.Key == "dryer" {
+			dryers++
+		}
+	}
+	if dryers != 1 || len(packs) != len(builtinTaskPacks) {
+		t.Fatalf("expected the saved dryer pack in place of the built-in one, got %d of %d", dryers, len(packs))
+	}
+	packs, _ = ListTaskPacks(db, TaskPackFilter{Space: "yard"})
+	if len(packs) != 2 {
+		t.Fatalf("expected the spring and fall checklists for the yard, got %d", len(packs))
+	}
+
+	file, err := ExportTaskPacks(db, []string{"dryer"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := DeleteTaskPack(db, "dryer"); err != nil {
+		t.Fatal(err)
+	}
+	if pack, _ := GetTaskPack(db, "dryer"); !pack.BuiltIn {
+		t.Fatal("expected the built-in dryer pack back")
+	}
+	if err := DeleteTaskPack(db, "dryer"); !errors.Is(err, ErrBuiltInTaskPack) {
+		t.Fatalf("expected ErrBuiltInTaskPack, got %v", err)
+	}
+
+	if _, err := ImportTaskPacks(db, &models.TaskPackFile{Version: 9, Packs: file.Packs}); !errors.As(err, &invalid) || invalid.Field != "version" {
+		t.Fatalf("expected an unknown version to be refused, got %v", err)
+	}
+	if _, err := ImportTaskPacks(db, file); err != nil {
+		t.Fatal(err)
+	}
+	pack, err = GetTaskPack(db, "dryer")
+	if err != nil || pack.BuiltIn || pack.Name != "Our dryer" || *pack.Tasks[0].DueInDays != 7 {
+		t.Fatalf("expected the imported dryer pack, got %+v, %v", pack, err)
+	}
+}
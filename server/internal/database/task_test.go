@@ -1,6 +1,7 @@
 package database
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/masoncfrancis/homelogger/server/internal/models"
@@ -55,10 +56,10 @@ func TestGetTasksFilter(t *testing.T) {
 	spaceHVAC := "HVAC"
 	spacePlumbing := "Plumbing"
 
-	_, _ = AddTask(db, &models.Task{Label: "HVAC task", SpaceType: &spaceHVAC, UserID: "1"})
+	hvac, _ := AddTask(db, &models.Task{Label: "HVAC task", SpaceType: &spaceHVAC, UserID: "1"})
 	_, _ = AddTask(db, &models.Task{Label: "Plumbing task", SpaceType: &spacePlumbing, UserID: "1"})
 
-	hvacTasks, err := GetTasks(db, 0, "HVAC", false)
+	hvacTasks, err := GetTasks(db, 0, 0, *hvac.SpaceID, false)
 	if err != nil {
 		t.Fatalf(getTasksErrFmt, err)
 	}
@@ -77,7 +78,7 @@ func TestGetAllActiveTasks(t *testing.T) {
 	_, _ = AddTask(db, &models.Task{Label: "Mow lawn", SpaceType: &spaceType, UserID: "1"})
 	_, _ = AddTask(db, &models.Task{Label: "Check fence", SpaceType: &spaceType, UserID: "1"})
 
-	tasks, err := GetAllActiveTasks(db)
+	tasks, err := GetAllActiveTasks(db, 0)
 	if err != nil {
 		t.Fatalf("GetAllActiveTasks error: %v", err)
 	}
@@ -112,7 +113,7 @@ func TestCompleteNonRecurringTask(t *testing.T) {
 	}
 
 	// Should not appear in active tasks
-	active, _ := GetAllActiveTasks(db)
+	active, _ := GetAllActiveTasks(db, 0)
 	for _, t2 := range active {
 		if t2.ID == created.ID {
 			t.Fatal("completed task should not appear in active tasks")
@@ -240,3 +241,162 @@ func TestUncompleteTask(t *testing.T) {
 		t.Fatal("expected task to be unchecked after uncomplete")
 	}
 }
+
+func TestCompleteRecurringTask_RRule(t *testing.T) {
+	db := TestDB(t)
+
+	// Second Saturday of April and October.
+	due := "2026-04-11"
+	created, err := AddTask(db, &models.Task{
+		Label:          "Test smoke detectors",
+		DueDate:        &due,
+		RRule:          "rrule:freq=yearly;bymonth=4,10;byday=2sa",
+		RecurrenceMode: "due_date",
+		UserID:         "1",
+	})
+	if err != nil {
+		t.Fatalf(addTaskErrFmt, err)
+	}
+	if !created.IsRecurring || created.RRule != "FREQ=YEARLY;BYMONTH=4,10;BYDAY=2SA" || created.RecurrenceUnit != "years" {
+		t.Fatalf("expected a normalized yearly rule, got %+v", created)
+	}
+
+	completed, err := CompleteTask(db, created.ID, "2026-04-12")
+	if err != nil {
+		t.Fatalf(completeTaskErrFmt, err)
+	}
+	if completed.DueDate == nil || *completed.DueDate != "2026-10-10" {
+		t.Fatalf("expected next due date 2026-10-10, got %v", completed.DueDate)
+	}
+	completed, err = CompleteTask(db, created.ID, "2026-10-10")
+	if err != nil {
+		t.Fatalf(completeTaskErrFmt, err)
+	}
+	if *completed.DueDate != "2027-04-10" {
+		t.Fatalf("expected next due date 2027-04-10, got %s", *completed.DueDate)
+	}
+}
+
+func TestCompleteRecurringTask_RRuleCount(t *testing.T) {
+	db := TestDB(t)
+
+	created, err := AddTask(db, &models.Task{Label: "Seal deck", RRule: "FREQ=WEEKLY;COUNT=2", UserID: "1"})
+	if err != nil {
+		t.Fatalf(addTaskErrFmt, err)
+	}
+	completed, err := CompleteTask(db, created.ID, date2026Mar31)
+	if err != nil {
+		t.Fatalf(completeTaskErrFmt, err)
+	}
+	if completed.Checked || *completed.DueDate != "2026-04-07" {
+		t.Fatalf("expected the task due again 2026-04-07, got checked=%v due=%v", completed.Checked, completed.DueDate)
+	}
+	completed, err = CompleteTask(db, created.ID, "2026-04-07")
+	if err != nil {
+		t.Fatalf(completeTaskErrFmt, err)
+	}
+	if !completed.Checked {
+		t.Fatal("expected the task to be checked once its COUNT is used up")
+	}
+}
+
+func TestCompleteRecurringTask_RRuleKeepsItsStart(t *testing.T) {
+	db := TestDB(t)
+
+	// Every other month on the second Saturday, from January.
+	due := "2026-01-10"
+	created, err := AddTask(db, &models.Task{Label: "Flush water heater", DueDate: &due, RRule: "FREQ=MONTHLY;INTERVAL=2;BYDAY=2SA", UserID: "1"})
+	if err != nil {
+		t.Fatalf(addTaskErrFmt, err)
+	}
+	if created.RRuleStart == nil || *created.RRuleStart != due {
+		t.Fatalf("expected the series to start on the due date, got %v", created.RRuleStart)
+	}
+
+	// Done late, in February: the series stays on odd months.
+	completed, err := CompleteTask(db, created.ID, "2026-02-03")
+	if err != nil {
+		t.Fatalf(completeTaskErrFmt, err)
+	}
+	if *completed.DueDate != "2026-03-14" {
+		t.Fatalf("expected next due date 2026-03-14, got %s", *completed.DueDate)
+	}
+	completed, err = CompleteTask(db, created.ID, "2026-04-01")
+	if err != nil {
+		t.Fatalf(completeTaskErrFmt, err)
+	}
+	if *completed.DueDate != "2026-05-09" {
+		t.Fatalf("expected next due date 2026-05-09, got %s", *completed.DueDate)
+	}
+}
+
+func TestCompleteRecurringTask_RRuleCountFromStart(t *testing.T) {
+	db := TestDB(t)
+
+	due := "2026-01-01"
+	task, err := AddTask(db, &models.Task{Label: "Test sump pump", DueDate: &due, RRule: "FREQ=MONTHLY", RecurrenceMode: "due_date", UserID: "1"})
+	if err != nil {
+		t.Fatalf(addTaskErrFmt, err)
+	}
+	for _, day := range []string{"2026-01-01", "2026-02-01"} {
+		if task, err = CompleteTask(db, task.ID, day); err != nil {
+			t.Fatalf(completeTaskErrFmt, err)
+		}
+	}
+
+	// Completions from before the rule changed don't count towards it.
+	task.RRule = "FREQ=WEEKLY;COUNT=2"
+	if task, err = UpdateTask(db, task); err != nil {
+		t.Fatal(err)
+	}
+	if task.RRuleStart == nil || *task.RRuleStart != "2026-03-01" {
+		t.Fatalf("expected the series to start over on 2026-03-01, got %v", task.RRuleStart)
+	}
+	completed, err := CompleteTask(db, task.ID, "2026-03-01")
+	if err != nil {
+		t.Fatalf(completeTaskErrFmt, err)
+	}
+	if completed.Checked || *completed.DueDate != "2026-03-08" {
+		t.Fatalf("expected the task due again 2026-03-08, got checked=%v due=%v", completed.Checked, completed.DueDate)
+	}
+	completed, err = CompleteTask(db, task.ID, "2026-03-08")
+	if err != nil {
+		t.Fatalf(completeTaskErrFmt, err)
+	}
+	if !completed.Checked {
+		t.Fatal("expected the task to be checked once its COUNT is used up")
+	}
+}
+
+func TestAddTask_InvalidRRule(t *testing.T) {
+	db := TestDB(t)
+
+	_, err := AddTask(db, &models.Task{Label: "Never", RRule: "FREQ=HOURLY", UserID: "1"})
+	var invalid *ValidationError
+	if !errors.As(err, &invalid) || invalid.Field != "rrule" {
+		t.Fatalf("expected a validation error on rrule, got %v", err)
+	}
+}
+
+func TestMigrateTaskRecurrence(t *testing.T) {
+	db := TestDB(t)
+
+	propertyID, err := DefaultPropertyID(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacy := &models.Task{PropertyID: propertyID, Label: "Clean gutters", IsRecurring: true, RecurrenceInterval: 6, RecurrenceUnit: "months", UserID: "1"}
+	if err := db.Create(legacy).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := MigrateTaskRecurrence(db); err != nil {
+		t.Fatalf("MigrateTaskRecurrence error: %v", err)
+	}
+	got, err := GetTask(db, legacy.ID)
+	if err != nil {
+		t.Fatalf(getTaskErrFmt, err)
+	}
+	if got.RRule != "FREQ=MONTHLY;INTERVAL=6" {
+		t.Fatalf("expected FREQ=MONTHLY;INTERVAL=6, got %q", got.RRule)
+	}
+}
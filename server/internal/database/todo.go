@@ -5,11 +5,11 @@ import (
 	"gorm.io/gorm"
 )
 
-// GetTodos returns all todos
-// GetTodos returns todos filtered by optional applianceId and spaceType. Pass applianceId=0 and spaceType="" for no filter.
-func GetTodos(db *gorm.DB, applianceId uint, spaceType string) ([]models.Todo, error) {
+// GetTodos returns the todos owned by userID, filtered by optional applianceId and spaceType.
+// Pass applianceId=0 and spaceType="" for no filter.
+func GetTodos(db *gorm.DB, userID string, applianceId uint, spaceType string) ([]models.Todo, error) {
 	var todos []models.Todo
-	query := db.Model(&models.Todo{}).Where(&models.Todo{UserID: "1"})
+	query := db.Model(&models.Todo{}).Where(&models.Todo{UserID: userID})
 
 	if applianceId != 0 {
 		query = query.Where("appliance_id = ?", applianceId)
@@ -27,12 +27,9 @@ func GetTodos(db *gorm.DB, applianceId uint, spaceType string) ([]models.Todo, e
 
 // ChangeTodoChecked changes the checked status of a todo
 func ChangeTodoChecked(db *gorm.DB, id uint, checked bool) error {
-	result := db.Model(&models.Todo{}).Where("id = ?", id).Update("checked", checked)
-	if result.Error != nil {
-		return result.Error
-	}
-
-	return nil
+	return updateAudited[models.Todo](db, AuditTodo, id, func(tx *gorm.DB) error {
+		return tx.Model(&models.Todo{}).Where("id = ?", id).Update("checked", checked).Error
+	})
 }
 
 // AddTodo adds a todo and returns the created todo
@@ -46,9 +43,8 @@ func AddTodo(db *gorm.DB, label string, checked bool, userID string, applianceId
 		todo.SpaceType = &spaceType
 	}
 
-	result := db.Create(&todo)
-	if result.Error != nil {
-		return models.Todo{}, result.Error
+	if err := createAudited(db, AuditTodo, &todo); err != nil {
+		return models.Todo{}, err
 	}
 
 	return todo, nil
@@ -56,10 +52,5 @@ func AddTodo(db *gorm.DB, label string, checked bool, userID string, applianceId
 
 // DeleteTodo deletes a todo
 func DeleteTodo(db *gorm.DB, id uint) error {
-	result := db.Where("id = ?", id).Delete(&models.Todo{})
-	if result.Error != nil {
-		return result.Error
-	}
-
-	return nil
+	return deleteAudited[models.Todo](db, AuditTodo, id)
 }
@@ -25,8 +25,8 @@ func TestAddAndGetTodos(t *testing.T) {
         t.Fatalf("AddTodo for appliance failed: %v", err)
     }
 
-    // GetTodos with no filters (userID is fixed to "1" in code, but AddTodo uses provided userID)
-    todos, err := GetTodos(db, 0, "")
+    // GetTodos with no filters for user "1"
+    todos, err := GetTodos(db, "1", 0, "")
     if err != nil {
         t.Fatalf("GetTodos failed: %v", err)
     }
@@ -65,7 +65,7 @@ func TestAddGetDeleteTodo(t *testing.T) {
     }
 
     // get todos
-    todos, err := GetTodos(db, 0, "")
+    todos, err := GetTodos(db, "1", 0, "")
     if err != nil {
         t.Fatalf("GetTodos failed: %v", err)
     }
@@ -82,7 +82,7 @@ func TestAddGetDeleteTodo(t *testing.T) {
     }
 
     // verify deletion
-    todos, err = GetTodos(db, 0, "")
+    todos, err = GetTodos(db, "1", 0, "")
     if err != nil {
         t.Fatalf("GetTodos failed after delete: %v", err)
     }
@@ -0,0 +1,414 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/masoncfrancis/homelogger/server/internal/models"
+	"gorm.io/gorm"
+)
+
+// Deleted appliances, maintenance and repair records, tasks, notes and files
+// stay in their tables with deleted_at set; together they make up the trash.
+// Deleting an item also deletes its children (an appliance's records and
+// files, a record's files) with the very same deleted_at time, which is how
+// restoring or purging the item finds them again. Uploaded files stay on disk
+// until they are purged.
+
+// ErrTrashParentDeleted is returned when restoring an item whose appliance or
+// record is still in the trash.
+var ErrTrashParentDeleted = errors.New("the item this belongs to is in the trash; restore that first")
+
+// Trash item types, as used by the trash API.
+const (
+	TrashAppliance   = "appliance"
+	TrashMaintenance = "maintenance"
+	TrashRepair      = "repair"
+	TrashTask        = "task"
+	TrashNote        = "note"
+	TrashFile        = "file"
+)
+
+// TrashTypes lists the trash item types in the order ListTrash returns them
+// when deleted at the same time.
+var TrashTypes = []string{TrashAppliance, TrashMaintenance, TrashRepair, TrashTask, TrashNote, TrashFile}
+
+// purgeOrder removes children before their parents.
+var purgeOrder = []string{TrashFile, TrashNote, TrashTask, TrashRepair, TrashMaintenance, TrashAppliance}
+
+type trashKind struct {
+	table    string
+	label    string // column shown as the item's label
+	model    any    // the row type, for audit snapshots
+	children []trashLink
+}
+
+// trashLink is a child type and its column holding the parent's ID.
+type trashLink struct {
+	kind, column string
+}
+
+var trashKinds = map[string]trashKind{
+	TrashAppliance: {table: "appliances", label: "appliance_name", model: models.Appliance{}, children: []trashLink{
+		{TrashMaintenance, "appliance_id"}, {TrashRepair, "appliance_id"}, {TrashFile, "appliance_id"},
+	}},
+	TrashMaintenance: {table: "maintenances", label: "description", model: models.Maintenance{}, children: []trashLink{{TrashFile, "maintenance_id"}}},
+	TrashRepair:      {table: "repairs", label: "description", model: models.Repair{}, children: []trashLink{{TrashFile, "repair_id"}}},
+	TrashTask:        {table: "tasks", label: "label", model: models.Task{}, children: []trashLink{{TrashTask, "parent_id"}}},
+	TrashNote:        {table: "notes", label: "title", model: models.Note{}},
+	TrashFile:        {table: "saved_files", label: "original_name", model: models.SavedFile{}},
+}
+
+// trashParents returns the links pointing at kind from its parent types.
+func trashParents(kind string) map[string]string {
+	parents := map[string]string{}
+	for parent, k := range trashKinds {
+		for _, link := range k.children {
+			if link.kind == kind {
+				parents[parent] = link.column
+			}
+		}
+	}
+	return parents
+}
+
+// TrashItem is an item in the trash. Children deleted along with it are not
+// listed on their own; they are restored and purged with it.
+type TrashItem struct {
+	Type       string    `json:"type"`
+	ID         uint      `json:"id"`
+	PropertyID uint      `json:"propertyId"`
+	Label      string    `json:"label"`
+	DeletedAt  time.Time `json:"deletedAt"`
+	// Children counts the records and files deleted with the item.
+	Children int `json:"children"`
+}
+
+// trashRow is a deleted row with the columns that may point at a parent.
+type trashRow struct {
+	ID            uint
+	PropertyID    uint
+	Label         string
+	DeletedAt     time.Time
+	ApplianceID   *uint
+	MaintenanceID *uint
+	RepairID      *uint
+	ParentID      *uint
+}
+
+func (r trashRow) parentID(column string) *uint {
+	switch column {
+	case "appliance_id":
+		return r.ApplianceID
+	case "maintenance_id":
+		return r.MaintenanceID
+	case "repair_id":
+		return r.RepairID
+	case "parent_id":
+		return r.ParentID
+	}
+	return nil
+}
+
+// trashNow is the deleted_at time for a delete. It is rounded to what every
+// supported database stores, so that children compare equal to their parent.
+func trashNow() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
+// findTrashRows loads the rows of kind matching the condition, deleted or not.
+func findTrashRows(tx *gorm.DB, kind string, query string, args ...any) ([]trashRow, error) {
+	k := trashKinds[kind]
+	columns := []string{"id", "property_id", k.label + " AS label", "deleted_at"}
+	for _, column := range trashParents(kind) {
+		columns = append(columns, column)
+	}
+	var rows []trashRow
+	err := tx.Table(k.table).Select(strings.Join(columns, ", ")).Where(query, args...).Scan(&rows).Error
+	return rows, err
+}
+
+// trashRecords loads the rows of kind with the given IDs, deleted or not, as
+// pointers to their models.
+func trashRecords(tx *gorm.DB, kind string, ids []uint) ([]any, error) {
+	rows := reflect.New(reflect.SliceOf(reflect.TypeOf(trashKinds[kind].model)))
+	if err := tx.Unscoped().Where("id IN ?", ids).Order("id").Find(rows.Interface()).Error; err != nil {
+		return nil, err
+	}
+	records := make([]any, rows.Elem().Len())
+	for i := range records {
+		records[i] = rows.Elem().Index(i).Addr().Interface()
+	}
+	return records, nil
+}
+
+// auditTrash records action for each row of kind with the given IDs. Deletes
+// and purges record the rows' last values, restores their values again.
+func auditTrash(tx *gorm.DB, action, kind string, ids []uint) error {
+	records, err := trashRecords(tx, kind, ids)
+	if err != nil {
+		return err
+	}
+	for _, record := range records {
+		before, after := record, any(nil)
+		if action == AuditRestore {
+			before, after = nil, record
+		}
+		if err := audit(tx, action, kind, before, after); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// moveToTrash deletes the live items of kind matching the condition, and
+// their children, in one transaction.
+func moveToTrash(db *gorm.DB, kind string, query string, args ...any) error {
+	at := trashNow()
+	return db.Transaction(func(tx *gorm.DB) error {
+		var ids []uint
+		if err := tx.Table(trashKinds[kind].table).Where(query, args...).Where("deleted_at IS NULL").Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		return softDelete(tx, kind, ids, at)
+	})
+}
+
+func softDelete(tx *gorm.DB, kind string, ids []uint, at time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	k := trashKinds[kind]
+	// Children first: once the parents are marked they can no longer be told
+	// apart from ones deleted earlier.
+	for _, link := range k.children {
+		var childIDs []uint
+		if err := tx.Table(trashKinds[link.kind].table).Where(link.column+" IN ? AND deleted_at IS NULL", ids).Pluck("id", &childIDs).Error; err != nil {
+			return err
+		}
+		if err := softDelete(tx, link.kind, childIDs, at); err != nil {
+			return err
+		}
+	}
+	if err := auditTrash(tx, AuditDelete, kind, ids); err != nil {
+		return err
+	}
+	return tx.Table(k.table).Where("id IN ? AND deleted_at IS NULL", ids).Update("deleted_at", at).Error
+}
+
+// trashBatch returns the deleted item and everything deleted with it, by type.
+func trashBatch(tx *gorm.DB, kind string, id uint) (trashRow, map[string][]uint, error) {
+	if _, ok := trashKinds[kind]; !ok {
+		return trashRow{}, nil, fmt.Errorf("unknown trash type %q: %w", kind, gorm.ErrRecordNotFound)
+	}
+	rows, err := findTrashRows(tx, kind, "id = ? AND deleted_at IS NOT NULL", id)
+	if err != nil {
+		return trashRow{}, nil, err
+	}
+	if len(rows) == 0 {
+		return trashRow{}, nil, gorm.ErrRecordNotFound
+	}
+	item := rows[0]
+	batch := map[string][]uint{kind: {id}}
+
+	var walk func(kind string, ids []uint) error
+	walk = func(kind string, ids []uint) error {
+		for _, link := range trashKinds[kind].children {
+			children, err := findTrashRows(tx, link.kind, link.column+" IN ? AND deleted_at IS NOT NULL", ids)
+			if err != nil {
+				return err
+			}
+			var same []uint
+			for _, c := range children {
+				if c.DeletedAt.Equal(item.DeletedAt) {
+					same = append(same, c.ID)
+				}
+			}
+			if len(same) == 0 {
+				continue
+			}
+			batch[link.kind] = append(batch[link.kind], same...)
+			if err := walk(link.kind, same); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := walk(kind, []uint{id}); err != nil {
+		return trashRow{}, nil, err
+	}
+	return item, batch, nil
+}
+
+// ListTrash returns the items in the trash, most recently deleted first.
+// Pass propertyID 0 to list every property's.
+func ListTrash(db *gorm.DB, propertyID uint) (*Page[TrashItem], error) {
+	type key struct {
+		kind string
+		id   uint
+	}
+	deleted := map[key]trashRow{}
+	rowsByKind := map[string][]trashRow{}
+	for _, kind := range TrashTypes {
+		query, args := "deleted_at IS NOT NULL", []any{}
+		if propertyID != 0 {
+			query, args = query+" AND property_id = ?", append(args, propertyID)
+		}
+		rows, err := findTrashRows(db, kind, query, args...)
+		if err != nil {
+			return nil, err
+		}
+		rowsByKind[kind] = rows
+		for _, r := range rows {
+			deleted[key{kind, r.ID}] = r
+		}
+	}
+
+	// Fold children into the item they were deleted with.
+	children := map[key]int{}
+	root := func(kind string, r trashRow) key {
+		k := key{kind, r.ID}
+		for {
+			parentKey, found := key{}, false
+			for parent, column := range trashParents(k.kind) {
+				if id := deleted[k].parentID(column); id != nil {
+					if p, ok := deleted[key{parent, *id}]; ok && p.DeletedAt.Equal(r.DeletedAt) {
+						parentKey, found = key{parent, *id}, true
+						break
+					}
+				}
+			}
+			if !found {
+				return k
+			}
+			k = parentKey
+		}
+	}
+	var items []TrashItem
+	for _, kind := range TrashTypes {
+		for _, r := range rowsByKind[kind] {
+			if k := root(kind, r); k != (key{kind, r.ID}) {
+				children[k]++
+				continue
+			}
+			items = append(items, TrashItem{Type: kind, ID: r.ID, PropertyID: r.PropertyID, Label: r.Label, DeletedAt: r.DeletedAt})
+		}
+	}
+	for i := range items {
+		items[i].Children = children[key{items[i].Type, items[i].ID}]
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].DeletedAt.After(items[j].DeletedAt) })
+	if items == nil {
+		items = []TrashItem{}
+	}
+	return &Page[TrashItem]{Items: items, Total: int64(len(items))}, nil
+}
+
+// RestoreFromTrash brings back an item and everything deleted with it.
+func RestoreFromTrash(db *gorm.DB, kind string, id uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		item, batch, err := trashBatch(tx, kind, id)
+		if err != nil {
+			return err
+		}
+		for parent, column := range trashParents(kind) {
+			parentID := item.parentID(column)
+			if parentID == nil {
+				continue
+			}
+			var parentDeleted int64
+			if err := tx.Table(trashKinds[parent].table).Where("id = ? AND deleted_at IS NOT NULL", *parentID).Count(&parentDeleted).Error; err != nil {
+				return err
+			}
+			if parentDeleted > 0 {
+				return ErrTrashParentDeleted
+			}
+		}
+		for kind, ids := range batch {
+			if err := tx.Table(trashKinds[kind].table).Where("id IN ?", ids).Update("deleted_at", nil).Error; err != nil {
+				return err
+			}
+			if err := auditTrash(tx, AuditRestore, kind, ids); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// PurgeFromTrash deletes an item and everything deleted with it for good,
+// including their uploaded files.
+func PurgeFromTrash(db *gorm.DB, kind string, id uint) error {
+	return deleteWithFiles(db, func(tx *gorm.DB, trash *fileTrash) error {
+		_, batch, err := trashBatch(tx, kind, id)
+		if err != nil {
+			return err
+		}
+		for _, kind := range purgeOrder {
+			if ids := batch[kind]; len(ids) > 0 {
+				if _, err := purgeWhere(tx, trash, kind, "id IN ?", ids); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// PurgeTrash empties the trash of everything deleted before the given time,
+// or of everything when before is zero. It returns how many rows it removed.
+func PurgeTrash(db *gorm.DB, before time.Time) (int64, error) {
+	query, args := "deleted_at IS NOT NULL", []any{}
+	if !before.IsZero() {
+		query, args = query+" AND deleted_at < ?", append(args, before.UTC())
+	}
+	var purged int64
+	err := deleteWithFiles(db, func(tx *gorm.DB, trash *fileTrash) error {
+		purged = 0
+		for _, kind := range purgeOrder {
+			n, err := purgeWhere(tx, trash, kind, query, args...)
+			if err != nil {
+				return err
+			}
+			purged += n
+		}
+		return nil
+	})
+	return purged, err
+}
+
+// purgeWhere hard-deletes the rows of kind matching the condition, passing
+// uploaded files to trash.
+func purgeWhere(tx *gorm.DB, trash *fileTrash, kind string, query string, args ...any) (int64, error) {
+	var ids []uint
+	if err := tx.Table(trashKinds[kind].table).Where(query, args...).Pluck("id", &ids).Error; err != nil {
+		return 0, err
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	if err := auditTrash(tx, AuditPurge, kind, ids); err != nil {
+		return 0, err
+	}
+	if err := purgeRevisions(tx, kind, ids); err != nil {
+		return 0, err
+	}
+	if err := purgeTaskCompletions(tx, kind, ids); err != nil {
+		return 0, err
+	}
+	if err := purgeMeters(tx, kind, ids); err != nil {
+		return 0, err
+	}
+	if err := purgeTaskLinks(tx, kind, ids); err != nil {
+		return 0, err
+	}
+	if kind == TrashFile {
+		return trash.trashFilesWhere(tx, "id IN ?", ids)
+	}
+	result := tx.Exec(fmt.Sprintf("DELETE FROM %s WHERE id IN ?", trashKinds[kind].table), ids)
+	return result.RowsAffected, result.Error
+}
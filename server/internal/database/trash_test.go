@@ -0,0 +1,155 @@
+package database
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/masoncfrancis/homelogger/server/internal/models"
+)
+
+func trashItem(t *testing.T, page *Page[TrashItem], kind string, id uint) *TrashItem {
+	t.Helper()
+	for i := range page.Items {
+		if page.Items[i].Type == kind && page.Items[i].ID == id {
+			return &page.Items[i]
+		}
+	}
+	return nil
+}
+
+func TestTrashRestoreBringsBackChildren(t *testing.T) {
+	db := TestDB(t)
+	appliance, _ := applianceWithFiles(t, db, t.TempDir())
+	var maintenance models.Maintenance
+	db.Where("appliance_id = ?", appliance.ID).First(&maintenance)
+
+	// The maintenance record is deleted on its own first, then the appliance.
+	if err := DeleteMaintenance(db, maintenance.ID); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond)
+	if err := DeleteAppliance(db, appliance.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	page, err := ListTrash(db, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 2 {
+		t.Fatalf("expected the appliance and the maintenance record, got %+v", page.Items)
+	}
+	if item := trashItem(t, page, TrashAppliance, appliance.ID); item == nil || item.Label != "Furnace" || item.Children != 3 {
+		t.Fatalf("expected the appliance with its repair and two files, got %+v", item)
+	}
+	if item := trashItem(t, page, TrashMaintenance, maintenance.ID); item == nil || item.Children != 1 {
+		t.Fatalf("expected the maintenance record with its file, got %+v", item)
+	}
+
+	if err := RestoreFromTrash(db, TrashMaintenance, maintenance.ID); !errors.Is(err, ErrTrashParentDeleted) {
+		t.Fatalf("expected ErrTrashParentDeleted while the appliance is in the trash, got %v", err)
+	}
+
+	if err := RestoreFromTrash(db, TrashAppliance, appliance.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := GetAppliance(db, appliance.ID); err != nil {
+		t.Fatalf("appliance not restored: %v", err)
+	}
+	var repairs, maintenances, files int64
+	db.Model(&models.Repair{}).Count(&repairs)
+	db.Model(&models.Maintenance{}).Count(&maintenances)
+	db.Model(&models.SavedFile{}).Count(&files)
+	if repairs != 1 || maintenances != 0 || files != 2 {
+		t.Fatalf("expected the repair and two files back but not the maintenance record, got %d repairs, %d maintenance, %d files", repairs, maintenances, files)
+	}
+
+	if err := RestoreFromTrash(db, TrashMaintenance, maintenance.ID); err != nil {
+		t.Fatal(err)
+	}
+	db.Model(&models.SavedFile{}).Count(&files)
+	if files != 3 {
+		t.Fatalf("expected the maintenance file back, got %d files", files)
+	}
+	if page, _ := ListTrash(db, 0); page.Total != 0 {
+		t.Fatalf("expected an empty trash, got %+v", page.Items)
+	}
+}
+
+func TestTrashSurvivesBackupAndRestore(t *testing.T) {
+	db := TestDB(t)
+	appliance, err := AddAppliance(db, &models.Appliance{ApplianceName: "Dehumidifier"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := AddRepair(db, &models.Repair{Description: "New float switch", Date: "2026-02-03", ApplianceID: &appliance.ID, ReferenceType: "Appliance"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := DeleteAppliance(db, appliance.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	exported, err := ExportToJSON(db, dialectSQLite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Backups are stored as JSON, so restore from what was written.
+	data, err := json.Marshal(exported)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var payload models.BackupPayload
+	if err := json.Unmarshal(data, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ImportFromJSON(db, &payload, ""); err != nil {
+		t.Fatalf("ImportFromJSON error: %v", err)
+	}
+
+	page, err := ListTrash(db, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item := trashItem(t, page, TrashAppliance, appliance.ID); page.Total != 1 || item == nil || item.Children != 1 {
+		t.Fatalf("expected the appliance and its repair still in the trash, got %+v", page.Items)
+	}
+	if _, err := GetAppliance(db, appliance.ID); err == nil {
+		t.Fatal("expected the appliance to stay deleted after the restore")
+	}
+	if err := RestoreFromTrash(db, TrashAppliance, appliance.ID); err != nil {
+		t.Fatal(err)
+	}
+	var repairs int64
+	db.Model(&models.Repair{}).Where("appliance_id = ?", appliance.ID).Count(&repairs)
+	if repairs != 1 {
+		t.Fatalf("expected the repair back with the appliance, got %d", repairs)
+	}
+}
+
+func TestPurgeTrashOlderThan(t *testing.T) {
+	db := TestDB(t)
+	note, err := AddNote(db, &models.Note{Title: "Paint colours", Body: "Hallway: eggshell"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := DeleteNote(db, note.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if n, err := PurgeTrash(db, time.Now().Add(-time.Hour)); err != nil || n != 0 {
+		t.Fatalf("expected nothing older than an hour, purged %d: %v", n, err)
+	}
+	if n, err := PurgeTrash(db, time.Now().Add(time.Hour)); err != nil || n != 1 {
+		t.Fatalf("expected the note purged, purged %d: %v", n, err)
+	}
+	var notes int64
+	db.Unscoped().Model(&models.Note{}).Count(&notes)
+	if notes != 0 {
+		t.Fatalf("expected no notes left, got %d", notes)
+	}
+	if err := RestoreFromTrash(db, TrashNote, note.ID); err == nil {
+		t.Fatal("expected a purged note to be gone for good")
+	}
+}
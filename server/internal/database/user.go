@@ -0,0 +1,350 @@
+package database
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/masoncfrancis/homelogger/server/internal/models"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// MinPasswordLength is the shortest password accepted for a local account.
+const MinPasswordLength = 8
+
+// ErrInvalidCredentials is returned when a username/password pair does not match.
+var ErrInvalidCredentials = errors.New("invalid username or password")
+
+// ErrSessionExpired is returned when a session token is unknown or past its expiry.
+var ErrSessionExpired = errors.New("session expired or invalid")
+
+// ErrLastOwner is returned when a change would leave the household without an owner.
+var ErrLastOwner = errors.New("at least one owner account is required")
+
+// ErrSetupDone is returned when setting up the first account after one exists.
+var ErrSetupDone = errors.New("setup has already been completed")
+
+// setupMu keeps two setups in this process from both finding no accounts.
+var setupMu sync.Mutex
+
+// CountUsers returns the number of local accounts.
+func CountUsers(db *gorm.DB) (int64, error) {
+	var count int64
+	if err := db.Model(&models.User{}).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// CreateUser hashes the password and creates a new local account with the given role.
+func CreateUser(db *gorm.DB, username, displayName, password, role string) (*models.User, error) {
+	username = strings.TrimSpace(username)
+	if username == "" {
+		return nil, fmt.Errorf("username is required")
+	}
+	if !models.ValidRole(role) {
+		return nil, fmt.Errorf("invalid role %q", role)
+	}
+	if len(password) < MinPasswordLength {
+		return nil, fmt.Errorf("password must be at least %d characters", MinPasswordLength)
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("hash password: %w", err)
+	}
+
+	user := &models.User{
+		Username:     username,
+		DisplayName:  displayName,
+		PasswordHash: string(hash),
+		Role:         role,
+	}
+	if err := createAudited(db, AuditUser, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// SetupOwner creates the first account, as the household owner. It returns
+// ErrSetupDone once any account exists, even when several setups race: on
+// Postgres the users table is locked against other servers for the check.
+func SetupOwner(db *gorm.DB, username, displayName, password string) (*models.User, error) {
+	var user *models.User
+	err := withUsersLocked(db, func(tx *gorm.DB) error {
+		count, err := CountUsers(tx)
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrSetupDone
+		}
+		user, err = CreateUser(tx, username, displayName, password, models.RoleOwner)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// withUsersLocked runs fn in a transaction that no other account setup can
+// interleave with, in this process or, on Postgres, on another server.
+func withUsersLocked(db *gorm.DB, fn func(tx *gorm.DB) error) error {
+	setupMu.Lock()
+	defer setupMu.Unlock()
+	return db.Transaction(func(tx *gorm.DB) error {
+		if tx.Dialector.Name() == dialectPostgres {
+			if err := tx.Exec("LOCK TABLE users IN SHARE ROW EXCLUSIVE MODE").Error; err != nil {
+				return err
+			}
+		}
+		return fn(tx)
+	})
+}
+
+// GetUser returns a single user by ID.
+func GetUser(db *gorm.DB, id uint) (*models.User, error) {
+	var user models.User
+	if err := db.Where("id = ?", id).First(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// ListUsers returns every account ordered by username.
+func ListUsers(db *gorm.DB) ([]models.User, error) {
+	var users []models.User
+	if err := db.Order("username ASC").Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// UpdateUserRole changes a user's role. Demoting the last owner is refused.
+func UpdateUserRole(db *gorm.DB, id uint, role string) (*models.User, error) {
+	if !models.ValidRole(role) {
+		return nil, fmt.Errorf("invalid role %q", role)
+	}
+	user, err := GetUser(db, id)
+	if err != nil {
+		return nil, err
+	}
+	if user.Role == models.RoleOwner && role != models.RoleOwner {
+		var owners int64
+		if err := db.Model(&models.User{}).Where("role = ?", models.RoleOwner).Count(&owners).Error; err != nil {
+			return nil, err
+		}
+		if owners <= 1 {
+			return nil, ErrLastOwner
+		}
+	}
+	err = updateAudited[models.User](db, AuditUser, id, func(tx *gorm.DB) error {
+		return tx.Model(user).Update("role", role).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	user.Role = role
+	return user, nil
+}
+
+// EnsureOwner promotes the oldest account to owner when accounts exist but none
+// is an owner, e.g. after upgrading from a version without roles.
+func EnsureOwner(db *gorm.DB) error {
+	var owners int64
+	if err := db.Model(&models.User{}).Where("role = ?", models.RoleOwner).Count(&owners).Error; err != nil {
+		return err
+	}
+	if owners > 0 {
+		return nil
+	}
+	var first models.User
+	result := db.Order("id ASC").Limit(1).Find(&first)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return result.Error
+	}
+	return db.Model(&first).Update("role", models.RoleOwner).Error
+}
+
+// GetUserByUsername returns a single user by username.
+func GetUserByUsername(db *gorm.DB, username string) (*models.User, error) {
+	var user models.User
+	if err := db.Where("username = ?", strings.TrimSpace(username)).First(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// GetUserByOIDCSubject returns the user linked to an OIDC issuer and subject.
+func GetUserByOIDCSubject(db *gorm.DB, issuer, subject string) (*models.User, error) {
+	var user models.User
+	if err := db.Where("oidc_issuer = ? AND oidc_subject = ?", issuer, subject).First(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// LinkOIDCSubject links an existing account to an OIDC issuer and subject.
+func LinkOIDCSubject(db *gorm.DB, userID uint, issuer, subject string) error {
+	return updateAudited[models.User](db, AuditUser, userID, func(tx *gorm.DB) error {
+		return tx.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+			"oidc_issuer":  issuer,
+			"oidc_subject": subject,
+		}).Error
+	})
+}
+
+// CreateOIDCUser provisions a password-less account for an OIDC identity.
+// If the preferred username is taken, a numeric suffix is added.
+func CreateOIDCUser(db *gorm.DB, issuer, subject, username, displayName, role string) (*models.User, error) {
+	if !models.ValidRole(role) {
+		return nil, fmt.Errorf("invalid role %q", role)
+	}
+	base := strings.TrimSpace(username)
+	if base == "" {
+		base = subject
+	}
+
+	candidate := base
+	for i := 2; ; i++ {
+		var count int64
+		if err := db.Unscoped().Model(&models.User{}).Where("username = ?", candidate).Count(&count).Error; err != nil {
+			return nil, err
+		}
+		if count == 0 {
+			break
+		}
+		candidate = fmt.Sprintf("%s%d", base, i)
+	}
+
+	user := &models.User{
+		Username:    candidate,
+		DisplayName: displayName,
+		Role:        role,
+		OIDCIssuer:  &issuer,
+		OIDCSubject: &subject,
+	}
+	if err := createAudited(db, AuditUser, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// ProvisionOIDCUser returns the account linked to an OIDC identity, creating
+// it with role if there is none. The first account is always made the owner;
+// like SetupOwner, the check and the insert hold the setup lock so two first
+// logins cannot both become owner or provision the same identity twice.
+func ProvisionOIDCUser(db *gorm.DB, issuer, subject, username, displayName, role string) (*models.User, error) {
+	var user *models.User
+	err := withUsersLocked(db, func(tx *gorm.DB) error {
+		existing, err := GetUserByOIDCSubject(tx, issuer, subject)
+		if err == nil {
+			user = existing
+			return nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		count, err := CountUsers(tx)
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			role = models.RoleOwner
+		}
+		user, err = CreateOIDCUser(tx, issuer, subject, username, displayName, role)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// AuthenticateUser checks a username/password pair and returns the matching user.
+func AuthenticateUser(db *gorm.DB, username, password string) (*models.User, error) {
+	user, err := GetUserByUsername(db, username)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, err
+	}
+	if user.PasswordHash == "" {
+		// SSO-only account
+		return nil, ErrInvalidCredentials
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	return user, nil
+}
+
+// newToken returns a random hex token and its SHA-256 hash.
+func newToken() (string, string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	token := hex.EncodeToString(buf)
+	return token, hashToken(token), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateSession starts a session for the user and returns the raw token.
+// The token is only returned here; the database keeps its hash.
+func CreateSession(db *gorm.DB, userID uint, ttl time.Duration) (string, *models.Session, error) {
+	token, hash, err := newToken()
+	if err != nil {
+		return "", nil, fmt.Errorf("generate token: %w", err)
+	}
+	session := &models.Session{
+		TokenHash: hash,
+		UserID:    userID,
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	if err := db.Create(session).Error; err != nil {
+		return "", nil, err
+	}
+	return token, session, nil
+}
+
+// GetSessionUser resolves a raw session token to its user.
+func GetSessionUser(db *gorm.DB, token string) (*models.User, error) {
+	var session models.Session
+	if err := db.Preload("User").Where("token_hash = ?", hashToken(token)).First(&session).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSessionExpired
+		}
+		return nil, err
+	}
+	if time.Now().After(session.ExpiresAt) {
+		_ = db.Delete(&session).Error
+		return nil, ErrSessionExpired
+	}
+	if session.User.ID == 0 {
+		return nil, ErrSessionExpired
+	}
+	return &session.User, nil
+}
+
+// DeleteSession ends the session identified by the raw token.
+func DeleteSession(db *gorm.DB, token string) error {
+	return db.Where("token_hash = ?", hashToken(token)).Delete(&models.Session{}).Error
+}
+
+// DeleteExpiredSessions removes every session past its expiry.
+func DeleteExpiredSessions(db *gorm.DB) error {
+	return db.Where("expires_at < ?", time.Now()).Delete(&models.Session{}).Error
+}
@@ -0,0 +1,227 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/masoncfrancis/homelogger/server/internal/models"
+)
+
+func TestCreateAndAuthenticateUser(t *testing.T) {
+	db := TestDB(t)
+
+	user, err := CreateUser(db, "alice", "Alice", "correct-horse", models.RoleOwner)
+	if err != nil {
+		t.Fatalf("CreateUser error: %v", err)
+	}
+	if user.PasswordHash == "" || user.PasswordHash == "correct-horse" {
+		t.Fatalf("expected password to be hashed, got %q", user.PasswordHash)
+	}
+
+	if _, err := CreateUser(db, "bob", "", "short", models.RoleMember); err == nil {
+		t.Fatal("expected error for short password")
+	}
+	if _, err := CreateUser(db, "alice", "", "another-password", models.RoleMember); err == nil {
+		t.Fatal("expected error for duplicate username")
+	}
+
+	got, err := AuthenticateUser(db, "alice", "correct-horse")
+	if err != nil {
+		t.Fatalf("AuthenticateUser error: %v", err)
+	}
+	if got.ID != user.ID {
+		t.Fatalf("expected user %d, got %d", user.ID, got.ID)
+	}
+
+	if _, err := AuthenticateUser(db, "alice", "wrong-password"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("expected ErrInvalidCredentials for wrong password, got %v", err)
+	}
+	if _, err := AuthenticateUser(db, "nobody", "correct-horse"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("expected ErrInvalidCredentials for unknown user, got %v", err)
+	}
+}
+
+func TestSetupOwnerCreatesOnlyOneOwner(t *testing.T) {
+	db := TestDB(t)
+
+	var wg sync.WaitGroup
+	errs := make([]error, 5)
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errs[i] = SetupOwner(db, fmt.Sprintf("owner-%d", i), "", "correct-horse")
+		}()
+	}
+	wg.Wait()
+
+	created := 0
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			created++
+		case !errors.Is(err, ErrSetupDone):
+			t.Fatalf("expected ErrSetupDone, got %v", err)
+		}
+	}
+	if count, _ := CountUsers(db); created != 1 || count != 1 {
+		t.Fatalf("expected exactly one owner, created %d and found %d", created, count)
+	}
+	if _, err := SetupOwner(db, "late", "", "correct-horse"); !errors.Is(err, ErrSetupDone) {
+		t.Fatalf("expected ErrSetupDone after setup, got %v", err)
+	}
+}
+
+func TestProvisionOIDCUserMakesOneOwner(t *testing.T) {
+	db := TestDB(t)
+
+	var wg sync.WaitGroup
+	users := make([]*models.User, 5)
+	errs := make([]error, 5)
+	for i := range users {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			users[i], errs[i] = ProvisionOIDCUser(db, "https://id.example", fmt.Sprintf("sub-%d", i), fmt.Sprintf("user-%d", i), "", models.RoleMember)
+		}()
+	}
+	wg.Wait()
+
+	owners := 0
+	for i, user := range users {
+		if errs[i] != nil {
+			t.Fatal(errs[i])
+		}
+		if user.Role == models.RoleOwner {
+			owners++
+		}
+	}
+	if owners != 1 {
+		t.Fatalf("expected exactly one owner, got %d", owners)
+	}
+
+	again, err := ProvisionOIDCUser(db, "https://id.example", "sub-0", "user-0", "", models.RoleMember)
+	if err != nil || again.ID != users[0].ID {
+		t.Fatalf("expected the existing account back, got %+v, %v", again, err)
+	}
+	if count, _ := CountUsers(db); count != 5 {
+		t.Fatalf("expected 5 accounts, got %d", count)
+	}
+}
+
+func TestSessionLifecycle(t *testing.T) {
+	db := TestDB(t)
+
+	user, err := CreateUser(db, "alice", "", "correct-horse", models.RoleOwner)
+	if err != nil {
+		t.Fatalf("CreateUser error: %v", err)
+	}
+
+	token, session, err := CreateSession(db, user.ID, time.Hour)
+	if err != nil {
+		t.Fatalf("CreateSession error: %v", err)
+	}
+	if session.TokenHash == token {
+		t.Fatal("expected session to store a hash, not the raw token")
+	}
+
+	got, err := GetSessionUser(db, token)
+	if err != nil {
+		t.Fatalf("GetSessionUser error: %v", err)
+	}
+	if got.Username != "alice" {
+		t.Fatalf("expected alice, got %q", got.Username)
+	}
+
+	if err := DeleteSession(db, token); err != nil {
+		t.Fatalf("DeleteSession error: %v", err)
+	}
+	if _, err := GetSessionUser(db, token); !errors.Is(err, ErrSessionExpired) {
+		t.Fatalf("expected ErrSessionExpired after logout, got %v", err)
+	}
+
+	expired, _, err := CreateSession(db, user.ID, -time.Minute)
+	if err != nil {
+		t.Fatalf("CreateSession error: %v", err)
+	}
+	if _, err := GetSessionUser(db, expired); !errors.Is(err, ErrSessionExpired) {
+		t.Fatalf("expected ErrSessionExpired for expired session, got %v", err)
+	}
+
+	// A session nobody uses again is only removed by DeleteExpiredSessions.
+	if _, _, err := CreateSession(db, user.ID, -time.Minute); err != nil {
+		t.Fatalf("CreateSession error: %v", err)
+	}
+	live, _, err := CreateSession(db, user.ID, time.Hour)
+	if err != nil {
+		t.Fatalf("CreateSession error: %v", err)
+	}
+	if err := DeleteExpiredSessions(db); err != nil {
+		t.Fatalf("DeleteExpiredSessions error: %v", err)
+	}
+	var sessions int64
+	db.Model(&models.Session{}).Count(&sessions)
+	if sessions != 1 {
+		t.Fatalf("expected only the live session left, got %d", sessions)
+	}
+	if _, err := GetSessionUser(db, live); err != nil {
+		t.Fatalf("expected the live session to survive, got %v", err)
+	}
+}
+
+func TestUpdateUserRoleKeepsAnOwner(t *testing.T) {
+	db := TestDB(t)
+
+	owner, err := CreateUser(db, "alice", "", "correct-horse", models.RoleOwner)
+	if err != nil {
+		t.Fatalf("CreateUser error: %v", err)
+	}
+	member, err := CreateUser(db, "bob", "", "correct-horse", models.RoleMember)
+	if err != nil {
+		t.Fatalf("CreateUser error: %v", err)
+	}
+
+	if _, err := CreateUser(db, "carol", "", "correct-horse", "admin"); err == nil {
+		t.Fatal("expected error for unknown role")
+	}
+	if _, err := UpdateUserRole(db, owner.ID, models.RoleViewer); !errors.Is(err, ErrLastOwner) {
+		t.Fatalf("expected ErrLastOwner demoting the only owner, got %v", err)
+	}
+
+	if _, err := UpdateUserRole(db, member.ID, models.RoleOwner); err != nil {
+		t.Fatalf("UpdateUserRole error: %v", err)
+	}
+	updated, err := UpdateUserRole(db, owner.ID, models.RoleViewer)
+	if err != nil {
+		t.Fatalf("UpdateUserRole error: %v", err)
+	}
+	if updated.Role != models.RoleViewer {
+		t.Fatalf("expected viewer, got %q", updated.Role)
+	}
+}
+
+func TestEnsureOwnerPromotesOldestUser(t *testing.T) {
+	db := TestDB(t)
+
+	first, err := CreateUser(db, "alice", "", "correct-horse", models.RoleMember)
+	if err != nil {
+		t.Fatalf("CreateUser error: %v", err)
+	}
+	if _, err := CreateUser(db, "bob", "", "correct-horse", models.RoleMember); err != nil {
+		t.Fatalf("CreateUser error: %v", err)
+	}
+
+	if err := EnsureOwner(db); err != nil {
+		t.Fatalf("EnsureOwner error: %v", err)
+	}
+	got, err := GetUser(db, first.ID)
+	if err != nil {
+		t.Fatalf("GetUser error: %v", err)
+	}
+	if got.Role != models.RoleOwner {
+		t.Fatalf("expected oldest user promoted to owner, got %q", got.Role)
+	}
+}
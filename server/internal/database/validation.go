@@ -0,0 +1,19 @@
+package database
+
+import "fmt"
+
+// ValidationError reports a record field with an invalid value, such as a
+// missing name or a reference to a space in another property. Field is the
+// JSON name of the offending input.
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+func invalidField(field, format string, args ...any) error {
+	return &ValidationError{Field: field, Message: fmt.Sprintf(format, args...)}
+}
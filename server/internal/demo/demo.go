@@ -192,14 +192,19 @@ func Seed(db *gorm.DB, demoFilePath string) error {
 
     // notes
     for i, n := range d.Notes {
-        var aid uint = 0
+        note := &models.Note{Title: n.Title, Body: n.Body}
         if n.ApplianceIndex != nil {
             idx := *n.ApplianceIndex
             if idx >= 0 && idx < len(applianceIDs) {
-                aid = applianceIDs[idx]
+                aid := applianceIDs[idx]
+                note.ApplianceID = &aid
             }
         }
-        if _, err := database.AddNote(db, n.Title, n.Body, aid, n.SpaceType); err != nil {
+        if n.SpaceType != "" {
+            spaceType := n.SpaceType
+            note.SpaceType = &spaceType
+        }
+        if _, err := database.AddNote(db, note); err != nil {
             fmt.Printf("demo: error adding note %d: %v\n", i, err)
         }
     }
@@ -299,7 +304,7 @@ func Seed(db *gorm.DB, demoFilePath string) error {
             }
         }
         if f.SpaceType != "" {
-            _ = database.AttachFileToSpace(db, created.ID, f.SpaceType)
+            _ = database.AttachFileToSpace(db, created.ID, 0, f.SpaceType)
         }
     }
 
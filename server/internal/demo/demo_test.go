@@ -41,7 +41,7 @@ func TestSeedLoadsSampleData(t *testing.T) {
     }
 
     // appliances
-    apps, err := database.GetAppliances(db)
+    apps, err := database.GetAppliances(db, 0)
     if err != nil {
         t.Fatalf("GetAppliances error: %v", err)
     }
@@ -51,7 +51,7 @@ func TestSeedLoadsSampleData(t *testing.T) {
 
     // tasks for first appliance should include a known label
     aid := apps[0].ID
-    tasks, err := database.GetTasks(db, aid, "", true)
+    tasks, err := database.GetTasks(db, 0, aid, 0, true)
     if err != nil {
         t.Fatalf("GetTasks error: %v", err)
     }
@@ -62,7 +62,7 @@ func TestSeedLoadsSampleData(t *testing.T) {
     verifySeedTaskDates(t, tasks)
 
     // notes for first appliance
-    notes, err := database.GetNotes(db, aid, "")
+    notes, err := database.GetNotes(db, 0, aid, 0)
     if err != nil {
         t.Fatalf("GetNotes error: %v", err)
     }
@@ -71,7 +71,7 @@ func TestSeedLoadsSampleData(t *testing.T) {
     }
 
     // maintenances and repairs should exist for appliance 0
-    maint, err := database.GetMaintenances(db, aid, "Appliance", "")
+    maint, err := database.GetMaintenances(db, 0, aid, "Appliance", 0)
     if err != nil {
         t.Fatalf("GetMaintenances error: %v", err)
     }
@@ -79,7 +79,7 @@ func TestSeedLoadsSampleData(t *testing.T) {
         t.Fatalf("expected maintenances for appliance %d, got 0", aid)
     }
 
-    rep, err := database.GetRepairs(db, aid, "Appliance", "")
+    rep, err := database.GetRepairs(db, 0, aid, "Appliance", 0)
     if err != nil {
         t.Fatalf("GetRepairs error: %v", err)
     }
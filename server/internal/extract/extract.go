@@ -0,0 +1,293 @@
+// Package extract pulls plain text out of uploaded documents so they can be
+// searched. Everything is pure Go: PDFs are read with ledongthuc/pdf, and
+// Office Open XML and OpenDocument files are unzipped and their XML walked.
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// ErrUnsupported is returned for files whose format has no extractor.
+var ErrUnsupported = errors.New("unsupported file type")
+
+const (
+	// MaxFileSize is the largest file extraction is attempted on.
+	MaxFileSize = 64 << 20
+	// MaxTextSize caps the stored text per file.
+	MaxTextSize = 1 << 20
+)
+
+var plainTextExts = map[string]bool{
+	".txt": true, ".text": true, ".md": true, ".markdown": true, ".csv": true,
+	".tsv": true, ".log": true, ".json": true, ".xml": true, ".yaml": true, ".yml": true,
+}
+
+// Text returns the text of the file at path. name is the original file name,
+// whose extension picks the format; files without a known extension are
+// sniffed. The result has its whitespace tidied and is at most MaxTextSize bytes.
+func Text(path, name string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if info.Size() > MaxFileSize {
+		return "", fmt.Errorf("file is larger than %d MiB", MaxFileSize>>20)
+	}
+
+	var text string
+	switch ext := strings.ToLower(filepath.Ext(name)); {
+	case ext == ".pdf":
+		text, err = pdfText(path)
+	case ext == ".docx" || ext == ".docm":
+		text, err = zipText(path, docxParts, xmlText([]string{"t"}, []string{"p", "br"}, []string{"tab"}))
+	case ext == ".pptx":
+		text, err = zipText(path, pptxParts, xmlText([]string{"t"}, []string{"p"}, nil))
+	case ext == ".xlsx" || ext == ".xlsm":
+		text, err = zipText(path, xlsxParts, xmlText([]string{"t"}, []string{"si", "is"}, nil))
+	case ext == ".odt" || ext == ".ods" || ext == ".odp":
+		text, err = zipText(path, odfParts, xmlText(nil, []string{"p", "h", "line-break"}, []string{"s", "tab"}))
+	case plainTextExts[ext]:
+		text, err = plainText(path)
+	default:
+		text, err = sniffText(path)
+	}
+	if err != nil {
+		return "", err
+	}
+	return tidy(text), nil
+}
+
+// sniffText handles files without a recognised extension by content type.
+func sniffText(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(f, head)
+	f.Close()
+
+	switch contentType := http.DetectContentType(head[:n]); {
+	case contentType == "application/pdf":
+		return pdfText(path)
+	case strings.HasPrefix(contentType, "text/plain"):
+		return plainText(path)
+	default:
+		return "", ErrUnsupported
+	}
+}
+
+func plainText(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, MaxTextSize))
+	if err != nil {
+		return "", err
+	}
+	if !utf8.Valid(data) && bytes.IndexByte(data, 0) >= 0 {
+		return "", ErrUnsupported
+	}
+	return string(data), nil
+}
+
+// pdfText extracts the text layer of a PDF. Scanned PDFs without one yield
+// no text. The PDF reader panics on some malformed files, so that is
+// reported as an error.
+func pdfText(path string) (text string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("read pdf: %v", r)
+		}
+	}()
+	f, r, err := pdf.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("read pdf: %w", err)
+	}
+	defer f.Close()
+	plain, err := r.GetPlainText()
+	if err != nil {
+		return "", fmt.Errorf("read pdf: %w", err)
+	}
+	data, err := io.ReadAll(io.LimitReader(plain, MaxTextSize))
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// zipText reads the XML parts selected by parts, in order, through walk.
+func zipText(path string, parts func([]*zip.File) []*zip.File, walk func(io.Reader, *strings.Builder) error) (string, error) {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return "", fmt.Errorf("read document: %w", err)
+	}
+	defer zr.Close()
+
+	var sb strings.Builder
+	for _, part := range parts(zr.File) {
+		rc, err := part.Open()
+		if err != nil {
+			return "", fmt.Errorf("read %s: %w", part.Name, err)
+		}
+		err = walk(io.LimitReader(rc, MaxFileSize), &sb)
+		rc.Close()
+		if err != nil {
+			return "", fmt.Errorf("read %s: %w", part.Name, err)
+		}
+		sb.WriteString("\n")
+		if sb.Len() > MaxTextSize {
+			break
+		}
+	}
+	return sb.String(), nil
+}
+
+var partNumber = regexp.MustCompile(`(\d+)\.xml$`)
+
+// numberedParts returns the files matching pattern ordered by the number in
+// their name, so slide10 follows slide9.
+func numberedParts(files []*zip.File, pattern string) []*zip.File {
+	var out []*zip.File
+	for _, f := range files {
+		if ok, _ := filepath.Match(pattern, f.Name); ok {
+			out = append(out, f)
+		}
+	}
+	num := func(name string) int {
+		m := partNumber.FindStringSubmatch(name)
+		if m == nil {
+			return 0
+		}
+		n, _ := strconv.Atoi(m[1])
+		return n
+	}
+	sort.Slice(out, func(i, j int) bool { return num(out[i].Name) < num(out[j].Name) })
+	return out
+}
+
+func namedParts(files []*zip.File, names ...string) []*zip.File {
+	var out []*zip.File
+	for _, name := range names {
+		for _, f := range files {
+			if f.Name == name {
+				out = append(out, f)
+			}
+		}
+	}
+	return out
+}
+
+func docxParts(files []*zip.File) []*zip.File {
+	return namedParts(files, "word/document.xml")
+}
+
+func pptxParts(files []*zip.File) []*zip.File {
+	return numberedParts(files, "ppt/slides/slide*.xml")
+}
+
+func xlsxParts(files []*zip.File) []*zip.File {
+	// Most cell text lives in the shared strings table; inline strings sit in the sheets.
+	return append(namedParts(files, "xl/sharedStrings.xml"), numberedParts(files, "xl/worksheets/sheet*.xml")...)
+}
+
+func odfParts(files []*zip.File) []*zip.File {
+	return namedParts(files, "content.xml")
+}
+
+// xmlText returns a walker collecting character data inside elements named in
+// textElems (any element when nil). Elements named in breakElems end a line
+// and those in spaceElems stand for a space. Names are matched without their
+// namespace prefix.
+func xmlText(textElems, breakElems, spaceElems []string) func(io.Reader, *strings.Builder) error {
+	isText := make(map[string]bool, len(textElems))
+	for _, e := range textElems {
+		isText[e] = true
+	}
+	isBreak := make(map[string]bool, len(breakElems))
+	for _, e := range breakElems {
+		isBreak[e] = true
+	}
+	isSpace := make(map[string]bool, len(spaceElems))
+	for _, e := range spaceElems {
+		isSpace[e] = true
+	}
+	return func(r io.Reader, sb *strings.Builder) error {
+		dec := xml.NewDecoder(r)
+		depth := 0
+		for {
+			tok, err := dec.Token()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			switch t := tok.(type) {
+			case xml.StartElement:
+				if isText[t.Name.Local] {
+					depth++
+				}
+				if isSpace[t.Name.Local] {
+					sb.WriteString(" ")
+				}
+			case xml.EndElement:
+				if isText[t.Name.Local] {
+					depth--
+				}
+				if isBreak[t.Name.Local] {
+					sb.WriteString("\n")
+				}
+			case xml.CharData:
+				if textElems == nil || depth > 0 {
+					sb.Write(t)
+				}
+			}
+			if sb.Len() > MaxTextSize {
+				return nil
+			}
+		}
+	}
+}
+
+var (
+	spaceRun   = regexp.MustCompile(`[ \t\r\f\v]+`)
+	newlineRun = regexp.MustCompile(`\n\s*\n\s*`)
+)
+
+// tidy collapses runs of whitespace, drops invalid UTF-8 and trims the text
+// to MaxTextSize without splitting a character.
+func tidy(text string) string {
+	text = strings.ToValidUTF8(text, "")
+	text = strings.ReplaceAll(text, "\x00", "")
+	text = spaceRun.ReplaceAllString(text, " ")
+	text = strings.ReplaceAll(text, " \n", "\n")
+	text = strings.ReplaceAll(text, "\n ", "\n")
+	text = newlineRun.ReplaceAllString(text, "\n\n")
+	text = strings.TrimSpace(text)
+	if len(text) > MaxTextSize {
+		cut := MaxTextSize
+		for cut > 0 && !utf8.RuneStart(text[cut]) {
+			cut--
+		}
+		text = text[:cut]
+	}
+	return text
+}
@@ -0,0 +1,153 @@
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeZip stores parts as a zip file under dir and returns its path.
+func writeZip(t *testing.T, dir, name string, parts map[string]string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for partName, content := range parts {
+		w, err := zw.Create(partName)
+		if err != nil {
+			t.Fatalf("zip create: %v", err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatalf("zip write: %v", err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("zip close: %v", err)
+	}
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	return path
+}
+
+// minimalPDF builds a one-page PDF showing text in Helvetica.
+func minimalPDF(text string) []byte {
+	stream := fmt.Sprintf("BT /F1 12 Tf 72 720 Td (%s) Tj ET", text)
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Contents 4 0 R /Resources << /Font << /F1 5 0 R >> >> >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(stream), stream),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+	}
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return buf.Bytes()
+}
+
+func TestTextFormats(t *testing.T) {
+	dir := t.TempDir()
+
+	// Uploads are stored under their ID, so the format comes from the original name
+	pdfPath := filepath.Join(dir, "1")
+	if err := os.WriteFile(pdfPath, minimalPDF("Compressor warranty 10 years"), 0644); err != nil {
+		t.Fatalf("write pdf: %v", err)
+	}
+	txtPath := filepath.Join(dir, "2")
+	if err := os.WriteFile(txtPath, []byte("Filter size\t16x25x1\n\n\n\nbought at the hardware store"), 0644); err != nil {
+		t.Fatalf("write txt: %v", err)
+	}
+
+	cases := []struct {
+		name string
+		path string
+		want []string
+	}{
+		{"receipt.pdf", pdfPath, []string{"Compressor warranty 10 years"}},
+		{"notes.txt", txtPath, []string{"Filter size 16x25x1\n\nbought at the hardware store"}},
+		{"manual.docx", writeZip(t, dir, "3", map[string]string{
+			"word/document.xml": `<w:document xmlns:w="w"><w:body><w:p><w:r><w:t>Installation</w:t></w:r></w:p><w:p><w:r><w:t>Clean the </w:t></w:r><w:r><w:t>condenser coils</w:t></w:r></w:p></w:body></w:document>`,
+		}), []string{"Installation\nClean the condenser coils"}},
+		{"slides.pptx", writeZip(t, dir, "4", map[string]string{
+			"ppt/slides/slide10.xml": `<p:sld xmlns:p="p" xmlns:a="a"><a:p><a:t>Last slide</a:t></a:p></p:sld>`,
+			"ppt/slides/slide2.xml":  `<p:sld xmlns:p="p" xmlns:a="a"><a:p><a:t>First slide</a:t></a:p></p:sld>`,
+		}), []string{"First slide\n\nLast slide"}},
+		{"costs.xlsx", writeZip(t, dir, "5", map[string]string{
+			"xl/sharedStrings.xml":     `<sst><si><t>Water heater</t></si><si><t>Anode rod</t></si></sst>`,
+			"xl/worksheets/sheet1.xml": `<worksheet><sheetData><row><c t="inlineStr"><is><t>Inline note</t></is></c><c><v>42</v></c></row></sheetData></worksheet>`,
+		}), []string{"Water heater", "Anode rod", "Inline note"}},
+		{"invoice.odt", writeZip(t, dir, "6", map[string]string{
+			"content.xml": `<office:document-content xmlns:office="o" xmlns:text="t"><office:body><office:text><text:h>Invoice</text:h><text:p>Sump pump<text:s/>replacement</text:p></office:text></office:body></office:document-content>`,
+		}), []string{"Invoice", "Sump pump replacement"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := Text(tc.path, tc.name)
+			if err != nil {
+				t.Fatalf("Text error: %v", err)
+			}
+			for _, want := range tc.want {
+				if !strings.Contains(got, want) {
+					t.Fatalf("expected %q in %q", want, got)
+				}
+			}
+			if strings.Contains(got, "42") {
+				t.Fatalf("expected numeric cells to be skipped, got %q", got)
+			}
+		})
+	}
+}
+
+func TestTextSniffsUnnamedFiles(t *testing.T) {
+	dir := t.TempDir()
+
+	pdfPath := filepath.Join(dir, "1")
+	if err := os.WriteFile(pdfPath, minimalPDF("Dishwasher receipt"), 0644); err != nil {
+		t.Fatalf("write pdf: %v", err)
+	}
+	if got, err := Text(pdfPath, "scan"); err != nil || !strings.Contains(got, "Dishwasher receipt") {
+		t.Fatalf("expected the PDF to be sniffed, got %q, %v", got, err)
+	}
+
+	imgPath := filepath.Join(dir, "2")
+	if err := os.WriteFile(imgPath, []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"), 0644); err != nil {
+		t.Fatalf("write png: %v", err)
+	}
+	if _, err := Text(imgPath, "photo.png"); !errors.Is(err, ErrUnsupported) {
+		t.Fatalf("expected ErrUnsupported for an image, got %v", err)
+	}
+}
+
+func TestTextRejectsBrokenDocuments(t *testing.T) {
+	dir := t.TempDir()
+
+	path := filepath.Join(dir, "1")
+	if err := os.WriteFile(path, []byte("%PDF-1.4\nnot really a pdf"), 0644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	if _, err := Text(path, "broken.pdf"); err == nil {
+		t.Fatal("expected an error for a broken PDF")
+	}
+	if _, err := Text(path, "broken.docx"); err == nil {
+		t.Fatal("expected an error for a broken docx")
+	}
+	if _, err := Text(filepath.Join(dir, "missing"), "gone.txt"); err == nil {
+		t.Fatal("expected an error for a missing file")
+	}
+}
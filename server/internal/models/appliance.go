@@ -7,6 +7,7 @@ import (
 type Appliance struct {
 	gorm.Model
 	ID            uint   `json:"id" gorm:"primaryKey"`
+	PropertyID    uint   `json:"propertyId" gorm:"not null;default:0;index"`
 	ApplianceName string `json:"applianceName" gorm:"not null"`
 	Manufacturer  string `json:"manufacturer" gorm:"not null"`
 	ModelNumber   string `json:"modelNumber" gorm:"not null"`
@@ -14,5 +15,6 @@ type Appliance struct {
 	YearPurchased string `json:"yearPurchased" gorm:"not null"`
 	PurchasePrice string `json:"purchasePrice" gorm:"not null"`
 	Location      string `json:"location" gorm:"not null"`
+	LocationID    *uint  `json:"locationId" gorm:"default:null;index"`
 	Type          string `json:"type" gorm:"not null"`
 }
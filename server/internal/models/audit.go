@@ -0,0 +1,67 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// AuditEvent is an entry in the append-only audit log: who created, changed
+// or deleted which record, and how its fields changed.
+type AuditEvent struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt  time.Time `json:"createdAt" gorm:"index"`
+	Actor      string    `json:"actor" gorm:"not null;default:'';index"`
+	Action     string    `json:"action" gorm:"not null;index"`
+	EntityType string    `json:"entityType" gorm:"not null;index:idx_audit_events_entity"`
+	EntityID   uint      `json:"entityId" gorm:"not null;default:0;index:idx_audit_events_entity"`
+	PropertyID uint      `json:"propertyId" gorm:"not null;default:0;index"`
+	// Changes maps each changed field, by its JSON name, to its old and new value.
+	Changes AuditChanges `json:"changes"`
+}
+
+// AuditChange is a field's value before and after a change. Old is null for
+// a field that did not exist before (a create), New for one that is gone (a
+// delete).
+type AuditChange struct {
+	Old any `json:"old"`
+	New any `json:"new"`
+}
+
+// AuditChanges is stored as a JSON text column.
+type AuditChanges map[string]AuditChange
+
+// GormDataType stores the changes as text on every dialect.
+func (AuditChanges) GormDataType() string {
+	return "text"
+}
+
+// Value implements driver.Valuer.
+func (c AuditChanges) Value() (driver.Value, error) {
+	if c == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan implements sql.Scanner.
+func (c *AuditChanges) Scan(value any) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*c = nil
+		return nil
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		return fmt.Errorf("cannot scan %T into AuditChanges", value)
+	}
+	return json.Unmarshal(data, c)
+}
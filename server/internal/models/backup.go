@@ -12,6 +12,9 @@ type BackupPayload struct {
 
 // Entities holds all exported database tables.
 type Entities struct {
+	Properties   []Property    `json:"properties"`
+	Spaces       []Space       `json:"spaces"`
+	Locations    []Location    `json:"locations"`
 	Appliances   []Appliance   `json:"appliances"`
 	Tasks        []Task        `json:"tasks"`
 	Maintenance  []Maintenance `json:"maintenance"`
@@ -19,6 +22,13 @@ type Entities struct {
 	SavedFiles   []SavedFile   `json:"savedFiles"`
 	Notes        []Note        `json:"notes"`
 	Todos        []Todo        `json:"todos"`
+
+	TaskCompletions  []TaskCompletion `json:"taskCompletions"`
+	Meters           []Meter          `json:"meters"`
+	MeterReadings    []MeterReading   `json:"meterReadings"`
+	TaskPacks        []TaskPack       `json:"taskPacks"`
+	TaskDependencies []TaskDependency `json:"taskDependencies"`
+	Revisions        []Revision       `json:"revisions"`
 }
 
 // ImportResult summarizes the results of an import operation.
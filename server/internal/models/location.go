@@ -0,0 +1,24 @@
+package models
+
+import (
+	"gorm.io/gorm"
+)
+
+// Location kinds, from the top of the tree down. A location's parent must be
+// of an earlier kind; locations without a parent sit directly under the property.
+const (
+	LocationFloor = "floor"
+	LocationRoom  = "room"
+	LocationZone  = "zone"
+)
+
+// Location is a node in a property's floor → room → zone tree. Appliances,
+// tasks, notes and saved files may point at a location.
+type Location struct {
+	gorm.Model
+	ID         uint   `json:"id" gorm:"primaryKey"`
+	PropertyID uint   `json:"propertyId" gorm:"not null;default:0;index"`
+	ParentID   *uint  `json:"parentId" gorm:"default:null;index"`
+	Kind       string `json:"kind" gorm:"not null"`
+	Name       string `json:"name" gorm:"not null"`
+}
@@ -7,10 +7,12 @@ import (
 type Maintenance struct {
 	gorm.Model
 	ID            uint      `json:"id" gorm:"primaryKey"`
+	PropertyID    uint      `json:"propertyId" gorm:"not null;default:0;index"`
 	Description   string    `json:"description" gorm:"not null" gorm:"default:''"`
 	Date          string    `json:"date" gorm:"not null" gorm:"default:''"`
 	Cost          float64   `json:"cost" gorm:"not null" gorm:"default:0.0"`
 	Notes         string    `json:"notes" gorm:"not null" gorm:"default:''"`
+	SpaceID       *uint     `json:"spaceId" gorm:"default:null;index"`
 	SpaceType     string    `json:"spaceType" gorm:"not null" gorm:"default:''"`
 	ReferenceType string    `json:"referenceType" gorm:"not null" gorm:"default:''"`
 	ApplianceID   *uint     `json:"applianceId" gorm:"default:null"`
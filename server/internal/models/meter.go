@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+)
+
+// Meter measures how much an appliance is used, such as a furnace's runtime
+// hours or the gallons through a water softener, so that tasks can come due
+// by usage rather than by date.
+type Meter struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+	PropertyID  uint      `json:"propertyId" gorm:"not null;default:0;index"`
+	ApplianceID uint      `json:"applianceId" gorm:"not null;index"`
+	Name        string    `json:"name" gorm:"not null"`
+	Unit        string    `json:"unit" gorm:"not null;default:''"`
+	// Reading and ReadAt are the latest reading, null before the first.
+	Reading *float64 `json:"reading" gorm:"default:null"`
+	ReadAt  *string  `json:"readAt" gorm:"default:null"`
+}
+
+// MeterReading is what a meter read on a day. Readings only go up: each one
+// is at least the reading before it.
+type MeterReading struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt  time.Time `json:"createdAt"`
+	MeterID    uint      `json:"meterId" gorm:"not null;index"`
+	Value      float64   `json:"value" gorm:"not null"`
+	ReadAt     string    `json:"readAt" gorm:"not null"`
+	RecordedBy string    `json:"recordedBy" gorm:"not null;default:''"`
+	Notes      string    `json:"notes" gorm:"not null;default:''"`
+}
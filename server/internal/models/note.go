@@ -7,8 +7,11 @@ import (
 type Note struct {
 	gorm.Model
 	ID          uint    `json:"id" gorm:"primaryKey"`
+	PropertyID  uint    `json:"propertyId" gorm:"not null;default:0;index"`
 	Title       string  `json:"title" gorm:"not null;default:''"`
 	Body        string  `json:"body" gorm:"not null;default:''"`
 	ApplianceID *uint   `json:"applianceId" gorm:"default:null"`
+	SpaceID     *uint   `json:"spaceId" gorm:"default:null;index"`
 	SpaceType   *string `json:"spaceType" gorm:"default:null"`
+	LocationID  *uint   `json:"locationId" gorm:"default:null;index"`
 }
@@ -0,0 +1,14 @@
+package models
+
+import (
+	"gorm.io/gorm"
+)
+
+// Property is a house, rental unit, cabin, etc. Every appliance, maintenance,
+// repair, task, note and saved file belongs to exactly one property.
+type Property struct {
+	gorm.Model
+	ID      uint   `json:"id" gorm:"primaryKey"`
+	Name    string `json:"name" gorm:"not null"`
+	Address string `json:"address" gorm:"not null;default:''"`
+}
@@ -0,0 +1,58 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Revision is a saved version of a note, task, maintenance or repair record.
+// Versions count up from 1 for each record.
+type Revision struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt  time.Time `json:"createdAt"`
+	EntityType string    `json:"entityType" gorm:"not null;uniqueIndex:idx_revisions_version"`
+	EntityID   uint      `json:"entityId" gorm:"not null;uniqueIndex:idx_revisions_version"`
+	Version    int       `json:"version" gorm:"not null;uniqueIndex:idx_revisions_version"`
+	Actor      string    `json:"actor" gorm:"not null;default:''"`
+	// Fields holds the record's fields, by their JSON names, as of this version.
+	Fields RevisionFields `json:"fields"`
+}
+
+// RevisionFields is stored as a JSON text column.
+type RevisionFields map[string]any
+
+// GormDataType stores the fields as text on every dialect.
+func (RevisionFields) GormDataType() string {
+	return "text"
+}
+
+// Value implements driver.Valuer.
+func (f RevisionFields) Value() (driver.Value, error) {
+	if f == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(f)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan implements sql.Scanner.
+func (f *RevisionFields) Scan(value any) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*f = nil
+		return nil
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		return fmt.Errorf("cannot scan %T into RevisionFields", value)
+	}
+	return json.Unmarshal(data, f)
+}
@@ -4,9 +4,19 @@ import (
 	"gorm.io/gorm"
 )
 
+// Text extraction states. Files uploaded before extraction existed have an
+// empty status and are treated as pending.
+const (
+	TextStatusPending     = "pending"
+	TextStatusDone        = "done"
+	TextStatusUnsupported = "unsupported"
+	TextStatusFailed      = "failed"
+)
+
 type SavedFile struct {
 	gorm.Model
 	ID            uint    `json:"id" gorm:"primaryKey"`
+	PropertyID    uint    `json:"propertyId" gorm:"not null;default:0;index"`
 	Path          string  `json:"path" gorm:"not null"`
 	OriginalName  string  `json:"originalName" gorm:"default:'';not null"`
 	Type          string  `json:"type" gorm:"default:'';not null"`
@@ -14,5 +24,10 @@ type SavedFile struct {
 	MaintenanceID *uint   `json:"maintenanceId" gorm:"default:null"`
 	RepairID      *uint   `json:"repairId" gorm:"default:null"`
 	ApplianceID   *uint   `json:"applianceId" gorm:"default:null"`
+	SpaceID       *uint   `json:"spaceId" gorm:"default:null;index"`
 	SpaceType     *string `json:"spaceType" gorm:"default:null"`
+	LocationID    *uint   `json:"locationId" gorm:"default:null;index"`
+	// TextStatus tracks background text extraction; ExtractedText feeds search.
+	TextStatus    string `json:"textStatus" gorm:"not null;default:''"`
+	ExtractedText string `json:"extractedText" gorm:"type:text;not null;default:''"`
 }
@@ -0,0 +1,15 @@
+package models
+
+import (
+	"gorm.io/gorm"
+)
+
+// Space is a named area of a property (Yard, HVAC, Garage, Pool, ...).
+// Maintenance, repairs, tasks, notes and saved files reference spaces by ID,
+// so a space can be renamed without orphaning its records.
+type Space struct {
+	gorm.Model
+	ID         uint   `json:"id" gorm:"primaryKey"`
+	PropertyID uint   `json:"propertyId" gorm:"not null;default:0;index"`
+	Name       string `json:"name" gorm:"not null"`
+}
@@ -8,6 +8,7 @@ import (
 type Task struct {
 	gorm.Model
 	ID                 uint     `json:"id" gorm:"primaryKey"`
+	PropertyID         uint     `json:"propertyId" gorm:"not null;default:0;index"`
 	Label              string   `json:"label" gorm:"not null;default:''"`
 	Notes              string   `json:"notes" gorm:"default:''"`
 	Checked            bool     `json:"checked" gorm:"default:false;not null"`
@@ -21,5 +22,39 @@ type Task struct {
 	LastCompletedAt    *string  `json:"lastCompletedAt" gorm:"default:null"`
 	UserID             string   `json:"userid" gorm:"not null;default:''"`
 	ApplianceID        *uint    `json:"applianceId" gorm:"default:null"`
+	SpaceID            *uint    `json:"spaceId" gorm:"default:null;index"`
 	SpaceType          *string  `json:"spaceType" gorm:"default:null"`
+	LocationID         *uint    `json:"locationId" gorm:"default:null;index"`
+
+	// RRule is the RFC 5545 rule a recurring task follows, such as
+	// "FREQ=YEARLY;BYMONTH=4,10;BYDAY=2SA". RecurrenceInterval and
+	// RecurrenceUnit mirror its FREQ and INTERVAL for older clients.
+	// RRuleStart is the day its series starts (DTSTART): the due date when
+	// the rule was set or changed, or else the first completion after.
+	RRule      string  `json:"rrule" gorm:"column:rrule;not null;default:''"`
+	RRuleStart *string `json:"rruleStart" gorm:"column:rrule_start;default:null"`
+
+	// MeterID makes a task come due by usage: once the meter reads
+	// MeterDueAt or more. Completing it moves MeterDueAt to the reading then
+	// plus MeterInterval; without an interval the task is done once.
+	MeterID       *uint    `json:"meterId" gorm:"default:null;index"`
+	MeterInterval float64  `json:"meterInterval" gorm:"not null;default:0"`
+	MeterDueAt    *float64 `json:"meterDueAt" gorm:"default:null"`
+
+	// SnoozedFrom is the day the current occurrence was due before it was
+	// snoozed to DueDate. The series carries on from it, not from DueDate.
+	SnoozedFrom *string `json:"snoozedFrom" gorm:"default:null"`
+
+	// ParentID makes a task one step of a larger job. Steps are one-off
+	// tasks; the job can't be completed while any is open, and a recurring
+	// job reopens them when it moves on to its next occurrence.
+	ParentID *uint `json:"parentId" gorm:"default:null;index"`
+
+	// AssigneeID is the household member the task is assigned to, null
+	// when it is anyone's. LastCompletedByID is whoever last completed it,
+	// who need not be the assignee; LastCompletedBy is their name, filled in
+	// when the task is read.
+	AssigneeID        *uint  `json:"assigneeId" gorm:"default:null;index"`
+	LastCompletedByID *uint  `json:"lastCompletedById" gorm:"default:null"`
+	LastCompletedBy   string `json:"lastCompletedBy" gorm:"-"`
 }
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+)
+
+// TaskCompletion records one entry in a task's history: a completion, or an
+// occurrence of a recurring task that was skipped or snoozed (see Action).
+// The task's due date, last completion and checked state from just before are
+// kept so the entry can be undone.
+type TaskCompletion struct {
+	ID             uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt      time.Time `json:"createdAt"`
+	TaskID         uint      `json:"taskId" gorm:"not null;index"`
+	Action         string    `json:"action" gorm:"not null;default:'complete'"`
+	CompletionDate string    `json:"completionDate" gorm:"not null"`
+	// DueDate is when the task was due at the time it was completed.
+	DueDate       *string `json:"dueDate" gorm:"default:null"`
+	CompletedByID *uint   `json:"completedById" gorm:"default:null"`
+	CompletedBy   string  `json:"completedBy" gorm:"-"`
+	Cost          float64 `json:"cost" gorm:"not null;default:0"`
+	Notes         string  `json:"notes" gorm:"not null;default:''"`
+	MaintenanceID *uint   `json:"maintenanceId" gorm:"default:null"`
+	RepairID      *uint   `json:"repairId" gorm:"default:null"`
+
+	PreviousLastCompletedAt *string `json:"previousLastCompletedAt" gorm:"default:null"`
+	PreviousChecked         bool    `json:"previousChecked" gorm:"not null;default:false"`
+	PreviousSnoozedFrom     *string `json:"previousSnoozedFrom" gorm:"default:null"`
+
+	// MeterReading is what the task's meter read when it was completed.
+	MeterReading       *float64 `json:"meterReading" gorm:"default:null"`
+	PreviousMeterDueAt *float64 `json:"previousMeterDueAt" gorm:"default:null"`
+
+	// AssigneeID is who the task was assigned to at the time; CompletedByID
+	// is who actually did it, and CompletedBy their name, filled in when the
+	// completion is read.
+	AssigneeID                *uint `json:"assigneeId" gorm:"default:null"`
+	PreviousLastCompletedByID *uint `json:"previousLastCompletedById" gorm:"default:null"`
+}
@@ -0,0 +1,14 @@
+package models
+
+import (
+	"time"
+)
+
+// TaskDependency says a task waits on another: the task with TaskID is
+// blocked until the one with BlockerID is completed.
+type TaskDependency struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"createdAt"`
+	TaskID    uint      `json:"taskId" gorm:"not null;uniqueIndex:idx_task_dependencies_pair"`
+	BlockerID uint      `json:"blockerId" gorm:"not null;uniqueIndex:idx_task_dependencies_pair;index"`
+}
@@ -0,0 +1,93 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// TaskPack is a named bundle of task templates, such as everything a water
+// heater needs or a fall checklist, that can be turned into tasks on an
+// appliance, a space or a whole property in one go. Packs are found by Key.
+// The server ships built-in packs; a saved pack with a built-in pack's key
+// takes its place.
+type TaskPack struct {
+	ID          uint          `json:"id,omitempty" gorm:"primaryKey"`
+	CreatedAt   time.Time     `json:"-"`
+	UpdatedAt   time.Time     `json:"-"`
+	Key         string        `json:"key" gorm:"not null;uniqueIndex"`
+	Name        string        `json:"name" gorm:"not null"`
+	Description string        `json:"description" gorm:"not null;default:''"`
+	Tasks       TaskTemplates `json:"tasks"`
+	// BuiltIn is set on packs that ship with the server and have not been
+	// replaced by a saved one.
+	BuiltIn bool `json:"builtIn" gorm:"-"`
+}
+
+// TaskTemplate is the definition of one task in a pack. ApplianceType and
+// Space say what the task is for: a template with an appliance type is only
+// used on appliances of that type, and applied to a whole property it goes
+// on each of them; applied to a property, a template with a space goes into
+// that space, created if needed.
+type TaskTemplate struct {
+	Label              string   `json:"label"`
+	Notes              string   `json:"notes,omitempty"`
+	Priority           string   `json:"priority,omitempty"`
+	EstimatedCost      *float64 `json:"estimatedCost,omitempty"`
+	IsRecurring        bool     `json:"isRecurring,omitempty"`
+	RecurrenceInterval int      `json:"recurrenceInterval,omitempty"`
+	RecurrenceUnit     string   `json:"recurrenceUnit,omitempty"`
+	RecurrenceMode     string   `json:"recurrenceMode,omitempty"`
+	RRule              string   `json:"rrule,omitempty"`
+	// DueInDays dates the first occurrence that many days after the pack is
+	// applied. Without it, a recurring task is first due on its first
+	// occurrence after that day, and a one-off task has no due date.
+	DueInDays     *int   `json:"dueInDays,omitempty"`
+	ApplianceType string `json:"applianceType,omitempty"`
+	Space         string `json:"space,omitempty"`
+}
+
+// TaskTemplates is stored as a JSON text column.
+type TaskTemplates []TaskTemplate
+
+// GormDataType stores the templates as text on every dialect.
+func (TaskTemplates) GormDataType() string {
+	return "text"
+}
+
+// Value implements driver.Valuer.
+func (t TaskTemplates) Value() (driver.Value, error) {
+	if t == nil {
+		t = TaskTemplates{}
+	}
+	data, err := json.Marshal(t)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan implements sql.Scanner.
+func (t *TaskTemplates) Scan(value any) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*t = nil
+		return nil
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		return fmt.Errorf("cannot scan %T into TaskTemplates", value)
+	}
+	return json.Unmarshal(data, t)
+}
+
+// TaskPackFile is the JSON file format task packs are exported and imported
+// in.
+type TaskPackFile struct {
+	Version int        `json:"version"`
+	Packs   []TaskPack `json:"packs"`
+}
@@ -0,0 +1,86 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Household roles, from most to least privileged.
+const (
+	RoleOwner  = "owner"  // everything, including backups and account management
+	RoleMember = "member" // create and edit records
+	RoleViewer = "viewer" // read-only
+)
+
+// ValidRole reports whether role is one of the household roles.
+func ValidRole(role string) bool {
+	return role == RoleOwner || role == RoleMember || role == RoleViewer
+}
+
+// User is a local HomeLogger account.
+type User struct {
+	gorm.Model
+	ID           uint   `json:"id" gorm:"primaryKey"`
+	Username     string `json:"username" gorm:"uniqueIndex;not null"`
+	DisplayName  string `json:"displayName" gorm:"not null;default:''"`
+	PasswordHash string `json:"-" gorm:"not null;default:''"`
+	Role         string `json:"role" gorm:"not null;default:'member'"`
+	// OIDCIssuer and OIDCSubject link the account to a single sign-on identity.
+	// Accounts provisioned through OIDC have no password and can only log in that way.
+	OIDCIssuer  *string `json:"oidcIssuer" gorm:"column:oidc_issuer;uniqueIndex:idx_users_oidc;default:null"`
+	OIDCSubject *string `json:"oidcSubject" gorm:"column:oidc_subject;uniqueIndex:idx_users_oidc;default:null"`
+}
+
+// Session is a login session. Only the SHA-256 hash of the token is stored.
+type Session struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	TokenHash string    `json:"-" gorm:"uniqueIndex;not null"`
+	UserID    uint      `json:"userId" gorm:"not null;index"`
+	User      User      `json:"-" gorm:"foreignKey:UserID;references:ID"`
+	CreatedAt time.Time `json:"createdAt"`
+	ExpiresAt time.Time `json:"expiresAt" gorm:"not null"`
+}
+
+// APITokenPrefix marks personal API tokens so they can be told apart from session tokens.
+const APITokenPrefix = "hlt_"
+
+// TokenScopeResources are the resources a personal API token can be scoped to.
+// "*" covers every API route; a ":read" suffix limits a scope to read-only requests.
+var TokenScopeResources = []string{"*", "tasks", "maintenance", "repairs", "appliances", "notes", "files", "meters"}
+
+// ValidTokenScope reports whether scope is a resource from TokenScopeResources,
+// optionally suffixed with ":read".
+func ValidTokenScope(scope string) bool {
+	resource := strings.TrimSuffix(scope, ":read")
+	for _, r := range TokenScopeResources {
+		if r == resource {
+			return true
+		}
+	}
+	return false
+}
+
+// APIToken is a long-lived, revocable personal token for scripts and home automation.
+// Only the SHA-256 hash of the token is stored; Prefix keeps a few characters for display.
+type APIToken struct {
+	gorm.Model
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	UserID     uint       `json:"userId" gorm:"not null;index"`
+	User       User       `json:"-" gorm:"foreignKey:UserID;references:ID"`
+	Name       string     `json:"name" gorm:"not null;default:''"`
+	TokenHash  string     `json:"-" gorm:"uniqueIndex;not null"`
+	Prefix     string     `json:"prefix" gorm:"not null;default:''"`
+	Scopes     string     `json:"scopes" gorm:"not null;default:''"` // comma-separated
+	ExpiresAt  *time.Time `json:"expiresAt" gorm:"default:null"`
+	LastUsedAt *time.Time `json:"lastUsedAt" gorm:"default:null"`
+}
+
+// ScopeList returns the token's scopes as a slice.
+func (t *APIToken) ScopeList() []string {
+	if t.Scopes == "" {
+		return nil
+	}
+	return strings.Split(t.Scopes, ",")
+}
@@ -0,0 +1,484 @@
+// Package rrule parses the date-based part of RFC 5545 recurrence rules, as
+// used for task schedules, and finds their occurrences. Tasks are due on days
+// rather than at times, so the rule parts that work below a day (FREQ=HOURLY,
+// BYHOUR and so on) are rejected, as are the rarely used BYYEARDAY and
+// BYWEEKNO.
+package rrule
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Frequency is a rule's FREQ.
+type Frequency int
+
+const (
+	Daily Frequency = iota
+	Weekly
+	Monthly
+	Yearly
+)
+
+var frequencyNames = map[string]Frequency{"DAILY": Daily, "WEEKLY": Weekly, "MONTHLY": Monthly, "YEARLY": Yearly}
+
+func (f Frequency) String() string {
+	return [...]string{"DAILY", "WEEKLY", "MONTHLY", "YEARLY"}[f]
+}
+
+var weekdayNames = []string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+// WeekdayNum is a BYDAY entry: a weekday, optionally the Nth (or, when
+// negative, Nth from last) of its month or year.
+type WeekdayNum struct {
+	Weekday time.Weekday
+	N       int
+}
+
+func (w WeekdayNum) String() string {
+	if w.N == 0 {
+		return weekdayNames[w.Weekday]
+	}
+	return strconv.Itoa(w.N) + weekdayNames[w.Weekday]
+}
+
+// Rule is a parsed recurrence rule. Until is a day; Count and Until are
+// zero when the rule goes on forever.
+type Rule struct {
+	Freq       Frequency
+	Interval   int
+	Count      int
+	Until      time.Time
+	ByDay      []WeekdayNum
+	ByMonthDay []int
+	ByMonth    []int
+	BySetPos   []int
+	WeekStart  time.Weekday
+}
+
+// Parse reads a rule such as "FREQ=YEARLY;BYMONTH=4,10;BYDAY=2SA". A leading
+// "RRULE:" is allowed.
+func Parse(s string) (*Rule, error) {
+	s = strings.TrimSpace(s)
+	if len(s) >= 6 && strings.EqualFold(s[:6], "RRULE:") {
+		s = s[6:]
+	}
+	if s == "" {
+		return nil, fmt.Errorf("empty rule")
+	}
+	rule := &Rule{Interval: 1, WeekStart: time.Monday}
+	seen := map[string]bool{}
+	hasFreq := false
+	for _, part := range strings.Split(s, ";") {
+		name, value, ok := strings.Cut(part, "=")
+		name = strings.ToUpper(strings.TrimSpace(name))
+		value = strings.ToUpper(strings.TrimSpace(value))
+		if !ok || value == "" {
+			return nil, fmt.Errorf("%q is not NAME=VALUE", part)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("%s is given twice", name)
+		}
+		seen[name] = true
+
+		var err error
+		switch name {
+		case "FREQ":
+			freq, known := frequencyNames[value]
+			if !known {
+				return nil, fmt.Errorf("FREQ must be DAILY, WEEKLY, MONTHLY or YEARLY")
+			}
+			rule.Freq, hasFreq = freq, true
+		case "INTERVAL":
+			rule.Interval, err = parseNumber(name, value, 1, 1000)
+		case "COUNT":
+			rule.Count, err = parseNumber(name, value, 1, 100000)
+		case "UNTIL":
+			rule.Until, err = parseUntil(value)
+		case "BYDAY":
+			rule.ByDay, err = parseByDay(value)
+		case "BYMONTHDAY":
+			rule.ByMonthDay, err = parseList(name, value, 1, 31, true)
+		case "BYMONTH":
+			rule.ByMonth, err = parseList(name, value, 1, 12, false)
+		case "BYSETPOS":
+			rule.BySetPos, err = parseList(name, value, 1, 366, true)
+		case "WKST":
+			day := slices.Index(weekdayNames, value)
+			if day < 0 {
+				return nil, fmt.Errorf("WKST must be a weekday such as MO")
+			}
+			rule.WeekStart = time.Weekday(day)
+		default:
+			return nil, fmt.Errorf("%s is not supported; schedules repeat on days", name)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if !hasFreq {
+		return nil, fmt.Errorf("FREQ is required")
+	}
+	if rule.Count > 0 && !rule.Until.IsZero() {
+		return nil, fmt.Errorf("COUNT and UNTIL cannot both be given")
+	}
+	if rule.Freq == Weekly && len(rule.ByMonthDay) > 0 {
+		return nil, fmt.Errorf("BYMONTHDAY cannot be used with FREQ=WEEKLY")
+	}
+	for _, day := range rule.ByDay {
+		if day.N != 0 && (rule.Freq == Daily || rule.Freq == Weekly) {
+			return nil, fmt.Errorf("numbered BYDAY such as 2SA needs FREQ=MONTHLY or YEARLY")
+		}
+		if day.N != 0 && rule.Freq == Monthly && (day.N > 5 || day.N < -5) {
+			return nil, fmt.Errorf("a month has at most 5 of each weekday")
+		}
+	}
+	if len(rule.BySetPos) > 0 && len(rule.ByDay) == 0 && len(rule.ByMonthDay) == 0 && len(rule.ByMonth) == 0 {
+		return nil, fmt.Errorf("BYSETPOS needs another BY rule part")
+	}
+	forever := *rule
+	forever.Until = time.Time{}
+	if epoch := time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC); !forever.matchesAny(epoch) {
+		return nil, fmt.Errorf("the rule never falls on a day")
+	}
+	return rule, nil
+}
+
+func parseNumber(name, value string, lowest, highest int) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < lowest || n > highest {
+		return 0, fmt.Errorf("%s must be a number from %d to %d", name, lowest, highest)
+	}
+	return n, nil
+}
+
+// parseList reads a comma-separated list of numbers from lowest to highest,
+// or down to -highest when negative counts from the end.
+func parseList(name, value string, lowest, highest int, negative bool) ([]int, error) {
+	var list []int
+	for _, item := range strings.Split(value, ",") {
+		n, err := strconv.Atoi(item)
+		if err != nil || n == 0 || n > highest || (n < 0 && (!negative || n < -highest)) || (n > 0 && n < lowest) {
+			return nil, fmt.Errorf("%s has an invalid value %q", name, item)
+		}
+		list = append(list, n)
+	}
+	return list, nil
+}
+
+func parseByDay(value string) ([]WeekdayNum, error) {
+	var days []WeekdayNum
+	for _, item := range strings.Split(value, ",") {
+		if len(item) < 2 {
+			return nil, fmt.Errorf("BYDAY has an invalid value %q", item)
+		}
+		day := slices.Index(weekdayNames, item[len(item)-2:])
+		if day < 0 {
+			return nil, fmt.Errorf("BYDAY has an invalid value %q", item)
+		}
+		entry := WeekdayNum{Weekday: time.Weekday(day)}
+		if prefix := item[:len(item)-2]; prefix != "" {
+			n, err := strconv.Atoi(prefix)
+			if err != nil || n == 0 || n > 53 || n < -53 {
+				return nil, fmt.Errorf("BYDAY has an invalid value %q", item)
+			}
+			entry.N = n
+		}
+		days = append(days, entry)
+	}
+	return days, nil
+}
+
+// parseUntil accepts a date (20261231) or a date-time (20261231T235959Z);
+// only the day is kept.
+func parseUntil(value string) (time.Time, error) {
+	date, _, _ := strings.Cut(value, "T")
+	day, err := time.Parse("20060102", date)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("UNTIL must be a date such as 20261231")
+	}
+	return day, nil
+}
+
+// String formats the rule in a canonical order, without the "RRULE:" prefix.
+func (r *Rule) String() string {
+	parts := []string{"FREQ=" + r.Freq.String()}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+r.Until.Format("20060102"))
+	}
+	if len(r.ByMonth) > 0 {
+		parts = append(parts, "BYMONTH="+joinInts(r.ByMonth))
+	}
+	if len(r.ByMonthDay) > 0 {
+		parts = append(parts, "BYMONTHDAY="+joinInts(r.ByMonthDay))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, len(r.ByDay))
+		for i, day := range r.ByDay {
+			days[i] = day.String()
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(r.BySetPos) > 0 {
+		parts = append(parts, "BYSETPOS="+joinInts(r.BySetPos))
+	}
+	if r.WeekStart != time.Monday {
+		parts = append(parts, "WKST="+weekdayNames[r.WeekStart])
+	}
+	return strings.Join(parts, ";")
+}
+
+func joinInts(list []int) string {
+	items := make([]string, len(list))
+	for i, n := range list {
+		items[i] = strconv.Itoa(n)
+	}
+	return strings.Join(items, ",")
+}
+
+var intervalUnits = map[string]Frequency{"days": Daily, "weeks": Weekly, "months": Monthly, "years": Yearly}
+
+// FromInterval writes the rule for the older interval schedule: every
+// interval days, weeks, months or years. Unknown units are months, as they
+// always were.
+func FromInterval(interval int, unit string) string {
+	freq, known := intervalUnits[unit]
+	if !known {
+		freq = Monthly
+	}
+	rule := Rule{Freq: freq, Interval: max(interval, 1), WeekStart: time.Monday}
+	return rule.String()
+}
+
+// maxPeriods bounds the search for an occurrence, so a rule that can never
+// match (BYMONTH=2;BYMONTHDAY=30) gives up rather than looping forever.
+const maxPeriods = 5000
+
+// Next returns the first occurrence of the rule after the given day, for the
+// series that starts on start. It reports false when the series ends (by
+// UNTIL) first. COUNT is not applied here; callers know how many
+// occurrences have gone by.
+func (r *Rule) Next(start, after time.Time) (time.Time, bool) {
+	start, after = day(start), day(after)
+	first := r.periodOf(start, after)
+	for period := first; period < first+maxPeriods; period++ {
+		for _, candidate := range r.occurrences(start, period) {
+			if !r.Until.IsZero() && candidate.After(r.Until) {
+				return time.Time{}, false
+			}
+			if candidate.Before(start) || !candidate.After(after) {
+				continue
+			}
+			return candidate, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// periodOf returns the period of the series from start that after falls in,
+// less one, so a series that started long ago is searched from near after
+// rather than from its start. Earlier periods only have earlier days.
+func (r *Rule) periodOf(start, after time.Time) int {
+	var periods int
+	switch r.Freq {
+	case Daily:
+		periods = int(after.Sub(start).Hours()/24) / r.Interval
+	case Weekly:
+		weekStart := start.AddDate(0, 0, -int((start.Weekday()-r.WeekStart+7)%7))
+		periods = int(after.Sub(weekStart).Hours()/24) / 7 / r.Interval
+	case Monthly:
+		periods = ((after.Year()-start.Year())*12 + int(after.Month()-start.Month())) / r.Interval
+	case Yearly:
+		periods = (after.Year() - start.Year()) / r.Interval
+	}
+	return max(periods-1, 0)
+}
+
+// Calendar reports whether the rule picks its days from the calendar, with
+// any BY part, rather than only stepping on by its interval.
+func (r *Rule) Calendar() bool {
+	return len(r.ByDay) > 0 || len(r.ByMonthDay) > 0 || len(r.ByMonth) > 0 || len(r.BySetPos) > 0
+}
+
+// matchesAny reports whether the series from start has any occurrence at
+// all. Every period after the first is searched from its start.
+func (r *Rule) matchesAny(start time.Time) bool {
+	_, ok := r.Next(start, start.AddDate(0, 0, -1))
+	return ok
+}
+
+func day(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// occurrences lists, in order, the days of the series in its period'th
+// period (day, week, month or year, stepped by the interval) from start.
+func (r *Rule) occurrences(start time.Time, period int) []time.Time {
+	var days []time.Time
+	switch r.Freq {
+	case Daily:
+		d := start.AddDate(0, 0, period*r.Interval)
+		if r.matchesMonth(d) && r.matchesMonthDay(d) && r.matchesWeekday(d) {
+			days = append(days, d)
+		}
+	case Weekly:
+		weekStart := start.AddDate(0, 0, -int((start.Weekday()-r.WeekStart+7)%7)+7*period*r.Interval)
+		for i := 0; i < 7; i++ {
+			d := weekStart.AddDate(0, 0, i)
+			if !r.matchesMonth(d) {
+				continue
+			}
+			if len(r.ByDay) == 0 && d.Weekday() == start.Weekday() || len(r.ByDay) > 0 && r.matchesWeekday(d) {
+				days = append(days, d)
+			}
+		}
+	case Monthly:
+		first := time.Date(start.Year(), start.Month()+time.Month(period*r.Interval), 1, 0, 0, 0, 0, time.UTC)
+		if r.matchesMonth(first) {
+			days = r.monthDays(first, start.Day())
+		}
+	case Yearly:
+		year := start.Year() + period*r.Interval
+		days = r.yearDays(year, start)
+	}
+	return r.setPositions(days)
+}
+
+func (r *Rule) matchesMonth(d time.Time) bool {
+	return len(r.ByMonth) == 0 || slices.Contains(r.ByMonth, int(d.Month()))
+}
+
+func (r *Rule) matchesMonthDay(d time.Time) bool {
+	if len(r.ByMonthDay) == 0 {
+		return true
+	}
+	last := daysIn(d.Year(), d.Month())
+	for _, n := range r.ByMonthDay {
+		if n == d.Day() || n < 0 && last+n+1 == d.Day() {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesWeekday checks the unnumbered BYDAY entries.
+func (r *Rule) matchesWeekday(d time.Time) bool {
+	if len(r.ByDay) == 0 {
+		return true
+	}
+	for _, entry := range r.ByDay {
+		if entry.N == 0 && entry.Weekday == d.Weekday() {
+			return true
+		}
+	}
+	return false
+}
+
+func daysIn(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// monthDays expands one month. Without BYMONTHDAY or BYDAY the series falls
+// on the start's day of the month, and skips months too short for it.
+func (r *Rule) monthDays(first time.Time, startDay int) []time.Time {
+	last := daysIn(first.Year(), first.Month())
+	var days []time.Time
+	for n := 1; n <= last; n++ {
+		d := first.AddDate(0, 0, n-1)
+		switch {
+		case len(r.ByMonthDay) == 0 && len(r.ByDay) == 0:
+			if n == startDay {
+				days = append(days, d)
+			}
+		case len(r.ByDay) == 0:
+			if r.matchesMonthDay(d) {
+				days = append(days, d)
+			}
+		default:
+			if r.matchesMonthDay(d) && r.matchesNumberedWeekday(d, n, last) {
+				days = append(days, d)
+			}
+		}
+	}
+	return days
+}
+
+// matchesNumberedWeekday checks BYDAY entries, numbered ones counting within
+// a span (a month or a year) in which d is day n of last.
+func (r *Rule) matchesNumberedWeekday(d time.Time, n, last int) bool {
+	for _, entry := range r.ByDay {
+		if entry.Weekday != d.Weekday() {
+			continue
+		}
+		if entry.N == 0 ||
+			entry.N > 0 && (n-1)/7+1 == entry.N ||
+			entry.N < 0 && (last-n)/7+1 == -entry.N {
+			return true
+		}
+	}
+	return false
+}
+
+// yearDays expands one year. With BYMONTH each listed month is expanded like
+// a monthly rule; otherwise BYMONTHDAY applies to every month and numbered
+// BYDAY entries count through the whole year. Without any of them the series
+// falls on the start's month and day.
+func (r *Rule) yearDays(year int, start time.Time) []time.Time {
+	var days []time.Time
+	switch {
+	case len(r.ByMonth) > 0:
+		for _, month := range r.ByMonth {
+			days = append(days, r.monthDays(time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC), start.Day())...)
+		}
+	case len(r.ByMonthDay) > 0:
+		for month := time.January; month <= time.December; month++ {
+			first := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+			days = append(days, r.monthDays(first, start.Day())...)
+		}
+	case len(r.ByDay) > 0:
+		first := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+		last := first.AddDate(1, 0, -1).YearDay()
+		for n := 1; n <= last; n++ {
+			d := first.AddDate(0, 0, n-1)
+			if r.matchesNumberedWeekday(d, n, last) {
+				days = append(days, d)
+			}
+		}
+	default:
+		d := time.Date(year, start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+		// Feb 29 only comes round in leap years.
+		if d.Month() == start.Month() {
+			days = append(days, d)
+		}
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i].Before(days[j]) })
+	return slices.CompactFunc(days, func(a, b time.Time) bool { return a.Equal(b) })
+}
+
+// setPositions keeps the BYSETPOS-th days of a period, in order.
+func (r *Rule) setPositions(days []time.Time) []time.Time {
+	if len(r.BySetPos) == 0 || len(days) == 0 {
+		return days
+	}
+	var kept []time.Time
+	for i, d := range days {
+		for _, pos := range r.BySetPos {
+			if pos > 0 && pos-1 == i || pos < 0 && len(days)+pos == i {
+				kept = append(kept, d)
+				break
+			}
+		}
+	}
+	return kept
+}
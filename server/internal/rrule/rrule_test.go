@@ -0,0 +1,79 @@
+package rrule
+
+import (
+	"testing"
+	"time"
+)
+
+func date(s string) time.Time {
+	d, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func TestNext(t *testing.T) {
+	cases := []struct {
+		name, rule, start, after string
+		want                     []string // successive occurrences; "" when the series ends
+	}{
+		{"every three months", "FREQ=MONTHLY;INTERVAL=3", "2026-01-31", "2026-01-31", []string{"2026-07-31", "2026-10-31"}},
+		{"second Saturday of April and October", "FREQ=YEARLY;BYMONTH=4,10;BYDAY=2SA", "2026-01-01", "2026-01-01", []string{"2026-04-11", "2026-10-10", "2027-04-10"}},
+		{"every weekday", "FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR", "2026-10-16", "2026-10-16", []string{"2026-10-19", "2026-10-20"}},
+		{"last day of each quarter", "FREQ=YEARLY;BYMONTH=3,6,9,12;BYMONTHDAY=-1", "2026-01-15", "2026-01-15", []string{"2026-03-31", "2026-06-30", "2026-09-30", "2026-12-31"}},
+		{"last weekday of the month", "FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1", "2026-01-01", "2026-01-01", []string{"2026-01-30", "2026-02-27"}},
+		{"every other week on Monday and Thursday", "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH", "2026-10-05", "2026-10-05", []string{"2026-10-08", "2026-10-19", "2026-10-22"}},
+		{"leap day", "FREQ=YEARLY", "2024-02-29", "2024-02-29", []string{"2028-02-29"}},
+		{"until", "FREQ=DAILY;INTERVAL=10;UNTIL=20260125", "2026-01-01", "2026-01-01", []string{"2026-01-11", "2026-01-21", ""}},
+		{"every other month from the start", "FREQ=MONTHLY;INTERVAL=2;BYDAY=2SA", "2026-01-10", "2026-02-03", []string{"2026-03-14", "2026-05-09"}},
+		{"long after the start", "FREQ=DAILY;BYDAY=SA", "1990-01-01", "2026-10-16", []string{"2026-10-17", "2026-10-24"}},
+		{"every other week long after the start", "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO", "2000-01-03", "2026-10-14", []string{"2026-10-19", "2026-11-02"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rule, err := Parse(tc.rule)
+			if err != nil {
+				t.Fatal(err)
+			}
+			start, after := date(tc.start), date(tc.after)
+			for _, want := range tc.want {
+				next, ok := rule.Next(start, after)
+				if want == "" {
+					if ok {
+						t.Fatalf("expected the series to end, got %s", next.Format("2006-01-02"))
+					}
+					return
+				}
+				if !ok || !next.Equal(date(want)) {
+					t.Fatalf("expected %s, got %s (%v)", want, next.Format("2006-01-02"), ok)
+				}
+				after = next
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	rule, err := Parse("rrule:freq=monthly;byday=-1fr;interval=2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := rule.String(); got != "FREQ=MONTHLY;INTERVAL=2;BYDAY=-1FR" {
+		t.Fatalf("unexpected canonical form %q", got)
+	}
+	for _, bad := range []string{
+		"", "INTERVAL=2", "FREQ=HOURLY", "FREQ=DAILY;BYHOUR=9", "FREQ=WEEKLY;BYDAY=2MO",
+		"FREQ=DAILY;COUNT=3;UNTIL=20270101", "FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=30", "FREQ=DAILY;FREQ=WEEKLY",
+	} {
+		if _, err := Parse(bad); err == nil {
+			t.Errorf("expected %q to be rejected", bad)
+		}
+	}
+	if got := FromInterval(2, "weeks"); got != "FREQ=WEEKLY;INTERVAL=2" {
+		t.Fatalf("unexpected rule %q", got)
+	}
+	if got := FromInterval(1, "fortnights"); got != "FREQ=MONTHLY" {
+		t.Fatalf("expected unknown units to be months, got %q", got)
+	}
+}
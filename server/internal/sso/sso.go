@@ -0,0 +1,203 @@
+package sso
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/masoncfrancis/homelogger/server/internal/models"
+	"golang.org/x/oauth2"
+)
+
+// Config holds the OIDC_* settings.
+type Config struct {
+	IssuerURL     string
+	ClientID      string
+	ClientSecret  string
+	RedirectURL   string
+	Scopes        []string
+	UsernameClaim string
+	AutoProvision bool
+	DefaultRole   string
+	ProviderName  string
+	// PostLoginRedirect is where the browser is sent after a successful login.
+	PostLoginRedirect string
+}
+
+// Enabled reports whether enough settings are present to offer OIDC login.
+func (c Config) Enabled() bool {
+	return c.IssuerURL != "" && c.ClientID != "" && c.RedirectURL != ""
+}
+
+func envBool(name string) bool {
+	v := strings.ToLower(strings.TrimSpace(os.Getenv(name)))
+	return v == "1" || v == "true"
+}
+
+// ConfigFromEnv reads the OIDC_* environment variables.
+func ConfigFromEnv() Config {
+	cfg := Config{
+		IssuerURL:         strings.TrimSpace(os.Getenv("OIDC_ISSUER_URL")),
+		ClientID:          strings.TrimSpace(os.Getenv("OIDC_CLIENT_ID")),
+		ClientSecret:      os.Getenv("OIDC_CLIENT_SECRET"),
+		RedirectURL:       strings.TrimSpace(os.Getenv("OIDC_REDIRECT_URL")),
+		Scopes:            []string{oidc.ScopeOpenID, "profile", "email"},
+		UsernameClaim:     "preferred_username",
+		AutoProvision:     envBool("OIDC_AUTO_PROVISION"),
+		DefaultRole:       models.RoleMember,
+		ProviderName:      "SSO",
+		PostLoginRedirect: "/",
+	}
+	if raw := strings.TrimSpace(os.Getenv("OIDC_SCOPES")); raw != "" {
+		cfg.Scopes = strings.Fields(strings.ReplaceAll(raw, ",", " "))
+	}
+	if claim := strings.TrimSpace(os.Getenv("OIDC_USERNAME_CLAIM")); claim != "" {
+		cfg.UsernameClaim = claim
+	}
+	if role := strings.TrimSpace(os.Getenv("OIDC_DEFAULT_ROLE")); role != "" {
+		if models.ValidRole(role) {
+			cfg.DefaultRole = role
+		} else {
+			fmt.Printf("Warning: invalid OIDC_DEFAULT_ROLE %q, using %s\n", role, models.RoleMember)
+		}
+	}
+	if name := strings.TrimSpace(os.Getenv("OIDC_PROVIDER_NAME")); name != "" {
+		cfg.ProviderName = name
+	}
+	if redirect := strings.TrimSpace(os.Getenv("OIDC_POST_LOGIN_REDIRECT")); redirect != "" {
+		cfg.PostLoginRedirect = redirect
+	}
+	return cfg
+}
+
+// Identity is the verified user information from an ID token.
+type Identity struct {
+	Issuer      string
+	Subject     string
+	Username    string
+	DisplayName string
+	Email       string
+}
+
+// Provider talks to an OIDC issuer. Discovery is done lazily on first use so
+// that an unreachable issuer at startup does not stop the server.
+type Provider struct {
+	cfg Config
+
+	mu       sync.Mutex
+	oauth    *oauth2.Config
+	verifier *oidc.IDTokenVerifier
+}
+
+// NewProvider returns a Provider for cfg.
+func NewProvider(cfg Config) *Provider {
+	return &Provider{cfg: cfg}
+}
+
+// Config returns the provider's settings.
+func (p *Provider) Config() Config {
+	return p.cfg
+}
+
+func (p *Provider) discover(ctx context.Context) (*oauth2.Config, *oidc.IDTokenVerifier, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.oauth != nil {
+		return p.oauth, p.verifier, nil
+	}
+
+	provider, err := oidc.NewProvider(ctx, p.cfg.IssuerURL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("discover OIDC issuer: %w", err)
+	}
+	p.oauth = &oauth2.Config{
+		ClientID:     p.cfg.ClientID,
+		ClientSecret: p.cfg.ClientSecret,
+		RedirectURL:  p.cfg.RedirectURL,
+		Endpoint:     provider.Endpoint(),
+		Scopes:       p.cfg.Scopes,
+	}
+	p.verifier = provider.Verifier(&oidc.Config{ClientID: p.cfg.ClientID})
+	return p.oauth, p.verifier, nil
+}
+
+// AuthCodeURL returns the issuer's authorization URL for an authorization-code
+// flow with PKCE. state, nonce and verifier must be kept for the callback.
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	oauth, _, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	return oauth.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier)), nil
+}
+
+// Exchange trades an authorization code for tokens and verifies the ID token.
+func (p *Provider) Exchange(ctx context.Context, code, nonce, verifier string) (*Identity, error) {
+	oauth, idVerifier, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := oauth.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return nil, fmt.Errorf("exchange code: %w", err)
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok || rawIDToken == "" {
+		return nil, errors.New("token response has no id_token")
+	}
+	idToken, err := idVerifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("verify id_token: %w", err)
+	}
+	if idToken.Nonce != nonce {
+		return nil, errors.New("id_token nonce mismatch")
+	}
+
+	var claims map[string]interface{}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("decode claims: %w", err)
+	}
+	claim := func(name string) string {
+		s, _ := claims[name].(string)
+		return s
+	}
+
+	identity := &Identity{
+		Issuer:      idToken.Issuer,
+		Subject:     idToken.Subject,
+		DisplayName: claim("name"),
+		Email:       claim("email"),
+	}
+	for _, name := range []string{p.cfg.UsernameClaim, "preferred_username", "email"} {
+		if v := claim(name); v != "" {
+			identity.Username = v
+			break
+		}
+	}
+	if identity.Username == "" {
+		identity.Username = identity.Subject
+	}
+	return identity, nil
+}
+
+// RandomString returns a random hex string for state and nonce values.
+func RandomString() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// GenerateVerifier returns a PKCE code verifier.
+func GenerateVerifier() string {
+	return oauth2.GenerateVerifier()
+}
@@ -0,0 +1,10 @@
+// Package server holds files that live at the root of the server module and
+// are compiled into the binary.
+package server
+
+import _ "embed"
+
+// OpenAPISpec is openapi.yaml, the OpenAPI 3 description of the HTTP API.
+//
+//go:embed openapi.yaml
+var OpenAPISpec []byte